@@ -0,0 +1,234 @@
+// juicemud-admin performs maintenance on the database of a juicemud server.
+//
+// The server has to be stopped while running it, since it needs exclusive access to the
+// database files. Build it with `go build -o juicemud-admin ./admin`.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/mapping"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+)
+
+type subcommand struct {
+	description string
+	run         func(ctx context.Context, store *storage.Storage, args []string) error
+}
+
+var (
+	subcommands = map[string]subcommand{
+		"compact": {
+			description: "Rewrites the databases to reclaim space, and reports (or repairs) orphaned objects.",
+			run: func(ctx context.Context, store *storage.Storage, args []string) error {
+				flags := flag.NewFlagSet("compact", flag.ExitOnError)
+				repair := flags.Bool("repair", false, "Move orphaned objects to -repair_location")
+				repairLocation := flags.String("repair_location", "genesis", "Where to move orphaned objects when repairing")
+				if err := flags.Parse(args); err != nil {
+					return juicemud.WithStack(err)
+				}
+				orphans, err := store.FindOrphans(ctx)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				for _, orphan := range orphans {
+					if *repair {
+						if err := store.RepairOrphan(ctx, orphan.Object, *repairLocation); err != nil {
+							return juicemud.WithStack(err)
+						}
+						fmt.Printf("%q was located in missing %q, moved to %q\n", orphan.Object, orphan.Location, *repairLocation)
+					} else {
+						fmt.Printf("%q is located in missing %q\n", orphan.Object, orphan.Location)
+					}
+				}
+				if err := store.Compact(ctx); err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Println("Compacted")
+				return nil
+			},
+		},
+		"map": {
+			description: "Renders the room graph around a room as DOT, or as PNG if Graphviz `dot` is installed.",
+			run: func(ctx context.Context, store *storage.Storage, args []string) error {
+				flags := flag.NewFlagSet("map", flag.ExitOnError)
+				center := flags.String("center", "genesis", "The room to center the map on")
+				radius := flags.Int("radius", 5, "How many exits away from -center to include rooms")
+				format := flags.String("format", "dot", "Output format, dot or png")
+				out := flags.String("out", "", "Output file, stdout if empty")
+				if err := flags.Parse(args); err != nil {
+					return juicemud.WithStack(err)
+				}
+				graph, err := mapping.Build(*center, *radius, func(ids map[string]bool) (map[string]*structs.Object, error) {
+					return store.LoadObjects(ctx, ids, nil)
+				})
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				var output io.Writer = os.Stdout
+				if *out != "" {
+					f, err := os.Create(*out)
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					defer f.Close()
+					output = f
+				}
+				switch *format {
+				case "dot":
+					_, err := io.WriteString(output, graph.DOT())
+					return juicemud.WithStack(err)
+				case "png":
+					cmd := exec.CommandContext(ctx, "dot", "-Tpng")
+					cmd.Stdin = strings.NewReader(graph.DOT())
+					cmd.Stdout = output
+					cmd.Stderr = os.Stderr
+					return juicemud.WithStack(cmd.Run())
+				}
+				return errors.Errorf("unknown format %q", *format)
+			},
+		},
+		"recording": {
+			description: "Lists the session recordings of a user, or exports one in asciicast v2 format for asciinema.",
+			run: func(ctx context.Context, store *storage.Storage, args []string) error {
+				flags := flag.NewFlagSet("recording", flag.ExitOnError)
+				user := flags.String("user", "", "The user to list the recordings of")
+				id := flags.Int64("id", 0, "The recording to export")
+				out := flags.String("out", "", "Output file, stdout if empty")
+				if err := flags.Parse(args); err != nil {
+					return juicemud.WithStack(err)
+				}
+				if *id == 0 {
+					if *user == "" {
+						return errors.New("either -user or -id is required")
+					}
+					u, err := store.LoadUser(ctx, *user)
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					recordings, err := store.Recordings(ctx, u)
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					for _, recording := range recordings {
+						fmt.Printf("%v\t%s\t%vx%v\n", recording.Id, time.Unix(0, recording.Started).Format(time.DateTime), recording.Width, recording.Height)
+					}
+					return nil
+				}
+				recording, err := store.LoadRecording(ctx, *id)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				frames, err := store.RecordingFrames(ctx, *id)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				var output io.Writer = os.Stdout
+				if *out != "" {
+					f, err := os.Create(*out)
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					defer f.Close()
+					output = f
+				}
+				// Sessions without a pty didn't report a size, so assume the classic one.
+				width, height := recording.Width, recording.Height
+				if width == 0 || height == 0 {
+					width, height = 80, 24
+				}
+				encoder := json.NewEncoder(output)
+				if err := encoder.Encode(map[string]any{
+					"version":   2,
+					"width":     width,
+					"height":    height,
+					"timestamp": recording.Started / int64(time.Second),
+				}); err != nil {
+					return juicemud.WithStack(err)
+				}
+				for _, frame := range frames {
+					if err := encoder.Encode([]any{float64(frame.At-recording.Started) / float64(time.Second), "o", frame.Output}); err != nil {
+						return juicemud.WithStack(err)
+					}
+				}
+				return nil
+			},
+		},
+		"fsck": {
+			description: "Validates the referential integrity of the world, and optionally repairs what it can.",
+			run: func(ctx context.Context, store *storage.Storage, args []string) error {
+				flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+				repair := flags.Bool("repair", false, "Repair the problems found")
+				repairLocation := flags.String("repair_location", "genesis", "Where to move objects without valid location when repairing")
+				if err := flags.Parse(args); err != nil {
+					return juicemud.WithStack(err)
+				}
+				problems, err := store.Fsck(ctx, *repair, *repairLocation)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				for _, problem := range problems {
+					fmt.Println(problem)
+				}
+				fmt.Printf("%d problems found\n", len(problems))
+				return nil
+			},
+		},
+	}
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [subcommand] [subcommand flags]\n\nSubcommands:\n", os.Args[0])
+	names := sort.StringSlice{}
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n    \t%s\n", name, subcommands[name].description)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dir := flag.String("dir", filepath.Join(os.Getenv("HOME"), ".juicemud"), "Where the database and settings are saved")
+	flag.Usage = usage
+
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	cmd, found := subcommands[flag.Arg(0)]
+	if !found {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	ctx := juicemud.MakeMainContext(context.Background())
+	store, err := storage.New(ctx, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	if err := cmd.run(ctx, store, flag.Args()[1:]); err != nil {
+		log.Println(juicemud.StackTrace(err))
+		log.Fatal(err)
+	}
+}
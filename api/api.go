@@ -0,0 +1,207 @@
+// api is a JSON API over HTTPS for dashboards and automation, authenticated by API tokens
+// belonging to wizards.
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/storage/dbm"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	// Must be the same as the wizards group of the game.
+	wizardsGroup = "wizards"
+	// Sources larger than this are rejected.
+	maxSourceSize = 1 << 20
+)
+
+type API struct {
+	storage *storage.Storage
+	mux     *http.ServeMux
+}
+
+func New(storage *storage.Storage) *API {
+	a := &API{
+		storage: storage,
+		mux:     http.NewServeMux(),
+	}
+	a.mux.HandleFunc("GET /api/users", a.users)
+	a.mux.HandleFunc("GET /api/objects/{id}", a.object)
+	a.mux.HandleFunc("GET /api/sources/{path...}", a.getSource)
+	a.mux.HandleFunc("PUT /api/sources/{path...}", a.putSource)
+	a.mux.HandleFunc("GET /api/stats", a.stats)
+	return a
+}
+
+// ServeHTTP serves requests with an `Authorization: Bearer <token>` header for a token
+// belonging to a wizard, as that wizard.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	secret, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || secret == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	user, err := a.storage.LoadAPITokenUser(ctx, secret)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("\t(no such token)")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		a.fail(w, err)
+		return
+	}
+	if isWizard, err := a.storage.UserAccessToGroup(ctx, user, wizardsGroup); err != nil {
+		a.fail(w, err)
+		return
+	} else if !isWizard {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	a.mux.ServeHTTP(w, r.WithContext(storage.AuthenticateUser(ctx, user)))
+}
+
+func (a *API) fail(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, os.ErrPermission):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		log.Println(juicemud.StackTrace(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (a *API) respond(w http.ResponseWriter, value any) {
+	b, err := goccy.Marshal(value)
+	if err != nil {
+		a.fail(w, juicemud.WithStack(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(b); err != nil {
+		log.Printf("trying to write response: %v", err)
+	}
+}
+
+type user struct {
+	Id     int64
+	Name   string
+	Owner  bool
+	Object string
+}
+
+func (a *API) users(w http.ResponseWriter, r *http.Request) {
+	users, err := a.storage.Users(r.Context())
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	result := make([]user, 0, len(users))
+	for _, u := range users {
+		result = append(result, user{
+			Id:     u.Id,
+			Name:   u.Name,
+			Owner:  u.Owner,
+			Object: u.Object,
+		})
+	}
+	a.respond(w, result)
+}
+
+func (a *API) object(w http.ResponseWriter, r *http.Request) {
+	object, err := a.storage.LoadObject(r.Context(), r.PathValue("id"), nil)
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	a.respond(w, object)
+}
+
+func (a *API) getSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := "/" + r.PathValue("path")
+	file, err := a.storage.LoadFile(ctx, path)
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	if file.Dir {
+		http.Error(w, "Is a directory", http.StatusBadRequest)
+		return
+	}
+	if err := a.storage.CheckCallerAccessToGroupID(ctx, file.ReadGroup); err != nil {
+		a.fail(w, err)
+		return
+	}
+	content, _, err := a.storage.LoadSource(ctx, path)
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/javascript")
+	if _, err := w.Write(content); err != nil {
+		log.Printf("trying to write response: %v", err)
+	}
+}
+
+func (a *API) putSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := "/" + r.PathValue("path")
+	content, err := io.ReadAll(io.LimitReader(r.Body, maxSourceSize+1))
+	if err != nil {
+		a.fail(w, juicemud.WithStack(err))
+		return
+	}
+	if len(content) > maxSourceSize {
+		http.Error(w, "Source too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if _, _, err := a.storage.EnsureFile(ctx, path); err != nil {
+		a.fail(w, err)
+		return
+	}
+	if err := a.storage.StoreSource(ctx, path, content); err != nil {
+		a.fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type stats struct {
+	Objects     int64
+	DeadLetters int64
+	ObjectCache dbm.CacheStats
+	Scripts     js.ScriptStats
+}
+
+func (a *API) stats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	result := stats{
+		ObjectCache: a.storage.ObjectCache().Stats(),
+		Scripts:     js.GetScriptStats(),
+	}
+	var err error
+	if result.Objects, err = a.storage.CountObjects(ctx); err != nil {
+		a.fail(w, err)
+		return
+	}
+	if result.DeadLetters, err = a.storage.CountDeadLetters(ctx); err != nil {
+		a.fail(w, err)
+		return
+	}
+	a.respond(w, result)
+}
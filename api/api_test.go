@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+
+	goccy "github.com/goccy/go-json"
+)
+
+func TestAPI(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := storage.New(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EnsureGroup(ctx, &storage.Group{Name: wizardsGroup}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateDir(juicemud.MakeMainContext(ctx), "/"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := map[string]string{}
+	for _, u := range []*storage.User{{Name: "owner", Owner: true}, {Name: "player"}} {
+		if err := s.StoreUser(ctx, u, false); err != nil {
+			t.Fatal(err)
+		}
+		if tokens[u.Name], err = s.CreateAPIToken(ctx, u, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+	request := func(method string, path string, token string, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{tokens["player"], http.StatusForbidden},
+	} {
+		if got, _ := request("GET", "/api/users", tc.token, ""); got != tc.want {
+			t.Errorf("got %v with token %q, want %v", got, tc.token, tc.want)
+		}
+	}
+
+	code, body := request("GET", "/api/users", tokens["owner"], "")
+	users := []user{}
+	if err := goccy.Unmarshal([]byte(body), &users); code != http.StatusOK || err != nil || len(users) != 2 {
+		t.Errorf("got %v, %q, %v, want both users", code, body, err)
+	}
+	if code, _ := request("PUT", "/api/sources/hello.js", tokens["owner"], "// hello"); code != http.StatusNoContent {
+		t.Errorf("got %v, want the source stored", code)
+	}
+	if code, body := request("GET", "/api/sources/hello.js", tokens["owner"], ""); code != http.StatusOK || body != "// hello" {
+		t.Errorf("got %v, %q, want the stored source", code, body)
+	}
+	if code, _ := request("GET", "/api/objects/missing", tokens["owner"], ""); code != http.StatusNotFound {
+		t.Errorf("got %v, want missing objects not found", code)
+	}
+}
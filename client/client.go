@@ -0,0 +1,259 @@
+// Package client connects to a juicemud server over SSH and talks to its terminal like a
+// player would, for bots, bridges and tools.
+package client
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	// Prompt is printed by the server when it waits for a line.
+	Prompt = "> "
+	// DefaultTimeout is the default time to wait for expected output.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Client is a terminal session with a server.
+type Client struct {
+	// Timeout is how long to wait for expected output.
+	Timeout time.Duration
+
+	client      *gossh.Client
+	session     *gossh.Session
+	stdin       io.WriteCloser
+	cond        *sync.Cond
+	output      bytes.Buffer
+	err         error
+	subscribers map[int]func([]byte)
+	nextID      int
+}
+
+// Dial connects to the SSH server at addr and opens a terminal session. The host key of the
+// server isn't verified.
+func Dial(addr string) (*Client, error) {
+	return dial(addr, false)
+}
+
+// DialCompressed is like Dial, but asks the server to compress the output of the session,
+// which saves bandwidth on slow connections.
+func DialCompressed(addr string) (*Client, error) {
+	return dial(addr, true)
+}
+
+func dial(addr string, compressed bool) (*Client, error) {
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            "client",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, juicemud.WithStack(err)
+	}
+	c := &Client{
+		Timeout:     DefaultTimeout,
+		client:      client,
+		session:     session,
+		cond:        sync.NewCond(&sync.Mutex{}),
+		subscribers: map[int]func([]byte){},
+	}
+	if c.stdin, err = session.StdinPipe(); err != nil {
+		c.Close()
+		return nil, juicemud.WithStack(err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		c.Close()
+		return nil, juicemud.WithStack(err)
+	}
+	if compressed {
+		if err := session.Setenv(juicemud.CompressionEnv, juicemud.CompressionZlib); err != nil {
+			c.Close()
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	if err := session.RequestPty("xterm", 40, 120, gossh.TerminalModes{}); err != nil {
+		c.Close()
+		return nil, juicemud.WithStack(err)
+	}
+	if err := session.Shell(); err != nil {
+		c.Close()
+		return nil, juicemud.WithStack(err)
+	}
+	go func() {
+		if !compressed {
+			c.read(stdout)
+			return
+		}
+		// Creating the reader waits for the zlib header, sent with the first output.
+		inflated, err := zlib.NewReader(juicemud.NewlineUnescaper(stdout))
+		if err != nil {
+			c.cond.L.Lock()
+			defer c.cond.L.Unlock()
+			c.err = err
+			c.cond.Broadcast()
+			return
+		}
+		c.read(inflated)
+	}()
+	return c, nil
+}
+
+func (c *Client) read(r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		c.cond.L.Lock()
+		c.output.Write(buf[:n])
+		for _, subscriber := range c.subscribers {
+			subscriber(bytes.Clone(buf[:n]))
+		}
+		if err != nil {
+			c.err = err
+		}
+		c.cond.Broadcast()
+		c.cond.L.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Subscribe makes f receive all output from now on, until unsubscribe is called. f is called
+// synchronously while reading, and must not call other methods of the client.
+func (c *Client) Subscribe(f func(output []byte)) (unsubscribe func()) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.subscribers[id] = f
+	return func() {
+		c.cond.L.Lock()
+		defer c.cond.L.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+// Discard drops the output buffered for WaitFor, e.g. when only subscribing to the output.
+func (c *Client) Discard() {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.output.Reset()
+}
+
+func (c *Client) Close() error {
+	c.session.Close()
+	return juicemud.WithStack(c.client.Close())
+}
+
+// Send types line and presses enter.
+func (c *Client) Send(line string) error {
+	_, err := io.WriteString(c.stdin, line+"\r")
+	return juicemud.WithStack(err)
+}
+
+// WaitFor waits until the output contains s, and returns and consumes the output up to and
+// including it.
+func (c *Client) WaitFor(s string) (string, error) {
+	timeout := c.Timeout
+	timer := time.AfterFunc(timeout, func() {
+		c.cond.L.Lock()
+		defer c.cond.L.Unlock()
+		c.cond.Broadcast()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	for {
+		if index := strings.Index(c.output.String(), s); index != -1 {
+			return string(c.output.Next(index + len(s))), nil
+		}
+		if c.err != nil {
+			return "", juicemud.WithStack(c.err)
+		}
+		if !time.Now().Before(deadline) {
+			return "", errors.Errorf("timed out waiting for %q", s)
+		}
+		c.cond.Wait()
+	}
+}
+
+// Expect waits for s and the following prompt.
+func (c *Client) Expect(s string) error {
+	if _, err := c.WaitFor(s); err != nil {
+		return juicemud.WithStack(err)
+	}
+	_, err := c.WaitFor(Prompt)
+	return juicemud.WithStack(err)
+}
+
+// dialog expects each prompt in turn, and answers it.
+func (c *Client) dialog(steps [][2]string) error {
+	for _, step := range steps {
+		if err := c.Expect(step[0]); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if err := c.Send(step[1]); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// CreateUser creates and logs in a new user. It has to be called right after connecting.
+func (c *Client) CreateUser(username string, password string) error {
+	if err := c.dialog([][2]string{
+		{"Welcome!", "create user"},
+		{"Enter new username", username},
+		{"Enter new password:", password},
+		{"Repeat new password:", password},
+		{"[y/n/abort]", "y"},
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(c.Expect("Welcome " + username))
+}
+
+// Login logs in an existing user. It has to be called right after connecting.
+func (c *Client) Login(username string, password string) error {
+	if err := c.dialog([][2]string{
+		{"Welcome!", "login user"},
+		{"Enter username", username},
+		{"Enter password", password},
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(c.Expect("Welcome back, " + username))
+}
+
+// SendCommand sends line and returns the output until the next prompt. Output from events
+// arriving while the command runs is included.
+func (c *Client) SendCommand(line string) (string, error) {
+	if err := c.Send(line); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	// The terminal echoes the line before running it.
+	if _, err := c.WaitFor(line + "\r\n"); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	output, err := c.WaitFor(Prompt)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return strings.TrimSuffix(output, Prompt), nil
+}
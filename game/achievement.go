@@ -0,0 +1,156 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	achievementUnlockedEventType = "achievementUnlocked"
+)
+
+// achievementDefinition is the argument of defineAchievement.
+type achievementDefinition struct {
+	Id          string
+	Name        string
+	Description string
+	Object      string
+}
+
+// achievementUnlocked is the message of achievementUnlocked events.
+type achievementUnlocked struct {
+	Object      string
+	Id          string
+	Name        string
+	Description string
+}
+
+// grantAchievement unlocks the achievement with key for object, and tells the object, the
+// subscribers of the achievementUnlocked topic, and the user of the object if it's connected.
+func (g *Game) grantAchievement(ctx context.Context, object string, key string) (bool, error) {
+	achievement, unlocked, err := g.storage.GrantAchievement(ctx, object, key)
+	if err != nil || !unlocked {
+		return false, juicemud.WithStack(err)
+	}
+	subscribers, err := g.storage.ObjectIDsByTopic(ctx, achievementUnlockedEventType)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	subscribers[object] = true
+	message, err := goccy.Marshal(&achievementUnlocked{
+		Object:      object,
+		Id:          achievement.Key,
+		Name:        achievement.Name,
+		Description: achievement.Description,
+	})
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	at := g.storage.Queue().After(defaultReactionDelay)
+	for id := range subscribers {
+		if err := g.emitJSON(ctx, at, id, achievementUnlockedEventType, string(message)); err != nil {
+			return false, juicemud.WithStack(err)
+		}
+	}
+	if c, found := envByObjectID.GetHas(object); found {
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("Achievement unlocked: %s", achievement.Name)))
+	}
+	return true, nil
+}
+
+func (c *Connection) achievementsCommand() error {
+	achievements, err := c.game.storage.Achievements(c.sess.Context())
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(achievements) == 0 {
+		fmt.Fprintln(c.term, "No achievements defined")
+		return nil
+	}
+	unlocks, err := c.game.storage.AchievementUnlocks(c.sess.Context(), c.user.Object)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	unlocked := map[string]time.Time{}
+	for _, unlock := range unlocks {
+		unlocked[unlock.Achievement] = time.Unix(0, unlock.Unlocked)
+	}
+	t := c.table("Achievement", "Description", "Unlocked")
+	for _, achievement := range achievements {
+		when := ""
+		if at, found := unlocked[achievement.Key]; found {
+			when = at.Format(time.DateTime)
+		}
+		t.AddRow(achievement.Name, achievement.Description, when)
+	}
+	t.Print()
+	fmt.Fprintf(c.term, "%v of %v unlocked\n", len(unlocked), len(achievements))
+	return nil
+}
+
+// addAchievementCallbacks adds `defineAchievement({Id, Name, Description, Object?})`, and unless
+// object is nil, `grantAchievement(objectID, achievementID)` which resolves to whether the
+// achievement was unlocked now. Achievements with an Object can only be granted, and redefined,
+// by that object, or by the boot script.
+func (g *Game) addAchievementCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["defineAchievement"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("defineAchievement takes [Object] arguments")
+		}
+		definition := achievementDefinition{}
+		if err := rc.Copy(&definition, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to achievementDefinition{}: %v", args[0], err)
+		}
+		if definition.Id == "" || definition.Name == "" {
+			return rc.Throw("achievements must have an Id and a Name")
+		}
+		definer := ""
+		if object != nil {
+			definer = object.Id
+		}
+		if err := g.storage.StoreAchievement(ctx, &storage.Achievement{
+			Key:         definition.Id,
+			Name:        definition.Name,
+			Description: definition.Description,
+			Object:      definition.Object,
+		}, definer); err != nil {
+			return rc.Throw("trying to store achievement %q: %v", definition.Id, err)
+		}
+		return nil
+	}
+	if object == nil {
+		return
+	}
+	callbacks["grantAchievement"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("grantAchievement takes [string, string] arguments")
+		}
+		achievement, err := g.storage.LoadAchievement(ctx, args[1].String())
+		if err != nil {
+			return rc.Reject("trying to load achievement %q: %v", args[1].String(), err)
+		}
+		if achievement.Object != "" && achievement.Object != object.Id {
+			return rc.Reject("only %q can grant %q", achievement.Object, achievement.Key)
+		}
+		unlocked, err := g.grantAchievement(ctx, args[0].String(), achievement.Key)
+		if err != nil {
+			return rc.Reject("trying to grant %q to %q: %v", achievement.Key, args[0].String(), err)
+		}
+		val, err := rc.JSFromGo(unlocked)
+		if err != nil {
+			return rc.Reject("trying to convert %v to *v8go.Value: %v", unlocked, err)
+		}
+		return rc.Resolve(val)
+	}
+}
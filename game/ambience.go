@@ -0,0 +1,161 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	mathrand "math/rand/v2"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	ambienceTickInterval = time.Second
+	// ambienceRefreshInterval is how often the scheduler looks for rooms whose ambience changed.
+	ambienceRefreshInterval = 10 * time.Second
+	// minAmbienceInterval is the shortest average interval between ambient messages.
+	minAmbienceInterval = 1
+)
+
+// ambientRoom is the ambience of a room, and when each message is due next.
+type ambientRoom struct {
+	ambience []structs.Ambience
+	next     []time.Time
+}
+
+// nextAmbience returns when a message emitted on average every interval seconds is due next,
+// after now.
+func nextAmbience(now time.Time, interval float32) time.Time {
+	return now.Add(time.Duration(float64(interval) * mathrand.ExpFloat64() * float64(time.Second)))
+}
+
+// runAmbience is the central scheduler of ambient messages. It shows the due messages of all
+// rooms with ambience to the users in them every ambienceTickInterval, and picks up changed
+// ambience every ambienceRefreshInterval, until ctx is done.
+func (g *Game) runAmbience(ctx context.Context) {
+	ticker := time.NewTicker(ambienceTickInterval)
+	defer ticker.Stop()
+	rooms := map[string]*ambientRoom{}
+	refreshed := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		if now.Sub(refreshed) >= ambienceRefreshInterval {
+			if err := g.refreshAmbience(ctx, now, rooms); err != nil {
+				log.Printf("trying to refresh ambience: %v", err)
+				log.Println(juicemud.StackTrace(err))
+			}
+			refreshed = now
+		}
+		if err := g.emitAmbience(ctx, now, rooms); err != nil {
+			log.Printf("trying to emit ambience: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+// refreshAmbience updates rooms with the current ambience of all objects having any, keeping
+// the schedule of unchanged ambience.
+func (g *Game) refreshAmbience(ctx context.Context, now time.Time, rooms map[string]*ambientRoom) error {
+	ids, err := g.storage.AmbientObjectIDs(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for id := range rooms {
+		if !ids[id] {
+			delete(rooms, id)
+		}
+	}
+	for id := range ids {
+		object, err := g.storage.LoadObject(ctx, id, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			delete(rooms, id)
+			continue
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if room, found := rooms[id]; found && slices.Equal(room.ambience, object.Ambience) {
+			continue
+		}
+		room := &ambientRoom{ambience: object.Ambience}
+		for _, ambience := range object.Ambience {
+			room.next = append(room.next, nextAmbience(now, ambience.Interval))
+		}
+		rooms[id] = room
+	}
+	return nil
+}
+
+// emitAmbience shows the messages due at now to the connected users in their rooms, and
+// schedules the next ones. Rooms without connected users are skipped without looking at them.
+func (g *Game) emitAmbience(ctx context.Context, now time.Time, rooms map[string]*ambientRoom) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+	occupants := map[string][]*Connection{}
+	for c := range envByObjectID.Values() {
+		object, err := g.storage.LoadObject(ctx, c.actor(), nil)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if _, found := rooms[object.Location]; found {
+			occupants[object.Location] = append(occupants[object.Location], c)
+		}
+	}
+	for id, room := range rooms {
+		for index, ambience := range room.ambience {
+			if room.next[index].After(now) {
+				continue
+			}
+			room.next[index] = nextAmbience(now, ambience.Interval)
+			for _, c := range occupants[id] {
+				fmt.Fprintln(c.term, c.wrap(ambience.Text))
+			}
+		}
+	}
+	return nil
+}
+
+// addAmbienceCallbacks adds `setAmbience([{Text, Interval}])`, which makes the object show
+// each Text to the users in it on average every Interval seconds, at random, and
+// `getAmbience()`. All ambience is run by one scheduler, so it costs nothing while no users
+// are present.
+func addAmbienceCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setAmbience"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setAmbience takes [Array] arguments")
+		}
+		ambience := []structs.Ambience{}
+		if err := rc.Copy(&ambience, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []structs.Ambience: %v", args[0], err)
+		}
+		for _, entry := range ambience {
+			if entry.Text == "" || entry.Interval < minAmbienceInterval {
+				return rc.Throw("ambience needs Text and an Interval of at least %v seconds", minAmbienceInterval)
+			}
+		}
+		object.Ambience = ambience
+		return nil
+	}
+	callbacks["getAmbience"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		res, err := rc.JSFromGo(object.Ambience)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", object.Ambience, err)
+		}
+		return res
+	}
+}
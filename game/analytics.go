@@ -0,0 +1,232 @@
+package game
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+)
+
+const (
+	commandAnalytics   = "command"
+	roomAnalytics      = "room"
+	retentionAnalytics = "retention"
+	// unknownCommand is counted for commands nothing handled, to not store what players type.
+	unknownCommand         = "unknown"
+	analyticsDay           = 24 * time.Hour
+	analyticsWeek          = 7 * analyticsDay
+	analyticsFlushInterval = time.Minute
+	analyticsReportRows    = 20
+)
+
+// analyticsPeriod returns the number of the period of length t is in.
+func analyticsPeriod(t time.Time, length time.Duration) int64 {
+	return t.Unix() / int64(length/time.Second)
+}
+
+type analyticsKey struct {
+	kind   string
+	period int64
+	key    string
+}
+
+// analyticsCollector counts analytics in memory between flushes to the storage.
+type analyticsCollector struct {
+	mutex  sync.Mutex
+	counts map[analyticsKey]int64
+}
+
+func newAnalyticsCollector() *analyticsCollector {
+	return &analyticsCollector{counts: map[analyticsKey]int64{}}
+}
+
+func (a *analyticsCollector) add(kind string, period int64, key string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.counts[analyticsKey{kind: kind, period: period, key: key}]++
+}
+
+// drain returns the counts since the last drain.
+func (a *analyticsCollector) drain() []storage.AnalyticsCounter {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := make([]storage.AnalyticsCounter, 0, len(a.counts))
+	for key, count := range a.counts {
+		result = append(result, storage.AnalyticsCounter{Kind: key.kind, Period: key.period, Key: key.key, Count: count})
+	}
+	a.counts = map[analyticsKey]int64{}
+	return result
+}
+
+// SetAnalytics makes the game collect anonymous totals of command usage, room visits and
+// weekly returning players, for /analytics.
+func (g *Game) SetAnalytics(enabled bool) {
+	g.analyticsEnabled.Store(enabled)
+}
+
+// countAnalytics counts key of kind in the current period of length, if analytics are enabled.
+func (g *Game) countAnalytics(kind string, length time.Duration, key string) {
+	if g.analyticsEnabled.Load() {
+		g.analytics.add(kind, analyticsPeriod(time.Now(), length), key)
+	}
+}
+
+func (g *Game) flushAnalytics(ctx context.Context) error {
+	if counters := g.analytics.drain(); len(counters) > 0 {
+		return juicemud.WithStack(g.storage.AddAnalytics(ctx, counters))
+	}
+	return nil
+}
+
+// runAnalyticsFlushes stores the collected analytics every analyticsFlushInterval.
+func (g *Game) runAnalyticsFlushes(ctx context.Context) {
+	ticker := time.NewTicker(analyticsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.flushAnalytics(ctx); err != nil {
+			log.Printf("trying to store analytics: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+// countRetention counts the user as returning this week in the cohort of the week they
+// first logged in, unless they already logged in this week.
+func (c *Connection) countRetention() error {
+	if !c.game.analyticsEnabled.Load() {
+		return nil
+	}
+	now := time.Now()
+	if c.lastLogin != 0 && analyticsPeriod(time.Unix(0, c.lastLogin), analyticsWeek) == analyticsPeriod(now, analyticsWeek) {
+		return nil
+	}
+	logins, err := c.game.storage.UserLogins(c.sess.Context(), c.user.Id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	first := now
+	for _, login := range logins {
+		if at := time.Unix(0, login.First); at.Before(first) {
+			first = at
+		}
+	}
+	c.game.countAnalytics(retentionAnalytics, analyticsWeek, strconv.FormatInt(analyticsPeriod(first, analyticsWeek), 10))
+	return nil
+}
+
+// analyticsCommand handles `/analytics [-days days] [-weeks weeks] [commands|rooms|retention]`,
+// which shows the most used commands, the most visited rooms, or how many players of each
+// weekly cohort returned in the following weeks.
+func (c *Connection) analyticsCommand(args []string) error {
+	ctx := c.sess.Context()
+	flags := flag.NewFlagSet("/analytics", flag.ContinueOnError)
+	flags.SetOutput(c.term)
+	days := flags.Int("days", 7, "Report commands and rooms for this many days")
+	weeks := flags.Int("weeks", 8, "Report retention for cohorts of this many weeks")
+	if err := flags.Parse(args); err != nil {
+		return nil
+	}
+	report := "commands"
+	if flags.NArg() > 0 {
+		report = flags.Arg(0)
+	}
+	if !c.game.analyticsEnabled.Load() {
+		fmt.Fprint(c.term, "Analytics are disabled, start the server with -analytics to collect them.\n\n")
+	}
+	if err := c.game.flushAnalytics(ctx); err != nil {
+		return juicemud.WithStack(err)
+	}
+	now := time.Now()
+	today := analyticsPeriod(now, analyticsDay)
+	switch report {
+	case "commands":
+		totals, err := c.game.storage.AnalyticsTotals(ctx, commandAnalytics, today-int64(*days)+1, today+1)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		t := c.table("Command", "Uses")
+		for i, total := range totals {
+			if i == analyticsReportRows {
+				break
+			}
+			t.AddRow(total.Key, total.Count)
+		}
+		t.Print()
+	case "rooms":
+		totals, err := c.game.storage.AnalyticsTotals(ctx, roomAnalytics, today-int64(*days)+1, today+1)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if len(totals) > analyticsReportRows {
+			totals = totals[:analyticsReportRows]
+		}
+		ids := map[string]bool{}
+		for _, total := range totals {
+			ids[total.Key] = true
+		}
+		rooms, err := c.game.storage.LoadObjects(ctx, ids, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		t := c.table("Room", "Description", "Visits")
+		for _, total := range totals {
+			description := "(removed)"
+			if room, found := rooms[total.Key]; found {
+				description = shortDescription(room)
+			}
+			t.AddRow("#"+total.Key, description, total.Count)
+		}
+		t.Print()
+	case "retention":
+		thisWeek := analyticsPeriod(now, analyticsWeek)
+		counters, err := c.game.storage.AnalyticsCounters(ctx, retentionAnalytics, thisWeek-int64(*weeks)+1, thisWeek+1)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		cohorts := map[int64][]int64{}
+		for _, counter := range counters {
+			cohort, err := strconv.ParseInt(counter.Key, 10, 64)
+			if err != nil || cohort > counter.Period || counter.Period-cohort >= int64(*weeks) {
+				continue
+			}
+			if cohorts[cohort] == nil {
+				cohorts[cohort] = make([]int64, *weeks)
+			}
+			cohorts[cohort][counter.Period-cohort] = counter.Count
+		}
+		headers := []any{"Cohort"}
+		for week := 0; week < *weeks; week++ {
+			headers = append(headers, fmt.Sprintf("+%d", week))
+		}
+		t := c.table(headers...)
+		for cohort := thisWeek - int64(*weeks) + 1; cohort <= thisWeek; cohort++ {
+			counts, found := cohorts[cohort]
+			if !found {
+				continue
+			}
+			row := []any{time.Unix(cohort*int64(analyticsWeek/time.Second), 0).Format(time.DateOnly)}
+			for week := int64(0); week <= thisWeek-cohort; week++ {
+				row = append(row, counts[week])
+			}
+			for len(row) < len(headers) {
+				row = append(row, "")
+			}
+			t.AddRow(row...)
+		}
+		t.Print()
+	default:
+		fmt.Fprintln(c.term, "usage: /analytics [-days days] [-weeks weeks] [commands, rooms or retention]")
+	}
+	return nil
+}
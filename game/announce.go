@@ -0,0 +1,184 @@
+package game
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+var (
+	// countdownSteps are the remaining times at which countdown announcements are repeated.
+	countdownSteps = []time.Duration{
+		time.Hour,
+		30 * time.Minute,
+		10 * time.Minute,
+		5 * time.Minute,
+		time.Minute,
+		30 * time.Second,
+		10 * time.Second,
+	}
+)
+
+type scheduledAnnouncement struct {
+	id      int64
+	message string
+	at      time.Time
+	timers  []*time.Timer
+}
+
+// announcer keeps track of announcements scheduled for later.
+type announcer struct {
+	mutex     sync.Mutex
+	nextID    int64
+	scheduled map[int64]*scheduledAnnouncement
+}
+
+func newAnnouncer() *announcer {
+	return &announcer{
+		scheduled: map[int64]*scheduledAnnouncement{},
+	}
+}
+
+func formatAnnouncement(message string) string {
+	return fmt.Sprintf("*** %s ***", message)
+}
+
+// announce shows message to all connected users, or only those whose actor is in location if
+// location isn't empty. Users whose actor can't be loaded are skipped.
+func (g *Game) announce(ctx context.Context, location string, message string) {
+	for c := range envByObjectID.Values() {
+		if location != "" {
+			object, err := g.storage.LoadObject(ctx, c.actor(), nil)
+			if err != nil {
+				log.Printf("trying to load %q to announce %q: %v", c.actor(), message, err)
+				log.Println(juicemud.StackTrace(err))
+				continue
+			}
+			if object.Location != location {
+				continue
+			}
+		}
+		fmt.Fprintln(c.term, c.wrap(formatAnnouncement(message)))
+	}
+}
+
+// scheduleAnnouncement announces message to all connected users after delay. Countdowns
+// are also announced at each of the countdownSteps shorter than delay.
+func (g *Game) scheduleAnnouncement(message string, delay time.Duration, countdown bool) int64 {
+	g.announcer.mutex.Lock()
+	defer g.announcer.mutex.Unlock()
+	g.announcer.nextID++
+	scheduled := &scheduledAnnouncement{
+		id:      g.announcer.nextID,
+		message: message,
+		at:      time.Now().Add(delay),
+	}
+	announce := func(message string) func() {
+		return func() {
+			g.announce(context.Background(), "", message)
+		}
+	}
+	if countdown {
+		for _, step := range countdownSteps {
+			if step < delay {
+				scheduled.timers = append(scheduled.timers, time.AfterFunc(delay-step, announce(fmt.Sprintf("%s in %v", message, step))))
+			}
+		}
+	}
+	scheduled.timers = append(scheduled.timers, time.AfterFunc(delay, func() {
+		g.announcer.mutex.Lock()
+		delete(g.announcer.scheduled, scheduled.id)
+		g.announcer.mutex.Unlock()
+		announce(message)()
+	}))
+	g.announcer.scheduled[scheduled.id] = scheduled
+	return scheduled.id
+}
+
+func (g *Game) cancelAnnouncement(id int64) bool {
+	g.announcer.mutex.Lock()
+	defer g.announcer.mutex.Unlock()
+	scheduled, found := g.announcer.scheduled[id]
+	if !found {
+		return false
+	}
+	for _, timer := range scheduled.timers {
+		timer.Stop()
+	}
+	delete(g.announcer.scheduled, id)
+	return true
+}
+
+func (g *Game) scheduledAnnouncements() []scheduledAnnouncement {
+	g.announcer.mutex.Lock()
+	defer g.announcer.mutex.Unlock()
+	result := []scheduledAnnouncement{}
+	for _, scheduled := range g.announcer.scheduled {
+		result = append(result, *scheduled)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].at.Before(result[j].at)
+	})
+	return result
+}
+
+func (c *Connection) announceCommand(args []string) error {
+	flags := flag.NewFlagSet("/announce", flag.ContinueOnError)
+	flags.SetOutput(c.term)
+	in := flags.Duration("in", 0, "Announce after this long")
+	countdown := flags.Bool("countdown", false, "Count down to the announcement")
+	list := flags.Bool("list", false, "List scheduled announcements")
+	cancel := flags.Int64("cancel", 0, "Cancel the scheduled announcement with this ID")
+	if err := flags.Parse(args); err != nil {
+		return nil
+	}
+	switch {
+	case *list:
+		t := c.table("Id", "At", "Message")
+		for _, scheduled := range c.game.scheduledAnnouncements() {
+			t.AddRow(scheduled.id, scheduled.at.Format(time.DateTime), scheduled.message)
+		}
+		t.Print()
+	case *cancel != 0:
+		if c.game.cancelAnnouncement(*cancel) {
+			fmt.Fprintf(c.term, "Canceled announcement %v\n", *cancel)
+		} else {
+			fmt.Fprintf(c.term, "No announcement %v scheduled\n", *cancel)
+		}
+	case flags.NArg() == 0:
+		fmt.Fprintln(c.term, "usage: /announce [-in duration] [-countdown] [message], /announce -list, /announce -cancel [id]")
+	case *in > 0:
+		id := c.game.scheduleAnnouncement(strings.Join(flags.Args(), " "), *in, *countdown)
+		fmt.Fprintf(c.term, "Scheduled announcement %v\n", id)
+	default:
+		c.game.announce(c.sess.Context(), "", strings.Join(flags.Args(), " "))
+	}
+	return nil
+}
+
+// addAnnounceCallbacks adds `announce(message, location)`, which shows message to all
+// connected users, or only those in location if given.
+func (g *Game) addAnnounceCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["announce"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsString() || (len(args) == 2 && !args[1].IsString()) {
+			return rc.Throw("announce takes [string, string?] arguments")
+		}
+		location := ""
+		if len(args) == 2 {
+			location = args[1].String()
+		}
+		g.announce(ctx, location, args[0].String())
+		return nil
+	}
+}
@@ -0,0 +1,219 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"gopkg.in/yaml.v3"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	archetypesSource = "/archetypes.yaml"
+	maxCharacterName = 20
+)
+
+// ArchetypeDefinitions are the archetypes new characters choose between, written in YAML (or
+// JSON, which is valid YAML) in archetypesSource.
+//
+//	start: "#tutorial"
+//	archetypes:
+//	  warrior:
+//	    description: Strong, and good with a sword.
+//	    start: "#barracks"
+//	    skills:
+//	      swords: 5
+//	    equipment:
+//	      - /items/sword.js
+type ArchetypeDefinitions struct {
+	// Start is "#id" of the object new characters start in, and defaults to genesis.
+	Start      string               `yaml:"start"`
+	Archetypes map[string]Archetype `yaml:"archetypes"`
+}
+
+type Archetype struct {
+	Description string `yaml:"description"`
+	// Start is "#id" of the object characters of the archetype start in, and defaults to the
+	// start of all archetypes.
+	Start string `yaml:"start"`
+	// Skills are the starting practical and theoretical levels of skills.
+	Skills map[string]float32 `yaml:"skills"`
+	// Equipment are the sources of the objects new characters start out carrying.
+	Equipment []string `yaml:"equipment"`
+}
+
+// character is what a new user chose in the character creation, and the creation params of
+// the user object.
+type character struct {
+	Name      string
+	Archetype string
+}
+
+func parseArchetypes(content []byte) (*ArchetypeDefinitions, error) {
+	definitions := &ArchetypeDefinitions{}
+	if err := yaml.Unmarshal(content, definitions); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if _, isRef := parseObjectRef(definitions.Start); definitions.Start != "" && !isRef {
+		return nil, errors.Errorf("start %q isn't #id", definitions.Start)
+	}
+	for name, archetype := range definitions.Archetypes {
+		if _, isRef := parseObjectRef(archetype.Start); archetype.Start != "" && !isRef {
+			return nil, errors.Errorf("start %q of archetype %q isn't #id", archetype.Start, name)
+		}
+		for _, source := range archetype.Equipment {
+			if source == "" {
+				return nil, errors.Errorf("archetype %q has equipment without source", name)
+			}
+		}
+	}
+	return definitions, nil
+}
+
+// loadArchetypes returns the archetypes defined in archetypesSource, if it exists.
+func (g *Game) loadArchetypes(ctx context.Context) (*ArchetypeDefinitions, error) {
+	// Users read the archetypes while creating their characters, before they are logged in.
+	if _, err := g.storage.LoadFile(juicemud.MakeMainContext(ctx), archetypesSource); errors.Is(err, os.ErrNotExist) {
+		return &ArchetypeDefinitions{Archetypes: map[string]Archetype{}}, nil
+	} else if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	content, _, err := g.storage.LoadSource(ctx, archetypesSource)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	definitions, err := parseArchetypes(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "trying to parse %q", archetypesSource)
+	}
+	if definitions.Archetypes == nil {
+		definitions.Archetypes = map[string]Archetype{}
+	}
+	return definitions, nil
+}
+
+// startLocation returns the ID of the object characters of archetype start in.
+func (a *ArchetypeDefinitions) startLocation(archetype Archetype) string {
+	start := archetype.Start
+	if start == "" {
+		start = a.Start
+	}
+	if id, isRef := parseObjectRef(start); isRef {
+		return id
+	}
+	return genesisID
+}
+
+// validCharacterName returns whether name is made of letters, spaces, hyphens and apostrophes,
+// and not too long.
+func validCharacterName(name string) bool {
+	if name == "" || len([]rune(name)) > maxCharacterName {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '\'' {
+			return false
+		}
+	}
+	return true
+}
+
+// createCharacter lets a new user choose the name and archetype of their character.
+func (c *Connection) createCharacter(username string) (*character, error) {
+	fmt.Fprint(c.term, "** Create character **\n\n")
+	result := &character{}
+	for result.Name == "" {
+		fmt.Fprintf(c.term, "Enter character name, or nothing for %q:\n", username)
+		line, err := c.term.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(line)
+		if name == "" {
+			name = username
+		}
+		if validCharacterName(name) {
+			result.Name = name
+		} else {
+			fmt.Fprintf(c.term, "Names are at most %d letters, spaces, hyphens or apostrophes!\n", maxCharacterName)
+		}
+	}
+	definitions, err := c.game.loadArchetypes(c.sess.Context())
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	archetypes := definitions.Archetypes
+	if len(archetypes) == 0 {
+		return result, nil
+	}
+	names := make(sort.StringSlice, 0, len(archetypes))
+	for name := range archetypes {
+		names = append(names, name)
+	}
+	sort.Sort(names)
+	t := c.table("Archetype", "Description")
+	for _, name := range names {
+		t.AddRow(name, archetypes[name].Description)
+	}
+	t.Print()
+	if result.Archetype, err = c.SelectReturn("Choose archetype", names); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// createCharacterObject creates the object of user, running userSource with char as creation
+// params, in the start location and with the starting skills and equipment of the archetype
+// of char. Removes the object and its equipment again if it fails.
+func (g *Game) createCharacterObject(ctx context.Context, user *storage.User, char *character) error {
+	definitions, err := g.loadArchetypes(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	archetype := Archetype{}
+	if char.Archetype != "" {
+		chosen, found := definitions.Archetypes[char.Archetype]
+		if !found {
+			return errors.Errorf("no archetype %q", char.Archetype)
+		}
+		archetype = chosen
+	}
+	object, err := structs.MakeObject(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for skill, level := range archetype.Skills {
+		object.Skills[skill] = structs.Skill{Practical: level, Theoretical: level}
+	}
+	params, err := goccy.Marshal(char)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.initObject(ctx, object, userSource, definitions.startLocation(archetype), string(params)); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := func() error {
+		for _, source := range archetype.Equipment {
+			// The equipment is chosen by the wizards, so the new user needn't be able to read it.
+			if _, err := g.createObjectFromSource(juicemud.MakeMainContext(ctx), source, object.Id, ""); err != nil {
+				return errors.Wrapf(err, "trying to create %q for %q", source, user.Name)
+			}
+		}
+		user.Object = object.Id
+		return juicemud.WithStack(g.storage.StoreUser(ctx, user, false))
+	}(); err != nil {
+		// Nobody can use the object or its equipment without the user.
+		user.Object = ""
+		return juicemud.WithStack(rollback(err, g.tearDown(ctx, object.Id)))
+	}
+	return nil
+}
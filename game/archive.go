@@ -0,0 +1,274 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	exportDir = "/exports"
+)
+
+// Archive is a portable copy of an object subtree, with the sources needed to run it.
+type Archive struct {
+	// Root is the ID of the object the subtree was exported from.
+	Root string
+	// Objects are sorted so that every object comes after its location.
+	Objects []*structs.Object
+	// Sources maps source paths to source content.
+	Sources map[string]string
+}
+
+// exportArchive exports the object with the given ID, and its content recursively down to
+// depth levels. A negative depth exports all content.
+func (g *Game) exportArchive(ctx context.Context, id string, depth int) (*Archive, error) {
+	result := &Archive{
+		Root:    id,
+		Sources: map[string]string{},
+	}
+	level := map[string]bool{id: true}
+	for currentDepth := 0; len(level) > 0; currentDepth++ {
+		objects, err := g.storage.LoadObjects(ctx, level, nil)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		nextLevel := map[string]bool{}
+		for _, object := range objects {
+			result.Objects = append(result.Objects, object)
+			if _, found := result.Sources[object.SourcePath]; !found && object.SourcePath != "" {
+				source, _, err := g.storage.LoadSource(ctx, object.SourcePath)
+				if err != nil {
+					return nil, juicemud.WithStack(err)
+				}
+				result.Sources[object.SourcePath] = string(source)
+			}
+			if depth < 0 || currentDepth < depth {
+				for contentID := range object.Content {
+					nextLevel[contentID] = true
+				}
+			}
+		}
+		level = nextLevel
+	}
+	return result, nil
+}
+
+func (g *Game) ensureParentDirs(ctx context.Context, path string) error {
+	dir := filepath.Dir(path)
+	if dir == root {
+		return nil
+	}
+	if err := g.ensureParentDirs(ctx, dir); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.storage.CreateDir(ctx, dir))
+}
+
+// importArchive recreates the objects of the archive inside location, with fresh IDs and remapped
+// references. Missing sources are created, and sources that exist with different content abort
+// the import before any object is created. Archives with objects located outside of the archive,
+// or before their locations, are rejected before anything is written. Exits to, and nicknames of,
+// objects neither in the archive nor in this world are dropped.
+//
+// Object state is copied verbatim, so IDs stored in state are not remapped.
+func (g *Game) importArchive(ctx context.Context, archive *Archive, location string) (*structs.Object, error) {
+	if err := archive.validate(); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if _, err := g.storage.LoadObject(ctx, location, nil); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	missingSources := map[string]string{}
+	for path, source := range archive.Sources {
+		existing, err := g.storage.LoadFile(ctx, path)
+		if errors.Is(err, os.ErrNotExist) {
+			missingSources[path] = source
+			continue
+		} else if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		content, _, err := g.storage.LoadSource(ctx, existing.Path)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if !bytes.Equal(content, []byte(source)) {
+			return nil, errors.Errorf("%q already exists with different content", path)
+		}
+	}
+	for path, source := range missingSources {
+		if err := g.ensureParentDirs(ctx, path); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if _, _, err := g.storage.EnsureFile(ctx, path); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if err := g.storage.StoreSource(ctx, path, []byte(source)); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	newIDs := map[string]string{}
+	for _, object := range archive.Objects {
+		newID, err := structs.NextObjectID()
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		newIDs[object.Id] = newID
+	}
+	externalIDs := map[string]bool{}
+	for _, object := range archive.Objects {
+		for _, exit := range object.Exits {
+			if _, found := newIDs[exit.Destination]; !found {
+				externalIDs[exit.Destination] = true
+			}
+		}
+		for id := range object.Nicknames {
+			if _, found := newIDs[id]; !found {
+				externalIDs[id] = true
+			}
+		}
+	}
+	existingExternal, err := g.storage.LoadObjects(ctx, externalIDs, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	var rootObject *structs.Object
+	for _, object := range archive.Objects {
+		if object.Id == archive.Root {
+			object.Location = location
+			rootObject = object
+		} else {
+			object.Location = newIDs[object.Location]
+		}
+		object.Id = newIDs[object.Id]
+		// Content is rebuilt as each object is stored in its location.
+		object.Content = map[string]bool{}
+		exits := []structs.Exit{}
+		for _, exit := range object.Exits {
+			if newDestination, found := newIDs[exit.Destination]; found {
+				exit.Destination = newDestination
+			} else if _, found := existingExternal[exit.Destination]; !found {
+				continue
+			}
+			exits = append(exits, exit)
+		}
+		object.Exits = exits
+		nicknames := map[string]string{}
+		for id, nickname := range object.Nicknames {
+			if newID, found := newIDs[id]; found {
+				nicknames[newID] = nickname
+			} else if _, found := existingExternal[id]; found {
+				nicknames[id] = nickname
+			}
+		}
+		object.Nicknames = nicknames
+		// Make sure the imported objects rerun their possibly changed sources when loaded.
+		object.SourceModTime = 0
+		if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+			return nil, rollback(juicemud.WithStack(err), g.tearDown(ctx, rootObject.Id))
+		}
+	}
+	return rootObject, nil
+}
+
+// validate returns an error unless the archive contains its root, and every other object
+// comes after its location in the archive.
+func (a *Archive) validate() error {
+	seen := map[string]bool{}
+	for _, object := range a.Objects {
+		if object == nil {
+			return errors.New("archive contains null objects")
+		}
+		if seen[object.Id] {
+			return errors.Errorf("archive object %q appears more than once", object.Id)
+		}
+		if object.Id != a.Root && !seen[object.Location] {
+			return errors.Errorf("archive object %q is located in %q, which doesn't come before it in the archive", object.Id, object.Location)
+		}
+		seen[object.Id] = true
+	}
+	if !seen[a.Root] {
+		return errors.Errorf("archive root %q not in archive", a.Root)
+	}
+	return nil
+}
+
+// contents splits the content of the root of the validated archive a into archives of their own.
+func (a *Archive) contents() []*Archive {
+	result := []*Archive{}
+	byRoot := map[string]*Archive{}
+	rootOf := map[string]string{}
+	for _, object := range a.Objects {
+		if object.Id == a.Root {
+			continue
+		}
+		root := object.Id
+		if object.Location != a.Root {
+			root = rootOf[object.Location]
+		}
+		rootOf[object.Id] = root
+		content, found := byRoot[root]
+		if !found {
+			content = &Archive{Root: root, Sources: a.Sources}
+			byRoot[root] = content
+			result = append(result, content)
+		}
+		content.Objects = append(content.Objects, object)
+	}
+	return result
+}
+
+// exportArchiveToFile exports the subtree of id to a file in exportDir and returns its path.
+func (g *Game) exportArchiveToFile(ctx context.Context, id string, depth int) (string, error) {
+	archive, err := g.exportArchive(ctx, id, depth)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	js, err := goccy.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if err := g.storage.CreateDir(ctx, exportDir); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	path := filepath.Join(exportDir, fmt.Sprintf("%s.json", id))
+	if _, _, err := g.storage.EnsureFile(ctx, path); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if err := g.storage.StoreSource(ctx, path, js); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return path, nil
+}
+
+// importArchiveFromFile imports the archive at path into location.
+func (g *Game) importArchiveFromFile(ctx context.Context, path string, location string) (*structs.Object, error) {
+	if _, err := g.storage.LoadFile(ctx, path); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	js, _, err := g.storage.LoadSource(ctx, path)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	archive := &Archive{}
+	if err := goccy.Unmarshal(js, archive); err != nil {
+		return nil, errors.Wrapf(err, "%q is not an archive", path)
+	}
+	return g.importArchive(ctx, archive, location)
+}
+
+func parseObjectRef(s string) (string, bool) {
+	if !strings.HasPrefix(s, "#") || len(s) < 2 {
+		return "", false
+	}
+	return s[1:], true
+}
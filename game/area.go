@@ -0,0 +1,196 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+	"gopkg.in/yaml.v3"
+
+	goccy "github.com/goccy/go-json"
+)
+
+// AreaDefinition is a declarative definition of rooms, exits and placed objects, written
+// in YAML (or JSON, which is valid YAML) so that geography can be built without writing
+// JS for every room.
+//
+//	rooms:
+//	  clearing:
+//	    short: A clearing
+//	    long: Sunlight streams through the canopy.
+//	    exits:
+//	      - name: north
+//	        to: path
+//	    objects:
+//	      - key: oak
+//	        source: /forest/oak.js
+//	        params: {height: 12}
+//	  path:
+//	    short: A winding path
+//	    exits:
+//	      - name: south
+//	        to: clearing
+//	      - name: gate
+//	        to: "#genesis"
+type AreaDefinition struct {
+	Rooms map[string]AreaRoom `yaml:"rooms"`
+}
+
+type AreaRoom struct {
+	// Source defaults to areaRoomSource.
+	Source  string       `yaml:"source"`
+	Short   string       `yaml:"short"`
+	Long    string       `yaml:"long"`
+	Tags    []string     `yaml:"tags"`
+	Exits   []AreaExit   `yaml:"exits"`
+	Objects []AreaObject `yaml:"objects"`
+}
+
+type AreaExit struct {
+	Name string `yaml:"name"`
+	Long string `yaml:"long"`
+	// To is either the key of a room in the same area, or "#id" for any object.
+	To string `yaml:"to"`
+}
+
+type AreaObject struct {
+	// Key defaults to the index of the object in the room.
+	Key    string         `yaml:"key"`
+	Source string         `yaml:"source"`
+	Params map[string]any `yaml:"params"`
+}
+
+func areaRoomID(areaPath string, key string) string {
+	return fmt.Sprintf("%s:%s", areaPath, key)
+}
+
+func areaObjectID(areaPath string, roomKey string, object AreaObject, index int) string {
+	key := object.Key
+	if key == "" {
+		key = fmt.Sprint(index)
+	}
+	return fmt.Sprintf("%s:%s:%s", areaPath, roomKey, key)
+}
+
+func parseArea(content []byte) (*AreaDefinition, error) {
+	area := &AreaDefinition{}
+	if err := yaml.Unmarshal(content, area); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	for key, room := range area.Rooms {
+		if strings.Contains(key, ":") {
+			return nil, errors.Errorf("room key %q contains ':'", key)
+		}
+		for _, exit := range room.Exits {
+			if _, isRef := parseObjectRef(exit.To); isRef {
+				continue
+			}
+			if _, found := area.Rooms[exit.To]; !found {
+				return nil, errors.Errorf("exit %q in room %q leads to unknown room %q", exit.Name, key, exit.To)
+			}
+		}
+		for _, object := range room.Objects {
+			if object.Source == "" {
+				return nil, errors.Errorf("object %q in room %q has no source", object.Key, key)
+			}
+		}
+	}
+	return area, nil
+}
+
+// loadArea compiles the area definition at path into objects. Rooms and placed objects get
+// IDs derived from the path and their keys, so loading the same area again updates the rooms
+// in place, and only creates placed objects that don't exist yet. Rooms and objects removed
+// from the definition are left alone.
+func (g *Game) loadArea(ctx context.Context, path string) ([]string, error) {
+	roomIDs, err := g.buildArea(ctx, path, path)
+	return roomIDs, juicemud.WithStack(err)
+}
+
+// buildArea compiles the area definition at path into objects with IDs derived from prefix,
+// and returns the IDs of the rooms.
+func (g *Game) buildArea(ctx context.Context, path string, prefix string) ([]string, error) {
+	if _, err := g.storage.LoadFile(ctx, path); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	content, _, err := g.storage.LoadSource(ctx, path)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	area, err := parseArea(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "trying to parse %q", path)
+	}
+	keys := make(sort.StringSlice, 0, len(area.Rooms))
+	for key := range area.Rooms {
+		keys = append(keys, key)
+	}
+	sort.Sort(keys)
+	roomIDs := []string{}
+	for _, key := range keys {
+		room := area.Rooms[key]
+		id := areaRoomID(prefix, key)
+		object, err := g.storage.LoadObject(ctx, id, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			if object, err = structs.MakeObject(ctx); err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			object.Id = id
+		} else if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		object.SourcePath = room.Source
+		if object.SourcePath == "" {
+			object.SourcePath = areaRoomSource
+		}
+		object.Descriptions = []structs.Description{{Short: room.Short, Long: room.Long}}
+		object.Tags = room.Tags
+		object.Exits = []structs.Exit{}
+		for _, exit := range room.Exits {
+			destination, isRef := parseObjectRef(exit.To)
+			if !isRef {
+				destination = areaRoomID(prefix, exit.To)
+			}
+			object.Exits = append(object.Exits, structs.Exit{
+				Descriptions: []structs.Description{{Short: exit.Name, Long: exit.Long}},
+				Destination:  destination,
+			})
+		}
+		if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		roomIDs = append(roomIDs, id)
+	}
+	for _, key := range keys {
+		for index, placed := range area.Rooms[key].Objects {
+			id := areaObjectID(prefix, key, placed, index)
+			if _, err := g.storage.LoadObject(ctx, id, nil); err == nil {
+				continue
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, juicemud.WithStack(err)
+			}
+			params := ""
+			if placed.Params != nil {
+				js, err := goccy.Marshal(placed.Params)
+				if err != nil {
+					return nil, juicemud.WithStack(err)
+				}
+				params = string(js)
+			}
+			object, err := structs.MakeObject(ctx)
+			if err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			object.Id = id
+			if err := g.initObject(ctx, object, placed.Source, areaRoomID(prefix, key), params); err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+		}
+	}
+	return roomIDs, nil
+}
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"github.com/zond/juicemud/game/skills"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// addAttributeConfigCallbacks adds `setAttribute(name, {Base, Weights})`, which configures an
+// attribute derived from skills as Base plus the sum of the levels of the skills in Weights
+// times their weights, and `getAttributes()`, which returns the configured attributes.
+func addAttributeConfigCallbacks(callbacks js.Callbacks) {
+	callbacks["setAttribute"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsObject() {
+			return rc.Throw("setAttribute takes [string, Object] arguments")
+		}
+		attribute := &skills.Attribute{}
+		if err := rc.Copy(attribute, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to skills.Attribute: %v", args[1], err)
+		}
+		skills.Attributes.Set(args[0].String(), attribute)
+		return nil
+	}
+	callbacks["getAttributes"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		res, err := rc.JSFromGo(skills.Attributes)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", skills.Attributes, err)
+		}
+		return res
+	}
+}
+
+// addAttributeCallbacks adds `getAttribute(objectID, name)`, which returns the value of the
+// derived attribute for the object, or null if there is no such attribute.
+func (g *Game) addAttributeCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["getAttribute"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("getAttribute takes [string, string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		value, found := holder.Attribute(args[1].String(), time.Now())
+		if !found {
+			return v8go.Null(rc.Context().Isolate())
+		}
+		res, err := rc.JSFromGo(value)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", value, err)
+		}
+		return res
+	}
+}
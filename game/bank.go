@@ -0,0 +1,244 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	bankFeeEventType = "bankFee"
+	depositAction    = "deposit"
+	withdrawAction   = "withdraw"
+	// defaultVaultCapacity is how many items vaults hold unless changed by setVaultCapacity.
+	defaultVaultCapacity = 20
+)
+
+var (
+	vaultCapacity atomic.Int64
+)
+
+func init() {
+	vaultCapacity.Store(defaultVaultCapacity)
+}
+
+// feeQuery is the message of bankFee calls to banks, which can reply with a fee the customer
+// pays the bank for the action.
+type feeQuery struct {
+	Action   string
+	Customer string
+	Item     string
+	Money    int64
+}
+
+// vaultID returns the ID of the vault of the object with the given ID. Vaults have no location,
+// so they keep their content when their owner dies.
+func vaultID(owner string) string {
+	return fmt.Sprintf("vault:%s", owner)
+}
+
+// loadVault returns the vault of the object with the given ID, creating it if necessary.
+func (g *Game) loadVault(ctx context.Context, owner string) (*structs.Object, error) {
+	id := vaultID(owner)
+	if err := g.storage.EnsureObject(ctx, id, func(vault *structs.Object) error {
+		vault.Content = map[string]bool{}
+		vault.Descriptions = []structs.Description{{Short: "a vault"}}
+		return nil
+	}); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	vault, err := g.storage.LoadObject(ctx, id, nil)
+	return vault, juicemud.WithStack(err)
+}
+
+// findBank returns the first object tagged as a bank, in ID order, detected by customer in its
+// location, or nil if there is none.
+func (g *Game) findBank(ctx context.Context, customer *structs.Object) (*structs.Object, error) {
+	if customer.Location == "" {
+		return nil, nil
+	}
+	room, err := g.storage.LoadObject(ctx, customer.Location, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	neighbours, err := g.storage.LoadObjects(ctx, room.Content, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	ids := make([]string, 0, len(neighbours))
+	for id := range neighbours {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if bank := neighbours[id]; bank.Flagged(structs.BankTag) && bank.Detectable(customer) {
+			return bank, nil
+		}
+	}
+	return nil, nil
+}
+
+// queryFee returns the fee the bankFee call handler of bank, if any, replies with for query.
+func (g *Game) queryFee(ctx context.Context, bank *structs.Object, query *feeQuery) (int64, error) {
+	if !bank.HasCallback(bankFeeEventType, callEventTag) {
+		return 0, nil
+	}
+	message, err := goccy.Marshal(query)
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	reply, err := g.callObject(ctx, bank.Id, bankFeeEventType, string(message))
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	var fee float64
+	if err := goccy.Unmarshal([]byte(reply), &fee); err != nil {
+		return 0, errors.Wrapf(err, "%q replied with a non numeric fee %s", bank.Id, reply)
+	}
+	if fee <= 0 {
+		return 0, nil
+	}
+	return int64(math.Round(fee)), nil
+}
+
+// bank makes the customer of transfer pay the fee and moves the money or item of transfer,
+// all at once.
+func (g *Game) bank(ctx context.Context, transfer *storage.BankTransfer) error {
+	ids := []string{transfer.Bank, transfer.Customer, transfer.Vault}
+	if transfer.Item != "" {
+		ids = append(ids, transfer.Item)
+	}
+	return lockSorted(ids, func() error {
+		return juicemud.WithStack(g.storage.Bank(ctx, transfer, func(customer *structs.Object) {
+			unequip(customer, transfer.Item)
+		}))
+	})
+}
+
+// bankCommand deposits or withdraws an item or an amount of money, or shows the vault.
+func (c *Connection) bankCommand(verb string, arg string) error {
+	ctx := c.sess.Context()
+	customer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	bank, err := c.game.findBank(ctx, customer)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if bank == nil {
+		return errors.New("There is no bank here")
+	}
+	vault, err := c.game.loadVault(ctx, customer.Id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if verb == "vault" {
+		items, err := c.game.storage.LoadObjects(ctx, vault.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintf(c.term, "Your vault holds %v money and %v of %v items\n", vault.Money, len(items), vaultCapacity.Load())
+		for _, item := range items {
+			fmt.Fprintln(c.term, shortDescription(item))
+		}
+		return nil
+	}
+	if arg == "" {
+		fmt.Fprintf(c.term, "usage: %s [item|amount]\n", verb)
+		return nil
+	}
+	query := &feeQuery{Action: verb, Customer: customer.Id}
+	transfer := &storage.BankTransfer{
+		Bank:     bank.Id,
+		Customer: customer.Id,
+		Vault:    vault.Id,
+		Withdraw: verb == withdrawAction,
+		Capacity: vaultCapacity.Load(),
+	}
+	if amount, err := strconv.ParseInt(arg, 10, 64); err == nil && amount > 0 {
+		query.Money = amount
+		if transfer.Fee, err = c.game.queryFee(ctx, bank, query); err != nil {
+			return juicemud.WithStack(err)
+		}
+		transfer.Money = amount
+		if err := c.game.bank(ctx, transfer); errors.Is(err, storage.ErrInsufficientFunds) {
+			if transfer.Fee > 0 {
+				return errors.Errorf("There isn't %v money to %s, or you can't afford the fee of %v", amount, verb, transfer.Fee)
+			}
+			return errors.Errorf("There isn't %v money to %s", amount, verb)
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintf(c.term, "You %s %v money\n", verb, amount)
+		return nil
+	}
+	container := customer
+	if transfer.Withdraw {
+		container = vault
+	}
+	carried, err := c.game.storage.LoadObjects(ctx, container.Content, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	item := matchObject(customer, carried, arg)
+	if item == nil {
+		return errors.Errorf("There is no %q to %s", arg, verb)
+	}
+	// Checked before asking for the fee too, to not ask for fees of deposits that can't be made.
+	if !transfer.Withdraw && int64(len(vault.Content)) >= transfer.Capacity {
+		return errors.New("Your vault is full")
+	}
+	query.Item = item.Id
+	if transfer.Fee, err = c.game.queryFee(ctx, bank, query); err != nil {
+		return juicemud.WithStack(err)
+	}
+	transfer.Item = item.Id
+	if err := c.game.bank(ctx, transfer); errors.Is(err, storage.ErrInsufficientFunds) {
+		return errors.Errorf("You can't afford the fee of %v", transfer.Fee)
+	} else if errors.Is(err, storage.ErrVaultFull) {
+		return errors.New("Your vault is full")
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You %s %s", verb, shortDescription(item))))
+	return nil
+}
+
+// addBankCallbacks adds `setVaultCapacity(items)` and `getVaultID(objectID)`, which returns the
+// ID of the vault of the object so banks can pay interest into it with createMoney. Objects
+// tagged "bank" let users in their location deposit and withdraw, and are asked for fees
+// with a `bankFee` call, if they handle it.
+func (g *Game) addBankCallbacks(ctx context.Context, callbacks js.Callbacks) {
+	callbacks["setVaultCapacity"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsNumber() || args[0].Integer() < 0 {
+			return rc.Throw("setVaultCapacity takes [non negative number] arguments")
+		}
+		vaultCapacity.Store(args[0].Integer())
+		return nil
+	}
+	callbacks["getVaultID"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getVaultID takes [string] arguments")
+		}
+		vault, err := g.loadVault(ctx, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load the vault of %q: %v", args[0].String(), err)
+		}
+		return rc.String(vault.Id)
+	}
+}
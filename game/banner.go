@@ -0,0 +1,90 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zond/juicemud"
+)
+
+const (
+	bannerSource = "/banner.txt"
+	// defaultBanner is shown when bannerSource is missing, empty or broken.
+	defaultBanner = "Welcome!"
+)
+
+const initialBanner = `Welcome!
+{{- if .ShowStats}}
+
+Up {{.Uptime}} since the reboot at {{.Rebooted}}, with {{.Online}} online.
+{{- end}}
+`
+
+// bannerData are the variables of the banner template in bannerSource. The statistics are
+// zero unless ShowStats.
+type bannerData struct {
+	// ShowStats is whether the server shows its statistics, see SetHideStats.
+	ShowStats bool
+	Uptime    string
+	Rebooted  string
+	Online    int
+}
+
+// SetHideStats makes the banner hide the server statistics, for servers that don't want to
+// reveal how busy they are.
+func (g *Game) SetHideStats(hide bool) {
+	g.hideStats.Store(hide)
+}
+
+func (g *Game) bannerData(lang string, now time.Time) bannerData {
+	if g.hideStats.Load() {
+		return bannerData{}
+	}
+	online := 0
+	for range envByObjectID.Keys() {
+		online++
+	}
+	return bannerData{
+		ShowStats: true,
+		Uptime:    formatDuration(lang, now.Sub(g.started)),
+		Rebooted:  g.started.Format(time.DateTime),
+		Online:    online,
+	}
+}
+
+// banner returns the template in bannerSource executed for a session in lang.
+func (g *Game) banner(ctx context.Context, lang string) (string, error) {
+	source, _, err := g.storage.LoadSource(ctx, bannerSource)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	// Missing sources load as empty.
+	if len(source) == 0 {
+		return defaultBanner, nil
+	}
+	tmpl, err := template.New(bannerSource).Parse(string(source))
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, g.bannerData(lang, time.Now())); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// showBanner shows the banner to a session that hasn't logged in yet.
+func (c *Connection) showBanner() {
+	banner, err := c.game.banner(c.sess.Context(), sessionLanguage(c.sess))
+	if err != nil {
+		log.Printf("trying to render %q: %v", bannerSource, err)
+		log.Println(juicemud.StackTrace(err))
+		banner = defaultBanner
+	}
+	fmt.Fprintf(c.term, "%s\n\n", c.wrap(banner))
+}
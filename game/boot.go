@@ -0,0 +1,120 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+)
+
+const (
+	bootDir           = "/boot"
+	bootScriptTimeout = time.Second
+)
+
+// bootResult is how running a boot script went.
+type bootResult struct {
+	Path     string
+	Duration time.Duration
+	Error    string
+}
+
+// bootScripts returns the paths of the scripts in bootDir, ordered by name.
+func (g *Game) bootScripts(ctx context.Context) ([]string, error) {
+	dir, err := g.storage.LoadFile(ctx, bootDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	children, err := g.storage.LoadChildren(ctx, dir.Id)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := []string{}
+	for _, child := range children {
+		if !child.Dir && strings.HasSuffix(child.Name, ".js") {
+			result = append(result, child.Path)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// runBootScript runs the script at path with the global callbacks, and the privileged
+// callbacks creating and registering objects without being an object.
+func (g *Game) runBootScript(ctx context.Context, path string) error {
+	source, _, err := g.storage.LoadSource(ctx, path)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	callbacks := js.Callbacks{}
+	g.addGlobalCallbacks(ctx, callbacks)
+	g.addAchievementCallbacks(ctx, nil, callbacks)
+	g.addCreateObjectCallbacks(ctx, callbacks)
+	g.addServiceCallbacks(ctx, nil, callbacks)
+	target := js.Target{
+		Source:    string(source),
+		Origin:    path,
+		State:     "{}",
+		Callbacks: callbacks,
+		Console:   os.Stderr,
+	}
+	_, err = target.Run(ctx, nil, bootScriptTimeout)
+	return juicemud.WithStack(err)
+}
+
+// runBoot runs bootSource and then the scripts in bootDir ordered by name, and recreates the
+// services still missing afterwards. Scripts failing are logged and don't stop the rest, and
+// the results are kept for `/stats boot`.
+func (g *Game) runBoot(ctx context.Context) error {
+	scripts, err := g.bootScripts(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	results := []bootResult{}
+	for _, path := range append([]string{bootSource}, scripts...) {
+		started := time.Now()
+		err := g.runBootScript(ctx, path)
+		result := bootResult{Path: path, Duration: time.Since(started)}
+		if err != nil {
+			log.Printf("trying to run %q: %v", path, err)
+			log.Println(juicemud.StackTrace(err))
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	recreated, err := g.recreateServices(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	g.bootResults = append(results, recreated...)
+	return nil
+}
+
+// printBootResults shows how running each boot script went at startup.
+func (c *Connection) printBootResults() {
+	t := c.table("Script", "Duration", "Result")
+	for _, result := range c.game.bootResults {
+		outcome := "ok"
+		if result.Error != "" {
+			outcome = result.Error
+		}
+		t.AddRow(result.Path, result.Duration.Round(time.Microsecond), outcome)
+	}
+	t.Print()
+	failed := 0
+	for _, result := range c.game.bootResults {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	fmt.Fprintf(c.term, "%d of %d boot scripts failed\n", failed, len(c.game.bootResults))
+}
@@ -0,0 +1,257 @@
+package game
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	calendarInterval      = 10 * time.Second
+	eventStartedEventType = "eventStarted"
+	eventEndedEventType   = "eventEnded"
+)
+
+// worldEvent is the message of eventStarted and eventEnded events, with times in
+// milliseconds since the epoch like JavaScript dates.
+type worldEvent struct {
+	Id          int64
+	Name        string
+	Description string
+	Start       int64
+	End         int64
+	// GameStart and GameEnd are Start and End as dates of the game calendar.
+	GameStart string
+	GameEnd   string
+}
+
+// runCalendar starts and ends the scheduled world events when they are due, and tells the
+// subscribers when the game hour changes, until ctx is done.
+func (g *Game) runCalendar(ctx context.Context) {
+	ticker := time.NewTicker(calendarInterval)
+	defer ticker.Stop()
+	last := GameDate{}
+	for {
+		now := time.Now()
+		if err := g.tickCalendar(ctx, now); err != nil {
+			log.Printf("trying to update the calendar: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+		if err := g.tickGameClock(ctx, now, &last); err != nil {
+			log.Printf("trying to update the game clock: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *Game) tickCalendar(ctx context.Context, now time.Time) error {
+	due, err := g.storage.DueWorldEvents(ctx, now.UnixNano())
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for i := range due {
+		event := &due[i]
+		if !event.Started && event.Start <= now.UnixNano() {
+			if err := g.emitWorldEvent(ctx, eventStartedEventType, event); err != nil {
+				return juicemud.WithStack(err)
+			}
+			event.Started = true
+		}
+		if !event.Ended && event.End <= now.UnixNano() {
+			if err := g.emitWorldEvent(ctx, eventEndedEventType, event); err != nil {
+				return juicemud.WithStack(err)
+			}
+			event.Ended = true
+		}
+		if err := g.storage.StoreWorldEvent(ctx, event); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// emitWorldEvent emits name to the objects subscribing to the topic name, and to the object
+// hooking event.
+func (g *Game) emitWorldEvent(ctx context.Context, name string, event *storage.WorldEvent) error {
+	subscribers, err := g.storage.ObjectIDsByTopic(ctx, name)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	recipients := map[string]bool{}
+	for id := range subscribers {
+		recipients[id] = true
+	}
+	if event.Object != "" {
+		recipients[event.Object] = true
+	}
+	calendar := g.gameCalendar.Load()
+	message, err := goccy.Marshal(&worldEvent{
+		Id:          event.Id,
+		Name:        event.Name,
+		Description: event.Description,
+		Start:       time.Unix(0, event.Start).UnixMilli(),
+		End:         time.Unix(0, event.End).UnixMilli(),
+		GameStart:   calendar.Date(time.Unix(0, event.Start)).String(),
+		GameEnd:     calendar.Date(time.Unix(0, event.End)).String(),
+	})
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	at := g.storage.Queue().After(0)
+	for id := range recipients {
+		if err := g.emitJSON(ctx, at, id, name, string(message)); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// parseEventTime parses either a duration from now prefixed with "+", a game date of calendar
+// like "@1000-3-12 06:00", or a local time in the time.DateTime format.
+func parseEventTime(s string, now time.Time, calendar *GameCalendar) (time.Time, error) {
+	if rest, found := strings.CutPrefix(s, "+"); found {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return time.Time{}, juicemud.WithStack(err)
+		}
+		return now.Add(d), nil
+	}
+	if rest, found := strings.CutPrefix(s, "@"); found {
+		var year, month, day, hour, minute int
+		if _, err := fmt.Sscanf(rest, "%d-%d-%d %d:%d", &year, &month, &day, &hour, &minute); err != nil {
+			return time.Time{}, errors.Wrapf(err, "%q isn't @YEAR-MONTH-DAY HH:MM", s)
+		}
+		t, err := calendar.Time(year, month, day, hour, minute)
+		return t, juicemud.WithStack(err)
+	}
+	t, err := time.ParseInLocation(time.DateTime, s, time.Local)
+	if err != nil {
+		return time.Time{}, juicemud.WithStack(err)
+	}
+	return t, nil
+}
+
+func (c *Connection) eventCommand(args []string) error {
+	usage := func() error {
+		fmt.Fprintln(c.term, "usage: /event list, /event create -start [time] -end [time] [-description text] [-hook #id] [name], /event delete [id]")
+		fmt.Fprintln(c.term, "times are +[duration], \"YYYY-MM-DD HH:MM:SS\", or game dates like \"@YEAR-MONTH-DAY HH:MM\"")
+		return nil
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	switch args[0] {
+	case "list":
+		return juicemud.WithStack(c.listEvents(true))
+	case "delete":
+		if len(args) != 2 {
+			return usage()
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return usage()
+		}
+		if err := c.game.storage.DeleteWorldEvent(c.sess.Context(), id); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintf(c.term, "Deleted event %v\n", id)
+		return nil
+	case "create":
+		flags := flag.NewFlagSet("/event create", flag.ContinueOnError)
+		flags.SetOutput(c.term)
+		start := flags.String("start", "", "When the event starts")
+		end := flags.String("end", "", "When the event ends")
+		description := flags.String("description", "", "What the event is about")
+		hook := flags.String("hook", "", "An object to tell about the event even if it doesn't subscribe")
+		if err := flags.Parse(args[1:]); err != nil {
+			return nil
+		}
+		if flags.NArg() == 0 || *start == "" || *end == "" {
+			return usage()
+		}
+		now := time.Now()
+		calendar := c.game.gameCalendar.Load()
+		startTime, err := parseEventTime(*start, now, calendar)
+		if err != nil {
+			fmt.Fprintf(c.term, "invalid start %q: %v\n", *start, err)
+			return nil
+		}
+		endTime, err := parseEventTime(*end, now, calendar)
+		if err != nil {
+			fmt.Fprintf(c.term, "invalid end %q: %v\n", *end, err)
+			return nil
+		}
+		if !endTime.After(startTime) {
+			fmt.Fprintln(c.term, "events must end after they start")
+			return nil
+		}
+		event := &storage.WorldEvent{
+			Name:        strings.Join(flags.Args(), " "),
+			Description: *description,
+			Start:       startTime.UnixNano(),
+			End:         endTime.UnixNano(),
+		}
+		if *hook != "" {
+			id, ok := parseObjectRef(*hook)
+			if !ok {
+				return usage()
+			}
+			if _, err := c.game.storage.LoadObject(c.sess.Context(), id, nil); err != nil {
+				return juicemud.WithStack(err)
+			}
+			event.Object = id
+		}
+		if err := c.game.storage.StoreWorldEvent(c.sess.Context(), event); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintf(c.term, "Created event %q, see /event list\n", event.Name)
+		return nil
+	}
+	return usage()
+}
+
+// listEvents shows the events that haven't ended yet, with their IDs and hooks if verbose.
+func (c *Connection) listEvents(verbose bool) error {
+	events, err := c.game.storage.UpcomingWorldEvents(c.sess.Context())
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(c.term, "No events scheduled")
+		return nil
+	}
+	now := time.Now().UnixNano()
+	headers := []any{"Event", "Starts", "Ends", "Description"}
+	if verbose {
+		headers = append([]any{"Id"}, append(headers, "Hook")...)
+	}
+	t := c.table(headers...)
+	for _, event := range events {
+		starts := time.Unix(0, event.Start).Format(time.DateTime)
+		if event.Start <= now {
+			starts = "ongoing"
+		}
+		row := []any{event.Name, starts, time.Unix(0, event.End).Format(time.DateTime), event.Description}
+		if verbose {
+			row = append([]any{event.Id}, append(row, event.Object)...)
+		}
+		t.AddRow(row...)
+	}
+	t.Print()
+	return nil
+}
@@ -0,0 +1,66 @@
+package game
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/rodaine/table"
+	"github.com/zond/juicemud/lang"
+)
+
+const (
+	charsetUTF8  = "utf-8"
+	charsetASCII = "ascii"
+)
+
+// charsetReadWriter sits between the terminal and the session, and replaces everything the
+// session can't show when in ASCII mode.
+type charsetReadWriter struct {
+	io.ReadWriter
+	ascii atomic.Bool
+}
+
+func (c *charsetReadWriter) Write(b []byte) (int, error) {
+	if !c.ascii.Load() {
+		return c.ReadWriter.Write(b)
+	}
+	if _, err := io.WriteString(c.ReadWriter, lang.ASCII(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// sessionCharset guesses the charset of the session from the locale it sent, assuming
+// UTF-8 if it sent none.
+func sessionCharset(sess ssh.Session) string {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		for _, env := range sess.Environ() {
+			if value, found := strings.CutPrefix(env, name+"="); found && value != "" {
+				value = strings.ToLower(value)
+				if strings.Contains(value, "utf-8") || strings.Contains(value, "utf8") {
+					return charsetUTF8
+				}
+				return charsetASCII
+			}
+		}
+	}
+	return charsetUTF8
+}
+
+// applyCharset makes the terminal show only what the charset of the user, or the guessed
+// charset of the session, can show.
+func (c *Connection) applyCharset() {
+	charset := c.user.Charset
+	if charset == "" {
+		charset = sessionCharset(c.sess)
+	}
+	c.charset.ascii.Store(charset == charsetASCII)
+}
+
+// table returns a table writing to the terminal, measuring the columns as the terminal
+// will show them.
+func (c *Connection) table(headers ...any) table.Table {
+	return table.New(headers...).WithWriter(c.term).WithWidthFunc(lang.Width)
+}
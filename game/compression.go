@@ -0,0 +1,50 @@
+package game
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"sync"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/zond/juicemud"
+)
+
+// compressedSession is a session whose output is a zlib stream, escaped with
+// juicemud.NewlineEscaper. The stream is flushed after every write, so prompts and messages
+// arrive right away instead of when a block is full.
+type compressedSession struct {
+	ssh.Session
+	mutex  sync.Mutex
+	writer *zlib.Writer
+}
+
+func (c *compressedSession) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	written := len(b)
+	// The session only turns newlines into CRLF for terminals if they reach it uncompressed.
+	if _, _, isPty := c.Session.Pty(); isPty {
+		b = bytes.ReplaceAll(b, []byte{'\n'}, []byte{'\r', '\n'})
+		b = bytes.ReplaceAll(b, []byte{'\r', '\r', '\n'}, []byte{'\r', '\n'})
+	}
+	if _, err := c.writer.Write(b); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return written, nil
+}
+
+// compressSession returns sess with compressed output if the client set juicemud.CompressionEnv
+// to juicemud.CompressionZlib, and sess otherwise. Only the output is compressed, since that's
+// where the text heavy traffic is.
+func compressSession(sess ssh.Session) ssh.Session {
+	for _, variable := range sess.Environ() {
+		if name, value, _ := strings.Cut(variable, "="); name == juicemud.CompressionEnv && value == juicemud.CompressionZlib {
+			return &compressedSession{Session: sess, writer: zlib.NewWriter(juicemud.NewlineEscaper(sess))}
+		}
+	}
+	return sess
+}
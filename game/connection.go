@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/buildkite/shellwords"
 	"github.com/gliderlabs/ssh"
 	"github.com/pkg/errors"
-	"github.com/rodaine/table"
 	"github.com/zond/juicemud"
 	"github.com/zond/juicemud/digest"
+	"github.com/zond/juicemud/game/mapping"
 	"github.com/zond/juicemud/lang"
 	"github.com/zond/juicemud/storage"
 	"github.com/zond/juicemud/structs"
@@ -51,10 +55,27 @@ func (e errs) Error() string {
 }
 
 type Connection struct {
-	game *Game
-	sess ssh.Session
-	term *term.Terminal
-	user *storage.User
+	game     *Game
+	sess     ssh.Session
+	term     *term.Terminal
+	charset  *charsetReadWriter
+	recorder *recorder
+	width    atomic.Int64
+	user     *storage.User
+	// puppet is the object the connection acts as instead of the object of the user, if any.
+	puppet string
+	// character is what the user chose when creating their character in this session, if
+	// they did.
+	character *character
+	// listener is what the session connected through.
+	listener *Listener
+	// lastLogin is when the user logged in before this session, in Unix nanoseconds.
+	lastLogin int64
+	// done is closed when the connection stops processing commands.
+	done chan struct{}
+	// readOnly is whether the connection can only run readOnlyCommands. It's cleared when
+	// the connection becomes the first session of its user.
+	readOnly atomic.Bool
 }
 
 func (c *Connection) SelectExec(options map[string]func() error) error {
@@ -96,7 +117,7 @@ func (c *Connection) SelectReturn(prompt string, options []string) (string, erro
 }
 
 func (c *Connection) object() (*structs.Object, error) {
-	return c.game.storage.LoadObject(c.sess.Context(), c.user.Object, c.game.rerunSource)
+	return c.game.storage.LoadObject(c.sess.Context(), c.actor(), c.game.rerunSource)
 }
 
 func (c *Connection) describeLong() error {
@@ -109,20 +130,53 @@ func (c *Connection) describeLong() error {
 	if err != nil {
 		return juicemud.WithStack(err)
 	}
+	languages := c.languages()
+	for _, object := range neigh.Location.All() {
+		object.Translate(languages)
+		object.Personalize(obj)
+	}
 	desc, exits, siblings := neigh.Location.Inspect(obj)
 	if desc != nil {
-		fmt.Fprintln(c.term, desc.Short)
+		long, err := c.game.composeDescription(c.sess.Context(), neigh.Location.Container, neigh.Location.Content, obj, desc.Long)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, c.wrap(desc.Short))
 		fmt.Fprintln(c.term)
-		fmt.Fprintln(c.term, desc.Long)
+		fmt.Fprintln(c.term, c.wrap(long))
 	}
 	if len(siblings) > 0 {
 		fmt.Fprintln(c.term)
-		fmt.Fprintf(c.term, "%s here\n", lang.Enumerator{Active: true}.Do(siblings.Short()...))
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("%s here", lang.Enumerator{Active: true}.Do(siblings.Short()...))))
 	}
 	if len(exits) > 0 {
 		fmt.Fprintln(c.term)
-		fmt.Fprintln(c.term, exits.Short())
+		fmt.Fprintln(c.term, c.wrap(exits.Short()))
+	}
+	return nil
+}
+
+func (c *Connection) printObjects(ids map[string]bool) error {
+	objects, err := c.game.storage.LoadObjects(c.sess.Context(), ids, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	sortedIDs := make(sort.StringSlice, 0, len(objects))
+	for id := range objects {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Sort(sortedIDs)
+	t := c.table("Id", "Short", "Source", "Location")
+	for _, id := range sortedIDs {
+		object := objects[id]
+		short := ""
+		if len(object.Descriptions) > 0 {
+			short = object.Descriptions[0].Short
+		}
+		t.AddRow(object.Id, short, object.SourcePath, object.Location)
 	}
+	t.Print()
+	fmt.Fprintf(c.term, "%s\n", lang.Declare(len(objects), "objects"))
 	return nil
 }
 
@@ -143,59 +197,761 @@ func m(s ...string) map[string]bool {
 var (
 	commands = []command{
 		{
-			names: m("groups"),
+			names: m("help"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.helpCommand())
+			},
+		},
+		{
+			names: m("stop"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.stopCommand())
+			},
+		},
+		{
+			names: m("groups"),
+			f: func(c *Connection, s string) error {
+				groups, err := c.game.storage.UserGroups(c.sess.Context(), c.user)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				sort.Sort(groups)
+				fmt.Fprintf(c.term, "Member of %v\n", lang.Declare(len(groups), "groups"))
+				for _, group := range groups {
+					fmt.Fprintln(c.term, group.Name)
+				}
+				return nil
+			},
+		},
+		{
+			names: m("charset"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) == 1 {
+					if c.user.Charset == "" {
+						fmt.Fprintf(c.term, "Charset is auto, currently %s\n", sessionCharset(c.sess))
+					} else {
+						fmt.Fprintf(c.term, "Charset is %s\n", c.user.Charset)
+					}
+					return nil
+				}
+				name := strings.ToLower(parts[1])
+				if len(parts) != 2 || (name != "auto" && name != charsetUTF8 && name != charsetASCII) {
+					fmt.Fprintf(c.term, "usage: charset [auto, %s or %s]\n", charsetUTF8, charsetASCII)
+					return nil
+				}
+				c.user.Charset = name
+				if name == "auto" {
+					c.user.Charset = ""
+				}
+				if err := c.game.storage.StoreUser(c.sess.Context(), c.user, true); err != nil {
+					return juicemud.WithStack(err)
+				}
+				c.applyCharset()
+				fmt.Fprintf(c.term, "Charset set to %s\n", name)
+				return nil
+			},
+		},
+		{
+			names: m("language"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) == 1 {
+					if c.user.Language == "" {
+						fmt.Fprintf(c.term, "Language is auto, currently %q\n", sessionLanguage(c.sess))
+					} else {
+						fmt.Fprintf(c.term, "Language is %s\n", c.user.Language)
+					}
+					return nil
+				}
+				if len(parts) != 2 {
+					fmt.Fprintln(c.term, "usage: language [auto or language, e.g. en or pt-br]")
+					return nil
+				}
+				c.user.Language = normalizeLanguage(parts[1])
+				if c.user.Language == "auto" {
+					c.user.Language = ""
+				}
+				if err := c.game.storage.StoreUser(c.sess.Context(), c.user, true); err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Language set to %s\n", parts[1])
+				return nil
+			},
+		},
+		{
+			names: m("achievements"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.achievementsCommand())
+			},
+		},
+		{
+			names: m("news"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.newsCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("top"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.topCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("events"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.listEvents(false))
+			},
+		},
+		{
+			names: m("record"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) == 1 {
+					if c.user.Record {
+						fmt.Fprintln(c.term, "Sessions are recorded")
+					} else {
+						fmt.Fprintln(c.term, "Sessions are not recorded")
+					}
+					return nil
+				}
+				if len(parts) != 2 || (parts[1] != "on" && parts[1] != "off") {
+					fmt.Fprintln(c.term, "usage: record [on or off]")
+					return nil
+				}
+				c.user.Record = parts[1] == "on"
+				if err := c.game.storage.StoreUser(c.sess.Context(), c.user, true); err != nil {
+					return juicemud.WithStack(err)
+				}
+				if c.user.Record {
+					if err := c.recorder.start(c.user, c.sess); err != nil {
+						return juicemud.WithStack(err)
+					}
+					fmt.Fprintln(c.term, "Recording sessions, review recent output with /recall")
+				} else {
+					c.recorder.stop()
+					fmt.Fprintln(c.term, "Stopped recording sessions")
+				}
+				return nil
+			},
+		},
+		{
+			names: m("/recall"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				lines := defaultRecallLines
+				if len(parts) == 2 {
+					if lines, err = strconv.Atoi(parts[1]); err != nil || lines < 1 {
+						fmt.Fprintln(c.term, "usage: /recall [lines]")
+						return nil
+					}
+				} else if len(parts) != 1 {
+					fmt.Fprintln(c.term, "usage: /recall [lines]")
+					return nil
+				}
+				recalled, found := c.recorder.recall(lines)
+				if !found {
+					fmt.Fprintln(c.term, "Not recording, start with `record on`")
+					return nil
+				}
+				fmt.Fprintln(c.term, recalled)
+				return nil
+			},
+		},
+		{
+			names: m("/export-me"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.exportMeCommand())
+			},
+		},
+		{
+			names:  m("/create"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) != 2 && len(parts) != 3 {
+					fmt.Fprintln(c.term, "usage: /create [path] [params JSON]")
+					return nil
+				}
+				params := ""
+				if len(parts) == 3 {
+					params = parts[2]
+					if !goccy.Valid([]byte(params)) {
+						fmt.Fprintf(c.term, "invalid params JSON: %q\n", params)
+						return nil
+					}
+				}
+				obj, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				created, err := c.game.createObjectFromSource(c.sess.Context(), parts[1], obj.Location, params)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Created #%s\n", created.Id)
+				return nil
+			},
+		},
+		{
+			names:  m("/find"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				kinds := append([]string{"location", "modified", "after", "limit"}, storage.IndexKinds...)
+				usage := fmt.Sprintf("usage: /find [kind]=[value]... (kind is %s, modified is a duration like 1h, after is the cursor of the previous page)", lang.Enumerator{Operator: "or"}.Do(kinds...))
+				if len(parts) < 2 {
+					fmt.Fprintln(c.term, usage)
+					return nil
+				}
+				filter := &storage.ObjectFilter{Index: map[string]string{}}
+				cursor, limit := "", 0
+				for _, part := range parts[1:] {
+					key, value, found := strings.Cut(part, "=")
+					if !found || !slices.Contains(kinds, key) {
+						fmt.Fprintln(c.term, usage)
+						return nil
+					}
+					switch key {
+					case storage.SourceIndex:
+						filter.SourcePath = value
+					case storage.TagIndex:
+						filter.Tags = append(filter.Tags, value)
+					case "location":
+						filter.Location = value
+					case "modified":
+						d, err := time.ParseDuration(value)
+						if err != nil {
+							fmt.Fprintln(c.term, usage)
+							return nil
+						}
+						filter.ModifiedAfter = time.Now().Add(-d).UnixNano()
+					case "after":
+						cursor = value
+					case "limit":
+						if limit, err = strconv.Atoi(value); err != nil {
+							fmt.Fprintln(c.term, usage)
+							return nil
+						}
+					default:
+						filter.Index[key] = value
+					}
+				}
+				objects, next, err := c.game.storage.QueryObjects(c.sess.Context(), filter, cursor, limit)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				ids := map[string]bool{}
+				for _, object := range objects {
+					ids[object.Id] = true
+				}
+				if err := c.printObjects(ids); err != nil {
+					return juicemud.WithStack(err)
+				}
+				if next != "" {
+					fmt.Fprintf(c.term, "More with after=%s\n", next)
+				}
+				return nil
+			},
+		},
+		{
+			names:  m("/stats"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.stats(parts[1:]))
+			},
+		},
+		{
+			names:  m("/reindex"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				if err := c.game.storage.ReindexObjects(c.sess.Context()); err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintln(c.term, "Object index rebuilt")
+				return nil
+			},
+		},
+		{
+			names:  m("/export"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				usage := "usage: /export #[id] [depth]"
+				if len(parts) != 2 && len(parts) != 3 {
+					fmt.Fprintln(c.term, usage)
+					return nil
+				}
+				id, ok := parseObjectRef(parts[1])
+				if !ok {
+					fmt.Fprintln(c.term, usage)
+					return nil
+				}
+				depth := -1
+				if len(parts) == 3 {
+					if depth, err = strconv.Atoi(parts[2]); err != nil {
+						fmt.Fprintln(c.term, usage)
+						return nil
+					}
+				}
+				path, err := c.game.exportArchiveToFile(c.sess.Context(), id, depth)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Exported #%s to %s\n", id, path)
+				return nil
+			},
+		},
+		{
+			names:  m("/import"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) != 2 {
+					fmt.Fprintln(c.term, "usage: /import [path]")
+					return nil
+				}
+				obj, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				imported, err := c.game.importArchiveFromFile(c.sess.Context(), parts[1], obj.Location)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Imported %s as #%s\n", parts[1], imported.Id)
+				return nil
+			},
+		},
+		{
+			names:  m("/import-player"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.importPlayerCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/area"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) != 2 {
+					fmt.Fprintln(c.term, "usage: /area [path]")
+					return nil
+				}
+				roomIDs, err := c.game.loadArea(c.sess.Context(), parts[1])
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				for _, id := range roomIDs {
+					fmt.Fprintf(c.term, "#%s\n", id)
+				}
+				fmt.Fprintf(c.term, "Loaded %s from %s\n", lang.Declare(len(roomIDs), "rooms"), parts[1])
+				return nil
+			},
+		},
+		{
+			names:  m("/map"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				radius := 3
+				if len(parts) == 2 {
+					if radius, err = strconv.Atoi(parts[1]); err != nil {
+						fmt.Fprintln(c.term, "usage: /map [radius]")
+						return nil
+					}
+				} else if len(parts) != 1 {
+					fmt.Fprintln(c.term, "usage: /map [radius]")
+					return nil
+				}
+				obj, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				graph, err := mapping.Build(obj.Location, radius, func(ids map[string]bool) (map[string]*structs.Object, error) {
+					return c.game.storage.LoadObjects(c.sess.Context(), ids, nil)
+				})
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprint(c.term, graph.ASCII())
+				return nil
+			},
+		},
+		{
+			names:  m("/dig"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				usage := "usage: /dig [direction] [#id or source path]"
+				if len(parts) != 2 && len(parts) != 3 {
+					fmt.Fprintln(c.term, usage)
+					return nil
+				}
+				destinationID, sourcePath := "", ""
+				if len(parts) == 3 {
+					if id, isRef := parseObjectRef(parts[2]); isRef {
+						destinationID = id
+					} else {
+						sourcePath = parts[2]
+					}
+				}
+				obj, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				ctx := c.sess.Context()
+				jsContextLocks.Lock(obj.Location)
+				defer jsContextLocks.Unlock(obj.Location)
+				location, err := c.game.storage.LoadObject(ctx, obj.Location, nil)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				destination, err := c.game.dig(ctx, location, parts[1], destinationID, sourcePath)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if err := c.game.storage.StoreObject(ctx, nil, location); err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Dug %s to #%s\n", parts[1], destination.Id)
+				return nil
+			},
+		},
+		{
+			names:  m("/redeliver"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				if len(parts) < 2 {
+					fmt.Fprintln(c.term, "usage: /redeliver [all or dead letter id...]")
+					return nil
+				}
+				ids := []int64{}
+				if len(parts) != 2 || parts[1] != "all" {
+					for _, part := range parts[1:] {
+						id, err := strconv.ParseInt(part, 10, 64)
+						if err != nil {
+							fmt.Fprintln(c.term, "usage: /redeliver [all or dead letter id...]")
+							return nil
+						}
+						ids = append(ids, id)
+					}
+				}
+				count, err := c.game.storage.RedeliverDeadLetters(c.sess.Context(), ids)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintf(c.term, "Redelivering %s\n", lang.Declare(count, "dead letters"))
+				return nil
+			},
+		},
+		{
+			names:  m("/apitoken"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				usage := func() error {
+					fmt.Fprintln(c.term, "usage: /apitoken [list], /apitoken create [name], /apitoken revoke [id]")
+					return nil
+				}
+				if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+					tokens, err := c.game.storage.APITokens(c.sess.Context(), c.user)
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					t := c.table("Id", "Name", "Created")
+					for _, token := range tokens {
+						t.AddRow(token.Id, token.Name, time.Unix(0, token.Created).Format(time.DateTime))
+					}
+					t.Print()
+					return nil
+				}
+				if len(parts) != 3 {
+					return usage()
+				}
+				switch parts[1] {
+				case "create":
+					secret, err := c.game.storage.CreateAPIToken(c.sess.Context(), c.user, parts[2])
+					if err != nil {
+						return juicemud.WithStack(err)
+					}
+					fmt.Fprintf(c.term, "Created API token %q, it will not be shown again:\n%s\n", parts[2], secret)
+				case "revoke":
+					id, err := strconv.ParseInt(parts[2], 10, 64)
+					if err != nil {
+						return usage()
+					}
+					if err := c.game.storage.DeleteAPIToken(c.sess.Context(), c.user, id); errors.Is(err, os.ErrNotExist) {
+						fmt.Fprintf(c.term, "No API token %v\n", id)
+						return nil
+					} else if err != nil {
+						return juicemud.WithStack(err)
+					}
+					fmt.Fprintf(c.term, "Revoked API token %v\n", id)
+				default:
+					return usage()
+				}
+				return nil
+			},
+		},
+		{
+			names:  m("/fsck"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				repair := false
+				if len(parts) == 2 && parts[1] == "repair" {
+					repair = true
+				} else if len(parts) != 1 {
+					fmt.Fprintln(c.term, "usage: /fsck [repair]")
+					return nil
+				}
+				problems, err := c.game.storage.Fsck(c.sess.Context(), repair, genesisID)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				for _, problem := range problems {
+					fmt.Fprintln(c.term, problem)
+				}
+				fmt.Fprintf(c.term, "%s found\n", lang.Declare(len(problems), "problems"))
+				return nil
+			},
+		},
+		{
+			names:  m("/state"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				obj, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				state := map[string]any{}
+				if err := goccy.Unmarshal([]byte(obj.State), &state); err != nil {
+					return juicemud.WithStack(err)
+				}
+				js, err := goccy.MarshalIndent(state, "  ", "  ")
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				fmt.Fprintln(c.term, string(js))
+				return nil
+			},
+		},
+		{
+			names:  m("/flags"),
+			wizard: true,
 			f: func(c *Connection, s string) error {
-				groups, err := c.game.storage.UserGroups(c.sess.Context(), c.user)
+				parts, err := shellwords.SplitPosix(s)
 				if err != nil {
 					return juicemud.WithStack(err)
 				}
-				sort.Sort(groups)
-				fmt.Fprintf(c.term, "Member of %v\n", lang.Declare(len(groups), "groups"))
-				for _, group := range groups {
-					fmt.Fprintln(c.term, group.Name)
+				return juicemud.WithStack(c.flagsCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/disable-source", "/enable-source"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
 				}
-				return nil
+				return juicemud.WithStack(c.disableSourceCommand(parts[0], parts[1:]))
 			},
 		},
 		{
-			names:  m("/create"),
+			names:  m("/login-policy"),
 			wizard: true,
 			f: func(c *Connection, s string) error {
 				parts, err := shellwords.SplitPosix(s)
 				if err != nil {
 					return juicemud.WithStack(err)
 				}
-				if len(parts) != 2 {
-					fmt.Fprintln(c.term, "usage: /create [path]")
+				return juicemud.WithStack(c.loginPolicyCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/ipban", "/ipunban"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
 				}
-				return nil
+				return juicemud.WithStack(c.ipBanCommand(parts[0], parts[1:]))
 			},
 		},
 		{
-			names:  m("/state"),
+			names:  m("/remove", "/trash", "/restore"),
 			wizard: true,
 			f: func(c *Connection, s string) error {
-				obj, err := c.game.storage.LoadObject(c.sess.Context(), c.user.Object, c.game.rerunSource)
+				parts, err := shellwords.SplitPosix(s)
 				if err != nil {
 					return juicemud.WithStack(err)
 				}
-				state := map[string]any{}
-				if err := goccy.Unmarshal([]byte(obj.State), &state); err != nil {
+				return juicemud.WithStack(c.trashCommand(parts[0], parts[1:]))
+			},
+		},
+		{
+			names:  m("/possess"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
 					return juicemud.WithStack(err)
 				}
-				js, err := goccy.MarshalIndent(state, "  ", "  ")
+				return juicemud.WithStack(c.possessCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/release"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				if !c.release() {
+					fmt.Fprintln(c.term, "Not possessing anything")
+					return nil
+				}
+				fmt.Fprint(c.term, "Released\n\n")
+				return juicemud.WithStack(c.describeLong())
+			},
+		},
+		{
+			names:  m("/invis"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
 				if err != nil {
 					return juicemud.WithStack(err)
 				}
-				fmt.Fprintln(c.term, string(js))
+				return juicemud.WithStack(c.invisCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/announce"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.announceCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/announce-room"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				_, message, _ := strings.Cut(s, " ")
+				if message = strings.TrimSpace(message); message == "" {
+					fmt.Fprintln(c.term, "usage: /announce-room [message]")
+					return nil
+				}
+				object, err := c.object()
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				c.game.announce(c.sess.Context(), object.Location, message)
 				return nil
 			},
 		},
+		{
+			names:  m("/analytics"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.analyticsCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/news-edit"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.newsEditCommand(parts[1:]))
+			},
+		},
+		{
+			names:  m("/event"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.eventCommand(parts[1:]))
+			},
+		},
 		{
 			names:  m("/debug"),
 			wizard: true,
 			f: func(c *Connection, s string) error {
-				addConsole(string(c.user.Object), c.term)
+				addConsole(c.actor(), c.term)
 				return nil
 			},
 		},
@@ -203,16 +959,189 @@ var (
 			names:  m("/undebug"),
 			wizard: true,
 			f: func(c *Connection, s string) error {
-				delConsole(string(c.user.Object), c.term)
+				delConsole(c.actor(), c.term)
+				return nil
+			},
+		},
+		{
+			names:  m("/trace"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				id := ""
+				if len(parts) == 2 {
+					id, _ = parseObjectRef(parts[1])
+				}
+				if id == "" {
+					fmt.Fprintln(c.term, "usage: /trace #[id]")
+					return nil
+				}
+				if _, err := c.game.storage.LoadObject(c.sess.Context(), id, nil); err != nil {
+					return juicemud.WithStack(err)
+				}
+				addTrace(id, c.term)
+				fmt.Fprintf(c.term, "Tracing #%s\n", id)
+				return nil
+			},
+		},
+		{
+			names:  m("/untrace"),
+			wizard: true,
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				ids := []string{}
+				if len(parts) == 2 {
+					id, isRef := parseObjectRef(parts[1])
+					if !isRef {
+						fmt.Fprintln(c.term, "usage: /untrace [#id]")
+						return nil
+					}
+					delTrace(id, c.term)
+					ids = append(ids, id)
+				} else {
+					ids = delTraces(c.term)
+				}
+				for _, id := range ids {
+					fmt.Fprintf(c.term, "Stopped tracing #%s\n", id)
+				}
 				return nil
 			},
 		},
 		{
 			names: m("l", "look"),
 			f: func(c *Connection, s string) error {
+				if _, name, _ := strings.Cut(s, " "); strings.TrimSpace(name) != "" {
+					return juicemud.WithStack(c.lookAt(strings.TrimSpace(name)))
+				}
 				return c.describeLong()
 			},
 		},
+		{
+			names: m(senses...),
+			f: func(c *Connection, s string) error {
+				channel, name, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.senseCommand(channel, strings.TrimSpace(name)))
+			},
+		},
+		{
+			names: m("wear", "wield", "remove"),
+			f: func(c *Connection, s string) error {
+				verb, name, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.equipCommand(verb, strings.TrimSpace(name)))
+			},
+		},
+		{
+			names: m("enter"),
+			f: func(c *Connection, s string) error {
+				_, name, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.enterCommand(strings.TrimSpace(name)))
+			},
+		},
+		{
+			names: m("list"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.listCommand())
+			},
+		},
+		{
+			names: m("buy", "sell"),
+			f: func(c *Connection, s string) error {
+				verb, name, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.shopCommand(verb, strings.TrimSpace(name)))
+			},
+		},
+		{
+			names: m("deposit", "withdraw", "vault"),
+			f: func(c *Connection, s string) error {
+				verb, arg, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.bankCommand(verb, strings.TrimSpace(arg)))
+			},
+		},
+		{
+			names: m("house"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.houseCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("trade"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.tradeCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("title"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.titleCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("talk"),
+			f: func(c *Connection, s string) error {
+				_, rest, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.talkCommand(rest))
+			},
+		},
+		{
+			names: m("remember"),
+			f: func(c *Connection, s string) error {
+				_, rest, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.rememberCommand(rest))
+			},
+		},
+		{
+			names: m("forget"),
+			f: func(c *Connection, s string) error {
+				_, rest, _ := strings.Cut(s, " ")
+				return juicemud.WithStack(c.forgetCommand(rest))
+			},
+		},
+		{
+			names: m("speak"),
+			f: func(c *Connection, s string) error {
+				parts, err := shellwords.SplitPosix(s)
+				if err != nil {
+					return juicemud.WithStack(err)
+				}
+				return juicemud.WithStack(c.speakCommand(parts[1:]))
+			},
+		},
+		{
+			names: m("who"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.whoCommand())
+			},
+		},
+		{
+			names: m("score"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.scoreCommand())
+			},
+		},
+		{
+			names: m("eq", "equipment"),
+			f: func(c *Connection, s string) error {
+				return juicemud.WithStack(c.equipmentCommand())
+			},
+		},
 		{
 			names:  m("!chwrite"),
 			wizard: true,
@@ -269,7 +1198,7 @@ var (
 					return nil
 				}
 				parts = parts[1:]
-				t := table.New("Path", "Read", "Write").WithWriter(c.term)
+				t := c.table("Path", "Read", "Write")
 				for _, part := range parts {
 					f, err := c.game.storage.LoadFile(c.sess.Context(), part)
 					if errors.Is(err, os.ErrNotExist) {
@@ -323,19 +1252,50 @@ func (c *Connection) Process() error {
 	if c.user == nil {
 		return errors.New("can't process without user")
 	}
-	envByObjectID.Set(string(c.user.Object), c)
-	defer envByObjectID.Del(string(c.user.Object))
+	defer close(c.done)
+	// Unregistered before done is closed, so that sessions taking over this one are the first
+	// session of the user when they stop waiting for it.
+	defer unregisterSession(c)
+	defer cancelTrade(string(c.user.Object))
+	defer cancelTravel(string(c.user.Object))
+	defer slowAlerts.unsubscribe(c.term)
+	defer delTraces(c.term)
+	defer c.release()
 	for {
 		line, err := c.term.ReadLine()
 		if err != nil {
 			return juicemud.WithStack(err)
 		}
+		if fields := strings.Fields(line); c.readOnly.Load() && len(fields) > 0 && !readOnlyCommands[fields[0]] {
+			fmt.Fprintln(c.term, "This session is read only.")
+			continue
+		}
+		// Wizard commands can't be hooked, so they work in any situation.
+		if !strings.HasPrefix(line, "/") && !strings.HasPrefix(line, "!") {
+			hooked, run, err := c.hookCommand(line)
+			if err != nil {
+				fmt.Fprintln(c.term, err)
+				continue
+			}
+			if !run {
+				continue
+			}
+			line = hooked
+		}
 		words := whitespacePattern.Split(line, -1)
 		if len(words) == 0 {
 			continue
 		}
+		if words[0] != "" {
+			if err := c.recordTutorialCommand(words[0]); err != nil {
+				fmt.Fprintln(c.term, err)
+			}
+		}
+		matched := false
 		for _, cmd := range commands {
 			if cmd.names[words[0]] {
+				matched = true
+				c.game.countAnalytics(commandAnalytics, analyticsDay, words[0])
 				if cmd.wizard {
 					if has, err := c.game.storage.UserAccessToGroup(c.sess.Context(), c.user, wizardsGroup); err != nil {
 						return juicemud.WithStack(err)
@@ -351,11 +1311,42 @@ func (c *Connection) Process() error {
 				}
 			}
 		}
+		if !matched && words[0] != "" {
+			// Script commands, exits and socials are counted by name, and speech only as say,
+			// to not store what players say.
+			if found, err := c.jsCommand(line); err != nil {
+				fmt.Fprintln(c.term, err)
+			} else if found {
+				c.game.countAnalytics(commandAnalytics, analyticsDay, words[0])
+			} else {
+				if found, err := c.exitCommand(line); err != nil {
+					fmt.Fprintln(c.term, err)
+				} else if found {
+					c.game.countAnalytics(commandAnalytics, analyticsDay, "exit")
+				} else {
+					if found, err := c.sayCommand(line); err != nil {
+						fmt.Fprintln(c.term, err)
+					} else if found {
+						c.game.countAnalytics(commandAnalytics, analyticsDay, "say")
+					} else {
+						if found, err := c.socialCommand(line); err != nil {
+							fmt.Fprintln(c.term, err)
+						} else if found {
+							c.game.countAnalytics(commandAnalytics, analyticsDay, words[0])
+						} else {
+							c.game.countAnalytics(commandAnalytics, analyticsDay, unknownCommand)
+							fmt.Fprintf(c.term, "Unknown command %q, see `help`\n", words[0])
+						}
+					}
+				}
+			}
+		}
 	}
 }
 
 func (c *Connection) Connect() error {
-	fmt.Fprint(c.term, "Welcome!\n\n")
+	c.watchWindow()
+	c.showBanner()
 	sel := func() error {
 		return c.SelectExec(map[string]func() error{
 			"login user":  c.loginUser,
@@ -368,6 +1359,42 @@ func (c *Connection) Connect() error {
 	if err != nil {
 		return juicemud.WithStack(err)
 	}
+	if c.listener.WizardsOnly {
+		if wizard, err := c.game.storage.UserAccessToGroup(c.sess.Context(), c.user, wizardsGroup); err != nil {
+			return juicemud.WithStack(err)
+		} else if !wizard {
+			fmt.Fprintln(c.term, "Only wizards can connect here.")
+			return nil
+		}
+	}
+	if c.listener.MOTD != "" {
+		fmt.Fprintf(c.term, "%s\n\n", c.wrap(c.listener.MOTD))
+	}
+	proceed, err := c.enforceLoginPolicy()
+	if proceed {
+		// In case the session ends before it processes commands.
+		defer unregisterSession(c)
+	}
+	if err != nil {
+		return juicemud.WithStack(err)
+	} else if !proceed {
+		return nil
+	}
+	if err := c.recordLogin(); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := c.countRetention(); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := c.showLoginNews(); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if c.user.Record {
+		if err := c.recorder.start(c.user, c.sess); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	defer c.recorder.stop()
 	if err := c.game.loadRunSave(c.sess.Context(), c.user.Object, &AnyCall{
 		Name: connectedEventType,
 		Tag:  emitEventTag,
@@ -379,6 +1406,20 @@ func (c *Connection) Connect() error {
 	}); err != nil {
 		return juicemud.WithStack(err)
 	}
+	if c.character != nil {
+		if err := c.game.loadRunSave(c.sess.Context(), c.user.Object, &AnyCall{
+			Name: firstLoginEventType,
+			Tag:  emitEventTag,
+			Content: map[string]any{
+				"username":  c.user.Name,
+				"object":    c.user.Object,
+				"name":      c.character.Name,
+				"archetype": c.character.Archetype,
+			},
+		}); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
 	if err := c.describeLong(); err != nil {
 		return juicemud.WithStack(err)
 	}
@@ -417,6 +1458,7 @@ func (c *Connection) loginUser() error {
 		}
 	}
 	storage.AuthenticateUser(c.sess.Context(), c.user)
+	c.applyCharset()
 	fmt.Fprintf(c.term, "Welcome back, %v!\n\n", c.user.Name)
 	return nil
 }
@@ -469,10 +1511,16 @@ func (c *Connection) createUser() error {
 			fmt.Fprintln(c.term, "Passwords don't match!")
 		}
 	}
-	if err := c.game.createUser(c.sess.Context(), c.user); err != nil {
+	char, err := c.createCharacter(c.user.Name)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := c.game.createUser(c.sess.Context(), c.user, char); err != nil {
 		return juicemud.WithStack(err)
 	}
+	c.character = char
 	storage.AuthenticateUser(c.sess.Context(), c.user)
+	c.applyCharset()
 	fmt.Fprintf(c.term, "Welcome %s!\n\n", c.user.Name)
 	return nil
 }
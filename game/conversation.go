@@ -0,0 +1,107 @@
+package game
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	conversationSweepInterval = time.Minute
+	// maxConversationTTL is how long objects remember things at most, and when not told otherwise.
+	maxConversationTTL = 30 * 24 * time.Hour
+)
+
+// runConversationSweeps forgets the expired conversation memories every
+// conversationSweepInterval until ctx is done.
+func (g *Game) runConversationSweeps(ctx context.Context) {
+	ticker := time.NewTicker(conversationSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := g.storage.ExpireConversationMemories(ctx, time.Now().UnixNano()); err != nil {
+			log.Printf("trying to expire conversation memories: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+// conversationTTL returns the TTL in milliseconds ms as a duration, capped to maxConversationTTL.
+func conversationTTL(ms float64) time.Duration {
+	ttl := time.Duration(ms * float64(time.Millisecond))
+	if ttl <= 0 || ttl > maxConversationTTL {
+		return maxConversationTTL
+	}
+	return ttl
+}
+
+// addConversationCallbacks adds `remember(subjectID, key, value, ttlMS?)`, which makes the
+// object remember value about subject for ttlMS milliseconds, at most and by default 30 days,
+// or forget it if value is null or undefined, and `recall(subjectID, key?)`, which returns
+// the remembered value of key, or an object with all remembered values if key is omitted.
+// Objects remember at most 64 keys about each subject, forgetting the ones closest to
+// expiring first.
+func (g *Game) addConversationCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["remember"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 3 || len(args) > 4 || !args[0].IsString() || !args[1].IsString() || (len(args) == 4 && !args[3].IsNumber()) {
+			return rc.Throw("remember takes [string, string, any, number?] arguments")
+		}
+		value, err := globalJSON(rc, args[2])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[2], err)
+		}
+		ttl := maxConversationTTL
+		if len(args) == 4 {
+			ttl = conversationTTL(args[3].Number())
+		}
+		if err := g.storage.Remember(ctx, object.Id, args[0].String(), args[1].String(), value, time.Now().Add(ttl).UnixNano()); err != nil {
+			return rc.Throw("trying to remember %q about %q: %v", args[1].String(), args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["recall"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsString() || (len(args) == 2 && !args[1].IsString()) {
+			return rc.Throw("recall takes [string, string?] arguments")
+		}
+		memories, err := g.storage.Recall(ctx, object.Id, args[0].String(), time.Now().UnixNano())
+		if err != nil {
+			return rc.Throw("trying to recall %q: %v", args[0].String(), err)
+		}
+		if len(args) == 2 {
+			value, found := memories[args[1].String()]
+			if !found {
+				return v8go.Null(rc.Context().Isolate())
+			}
+			res, err := v8go.JSONParse(rc.Context(), value)
+			if err != nil {
+				return rc.Throw("trying to parse %q: %v", value, err)
+			}
+			return res
+		}
+		result, err := v8go.NewObjectTemplate(rc.Context().Isolate()).NewInstance(rc.Context())
+		if err != nil {
+			return rc.Throw("trying to create object: %v", err)
+		}
+		for key, value := range memories {
+			parsed, err := v8go.JSONParse(rc.Context(), value)
+			if err != nil {
+				return rc.Throw("trying to parse %q: %v", value, err)
+			}
+			if err := result.Set(key, parsed); err != nil {
+				return rc.Throw("trying to set %q: %v", key, err)
+			}
+		}
+		return result.Value
+	}
+}
@@ -0,0 +1,34 @@
+package game
+
+import (
+	"time"
+
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// addCooldownCallbacks adds `cooldown(name, ms)`, which puts the object on the named cooldown for
+// ms milliseconds, and `isOnCooldown(name)`. Cooldowns are stored with the object, so they
+// survive reloads.
+func addCooldownCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["cooldown"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsNumber() {
+			return rc.Throw("cooldown takes [string, number] arguments")
+		}
+		object.StartCooldown(args[0].String(), time.Now(), time.Duration(args[1].Number()*float64(time.Millisecond)))
+		return nil
+	}
+	callbacks["isOnCooldown"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("isOnCooldown takes [string] arguments")
+		}
+		res, err := rc.JSFromGo(object.OnCooldown(args[0].String(), time.Now()))
+		if err != nil {
+			return rc.Throw("trying to convert result to *v8go.Value: %v", err)
+		}
+		return res
+	}
+}
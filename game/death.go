@@ -0,0 +1,271 @@
+package game
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	diedEventType      = "died"
+	respawnedEventType = "respawned"
+	// corpseLifetime is how long corpses take to decay.
+	corpseLifetime = 30 * time.Minute
+)
+
+var (
+	// respawnRoom is where users go when they die, if set.
+	respawnRoom atomic.Pointer[string]
+)
+
+func currentRespawnRoom() string {
+	if room := respawnRoom.Load(); room != nil {
+		return *room
+	}
+	return genesisID
+}
+
+// vitals is the argument of setVitals and the result of getVitals.
+type vitals struct {
+	Health    float32
+	MaxHealth float32
+}
+
+// died is the message of died events.
+type died struct {
+	Object string
+	Killer string
+	Corpse string
+}
+
+// respawned is the message of respawned events.
+type respawned struct {
+	Object   string
+	Location string
+}
+
+// corpseParams are the creation params of corpses.
+type corpseParams struct {
+	Id    string
+	Short string
+}
+
+// damage reduces the health of the object with the given ID by amount, and lets it die if
+// that kills it. The killer, or the running object if there is none, must be allowed to
+// damage the object where it is. Returns the remaining health.
+func (g *Game) damage(ctx context.Context, running *structs.Object, id string, amount float32, killer string) (float32, error) {
+	victim, err := g.loadObjectOrRunning(ctx, running, id)
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	attacker := killer
+	if attacker == "" {
+		attacker = running.Id
+	}
+	if err := g.checkDamage(ctx, running, victim, attacker); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	var health float32
+	killed := false
+	if err := g.modifyObject(ctx, running, id, func(object *structs.Object) error {
+		if object.MaxHealth <= 0 {
+			return errors.Errorf("%q has no vitals", id)
+		}
+		if object.Health <= 0 {
+			return nil
+		}
+		object.Health = max(0, object.Health-amount)
+		health = object.Health
+		killed = object.Health == 0
+		return nil
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	if killed {
+		if err := g.die(ctx, running, id, killer); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+	}
+	return health, nil
+}
+
+// die leaves a decaying corpse holding the content of the object with the given ID where it
+// is, clears its effects, and emits died to it, its location and killer. Users are then
+// restored to full health in the respawn room, and get a respawned event.
+func (g *Game) die(ctx context.Context, running *structs.Object, id string, killer string) error {
+	victim, err := g.loadObjectOrRunning(ctx, running, id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	location := victim.Location
+	params, err := goccy.Marshal(&corpseParams{Id: victim.Id, Short: shortDescription(victim)})
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	corpse, err := g.createObjectFromSourceLater(ctx, corpseSource, location, string(params))
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.modifyObject(ctx, nil, corpse.Id, func(corpse *structs.Object) error {
+		if !slices.Contains(corpse.Tags, structs.TrashTag) {
+			corpse.Tags = append(corpse.Tags, structs.TrashTag)
+		}
+		corpse.MaxDurability = 1
+		corpse.Durability = 1
+		corpse.DecayRate = float32(1 / corpseLifetime.Hours())
+		corpse.Decayed = time.Now().UnixNano()
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	for itemID := range maps.Clone(victim.Content) {
+		if err := g.moveObject(ctx, running, itemID, corpse.Id); err != nil {
+			return juicemud.WithStack(err)
+		}
+		// Keep the Content of the running object current for the rest of the run.
+		if victim == running {
+			delete(running.Content, itemID)
+		}
+	}
+
+	isUser, err := g.isUser(ctx, id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.modifyObject(ctx, running, id, func(object *structs.Object) error {
+		object.Effects = nil
+		if isUser {
+			object.Health = object.MaxHealth
+		}
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+
+	at := g.storage.Queue().After(0)
+	message := &died{Object: id, Killer: killer, Corpse: corpse.Id}
+	recipients := map[string]bool{id: true}
+	if location != "" {
+		recipients[location] = true
+	}
+	if killer != "" {
+		recipients[killer] = true
+	}
+	for recipient := range recipients {
+		if err := g.emitAny(ctx, at, recipient, diedEventType, message); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	if !isUser {
+		return nil
+	}
+	room := currentRespawnRoom()
+	if err := g.moveObject(ctx, running, id, room); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.emitAny(ctx, at, id, respawnedEventType, &respawned{Object: id, Location: room}))
+}
+
+// addRespawnCallbacks adds `setRespawnRoom(id)`, which sets where users go when they die.
+func addRespawnCallbacks(callbacks js.Callbacks) {
+	callbacks["setRespawnRoom"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("setRespawnRoom takes [string] arguments")
+		}
+		room := args[0].String()
+		respawnRoom.Store(&room)
+		return nil
+	}
+}
+
+// addVitalsCallbacks adds `setVitals({MaxHealth, Health?})`, `getVitals(objectID)`,
+// `damage(objectID, amount, killerID?)` and `heal(objectID, amount)`. damage and heal return
+// the new health, and objects brought to 0 health by damage die.
+func (g *Game) addVitalsCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setVitals"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setVitals takes [Object] arguments")
+		}
+		config := vitals{}
+		if err := rc.Copy(&config, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to vitals{}: %v", args[0], err)
+		}
+		if config.MaxHealth < 0 || config.Health < 0 || config.Health > config.MaxHealth {
+			return rc.Throw("vitals must have 0 <= Health <= MaxHealth")
+		}
+		object.MaxHealth = config.MaxHealth
+		object.Health = config.Health
+		if object.Health == 0 {
+			object.Health = object.MaxHealth
+		}
+		return nil
+	}
+	callbacks["getVitals"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getVitals takes [string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		result := vitals{Health: holder.Health, MaxHealth: holder.MaxHealth}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+	callbacks["damage"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 2 || len(args) > 3 || !args[0].IsString() || !args[1].IsNumber() || (len(args) == 3 && !args[2].IsString()) {
+			return rc.Throw("damage takes [string, number, string?] arguments")
+		}
+		killer := ""
+		if len(args) == 3 {
+			killer = args[2].String()
+		}
+		health, err := g.damage(ctx, object, args[0].String(), float32(args[1].Number()), killer)
+		if err != nil {
+			return rc.Throw("trying to damage %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(health)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", health, err)
+		}
+		return res
+	}
+	callbacks["heal"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsNumber() {
+			return rc.Throw("heal takes [string, number] arguments")
+		}
+		var health float32
+		if err := g.modifyObject(ctx, object, args[0].String(), func(target *structs.Object) error {
+			if target.Health > 0 {
+				target.Health = min(target.MaxHealth, target.Health+float32(args[1].Number()))
+			}
+			health = target.Health
+			return nil
+		}); err != nil {
+			return rc.Throw("trying to heal %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(health)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", health, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,182 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	describeEventType = "describe"
+)
+
+// describeQuery is the message of describe calls, which can reply with a string to add to the
+// long description of Object shown to Viewer.
+type describeQuery struct {
+	Object string
+	Viewer string
+}
+
+// composeDescription returns long, the long description of object detected by viewer, followed
+// by the descriptions of the active effects of object, and the replies to describe calls to
+// object and its content. Contributions like weather, wounds or worn equipment thus compose
+// instead of overwriting each other with setDescriptions.
+func (g *Game) composeDescription(ctx context.Context, object *structs.Object, content map[string]*structs.Object, viewer *structs.Object, long string) (string, error) {
+	parts := []string{}
+	if long != "" {
+		parts = append(parts, long)
+	}
+	now := time.Now()
+	for i := range object.Effects {
+		if effect := &object.Effects[i]; effect.Description != "" && effect.Active(now) {
+			parts = append(parts, effect.Description)
+		}
+	}
+	describers := []*structs.Object{object}
+	ids := make([]string, 0, len(content))
+	for id := range content {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if contained := content[id]; contained.Detectable(viewer) {
+			describers = append(describers, contained)
+		}
+	}
+	var message []byte
+	for _, describer := range describers {
+		if !describer.HasCallback(describeEventType, callEventTag) {
+			continue
+		}
+		if message == nil {
+			var err error
+			if message, err = goccy.Marshal(&describeQuery{Object: object.Id, Viewer: viewer.Id}); err != nil {
+				return "", juicemud.WithStack(err)
+			}
+		}
+		reply, err := g.callObject(ctx, describer.Id, describeEventType, string(message))
+		if err != nil {
+			return "", juicemud.WithStack(err)
+		}
+		contribution := ""
+		if err := goccy.Unmarshal([]byte(reply), &contribution); err != nil {
+			return "", errors.Wrapf(err, "%q replied with a non string description %s", describer.Id, reply)
+		}
+		if contribution = strings.TrimSpace(contribution); contribution != "" {
+			parts = append(parts, contribution)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// matchDetail returns the detail, in name order, whose name contains all keywords of name.
+func matchDetail(details map[string]string, name string) (string, bool) {
+	keywords := storage.Keywords(name)
+	if len(keywords) == 0 {
+		return "", false
+	}
+	names := make([]string, 0, len(details))
+	for detailName := range details {
+		names = append(names, detailName)
+	}
+	sort.Strings(names)
+	for _, detailName := range names {
+		if containsKeywords(detailName, keywords) {
+			return details[detailName], true
+		}
+	}
+	return "", false
+}
+
+// lookAt shows the composed description of the object in the location, or carried, matching
+// name, or else the detail of the location matching name.
+func (c *Connection) lookAt(name string) error {
+	ctx := c.sess.Context()
+	viewer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	candidates, err := c.game.storage.LoadObjects(ctx, viewer.Content, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	var location *structs.Location
+	if viewer.Location != "" {
+		if location, err = c.game.loadLocation(ctx, viewer.Location); err != nil {
+			return juicemud.WithStack(err)
+		}
+		for id, object := range location.Content {
+			candidates[id] = object
+		}
+	}
+	target := matchObject(viewer, candidates, name)
+	if target == nil {
+		if location != nil {
+			if detail, found := matchDetail(location.Container.Details, name); found {
+				fmt.Fprintln(c.term, c.wrap(detail))
+				return nil
+			}
+		}
+		return errors.Errorf("There is no %q here", name)
+	}
+	target.Translate(c.languages())
+	target.Personalize(viewer)
+	desc := structs.Descriptions(target.Descriptions).Detect(target, viewer)
+	if desc == nil {
+		return errors.Errorf("There is no %q here", name)
+	}
+	content, err := c.game.storage.LoadObjects(ctx, target.Content, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	long, err := c.game.composeDescription(ctx, target, content, viewer, desc.Long)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(desc.Short))
+	if long != "" {
+		fmt.Fprintln(c.term)
+		fmt.Fprintln(c.term, c.wrap(long))
+	}
+	return nil
+}
+
+// addDetailCallbacks adds `setDetails({name: description})`, which lets users in the object
+// `look` at parts of it, like a mural on a wall, that aren't objects of their own, and
+// `getDetails()`.
+func addDetailCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setDetails"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setDetails takes [Object] arguments")
+		}
+		details := map[string]string{}
+		if err := rc.Copy(&details, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to map[string]string: %v", args[0], err)
+		}
+		object.Details = details
+		return nil
+	}
+	callbacks["getDetails"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		details := object.Details
+		if details == nil {
+			details = map[string]string{}
+		}
+		res, err := rc.JSFromGo(details)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", details, err)
+		}
+		return res
+	}
+}
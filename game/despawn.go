@@ -0,0 +1,106 @@
+package game
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	despawnSweepInterval = time.Second
+	// despawnWarning is how long before despawning objects get the despawning event.
+	despawnWarning      = time.Second
+	despawningEventType = "despawning"
+)
+
+// despawning is the message of despawning events.
+type despawning struct {
+	At int64
+}
+
+// runDespawnSweeps removes the objects whose despawn time has passed every
+// despawnSweepInterval until ctx is done.
+func (g *Game) runDespawnSweeps(ctx context.Context) {
+	ticker := time.NewTicker(despawnSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.sweepDespawns(ctx, time.Now()); err != nil {
+			log.Printf("trying to sweep despawning objects: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+func (g *Game) sweepDespawns(ctx context.Context, now time.Time) error {
+	ids, err := g.storage.DespawningObjectIDs(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for id := range ids {
+		if err := g.despawnObject(ctx, id, now); err != nil && !errors.Is(err, os.ErrNotExist) {
+			// One object failing to despawn mustn't keep the others around.
+			log.Printf("trying to despawn %q: %v", id, err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+	return nil
+}
+
+// despawnObject removes the object with the given ID if its despawn time has passed. Whatever
+// it contained is left where it was.
+func (g *Game) despawnObject(ctx context.Context, id string, now time.Time) error {
+	jsContextLocks.Lock(id)
+	defer jsContextLocks.Unlock(id)
+
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if object.DespawnAt == 0 || object.DespawnAt > now.UnixNano() {
+		return nil
+	}
+	for itemID := range object.Content {
+		if err := g.moveObject(ctx, nil, itemID, object.Location); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return juicemud.WithStack(g.storage.DeleteObject(ctx, id))
+}
+
+// addDespawnCallbacks adds `despawnAfter(ms)`, which makes the engine remove the object after ms
+// milliseconds, even if its own scripts fail, leaving whatever it contains where it was. The
+// object gets a `despawning` event with the despawn time a second before it's removed, or at
+// once if that's sooner. Calling it again reschedules the removal, with another despawning event.
+func (g *Game) addDespawnCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["despawnAfter"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsNumber() || args[0].Integer() < 0 {
+			return rc.Throw("despawnAfter takes [non-negative number] arguments")
+		}
+		if user, err := g.isUser(ctx, object.Id); err != nil {
+			return rc.Throw("trying to check if %q is a user: %v", object.Id, err)
+		} else if user {
+			return rc.Throw("%q is a user, and can't despawn", object.Id)
+		}
+		after := time.Duration(args[0].Integer()) * time.Millisecond
+		despawnAt := time.Now().Add(after)
+		object.DespawnAt = despawnAt.UnixNano()
+		at := g.storage.Queue().After(max(0, after-despawnWarning))
+		if err := g.emitAny(ctx, at, object.Id, despawningEventType, &despawning{At: object.DespawnAt}); err != nil {
+			return rc.Throw("trying to emit despawning to %q: %v", object.Id, err)
+		}
+		return nil
+	}
+}
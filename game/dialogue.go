@@ -0,0 +1,248 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	dialogueStart              = "start"
+	dialogueEventType          = "dialogue"
+	dialogueConditionEventType = "dialogueCondition"
+)
+
+// dialogueChosen is the message of dialogue events, sent to the object talked to when the
+// actor makes a choice.
+type dialogueChosen struct {
+	Actor  string
+	Node   string
+	Choice string
+	Action string
+}
+
+// dialogueCondition is the message of dialogueCondition calls, asking the object talked to
+// whether the actor meets Condition.
+type dialogueCondition struct {
+	Actor     string
+	Node      string
+	Condition string
+}
+
+// validateDialogue returns an error unless dialogue has a start node, all choices have text,
+// and all choices lead to nodes that exist.
+func validateDialogue(dialogue map[string]structs.DialogueNode) error {
+	if len(dialogue) == 0 {
+		return nil
+	}
+	if _, found := dialogue[dialogueStart]; !found {
+		return errors.Errorf("dialogues need a %q node", dialogueStart)
+	}
+	for name, node := range dialogue {
+		for _, choice := range node.Choices {
+			if choice.Text == "" {
+				return errors.Errorf("node %q has a choice without text", name)
+			}
+			if _, found := dialogue[choice.Next]; choice.Next != "" && !found {
+				return errors.Errorf("choice %q of node %q leads to missing node %q", choice.Text, name, choice.Next)
+			}
+		}
+	}
+	return nil
+}
+
+// choiceAllowed returns whether actor, having unlocked the achievements in unlocked, meets the
+// achievement and skill requirements of choice at now.
+func choiceAllowed(choice structs.DialogueChoice, actor *structs.Object, unlocked map[string]bool, now time.Time) bool {
+	for _, achievement := range choice.RequiredAchievements {
+		if !unlocked[achievement] {
+			return false
+		}
+	}
+	for _, achievement := range choice.ForbiddenAchievements {
+		if unlocked[achievement] {
+			return false
+		}
+	}
+	for skill, level := range choice.RequiredSkills {
+		if actor.SkillLevel(skill, now) < level {
+			return false
+		}
+	}
+	return true
+}
+
+// availableChoices returns the choices of node actor is allowed to make, asking speaker about
+// the choices with conditions.
+func (g *Game) availableChoices(ctx context.Context, speaker *structs.Object, actor *structs.Object, name string, node structs.DialogueNode, unlocked map[string]bool) ([]structs.DialogueChoice, error) {
+	result := []structs.DialogueChoice{}
+	now := time.Now()
+	for _, choice := range node.Choices {
+		if !choiceAllowed(choice, actor, unlocked, now) {
+			continue
+		}
+		if choice.Condition != "" {
+			if !speaker.HasCallback(dialogueConditionEventType, callEventTag) {
+				continue
+			}
+			message, err := goccy.Marshal(&dialogueCondition{Actor: actor.Id, Node: name, Condition: choice.Condition})
+			if err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			reply, err := g.callObject(ctx, speaker.Id, dialogueConditionEventType, string(message))
+			if err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			if strings.TrimSpace(reply) != "true" {
+				continue
+			}
+		}
+		result = append(result, choice)
+	}
+	return result, nil
+}
+
+// makeChoice grants the achievements of choice to actor, and tells speaker about it.
+func (g *Game) makeChoice(ctx context.Context, speaker *structs.Object, actor *structs.Object, name string, choice structs.DialogueChoice, unlocked map[string]bool) error {
+	for _, key := range choice.GrantAchievements {
+		achievement, err := g.storage.LoadAchievement(ctx, key)
+		if err != nil {
+			return errors.Wrapf(err, "trying to load achievement %q", key)
+		}
+		if achievement.Object != "" && achievement.Object != speaker.Id {
+			return errors.Errorf("only %q can grant %q", achievement.Object, achievement.Key)
+		}
+		if _, err := g.grantAchievement(ctx, actor.Id, achievement.Key); err != nil {
+			return juicemud.WithStack(err)
+		}
+		unlocked[achievement.Key] = true
+	}
+	return juicemud.WithStack(g.emitAny(ctx, g.storage.Queue().After(0), speaker.Id, dialogueEventType, &dialogueChosen{
+		Actor:  actor.Id,
+		Node:   name,
+		Choice: choice.Text,
+		Action: choice.Action,
+	}))
+}
+
+// talkCommand handles `talk [target]`, walking the connected object through the dialogue of
+// target by letting it choose between the choices of each node until it leaves, or reaches a
+// choice that leads nowhere.
+func (c *Connection) talkCommand(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("usage: talk [target]")
+	}
+	ctx := c.sess.Context()
+	actor, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if actor.Location == "" {
+		return errors.Errorf("There is no %q here", name)
+	}
+	location, err := c.game.loadLocation(ctx, actor.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	speaker := matchObject(actor, location.Content, name)
+	if speaker == nil || speaker.Id == actor.Id {
+		return errors.Errorf("There is no %q here", name)
+	}
+	if len(speaker.Dialogue) == 0 {
+		return errors.Errorf("%s has nothing to say", capitalize(partyAs(actor, speaker).Name))
+	}
+	unlocks, err := c.game.storage.AchievementUnlocks(ctx, actor.Id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	unlocked := map[string]bool{}
+	for _, unlock := range unlocks {
+		unlocked[unlock.Achievement] = true
+	}
+	for current := dialogueStart; current != ""; {
+		node, found := speaker.Dialogue[current]
+		if !found {
+			return errors.Errorf("%q has no dialogue node %q", speaker.Id, current)
+		}
+		fmt.Fprintln(c.term, c.wrap(composeSocial(node.Text, partyAs(actor, speaker), partyAs(actor, actor))))
+		choices, err := c.game.availableChoices(ctx, speaker, actor, current, node, unlocked)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if len(choices) == 0 {
+			return nil
+		}
+		var chosen *structs.DialogueChoice
+		for chosen == nil {
+			for i, choice := range choices {
+				fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("%d. %s", i+1, choice.Text)))
+			}
+			fmt.Fprintln(c.term, "Choose, or enter nothing to leave:")
+			line, err := c.term.ReadLine()
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				return nil
+			}
+			if index, err := strconv.Atoi(line); err == nil && index > 0 && index <= len(choices) {
+				chosen = &choices[index-1]
+			}
+		}
+		if err := c.game.makeChoice(ctx, speaker, actor, current, *chosen, unlocked); err != nil {
+			return juicemud.WithStack(err)
+		}
+		current = chosen.Next
+	}
+	return nil
+}
+
+// addDialogueCallbacks adds `setDialogue(nodes)`, which lets users `talk` to the object, with
+// nodes `{[name]: {Text, Choices: [{Text, Next?, RequiredAchievements?,
+// ForbiddenAchievements?, RequiredSkills?, Condition?, GrantAchievements?, Action?}]}}`
+// starting at the "start" node, and `addDialogueConditions(handler)`. Texts can use the
+// substitution codes of socials, with the object as actor and the user as target. Choices are
+// only offered when the user has unlocked all RequiredAchievements and none of the
+// ForbiddenAchievements, has at least the RequiredSkills levels, and, when they have a
+// Condition, the handler replies true to `{Actor, Node, Condition}` calls. Chosen choices
+// grant GrantAchievements to the user, send dialogue events with `{Actor, Node, Choice,
+// Action}` to the object, and lead to Next, or end the dialogue if it's empty.
+func addDialogueCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setDialogue"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setDialogue takes [Object] arguments")
+		}
+		dialogue := map[string]structs.DialogueNode{}
+		if err := rc.Copy(&dialogue, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to dialogue: %v", args[0], err)
+		}
+		if err := validateDialogue(dialogue); err != nil {
+			return rc.Throw("invalid dialogue: %v", err)
+		}
+		object.Dialogue = dialogue
+		return nil
+	}
+	callbacks["addDialogueConditions"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsFunction() {
+			return rc.Throw("addDialogueConditions takes [function] arguments")
+		}
+		if err := addCallback(rc, dialogueConditionEventType, callEventTag, args[0]); err != nil {
+			return rc.Throw("trying to add dialogue conditions: %v", err)
+		}
+		return nil
+	}
+}
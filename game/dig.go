@@ -0,0 +1,77 @@
+package game
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+// dig adds an exit named direction from origin to destinationID, or to a new room running
+// sourcePath if destinationID is empty, and a reverse exit back unless the destination already has one.
+//
+// An origin without coordinates is put at 0, 0, 0, and a destination without coordinates is put at
+// the coordinates of origin offset by direction.
+//
+// The destination is stored, holding its lock if it already existed, but storing origin is left
+// to the caller.
+func (g *Game) dig(ctx context.Context, origin *structs.Object, direction string, destinationID string, sourcePath string) (*structs.Object, error) {
+	offset, found := structs.Direction(direction)
+	if !found {
+		return nil, errors.Errorf("%q is not a direction", direction)
+	}
+	reverse, _ := structs.ReverseDirection(direction)
+	if _, found := origin.ExitNamed(direction); found {
+		return nil, errors.Errorf("%q already has an exit %q", origin.Id, direction)
+	}
+	originPoint, found := origin.Point()
+	if !found {
+		origin.SetPoint(originPoint)
+	}
+
+	connect := func(destination *structs.Object) {
+		if _, found := destination.Point(); !found {
+			destination.SetPoint(originPoint.Add(offset))
+		}
+		if _, found := destination.ExitNamed(reverse); !found {
+			destination.Exits = append(destination.Exits, structs.Exit{
+				Descriptions: []structs.Description{{Short: reverse}},
+				Destination:  origin.Id,
+			})
+		}
+	}
+	var destination *structs.Object
+	if destinationID == "" {
+		if sourcePath == "" {
+			sourcePath = areaRoomSource
+		}
+		if _, err := g.storage.LoadFile(ctx, sourcePath); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		var err error
+		if destination, err = structs.MakeObject(ctx); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		connect(destination)
+		if err := g.initObjectLater(ctx, destination, sourcePath, "", ""); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	} else {
+		if destinationID == origin.Id {
+			return nil, errors.Errorf("can't dig from %q to itself", origin.Id)
+		}
+		if err := g.modifyObject(ctx, origin, destinationID, func(object *structs.Object) error {
+			connect(object)
+			destination = object
+			return nil
+		}); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	origin.Exits = append(origin.Exits, structs.Exit{
+		Descriptions: []structs.Description{{Short: direction}},
+		Destination:  destination.Id,
+	})
+	return destination, nil
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"sync"
+
+	"github.com/zond/juicemud/structs"
+)
+
+// eventDispatcher hands events to handle one at a time per target object, in the order
+// they were dispatched, so that e.g. a hit and the following death are never observed in
+// the wrong order. Events for different objects are handled concurrently.
+type eventDispatcher struct {
+	mutex   sync.Mutex
+	pending map[string][]*structs.Event
+	handle  func(*structs.Event)
+}
+
+func newEventDispatcher(handle func(*structs.Event)) *eventDispatcher {
+	return &eventDispatcher{
+		pending: map[string][]*structs.Event{},
+		handle:  handle,
+	}
+}
+
+func (d *eventDispatcher) dispatch(ev *structs.Event) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	queue, running := d.pending[ev.Object]
+	d.pending[ev.Object] = append(queue, ev)
+	if !running {
+		go d.drain(ev.Object)
+	}
+}
+
+func (d *eventDispatcher) drain(id string) {
+	for {
+		d.mutex.Lock()
+		queue := d.pending[id]
+		if len(queue) == 0 {
+			delete(d.pending, id)
+			d.mutex.Unlock()
+			return
+		}
+		ev := queue[0]
+		d.pending[id] = queue[1:]
+		d.mutex.Unlock()
+		d.handle(ev)
+	}
+}
@@ -0,0 +1,228 @@
+package game
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	decaySweepInterval  = time.Minute
+	itemBrokenEventType = "itemBroken"
+)
+
+// durability is the argument of setDurability and the result of getDurability, with
+// DecayRate in durability per hour and WearRate in durability per use.
+type durability struct {
+	Durability float32
+	Max        float32
+	DecayRate  float32
+	WearRate   float32
+}
+
+// itemBroken is the message of itemBroken events.
+type itemBroken struct {
+	Id string
+}
+
+// runDecaySweeps decays the decaying objects, and removes the fully decayed trash after
+// dropping its content, every decaySweepInterval until ctx is done.
+func (g *Game) runDecaySweeps(ctx context.Context) {
+	ticker := time.NewTicker(decaySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.sweepDecay(ctx, time.Now()); err != nil {
+			log.Printf("trying to sweep decayed objects: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+func (g *Game) sweepDecay(ctx context.Context, now time.Time) error {
+	ids, err := g.storage.DecayingObjectIDs(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for id := range ids {
+		if err := g.decayObject(ctx, id, now); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (g *Game) decayObject(ctx context.Context, id string, now time.Time) error {
+	jsContextLocks.Lock(id)
+	defer jsContextLocks.Unlock(id)
+
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	broke := object.Decay(now)
+	if object.Durability <= 0 && slices.Contains(object.Tags, structs.TrashTag) {
+		// Whatever decayed trash, like corpses, contained is left where it was.
+		for itemID := range object.Content {
+			if err := g.moveObject(ctx, nil, itemID, object.Location); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		if broke {
+			if err := g.emitBroken(ctx, object, false); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return juicemud.WithStack(g.storage.DeleteObject(ctx, id))
+	}
+	if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if broke {
+		return juicemud.WithStack(g.emitBroken(ctx, object, true))
+	}
+	return nil
+}
+
+// emitBroken emits itemBroken to the location of item, and to item itself if toItem.
+func (g *Game) emitBroken(ctx context.Context, item *structs.Object, toItem bool) error {
+	at := g.storage.Queue().After(0)
+	message := &itemBroken{Id: item.Id}
+	if toItem {
+		if err := g.emitAny(ctx, at, item.Id, itemBrokenEventType, message); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	if item.Location != "" {
+		if err := g.emitAny(ctx, at, item.Location, itemBrokenEventType, message); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// changeDurability decays the object with the given ID, and then changes its durability by
+// delta within 0 and its max. Returns the new durability.
+func (g *Game) changeDurability(ctx context.Context, running *structs.Object, id string, delta float32) (float32, error) {
+	var result float32
+	var broken *structs.Object
+	if err := g.modifyObject(ctx, running, id, func(object *structs.Object) error {
+		if object.MaxDurability <= 0 {
+			return errors.Errorf("%q has no durability", id)
+		}
+		broke := object.Decay(time.Now())
+		if delta < 0 {
+			broke = object.Damage(-delta) || broke
+		} else {
+			object.Durability = min(object.MaxDurability, object.Durability+delta)
+		}
+		if broke {
+			broken = &structs.Object{Id: object.Id, Location: object.Location}
+		}
+		result = object.Durability
+		return nil
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	if broken != nil {
+		if err := g.emitBroken(ctx, broken, true); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+	}
+	return result, nil
+}
+
+// addDurabilityCallbacks adds `setDurability({Max, DecayRate?, WearRate?})` which makes the object
+// as good as new, `getDurability(objectID)`, `useItem(objectID, amount?)` which reduces the durability
+// of the object by amount or its WearRate, and `repairItem(objectID, amount?)` which restores the
+// durability of the object by amount or fully. useItem and repairItem return the new durability.
+func (g *Game) addDurabilityCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setDurability"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setDurability takes [Object] arguments")
+		}
+		config := durability{}
+		if err := rc.Copy(&config, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to durability{}: %v", args[0], err)
+		}
+		if config.Max < 0 || config.DecayRate < 0 || config.WearRate < 0 {
+			return rc.Throw("durability can't be negative")
+		}
+		object.MaxDurability = config.Max
+		object.Durability = config.Max
+		object.DecayRate = config.DecayRate
+		object.WearRate = config.WearRate
+		object.Decayed = time.Now().UnixNano()
+		return nil
+	}
+	callbacks["getDurability"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getDurability takes [string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		current := *holder
+		current.Decay(time.Now())
+		result := durability{
+			Durability: current.Durability,
+			Max:        current.MaxDurability,
+			DecayRate:  current.DecayRate,
+			WearRate:   current.WearRate,
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+	change := func(name string, sign float32) func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		return func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+			args := info.Args()
+			if len(args) < 1 || len(args) > 2 || !args[0].IsString() || (len(args) == 2 && !args[1].IsNumber()) {
+				return rc.Throw("%s takes [string, number?] arguments", name)
+			}
+			amount := float32(-1)
+			if len(args) == 2 {
+				if amount = float32(args[1].Number()); amount < 0 {
+					return rc.Throw("%s can't take a negative amount", name)
+				}
+			}
+			if amount == -1 {
+				holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+				if err != nil {
+					return rc.Throw("trying to load %q: %v", args[0].String(), err)
+				}
+				if amount = holder.WearRate; sign > 0 {
+					amount = holder.MaxDurability
+				}
+			}
+			result, err := g.changeDurability(ctx, object, args[0].String(), sign*amount)
+			if err != nil {
+				return rc.Throw("trying to change the durability of %q: %v", args[0].String(), err)
+			}
+			res, err := rc.JSFromGo(result)
+			if err != nil {
+				return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+			}
+			return res
+		}
+	}
+	callbacks["useItem"] = change("useItem", -1)
+	callbacks["repairItem"] = change("repairItem", 1)
+}
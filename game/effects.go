@@ -0,0 +1,224 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	effectTickEventType    = "effectTick"
+	effectExpiredEventType = "effectExpired"
+)
+
+// effectDefinition is the argument of applyEffect, with durations in seconds.
+type effectDefinition struct {
+	Name         string
+	Duration     float64
+	SkillBonuses map[string]float32
+	Immobile     bool
+	TickInterval float64
+	Description  string
+}
+
+// effectEvent is the message of effectTick and effectExpired events.
+type effectEvent struct {
+	Id   string
+	Name string
+}
+
+// effectView is how effects are shown to JavaScript, with Expires in milliseconds since the
+// epoch like JavaScript dates and TickInterval in seconds.
+type effectView struct {
+	Id           string
+	Name         string
+	Expires      int64
+	SkillBonuses map[string]float32
+	Immobile     bool
+	TickInterval float64
+	Description  string
+}
+
+func viewEffect(effect *structs.Effect) effectView {
+	result := effectView{
+		Id:           effect.Id,
+		Name:         effect.Name,
+		SkillBonuses: effect.SkillBonuses,
+		Immobile:     effect.Immobile,
+		TickInterval: time.Duration(effect.TickInterval).Seconds(),
+		Description:  effect.Description,
+	}
+	if effect.Expires != 0 {
+		result.Expires = time.Unix(0, effect.Expires).UnixMilli()
+	}
+	return result
+}
+
+// modifyObject runs f with the object with the given ID and stores it. Modifying the running
+// object, if any, only updates it, since it's stored when the run finishes.
+func (g *Game) modifyObject(ctx context.Context, running *structs.Object, id string, f func(*structs.Object) error) error {
+	if running != nil && id == running.Id {
+		return juicemud.WithStack(f(running))
+	}
+	jsContextLocks.Lock(id)
+	defer jsContextLocks.Unlock(id)
+
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := f(object); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.storage.StoreObject(ctx, nil, object))
+}
+
+// applyEffect adds effect to object, and schedules its first tick and its expiry.
+func (g *Game) applyEffect(ctx context.Context, object *structs.Object, definition *effectDefinition) (*structs.Effect, error) {
+	id, err := structs.NextObjectID()
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	effect := structs.Effect{
+		Id:           id,
+		Name:         definition.Name,
+		SkillBonuses: definition.SkillBonuses,
+		Immobile:     definition.Immobile,
+		TickInterval: time.Duration(definition.TickInterval * float64(time.Second)).Nanoseconds(),
+		Description:  definition.Description,
+	}
+	event := &effectEvent{Id: effect.Id, Name: effect.Name}
+	if definition.Duration > 0 {
+		duration := time.Duration(definition.Duration * float64(time.Second))
+		effect.Expires = time.Now().Add(duration).UnixNano()
+		if err := g.emitAny(ctx, g.storage.Queue().After(duration), object.Id, effectExpiredEventType, event); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	if effect.TickInterval > 0 {
+		if err := g.emitAny(ctx, g.storage.Queue().After(time.Duration(effect.TickInterval)), object.Id, effectTickEventType, event); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	object.Effects = append(object.Effects, effect)
+	return &effect, nil
+}
+
+// removeEffect removes the effect with the given ID from object, and returns whether it was found.
+func removeEffect(object *structs.Object, id string) bool {
+	before := len(object.Effects)
+	object.Effects = slices.DeleteFunc(object.Effects, func(effect structs.Effect) bool {
+		return effect.Id == id
+	})
+	return len(object.Effects) != before
+}
+
+// handleEffectEvent returns whether call should run object. Ticks and expiries of removed
+// effects are dropped, and ticks of active effects schedule the next tick. Expired effects
+// are removed from object.
+func (g *Game) handleEffectEvent(ctx context.Context, object *structs.Object, call *structs.Call) (bool, error) {
+	now := time.Now()
+	if call != nil && (call.Name == effectTickEventType || call.Name == effectExpiredEventType) {
+		event := &effectEvent{}
+		if err := goccy.Unmarshal([]byte(call.Message), event); err != nil {
+			return false, juicemud.WithStack(err)
+		}
+		index := slices.IndexFunc(object.Effects, func(effect structs.Effect) bool {
+			return effect.Id == event.Id
+		})
+		if index == -1 {
+			return false, nil
+		}
+		effect := &object.Effects[index]
+		if call.Name == effectTickEventType {
+			if !effect.Active(now) {
+				return false, nil
+			}
+			next := now.Add(time.Duration(effect.TickInterval))
+			if effect.Expires == 0 || next.UnixNano() <= effect.Expires {
+				if err := g.emitAny(ctx, g.storage.Queue().After(time.Duration(effect.TickInterval)), object.Id, effectTickEventType, event); err != nil {
+					return false, juicemud.WithStack(err)
+				}
+			}
+		}
+	}
+	object.Effects = slices.DeleteFunc(object.Effects, func(effect structs.Effect) bool {
+		return !effect.Active(now)
+	})
+	return true, nil
+}
+
+// addEffectCallbacks adds `applyEffect(objectID, {Name, Duration?, SkillBonuses?, Immobile?,
+// TickInterval?, Description?})` which returns the ID of the new effect, `removeEffect(objectID,
+// effectID)` and `getEffects(objectID)`. Effects emit effectTick every TickInterval seconds, and
+// effectExpired after Duration seconds. Descriptions are added to the long descriptions of the
+// affected objects.
+func (g *Game) addEffectCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["applyEffect"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsObject() {
+			return rc.Throw("applyEffect takes [string, Object] arguments")
+		}
+		definition := &effectDefinition{}
+		if err := rc.Copy(definition, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to effectDefinition{}: %v", args[1], err)
+		}
+		if definition.Duration < 0 || definition.TickInterval < 0 {
+			return rc.Throw("effects can't have negative durations")
+		}
+		var effect *structs.Effect
+		if err := g.modifyObject(ctx, object, args[0].String(), func(target *structs.Object) error {
+			var err error
+			effect, err = g.applyEffect(ctx, target, definition)
+			return juicemud.WithStack(err)
+		}); err != nil {
+			return rc.Throw("trying to apply %q to %q: %v", definition.Name, args[0].String(), err)
+		}
+		return rc.String(effect.Id)
+	}
+	callbacks["removeEffect"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("removeEffect takes [string, string] arguments")
+		}
+		if err := g.modifyObject(ctx, object, args[0].String(), func(target *structs.Object) error {
+			if !removeEffect(target, args[1].String()) {
+				return errors.Errorf("%q has no effect %q", target.Id, args[1].String())
+			}
+			return nil
+		}); err != nil {
+			return rc.Throw("trying to remove %q from %q: %v", args[1].String(), args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["getEffects"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getEffects takes [string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		now := time.Now()
+		result := []effectView{}
+		for i := range holder.Effects {
+			if holder.Effects[i].Active(now) {
+				result = append(result, viewEffect(&holder.Effects[i]))
+			}
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,257 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	weaponSlot = "weapon"
+)
+
+var (
+	// equipmentSlots are the slots items can be equipped in.
+	equipmentSlots = juicemud.NewSyncMap[string, bool]()
+)
+
+func init() {
+	equipmentSlots.Replace(map[string]bool{
+		"head":     true,
+		"body":     true,
+		"hands":    true,
+		"legs":     true,
+		"feet":     true,
+		weaponSlot: true,
+		"shield":   true,
+	})
+}
+
+func equipmentEffectID(itemID string) string {
+	return fmt.Sprintf("equipment:%s", itemID)
+}
+
+func shortDescription(object *structs.Object) string {
+	if len(object.Descriptions) == 0 {
+		return object.Id
+	}
+	return object.Descriptions[0].Short
+}
+
+// equip puts item, which must be carried by wearer, in its slot of wearer, and gives wearer
+// the skill bonuses of item as an effect.
+func equip(wearer *structs.Object, item *structs.Object) error {
+	if item.Location != wearer.Id {
+		return errors.Errorf("You don't carry %s", shortDescription(item))
+	}
+	if item.Slot == "" || !equipmentSlots.Has(item.Slot) {
+		return errors.Errorf("%s can't be equipped", shortDescription(item))
+	}
+	if current, found := wearer.Equipment[item.Slot]; found {
+		if current == item.Id {
+			return errors.Errorf("%s is already equipped", shortDescription(item))
+		}
+		return errors.Errorf("Something is already equipped on your %s", item.Slot)
+	}
+	if wearer.Equipment == nil {
+		wearer.Equipment = map[string]string{}
+	}
+	wearer.Equipment[item.Slot] = item.Id
+	wearer.Effects = append(wearer.Effects, structs.Effect{
+		Id:           equipmentEffectID(item.Id),
+		Name:         shortDescription(item),
+		SkillBonuses: item.SkillBonuses,
+	})
+	return nil
+}
+
+// unequip removes the item with the given ID from the equipment of wearer, and returns
+// whether it was equipped.
+func unequip(wearer *structs.Object, itemID string) bool {
+	found := false
+	for slot, id := range wearer.Equipment {
+		if id == itemID {
+			delete(wearer.Equipment, slot)
+			found = true
+		}
+	}
+	removeEffect(wearer, equipmentEffectID(itemID))
+	return found
+}
+
+// unequipMoved unequips the item with the given ID from its previous location, if that
+// location is something wearing it.
+func (g *Game) unequipMoved(ctx context.Context, running *structs.Object, previousLocation string, itemID string) error {
+	if previousLocation == "" {
+		return nil
+	}
+	// TODO(zond): Unequip when objects are moved by setLocation too.
+	holder, err := g.loadObjectOrRunning(ctx, running, previousLocation)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if !equipped(holder, itemID) {
+		return nil
+	}
+	return juicemud.WithStack(g.modifyObject(ctx, running, previousLocation, func(wearer *structs.Object) error {
+		unequip(wearer, itemID)
+		return nil
+	}))
+}
+
+func equipped(wearer *structs.Object, itemID string) bool {
+	for _, id := range wearer.Equipment {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// equipCommand equips or unequips the carried item matching name, with wield only
+// equipping weapons and wear only equipping other items.
+func (c *Connection) equipCommand(verb string, name string) error {
+	if name == "" {
+		fmt.Fprintf(c.term, "usage: %s [item]\n", verb)
+		return nil
+	}
+	ctx := c.sess.Context()
+	var done string
+	if err := c.game.modifyObject(ctx, nil, c.actor(), func(wearer *structs.Object) error {
+		carried, err := c.game.storage.LoadObjects(ctx, wearer.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		item := matchObject(wearer, carried, name)
+		if item == nil {
+			return errors.Errorf("You don't carry %q", name)
+		}
+		switch verb {
+		case "remove":
+			if !unequip(wearer, item.Id) {
+				return errors.Errorf("%s isn't equipped", shortDescription(item))
+			}
+			done = fmt.Sprintf("You remove %s", shortDescription(item))
+			return nil
+		case "wield":
+			if item.Slot != weaponSlot {
+				return errors.Errorf("%s isn't a weapon", shortDescription(item))
+			}
+		case "wear":
+			if item.Slot == weaponSlot {
+				return errors.Errorf("%s is a weapon, wield it instead", shortDescription(item))
+			}
+		}
+		if err := equip(wearer, item); err != nil {
+			return juicemud.WithStack(err)
+		}
+		done = fmt.Sprintf("You %s %s", verb, shortDescription(item))
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(done))
+	return nil
+}
+
+func (c *Connection) equipmentCommand() error {
+	wearer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(wearer.Equipment) == 0 {
+		fmt.Fprintln(c.term, "You have nothing equipped")
+		return nil
+	}
+	ids := map[string]bool{}
+	slots := []string{}
+	for slot, id := range wearer.Equipment {
+		ids[id] = true
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	items, err := c.game.storage.LoadObjects(c.sess.Context(), ids, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	t := c.table("Slot", "Item")
+	for _, slot := range slots {
+		name := wearer.Equipment[slot]
+		if item, found := items[name]; found {
+			name = shortDescription(item)
+		}
+		t.AddRow(slot, name)
+	}
+	t.Print()
+	return nil
+}
+
+// addEquipmentCallbacks adds getters and setters for the Slot the object can be equipped in and
+// the SkillBonuses it gives when equipped, `getEquipment()` returning the items the object has
+// equipped by slot, `equip(itemID)` and `unequip(itemID)`.
+func (g *Game) addEquipmentCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	addGetSetPair("Slot", &object.Slot, callbacks)
+	addGetSetPair("SkillBonuses", &object.SkillBonuses, callbacks)
+	callbacks["getEquipment"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		res, err := rc.JSFromGo(object.Equipment)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", object.Equipment, err)
+		}
+		return res
+	}
+	callbacks["equip"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("equip takes [string] arguments")
+		}
+		item, err := g.storage.LoadObject(ctx, args[0].String(), nil)
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		if err := equip(object, item); err != nil {
+			return rc.Throw("trying to equip %q: %v", args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["unequip"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("unequip takes [string] arguments")
+		}
+		res, err := rc.JSFromGo(unequip(object, args[0].String()))
+		if err != nil {
+			return rc.Throw("trying to convert result to *v8go.Value: %v", err)
+		}
+		return res
+	}
+}
+
+// addEquipmentSlotCallbacks adds `setEquipmentSlots(slots)`, which replaces the slots items can
+// be equipped in.
+func addEquipmentSlotCallbacks(callbacks js.Callbacks) {
+	callbacks["setEquipmentSlots"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setEquipmentSlots takes [Array] arguments")
+		}
+		slots := []string{}
+		if err := rc.Copy(&slots, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []string: %v", args[0], err)
+		}
+		replacement := map[string]bool{}
+		for _, slot := range slots {
+			replacement[slot] = true
+		}
+		equipmentSlots.Replace(replacement)
+		return nil
+	}
+}
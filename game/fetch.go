@@ -0,0 +1,204 @@
+package game
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	fetchTimeout         = 10 * time.Second
+	maxFetchResponseSize = 1 << 20
+	maxFetchesPerMinute  = 10
+)
+
+// fetcher performs the HTTP requests of objects, to allowlisted hosts only, and at most
+// maxFetchesPerMinute per object.
+type fetcher struct {
+	mutex     sync.Mutex
+	allowlist []string
+	recent    map[string][]time.Time
+	client    *http.Client
+}
+
+func newFetcher() *fetcher {
+	f := &fetcher{
+		recent: map[string][]time.Time{},
+	}
+	f.client = &http.Client{
+		Timeout: fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			if !f.allowed(req.URL.Hostname()) {
+				return errors.Errorf("redirect to host %q not allowlisted", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// SetFetchAllowlist sets the hosts httpFetch may request. Entries starting with "*." allow
+// all subdomains of the rest.
+func (g *Game) SetFetchAllowlist(hosts []string) {
+	g.fetcher.mutex.Lock()
+	defer g.fetcher.mutex.Unlock()
+	g.fetcher.allowlist = nil
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			g.fetcher.allowlist = append(g.fetcher.allowlist, host)
+		}
+	}
+}
+
+func (f *fetcher) allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range f.allowlist {
+		if domain, found := strings.CutPrefix(entry, "*."); found && strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+		if entry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve checks whether objectID may fetch rawURL, and counts it against the rate limit.
+func (f *fetcher) reserve(objectID string, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("scheme %q not allowed", u.Scheme)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.allowed(u.Hostname()) {
+		return errors.Errorf("host %q not allowlisted", u.Hostname())
+	}
+	now := time.Now()
+	for id, ts := range f.recent {
+		recent := []time.Time{}
+		for _, t := range ts {
+			if now.Sub(t) < time.Minute {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(f.recent, id)
+		} else {
+			f.recent[id] = recent
+		}
+	}
+	if len(f.recent[objectID]) >= maxFetchesPerMinute {
+		return errors.Errorf("more than %d fetches per minute", maxFetchesPerMinute)
+	}
+	f.recent[objectID] = append(f.recent[objectID], now)
+	return nil
+}
+
+type fetchOptions struct {
+	Method  string
+	Headers map[string]string
+	Body    string
+}
+
+type fetchResponse struct {
+	URL       string
+	Status    int               `json:",omitempty"`
+	Headers   map[string]string `json:",omitempty"`
+	Body      string            `json:",omitempty"`
+	Truncated bool              `json:",omitempty"`
+	Error     string            `json:",omitempty"`
+}
+
+func (f *fetcher) fetch(ctx context.Context, rawURL string, options fetchOptions) *fetchResponse {
+	result := &fetchResponse{URL: rawURL}
+	if options.Method == "" {
+		options.Method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, options.Method, rawURL, strings.NewReader(options.Body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for key, value := range options.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Headers = map[string]string{}
+	for key := range resp.Header {
+		result.Headers[key] = resp.Header.Get(key)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchResponseSize+1))
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if len(body) > maxFetchResponseSize {
+		body = body[:maxFetchResponseSize]
+		result.Truncated = true
+	}
+	result.Body = string(body)
+	return result
+}
+
+// addFetchCallbacks adds `httpFetch(url, options, event)`, which requests url in the background
+// and emits event to the object with the response (or error). options can contain method, headers
+// and body.
+//
+// Responses arrive as events since requests can take much longer than a run is allowed to.
+func (g *Game) addFetchCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["httpFetch"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsObject() || !args[2].IsString() {
+			return rc.Throw("httpFetch takes [string, Object, string] arguments")
+		}
+		rawURL, event := args[0].String(), args[2].String()
+		options := fetchOptions{}
+		if err := rc.Copy(&options, args[1]); err != nil {
+			return rc.Throw("trying to copy %v to a &fetchOptions{}: %v", args[1], err)
+		}
+		if err := g.fetcher.reserve(object.Id, rawURL); err != nil {
+			return rc.Throw("can't fetch %q: %v", rawURL, err)
+		}
+		objectID := object.Id
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			response := g.fetcher.fetch(ctx, rawURL, options)
+			message, err := goccy.Marshal(response)
+			if err != nil {
+				log.Printf("trying to serialize %+v: %v", response, err)
+				return
+			}
+			at := g.storage.Queue().After(0)
+			if err := g.emitJSON(ctx, at, objectID, event, string(message)); err != nil {
+				log.Printf("trying to emit %s to %s: %v", event, objectID, err)
+				return
+			}
+			traceEmitted(objectID, objectID, event, string(message), at)
+		}()
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+// isUser returns whether the object with the given ID is a user.
+func (g *Game) isUser(ctx context.Context, id string) (bool, error) {
+	if _, err := g.storage.LoadUserByObject(ctx, id); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	return true, nil
+}
+
+// checkDamage returns an error if attacker isn't allowed to damage victim, because victim is
+// in a sanctuary, or both are users in a room without PvP.
+func (g *Game) checkDamage(ctx context.Context, running *structs.Object, victim *structs.Object, attacker string) error {
+	if victim.Location == "" {
+		return nil
+	}
+	room, err := g.loadObjectOrRunning(ctx, running, victim.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if room.Flagged(structs.SanctuaryTag) {
+		return errors.Errorf("%q is in a sanctuary", victim.Id)
+	}
+	if !room.Flagged(structs.NoPvPTag) || attacker == "" || attacker == victim.Id {
+		return nil
+	}
+	for _, id := range []string{victim.Id, attacker} {
+		if user, err := g.isUser(ctx, id); err != nil {
+			return juicemud.WithStack(err)
+		} else if !user {
+			return nil
+		}
+	}
+	return errors.Errorf("%q is in a room without PvP", victim.Id)
+}
+
+// checkSteal returns an error if mover isn't allowed to take the item with the given ID from
+// whatever carries it, because the carrier, or the room the carrier is in, has NoStealTag.
+// Objects without location, like rooms, only protect what their content carries.
+func (g *Game) checkSteal(ctx context.Context, mover *structs.Object, itemID string) error {
+	if itemID == mover.Id {
+		return nil
+	}
+	item, err := g.loadObjectOrRunning(ctx, mover, itemID)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if item.Location == "" || item.Location == mover.Id {
+		return nil
+	}
+	carrier, err := g.loadObjectOrRunning(ctx, mover, item.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if carrier.Location == "" {
+		return nil
+	}
+	if carrier.Flagged(structs.NoStealTag) {
+		return errors.Errorf("%q can't be taken from %q", itemID, carrier.Id)
+	}
+	room, err := g.loadObjectOrRunning(ctx, mover, carrier.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if room.Flagged(structs.NoStealTag) {
+		return errors.Errorf("%q can't be taken from %q in %q", itemID, carrier.Id, room.Id)
+	}
+	return nil
+}
+
+// flagsCommand shows the flags of an object, the current location by default, or sets and
+// clears them with +flag and -flag.
+func (c *Connection) flagsCommand(args []string) error {
+	usage := func() error {
+		fmt.Fprintf(c.term, "usage: /flags [#id] [+flag|-flag ...], with flags %s\n", strings.Join(structs.FlagTags, ", "))
+		return nil
+	}
+	actor, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	id := actor.Location
+	if len(args) > 0 && strings.HasPrefix(args[0], "#") {
+		var ok bool
+		if id, ok = parseObjectRef(args[0]); !ok {
+			return usage()
+		}
+		args = args[1:]
+	}
+	add, remove := map[string]bool{}, map[string]bool{}
+	for _, arg := range args {
+		if len(arg) < 2 || !slices.Contains(structs.FlagTags, arg[1:]) {
+			return usage()
+		}
+		switch arg[0] {
+		case '+':
+			add[arg[1:]] = true
+		case '-':
+			remove[arg[1:]] = true
+		default:
+			return usage()
+		}
+	}
+	var flags []string
+	if err := c.game.modifyObject(c.sess.Context(), nil, id, func(object *structs.Object) error {
+		object.Tags = slices.DeleteFunc(object.Tags, func(tag string) bool {
+			return remove[tag]
+		})
+		for tag := range add {
+			if !slices.Contains(object.Tags, tag) {
+				object.Tags = append(object.Tags, tag)
+			}
+		}
+		for _, tag := range structs.FlagTags {
+			if object.Flagged(tag) {
+				flags = append(flags, tag)
+			}
+		}
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(flags) == 0 {
+		fmt.Fprintf(c.term, "#%s has no flags\n", id)
+		return nil
+	}
+	fmt.Fprintf(c.term, "#%s: %s\n", id, strings.Join(flags, ", "))
+	return nil
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+	"rogchap.com/v8go"
+)
+
+const (
+	defaultFormatLanguage = "en"
+)
+
+// formatOptions are the options of the format helpers.
+type formatOptions struct {
+	// Viewer is the ID of the object whose user the text is for, and decides the locale.
+	Viewer   string
+	Decimals *int
+}
+
+// viewerLanguage returns the language of the user of the object with the given ID, or
+// defaultFormatLanguage if it isn't a user or prefers no language.
+func (g *Game) viewerLanguage(ctx context.Context, id string) (string, error) {
+	if id == "" {
+		return defaultFormatLanguage, nil
+	}
+	if c, found := envByObjectID.GetHas(id); found {
+		if languages := c.languages(); len(languages) > 0 {
+			return languages[0], nil
+		}
+		return defaultFormatLanguage, nil
+	}
+	user, err := g.storage.LoadUserByObject(ctx, id)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultFormatLanguage, nil
+	} else if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if user.Language == "" {
+		return defaultFormatLanguage, nil
+	}
+	return user.Language, nil
+}
+
+// formatNumber returns n with the digit grouping and decimal separator of lang, and with
+// decimals decimals, or as few as needed if decimals is nil.
+func formatNumber(lang string, n float64, decimals *int) string {
+	printer := message.NewPrinter(language.Make(lang))
+	if decimals == nil {
+		return printer.Sprint(number.Decimal(n))
+	}
+	return printer.Sprint(number.Decimal(n, number.Scale(*decimals)))
+}
+
+var durationUnits = []struct {
+	singular string
+	plural   string
+	size     time.Duration
+}{
+	{"day", "days", 24 * time.Hour},
+	{"hour", "hours", time.Hour},
+	{"minute", "minutes", time.Minute},
+	{"second", "seconds", time.Second},
+}
+
+// formatDuration returns d in its two largest units, like "2 hours 5 minutes", with the
+// numbers formatted for lang.
+func formatDuration(lang string, d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	parts := []string{}
+	for _, unit := range durationUnits {
+		count := d / unit.size
+		if count == 0 {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		d -= count * unit.size
+		name := unit.plural
+		if count == 1 {
+			name = unit.singular
+		}
+		if parts = append(parts, formatNumber(lang, float64(count), nil)+" "+name); len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "0 seconds"
+	}
+	return strings.Join(parts, " ")
+}
+
+// addFormatCallbacks adds `formatNumber(n, {Viewer?, Decimals?})` and `formatDuration(ms,
+// {Viewer?})`, which return player facing text for numbers and durations in the locale of
+// the user of Viewer, and `formatGameDate(ms?)`, which returns the JavaScript time, or now, as
+// a date of the game calendar.
+func (g *Game) addFormatCallbacks(ctx context.Context, callbacks js.Callbacks) {
+	options := func(rc *js.RunContext, args []*v8go.Value, index int) (formatOptions, string, error) {
+		result := formatOptions{}
+		if len(args) > index {
+			if err := rc.Copy(&result, args[index]); err != nil {
+				return result, "", errors.Wrapf(err, "trying to convert %v to formatOptions", args[index])
+			}
+		}
+		lang, err := g.viewerLanguage(ctx, result.Viewer)
+		return result, lang, juicemud.WithStack(err)
+	}
+	callbacks["formatNumber"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsNumber() || (len(args) == 2 && !args[1].IsObject()) {
+			return rc.Throw("formatNumber takes [number, Object?] arguments")
+		}
+		opts, lang, err := options(rc, args, 1)
+		if err != nil {
+			return rc.Throw("trying to format %v: %v", args[0], err)
+		}
+		return rc.String(formatNumber(lang, args[0].Number(), opts.Decimals))
+	}
+	callbacks["formatDuration"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsNumber() || (len(args) == 2 && !args[1].IsObject()) {
+			return rc.Throw("formatDuration takes [number, Object?] arguments")
+		}
+		_, lang, err := options(rc, args, 1)
+		if err != nil {
+			return rc.Throw("trying to format %v: %v", args[0], err)
+		}
+		return rc.String(formatDuration(lang, time.Duration(args[0].Number()*float64(time.Millisecond))))
+	}
+	callbacks["formatGameDate"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) > 1 || (len(args) == 1 && !args[0].IsNumber()) {
+			return rc.Throw("formatGameDate takes [number?] arguments")
+		}
+		at := time.Now()
+		if len(args) > 0 {
+			at = time.UnixMilli(int64(args[0].Number()))
+		}
+		return rc.String(g.gameCalendar.Load().Date(at).String())
+	}
+}
@@ -5,16 +5,17 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/gliderlabs/ssh"
 	"github.com/pkg/errors"
 	"github.com/zond/juicemud"
-	"github.com/zond/juicemud/js"
 	"github.com/zond/juicemud/storage"
 	"github.com/zond/juicemud/structs"
 	"golang.org/x/term"
+
+	goccy "github.com/goccy/go-json"
 )
 
 const (
@@ -24,13 +25,19 @@ const (
 
 const (
 	emitEventTag = "emit"
+	// initEventTag tags the queued first runs of objects created by running objects.
+	initEventTag = "init"
+	// refreshEventTag tags the queued reruns of objects whose source changed while they were busy.
+	refreshEventTag = "refresh"
 )
 
 const (
-	root          = "/"
-	userSource    = "/user.js"
-	genesisSource = "/genesis.js"
-	bootSource    = "/boot.js"
+	root           = "/"
+	userSource     = "/user.js"
+	genesisSource  = "/genesis.js"
+	bootSource     = "/boot.js"
+	areaRoomSource = "/area_room.js"
+	corpseSource   = "/corpse.js"
 )
 
 const (
@@ -44,15 +51,46 @@ const (
 var (
 	initialDirectories = []string{
 		root,
+		bootDir,
 	}
 	initialSources = map[string]string{
-		bootSource: "// This code is run each time the game server starts.",
+		bootSource:   "// This code is run each time the game server starts, before the scripts in /boot.",
+		motdSource:   "",
+		bannerSource: initialBanner,
 		userSource: `// This code runs all users.
+if (typeof creationParams !== 'undefined') {
+    state.name = creationParams.Name;
+    state.archetype = creationParams.Archetype;
+}
 setDescriptions([
     {
-        short: 'a person',
+        short: state.name || 'a person',
     }
 ]);
+`,
+		socialsSource: initialSocials,
+		archetypesSource: `# The archetypes new characters choose between, like:
+#
+# start: "#genesis"
+# archetypes:
+#   warrior:
+#     description: Strong, and good with a sword.
+#     start: "#barracks"
+#     skills:
+#       swords: 5
+#     equipment:
+#       - /items/sword.js
+archetypes: {}
+`,
+		areaRoomSource: "// This code runs rooms defined in area files without a source of their own.",
+		corpseSource: `// This code runs the corpses left when objects die.
+if (typeof creationParams !== 'undefined') {
+  setDescriptions([
+    {
+      short: 'the corpse of ' + creationParams.Short,
+    },
+  ]);
+}
 `,
 		genesisSource: `// This code runs the room where newly created users are dropped.
 setDescriptions([
@@ -78,7 +116,21 @@ setDescriptions([
 )
 
 type Game struct {
-	storage *storage.Storage
+	storage   *storage.Storage
+	fetcher   *fetcher
+	llm       *llmClient
+	announcer *announcer
+	// trashRetention is how many nanoseconds removed objects can be restored.
+	trashRetention atomic.Int64
+	gameCalendar   atomic.Pointer[GameCalendar]
+	// bootResults are how running the boot scripts went at startup.
+	bootResults []bootResult
+	started     time.Time
+	hideStats   atomic.Bool
+	// loginPolicy is the LoginPolicy of users without their own.
+	loginPolicy      atomic.Value
+	analytics        *analyticsCollector
+	analyticsEnabled atomic.Bool
 }
 
 func New(ctx context.Context, s *storage.Storage) (*Game, error) {
@@ -108,47 +160,104 @@ func New(ctx context.Context, s *storage.Storage) (*Game, error) {
 		}
 	}
 	g := &Game{
-		storage: s,
+		storage:   s,
+		fetcher:   newFetcher(),
+		llm:       newLLMClient(),
+		announcer: newAnnouncer(),
+		started:   time.Now(),
+		analytics: newAnalyticsCollector(),
+	}
+	g.SetTrashRetention(storage.DefaultTrashRetention)
+	g.loginPolicy.Store(string(TakeoverLogins))
+	g.gameCalendar.Store(defaultGameCalendar())
+	if err := g.loadDisabledSources(ctx); err != nil {
+		return nil, juicemud.WithStack(err)
 	}
+	if err := g.loadIPBans(ctx); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.loadSocials(ctx); err != nil {
+		log.Printf("trying to load %q: %v", socialsSource, err)
+		log.Println(juicemud.StackTrace(err))
+	}
+	dispatcher := newEventDispatcher(func(ev *structs.Event) {
+		var call Caller
+		if g.storage.Queue().Overdue(ev) {
+			ev.Call.Message = markLate(ev.Call.Message)
+		}
+		if ev.Call.Name != "" {
+			call = JSCall(ev.Call)
+		}
+		g.deliver(ctx, ev, call)
+	})
+	g.storage.SetMovementHandler(g.emitMovementToNeighbourhood)
 	go func() {
 		log.Panic(g.storage.StartQueue(ctx, func(ctx context.Context, ev *structs.Event) {
-			var call Caller
-			if ev.Call.Name != "" {
-				call = JSCall(ev.Call)
-			}
-			go func() {
-				if err := g.loadRunSave(ctx, ev.Object, call); err != nil {
-					log.Printf("trying to execute %+v: %v", ev, err)
-				}
-			}()
-		}, g.emitMovementToNeighbourhood))
+			dispatcher.dispatch(ev)
+		}))
 	}()
-	bootJS, _, err := g.storage.LoadSource(ctx, bootSource)
-	if err != nil {
+	go g.runCalendar(ctx)
+	go g.runDecaySweeps(ctx)
+	go g.runInstanceSweeps(ctx)
+	go g.runAmbience(ctx)
+	go g.runDrownings(ctx)
+	go g.runTrashSweeps(ctx)
+	go g.runDespawnSweeps(ctx)
+	go g.runConversationSweeps(ctx)
+	go g.runAnalyticsFlushes(ctx)
+	if err := g.runBoot(ctx); err != nil {
 		return nil, juicemud.WithStack(err)
 	}
-	callbacks := js.Callbacks{}
-	g.addGlobalCallbacks(ctx, callbacks)
-	bootTarget := js.Target{
-		Source:    string(bootJS),
-		Origin:    bootSource,
-		State:     "{}",
-		Callbacks: callbacks,
-		Console:   os.Stderr,
-	}
-	if _, err := bootTarget.Run(ctx, nil, time.Second); err != nil {
-		log.Printf("trying to run %q: %v", bootSource, err)
-		log.Println(juicemud.StackTrace(err))
-		return g, nil
-	}
 	return g, nil
 }
 
+// deliver runs the event, and stores it as a dead letter if the target is missing or the run
+// fails. Failed runs aren't retried, since they may have had side effects before failing, but
+// can be redelivered with /redeliver.
+func (g *Game) deliver(ctx context.Context, ev *structs.Event, call Caller) {
+	started := time.Now()
+	err := g.loadRunSave(ctx, ev.Object, call)
+	traceReceived(ev, started, err)
+	if err == nil {
+		return
+	}
+	log.Printf("trying to execute %+v: %v", ev, err)
+	if err := g.storage.StoreDeadLetter(ctx, ev, 1, err); err != nil {
+		log.Printf("trying to store dead letter %+v: %v", ev, err)
+	}
+}
+
+// markLate adds `"late": true` to JSON object messages, to let handlers know they were
+// delivered after a server restart instead of when they were due.
+func markLate(message string) string {
+	content := map[string]any{}
+	if err := goccy.Unmarshal([]byte(message), &content); err != nil {
+		return message
+	}
+	content["late"] = true
+	b, err := goccy.Marshal(content)
+	if err != nil {
+		return message
+	}
+	return string(b)
+}
+
 func (g *Game) HandleSession(sess ssh.Session) {
+	g.handleSession(sess, &Listener{Protocol: sshProtocol})
+}
+
+func (g *Game) handleSession(sess ssh.Session, listener *Listener) {
+	sess = compressSession(sess)
+	charset := &charsetReadWriter{ReadWriter: sess}
+	recorder := &recorder{ReadWriter: charset, storage: g.storage}
 	env := &Connection{
-		game: g,
-		term: term.NewTerminal(sess, "> "),
-		sess: sess,
+		game:     g,
+		term:     term.NewTerminal(recorder, "> "),
+		charset:  charset,
+		recorder: recorder,
+		sess:     sess,
+		listener: listener,
+		done:     make(chan struct{}),
 	}
 	if err := env.Connect(); err != nil {
 		if !errors.Is(err, io.EOF) {
@@ -174,7 +283,81 @@ func (g *Game) createObject(ctx context.Context, f func(*structs.Object) error)
 	return nil
 }
 
-func (g *Game) createUser(ctx context.Context, user *storage.User) error {
+// createObjectFromSource creates an object running sourcePath in location, and runs it
+// once with creationParams available as the read-only `creationParams` global.
+func (g *Game) createObjectFromSource(ctx context.Context, sourcePath string, location string, creationParams string) (*structs.Object, error) {
+	if _, err := g.storage.LoadFile(ctx, sourcePath); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	object, err := structs.MakeObject(ctx)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.initObject(ctx, object, sourcePath, location, creationParams); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return object, nil
+}
+
+// initObject sets up a new object running sourcePath in location, runs it once with
+// creationParams, and stores it.
+func (g *Game) initObject(ctx context.Context, object *structs.Object, sourcePath string, location string, creationParams string) error {
+	object.SourcePath = sourcePath
+	object.Location = location
+	if err := g.runWithParams(ctx, object, nil, creationParams); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.storage.StoreObject(ctx, nil, object))
+}
+
+// createObjectFromSourceLater creates an object running sourcePath in location, like
+// createObjectFromSource, but queues its first run instead of running it right away.
+func (g *Game) createObjectFromSourceLater(ctx context.Context, sourcePath string, location string, creationParams string) (*structs.Object, error) {
+	object, err := structs.MakeObject(ctx)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.initObjectLater(ctx, object, sourcePath, location, creationParams); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return object, nil
+}
+
+// initObjectLater sets up and stores a new object running sourcePath in location, and queues
+// its first run with creationParams. Running objects create objects this way, since running the
+// new object right away needs another isolate while the creator holds one.
+func (g *Game) initObjectLater(ctx context.Context, object *structs.Object, sourcePath string, location string, creationParams string) error {
+	if _, err := g.storage.LoadFile(ctx, sourcePath); err != nil {
+		return juicemud.WithStack(err)
+	}
+	modTime, err := g.storage.SourceModTime(ctx, sourcePath)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	object.SourcePath = sourcePath
+	object.Location = location
+	// Loading the object before the queued run mustn't run it without creationParams.
+	object.SourceModTime = modTime
+	if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.storage.Queue().Push(ctx, &structs.Event{
+		At:     uint64(g.storage.Queue().After(0)),
+		Object: object.Id,
+		Call: structs.Call{
+			Name:    initEventTag,
+			Message: creationParams,
+			Tag:     initEventTag,
+		},
+	}))
+}
+
+// createUser creates the object of user, as the character char chose in the character
+// creation, or as a bare object in genesis if char is nil.
+func (g *Game) createUser(ctx context.Context, user *storage.User, char *character) error {
+	if char != nil {
+		return juicemud.WithStack(g.createCharacterObject(ctx, user, char))
+	}
 	return juicemud.WithStack(g.createObject(ctx, func(object *structs.Object) error {
 		object.SourcePath = userSource
 		object.Location = genesisID
@@ -1,16 +1,35 @@
 package game
 
 import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"maps"
+	"net"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"text/template"
+	"time"
+	"unicode"
 
 	"github.com/bxcodec/faker/v4"
 	"github.com/bxcodec/faker/v4/pkg/options"
+	"github.com/gliderlabs/ssh"
+	"github.com/pkg/errors"
 	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/skills"
 	"github.com/zond/juicemud/storage"
 	"github.com/zond/juicemud/structs"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	goccy "github.com/goccy/go-json"
 )
 
 func fakeObject(t testing.TB, g *Game) *structs.Object {
@@ -66,7 +85,52 @@ func connect(t testing.TB, g *Game, obj1, obj2 *structs.Object) {
 	}
 }
 
-func withGame(b *testing.B, f func(*Game)) {
+// emptyObject stores and returns an object running the empty area room source in location.
+func emptyObject(t testing.TB, g *Game, location string) *structs.Object {
+	res, err := structs.MakeObject(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.SourcePath = areaRoomSource
+	res.Location = location
+	if err := g.storage.StoreObject(context.Background(), nil, res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// ownerContext returns a context authenticated as an owner, allowed to write any file.
+func ownerContext() context.Context {
+	return storage.AuthenticateUser(context.Background(), &storage.User{Name: "owner", Owner: true})
+}
+
+// storeSource creates the file at path, and its parent directories, with content.
+func storeSource(t testing.TB, g *Game, path string, content string) {
+	ctx := ownerContext()
+	if err := g.ensureParentDirs(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.storage.EnsureFile(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.storage.StoreSource(ctx, path, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor fails t unless f returns true within a few seconds.
+func waitFor(t testing.TB, description string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func withGame(b testing.TB, f func(*Game)) {
 	b.Helper()
 	tmpFile, err := os.CreateTemp("", "")
 	if err != nil {
@@ -92,6 +156,338 @@ func withGame(b *testing.B, f func(*Game)) {
 	f(g)
 }
 
+func TestCreateObjectQueuesFirstRun(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/creator.js", `addCallback('create', ['emit'], (msg) => {
+  createObject('/child.js', msg.Location, {N: 2});
+});`)
+		storeSource(t, g, "/child.js", `if (typeof creationParams !== 'undefined') {
+  state.n = creationParams.N;
+}`)
+		creator := emptyObject(t, g, genesisID)
+		creator.SourcePath = "/creator.js"
+		if err := g.storage.StoreObject(ctx, nil, creator); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.loadRunSave(ctx, creator.Id, &AnyCall{
+			Name:    "create",
+			Tag:     emitEventTag,
+			Content: map[string]any{"Location": creator.Id},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := g.storage.LoadObject(ctx, creator.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored.Content) != 1 {
+			t.Fatalf("got content %+v, want the created child to survive the creator being stored", stored.Content)
+		}
+		for childID := range stored.Content {
+			waitFor(t, "the first run of the child", func() bool {
+				child, err := g.storage.LoadObject(ctx, childID, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return child.State == `{"n":2}`
+			})
+		}
+	})
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	ctx := ownerContext()
+	withGame(t, func(g *Game) {
+		sourcePath := "/archived/room.js"
+		storeSource(t, g, sourcePath, "// room")
+		outside := emptyObject(t, g, genesisID)
+		room := emptyObject(t, g, genesisID)
+		room.SourcePath = sourcePath
+		child := emptyObject(t, g, room.Id)
+		room.Content[child.Id] = true
+		room.Exits = []structs.Exit{{Destination: child.Id}, {Destination: outside.Id}, {Destination: "missing"}}
+		if err := g.storage.StoreObject(ctx, nil, room); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := g.exportArchive(ctx, room.Id, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(archive.Objects) != 2 || archive.Sources[sourcePath] != "// room" {
+			t.Fatalf("got %+v, want the room, its child, and its source", archive)
+		}
+		js, err := goccy.Marshal(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied := &Archive{}
+		if err := goccy.Unmarshal(js, copied); err != nil {
+			t.Fatal(err)
+		}
+		imported, err := g.importArchive(ctx, copied, outside.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported.Id == room.Id || imported.Location != outside.Id || imported.SourcePath != sourcePath {
+			t.Errorf("got %+v, want a copy of the room in %q", imported, outside.Id)
+		}
+		stored, err := g.storage.LoadObject(ctx, imported.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored.Content) != 1 {
+			t.Fatalf("got content %+v, want one copied child", stored.Content)
+		}
+		for childID := range stored.Content {
+			if childID == child.Id {
+				t.Errorf("got the original child %q, want a copy", childID)
+			}
+			if len(stored.Exits) != 2 || stored.Exits[0].Destination != childID || stored.Exits[1].Destination != outside.Id {
+				t.Errorf("got exits %+v, want exits to the copied child and %q", stored.Exits, outside.Id)
+			}
+		}
+
+		invalid := &Archive{
+			Root:    "root",
+			Objects: []*structs.Object{{Id: "root"}, {Id: "stray", Location: "elsewhere"}},
+			Sources: map[string]string{"/invalid/source.js": "// invalid"},
+		}
+		if _, err := g.importArchive(ctx, invalid, outside.Id); err == nil {
+			t.Errorf("imported an object located outside of the archive")
+		}
+		if _, err := g.storage.LoadFile(ctx, "/invalid/source.js"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v, want the sources of invalid archives to not be written", err)
+		}
+	})
+}
+
+func TestDig(t *testing.T) {
+	ctx := context.Background()
+	withGame(t, func(g *Game) {
+		origin := emptyObject(t, g, genesisID)
+		destination := emptyObject(t, g, genesisID)
+		jsContextLocks.Lock(destination.Id)
+		done := make(chan error, 1)
+		go func() {
+			_, err := g.dig(ctx, origin, "north", destination.Id, "")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("dig finished with %v while the destination was locked", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		jsContextLocks.Unlock(destination.Id)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if exit, found := origin.ExitNamed("north"); !found || exit.Destination != destination.Id {
+			t.Errorf("got %+v, %v, want an exit north to %q", exit, found, destination.Id)
+		}
+		stored, err := g.storage.LoadObject(ctx, destination.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exit, found := stored.ExitNamed("south"); !found || exit.Destination != origin.Id {
+			t.Errorf("got %+v, %v, want an exit south to %q", exit, found, origin.Id)
+		}
+		if point, found := stored.Point(); !found || point != (structs.Point{X: 0, Y: 1, Z: 0}) {
+			t.Errorf("got %+v, %v, want a point north of origin", point, found)
+		}
+		if _, err := g.dig(ctx, origin, "north", destination.Id, ""); err == nil {
+			t.Errorf("dug a second exit north")
+		}
+		if _, err := g.dig(ctx, origin, "up", origin.Id, ""); err == nil {
+			t.Errorf("dug from origin to itself")
+		}
+	})
+}
+
+func TestDeliverDoesntRetry(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/failing/object.js", `addCallback('fail', ['emit'], (msg) => {
+  createObject('/failing/child.js', msg.Location);
+  throw new Error('failed');
+});`)
+		storeSource(t, g, "/failing/child.js", "// child")
+		container := emptyObject(t, g, genesisID)
+		object := emptyObject(t, g, genesisID)
+		object.SourcePath = "/failing/object.js"
+		if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+			t.Fatal(err)
+		}
+		ev := &structs.Event{Object: object.Id, Call: structs.Call{Name: "fail", Message: fmt.Sprintf(`{"Location":%q}`, container.Id), Tag: emitEventTag}}
+		g.deliver(ctx, ev, JSCall(ev.Call))
+		stored, err := g.storage.LoadObject(ctx, container.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored.Content) != 1 {
+			t.Errorf("got content %+v, want one object created by one run", stored.Content)
+		}
+		letters, err := g.storage.DeadLetters(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(letters) != 1 || letters[0].Object != object.Id || letters[0].Attempts != 1 {
+			t.Errorf("got %+v, want one dead letter for %q after one attempt", letters, object.Id)
+		}
+	})
+}
+
+func TestMoveObject(t *testing.T) {
+	ctx := context.Background()
+	withGame(t, func(g *Game) {
+		running := emptyObject(t, g, genesisID)
+		item := emptyObject(t, g, genesisID)
+		jsContextLocks.Lock(running.Id)
+		defer jsContextLocks.Unlock(running.Id)
+		if err := g.moveObject(ctx, running, item.Id, running.Id); err != nil {
+			t.Fatal(err)
+		}
+		// The running object is stored without item in its Content when the run finishes.
+		oldLocation := running.Location
+		if err := g.storage.StoreObject(ctx, &oldLocation, running); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := g.storage.LoadObject(ctx, running.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.Content[item.Id] {
+			t.Errorf("got content %+v, want %q moved into it", stored.Content, item.Id)
+		}
+
+		// Another run holding the lock of item, waiting for the running object, gives up after a while.
+		jsContextLocks.Lock(item.Id)
+		started := time.Now()
+		if err := g.moveObject(ctx, running, item.Id, genesisID); !errors.Is(err, ErrObjectBusy) {
+			t.Errorf("got %v, want %v", err, ErrObjectBusy)
+		}
+		if lapsed := time.Since(started); lapsed < otherLockTimeout {
+			t.Errorf("gave up after %v, want at least %v", lapsed, otherLockTimeout)
+		}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			jsContextLocks.Unlock(item.Id)
+		}()
+		if err := g.moveObject(ctx, running, item.Id, genesisID); err != nil {
+			t.Errorf("got %v, want the move to wait for the lock", err)
+		}
+	})
+}
+
+func TestGlobalWritable(t *testing.T) {
+	for _, tc := range []struct {
+		namespace  string
+		sourcePath string
+		want       bool
+	}{
+		{"/quests", "/quests/dragon.js", true},
+		{"/quests", "/quests/old/dragon.js", true},
+		{"/quests/old", "/quests/dragon.js", false},
+		{"/quest", "/quests/dragon.js", false},
+		{"/", "/user.js", false},
+		{"quests", "/quests/dragon.js", false},
+		{"/quests/../x", "/quests/../x/dragon.js", false},
+	} {
+		if got := globalWritable(tc.namespace, tc.sourcePath); got != tc.want {
+			t.Errorf("globalWritable(%q, %q) = %v, want %v", tc.namespace, tc.sourcePath, got, tc.want)
+		}
+	}
+}
+
+// fakeSession is a session with an environment, writing to a buffer.
+type fakeSession struct {
+	ssh.Session
+	environ []string
+	output  bytes.Buffer
+}
+
+// fakeContext is a session context wrapping ctx.
+type fakeContext struct {
+	ssh.Context
+	ctx context.Context
+}
+
+func (f fakeContext) Deadline() (time.Time, bool) {
+	return f.ctx.Deadline()
+}
+
+func (f fakeContext) Done() <-chan struct{} {
+	return f.ctx.Done()
+}
+
+func (f fakeContext) Err() error {
+	return f.ctx.Err()
+}
+
+func (f fakeContext) Value(key any) any {
+	return f.ctx.Value(key)
+}
+
+func (f *fakeSession) Context() ssh.Context {
+	return fakeContext{ctx: context.Background()}
+}
+
+// testConnection returns a connection to g of a user with the object with the given ID, and
+// the buffer its output is written to.
+func testConnection(g *Game, object string) (*Connection, *bytes.Buffer) {
+	output := &bytes.Buffer{}
+	c := &Connection{
+		game: g,
+		sess: &fakeSession{},
+		user: &storage.User{Name: object, Object: object},
+		term: term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{&bytes.Buffer{}, output}, ""),
+	}
+	c.width.Store(80)
+	return c, output
+}
+
+func (f *fakeSession) Environ() []string {
+	return f.environ
+}
+
+func (f *fakeSession) Pty() (ssh.Pty, <-chan ssh.Window, bool) {
+	return ssh.Pty{}, nil, false
+}
+
+func (f *fakeSession) Write(b []byte) (int, error) {
+	return f.output.Write(b)
+}
+
+func TestCompressSession(t *testing.T) {
+	plain := &fakeSession{}
+	if got := compressSession(plain); got != plain {
+		t.Errorf("got %v, want the session uncompressed without %s", got, juicemud.CompressionEnv)
+	}
+	raw := &fakeSession{environ: []string{juicemud.CompressionEnv + "=" + juicemud.CompressionZlib}}
+	sess := compressSession(raw)
+	text := strings.Repeat("A long corridor.\n", 20)
+	if _, err := sess.Write([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+	if raw.output.Len() >= len(text) {
+		t.Errorf("got %v bytes, want fewer than the %v written", raw.output.Len(), len(text))
+	}
+	if bytes.Contains(raw.output.Bytes(), []byte{'\n'}) {
+		t.Errorf("got %q, want no newlines for the session to rewrite", raw.output.Bytes())
+	}
+	inflated, err := zlib.NewReader(juicemud.NewlineUnescaper(bytes.NewReader(raw.output.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(text))
+	if _, err := io.ReadFull(inflated, got); err != nil || string(got) != text {
+		t.Errorf("got %q, %v, want each write readable right away", got, err)
+	}
+}
+
 func BenchmarkLoadNeighbourhood(b *testing.B) {
 	b.StopTimer()
 	withGame(b, func(g *Game) {
@@ -125,7 +521,7 @@ func BenchmarkCall(b *testing.B) {
 			PasswordHash: "blapp",
 			Owner:        false,
 		}
-		if err := g.createUser(ctx, user); err != nil {
+		if err := g.createUser(ctx, user, nil); err != nil {
 			b.Fatal(err)
 		}
 		b.StartTimer()
@@ -137,3 +533,1884 @@ func BenchmarkCall(b *testing.B) {
 		b.StopTimer()
 	})
 }
+
+func TestParseArea(t *testing.T) {
+	area, err := parseArea([]byte(`
+rooms:
+  clearing:
+    short: A clearing
+    exits:
+      - name: north
+        to: path
+    objects:
+      - source: /oak.js
+        params: {height: 12}
+  path:
+    short: A path
+    exits:
+      - name: gate
+        to: "#genesis"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := area.Rooms["clearing"].Exits[0].To; got != "path" {
+		t.Errorf("got %q, want %q", got, "path")
+	}
+	if got := area.Rooms["clearing"].Objects[0].Params["height"]; got != 12 {
+		t.Errorf("got %+v, want 12", got)
+	}
+	if _, err := parseArea([]byte(`{"rooms": {"a": {"exits": [{"name": "up", "to": "b"}]}}}`)); err == nil {
+		t.Errorf("got nil, want error for exit to unknown room")
+	}
+}
+
+func TestMarkLate(t *testing.T) {
+	if got, want := markLate(`{"a":1}`), `{"a":1,"late":true}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := markLate(`"a"`), `"a"`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRollTable(t *testing.T) {
+	tables.Set("test-gems", &rollableTable{{Value: "ruby", Weight: 1}, {Value: "diamond", Weight: 0, Luck: 1}})
+	tables.Set("test-loot", &rollableTable{{Weight: 1}, {Table: "test-gems", Weight: 1}})
+	for _, tc := range []struct {
+		random float64
+		luck   float64
+		want   string
+	}{
+		{0.1, 0, ""},
+		{0.6, 0, "ruby"},
+		{0.99, 0, "ruby"},
+		{0.99, 1, "diamond"},
+	} {
+		if got, err := rollTable("test-loot", tc.luck, func() float64 { return tc.random }); err != nil || got != tc.want {
+			t.Errorf("got %q, %v, want %q for %+v", got, err, tc.want, tc)
+		}
+	}
+	tables.Set("test-loop", &rollableTable{{Table: "test-loop", Weight: 1}})
+	if _, err := rollTable("test-loop", 0, func() float64 { return 0 }); err == nil {
+		t.Errorf("got nil, want error for a table rolling itself")
+	}
+}
+
+func TestLanguageChain(t *testing.T) {
+	languageFallbacks.Store(&map[string][]string{"nb": {"no", "da"}, "no": {"nb"}})
+	defer languageFallbacks.Store(&map[string][]string{})
+	if got, want := languageChain("nb_NO.UTF-8"), []string{"nb-no", "nb", "no", "da"}; !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := languageChain("C"); len(got) != 0 {
+		t.Errorf("got %+v, want nothing", got)
+	}
+}
+
+func TestMatchObjects(t *testing.T) {
+	viewer := &structs.Object{Id: "viewer"}
+	objects := map[string]*structs.Object{
+		"a": {Id: "a", Descriptions: []structs.Description{{Short: "a red orb"}}},
+		"b": {Id: "b", Descriptions: []structs.Description{{Short: "a blue orb"}}},
+		"c": {Id: "c", Descriptions: []structs.Description{{Short: "an orb", Tags: []string{"red"}}}},
+	}
+	for _, tc := range []struct {
+		phrase string
+		want   []string
+	}{
+		{"orb", []string{"a", "b", "c"}},
+		{"red orb", []string{"a", "c"}},
+		{"second red orb", []string{"c"}},
+		{"2.orb", []string{"b"}},
+		{"3rd red orb", nil},
+		{"green orb", nil},
+	} {
+		got := []string{}
+		for _, object := range matchObjects(viewer, objects, tc.phrase) {
+			got = append(got, object.Id)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("got %+v, want %+v for %q", got, tc.want, tc.phrase)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, tc := range []struct {
+		durations []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{nil, 50, 0},
+		{sorted, 50, 50},
+		{sorted, 95, 95},
+		{sorted, 99, 99},
+		{sorted, 100, 100},
+		{sorted, 0, 1},
+		{[]time.Duration{7}, 99, 7},
+	} {
+		if got := percentile(tc.durations, tc.p); got != tc.want {
+			t.Errorf("percentile(%v, %v): got %v, want %v", len(tc.durations), tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestStateSchemaValidate(t *testing.T) {
+	closed := false
+	minimum := 0.0
+	schema := &stateSchema{
+		Type:                 "object",
+		Required:             []string{"count"},
+		AdditionalProperties: &closed,
+		Properties: map[string]*stateSchema{
+			"count": {Type: "integer", Minimum: &minimum},
+			"mood":  {Enum: []any{"happy", "sad"}},
+			"items": {Type: "array", Items: &stateSchema{Type: []any{"string", "null"}}},
+		},
+	}
+	for _, tc := range []struct {
+		state string
+		valid bool
+	}{
+		{`{"count": 1}`, true},
+		{`{"count": 1, "mood": "sad", "items": ["a", null]}`, true},
+		{`{"cout": 1}`, false},
+		{`{"count": 1, "cout": 1}`, false},
+		{`{"count": 1.5}`, false},
+		{`{"count": -1}`, false},
+		{`{"count": 1, "mood": "angry"}`, false},
+		{`{"count": 1, "items": [1]}`, false},
+		{`[]`, false},
+	} {
+		var value any
+		if err := goccy.Unmarshal([]byte(tc.state), &value); err != nil {
+			t.Fatal(err)
+		}
+		if err := schema.validate("state", value); (err == nil) != tc.valid {
+			t.Errorf("validate(%s): got %v, want valid %v", tc.state, err, tc.valid)
+		}
+	}
+	if err := (&stateSchema{Type: "integr"}).check(); err == nil {
+		t.Errorf("check accepted unknown type")
+	}
+}
+
+func TestParseArchetypes(t *testing.T) {
+	definitions, err := parseArchetypes([]byte(`
+archetypes:
+  warrior:
+    description: Strong.
+    skills:
+      swords: 5
+    equipment:
+      - /items/sword.js
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := definitions.Archetypes["warrior"]; got.Skills["swords"] != 5 || !slices.Equal(got.Equipment, []string{"/items/sword.js"}) {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := parseArchetypes([]byte("archetypes:\n  mage:\n    equipment:\n      - ''\n")); err == nil {
+		t.Errorf("wanted error for equipment without source")
+	}
+	for name, want := range map[string]bool{
+		"Aragorn":               true,
+		"Mary-Jane O'Hara":      true,
+		"":                      false,
+		"R2D2":                  false,
+		"Abcdefghijklmnopqrstu": false,
+	} {
+		if got := validCharacterName(name); got != want {
+			t.Errorf("validCharacterName(%q): got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestComposeSocial(t *testing.T) {
+	definitions := &socialDefinitions{}
+	if err := yaml.Unmarshal([]byte(initialSocials), definitions); err != nil {
+		t.Fatal(err)
+	}
+	pat, found := definitions.Socials["pat"]
+	if !found {
+		t.Fatalf("no pat in %+v", definitions.Socials)
+	}
+	actor := socialParty{Name: "a tall elf", Pronouns: "she"}
+	target := socialParty{Name: "Bob"}
+	if got, want := composeSocial(pat.OthersTarget, actor, target), "A tall elf pats Bob on their head."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := composeSocial("%e %m %s %E %M %S 100%% %x %", actor, target), "she her her they them their 100% %x %"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestComprehend(t *testing.T) {
+	text := "Hello there, friend of the forest!"
+	for _, grade := range []skills.Grade{skills.Success, skills.CriticalSuccess} {
+		if got := comprehend(text, grade, "listener"); got != text {
+			t.Errorf("%v: got %q, want %q", grade, got, text)
+		}
+	}
+	gibberish := comprehend(text, skills.CriticalFailure, "listener")
+	words, garbled := strings.Fields(text), strings.Fields(gibberish)
+	if len(garbled) != len(words) {
+		t.Fatalf("got %q, want as many words as %q", gibberish, text)
+	}
+	for i := range words {
+		if len([]rune(garbled[i])) != len([]rune(words[i])) {
+			t.Errorf("garbled %q to %q, want the same length", words[i], garbled[i])
+		}
+	}
+	if !strings.HasSuffix(garbled[1], ",") || !strings.HasSuffix(garbled[5], "!") || !unicode.IsUpper([]rune(garbled[0])[0]) {
+		t.Errorf("got %q, want punctuation and case kept", gibberish)
+	}
+	if again := comprehend(text, skills.CriticalFailure, "other"); again != gibberish {
+		t.Errorf("got %q, then %q, want words garbled the same way", gibberish, again)
+	}
+	partial := comprehend(text, skills.Failure, "listener")
+	if partial != comprehend(text, skills.Failure, "listener") {
+		t.Errorf("partial comprehension of %q isn't deterministic", text)
+	}
+	for i, word := range strings.Fields(partial) {
+		if word != words[i] && word != garbled[i] {
+			t.Errorf("got %q in %q, want %q or %q", word, partial, words[i], garbled[i])
+		}
+	}
+}
+
+func TestDialogue(t *testing.T) {
+	dialogue := map[string]structs.DialogueNode{
+		"start": {
+			Text: "%N looks at you.",
+			Choices: []structs.DialogueChoice{
+				{Text: "Any work?", Next: "work"},
+				{Text: "Bye."},
+			},
+		},
+		"work": {Text: "Kill the rats."},
+	}
+	if err := validateDialogue(dialogue); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	dialogue["start"].Choices[1].Next = "missing"
+	if err := validateDialogue(dialogue); err == nil {
+		t.Errorf("got nil, want an error for a missing node")
+	}
+	delete(dialogue, "start")
+	if err := validateDialogue(dialogue); err == nil {
+		t.Errorf("got nil, want an error without start node")
+	}
+
+	actor := &structs.Object{Skills: map[string]structs.Skill{"persuasion": {Practical: 5, Theoretical: 5}}}
+	choice := structs.DialogueChoice{
+		Text:                  "I killed the rats.",
+		RequiredAchievements:  []string{"ratsKilled"},
+		ForbiddenAchievements: []string{"ratsRewarded"},
+		RequiredSkills:        map[string]float32{"persuasion": 3},
+	}
+	for _, tc := range []struct {
+		unlocked map[string]bool
+		want     bool
+	}{
+		{unlocked: map[string]bool{}, want: false},
+		{unlocked: map[string]bool{"ratsKilled": true}, want: true},
+		{unlocked: map[string]bool{"ratsKilled": true, "ratsRewarded": true}, want: false},
+	} {
+		if got := choiceAllowed(choice, actor, tc.unlocked, time.Now()); got != tc.want {
+			t.Errorf("with %v got %v, want %v", tc.unlocked, got, tc.want)
+		}
+	}
+	choice.RequiredSkills["persuasion"] = 10
+	if choiceAllowed(choice, actor, map[string]bool{"ratsKilled": true}, time.Now()) {
+		t.Errorf("got allowed, want too low skill to matter")
+	}
+}
+
+func TestConversationTTL(t *testing.T) {
+	for _, tc := range []struct {
+		ms   float64
+		want time.Duration
+	}{
+		{ms: 1500, want: 1500 * time.Millisecond},
+		{ms: 0, want: maxConversationTTL},
+		{ms: -1, want: maxConversationTTL},
+		{ms: float64(2 * maxConversationTTL / time.Millisecond), want: maxConversationTTL},
+	} {
+		if got := conversationTTL(tc.ms); got != tc.want {
+			t.Errorf("conversationTTL(%v) = %v, want %v", tc.ms, got, tc.want)
+		}
+	}
+}
+
+func TestLLMReserve(t *testing.T) {
+	g := &Game{llm: newLLMClient()}
+	if _, err := g.llm.reserve("a", "hello"); err == nil {
+		t.Errorf("got nil, want an error while disabled")
+	}
+	g.SetLLM(LLMConfig{Endpoint: "http://localhost/v1/chat/completions", PerObjectQuota: 2, Quota: 3, Blocklist: []string{" Secret ", ""}})
+	if _, err := g.llm.reserve("a", "tell me the SECRET"); err == nil {
+		t.Errorf("got nil, want an error for a blocked word")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := g.llm.reserve("a", "hello"); err != nil {
+			t.Fatalf("got %v, want nil", err)
+		}
+	}
+	if _, err := g.llm.reserve("a", "hello"); err == nil {
+		t.Errorf("got nil, want the object quota exceeded")
+	}
+	if _, err := g.llm.reserve("b", "hello"); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	if _, err := g.llm.reserve("c", "hello"); err == nil {
+		t.Errorf("got nil, want the total quota exceeded")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	two := 2
+	for _, tc := range []struct {
+		lang     string
+		n        float64
+		decimals *int
+		want     string
+	}{
+		{lang: "en", n: 1234567, want: "1,234,567"},
+		{lang: "en", n: 1234.5, decimals: &two, want: "1,234.50"},
+		{lang: "de", n: 1234.5, decimals: &two, want: "1.234,50"},
+	} {
+		if got := formatNumber(tc.lang, tc.n, tc.decimals); got != tc.want {
+			t.Errorf("formatNumber(%q, %v) = %q, want %q", tc.lang, tc.n, got, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0 seconds"},
+		{d: time.Second, want: "1 second"},
+		{d: 2*time.Hour + 5*time.Minute + 3*time.Second, want: "2 hours 5 minutes"},
+		{d: 24*time.Hour + 5*time.Minute, want: "1 day"},
+	} {
+		if got := formatDuration("en", tc.d); got != tc.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestGameCalendar(t *testing.T) {
+	calendar := &GameCalendar{
+		Months:      []GameMonth{{Name: "Frost", Days: 10}, {Name: "Thaw", Days: 5}},
+		WeekDays:    []string{"Moonday", "Sunday", "Starday"},
+		HoursPerDay: 20,
+		DawnHour:    5,
+		DuskHour:    15,
+		Speed:       2,
+		Epoch:       time.Unix(0, 0),
+		EpochYear:   100,
+	}
+	day := 20 * time.Hour / 2
+	for _, tc := range []struct {
+		at   time.Duration
+		want string
+	}{
+		{at: 0, want: "Moonday, 1 Frost 100, 00:00"},
+		{at: day + 30*time.Minute, want: "Sunday, 2 Frost 100, 01:00"},
+		{at: 12 * day, want: "Moonday, 3 Thaw 100, 00:00"},
+		{at: 15 * day, want: "Moonday, 1 Frost 101, 00:00"},
+		{at: -day, want: "Starday, 5 Thaw 99, 00:00"},
+	} {
+		if got := calendar.Date(time.Unix(0, int64(tc.at))).String(); got != tc.want {
+			t.Errorf("Date(%v) = %q, want %q", tc.at, got, tc.want)
+		}
+	}
+	if calendar.Date(time.Unix(0, 0)).Daylight || !calendar.Date(time.Unix(0, int64(day/2))).Daylight {
+		t.Errorf("want darkness at midnight and daylight at noon")
+	}
+	at, err := parseEventTime("@101-2-3 10:30", time.Now(), calendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := calendar.Date(at).String(), "Moonday, 3 Thaw 101, 10:30"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := parseEventTime("@101-3-1 10:30", time.Now(), calendar); err == nil {
+		t.Errorf("got nil, want an error for a missing month")
+	}
+	if _, err := parseGameCalendar([]byte("months: [{name: Frost, days: 0}]")); err == nil {
+		t.Errorf("got nil, want an error for a month without days")
+	}
+	parsed, err := parseGameCalendar([]byte("weekDays: [Moonday, Sunday]\nepochYear: 1000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Months) != 12 || len(parsed.WeekDays) != 2 || parsed.EpochYear != 1000 {
+		t.Errorf("got %+v, want the default months with the given week days and epoch year", parsed)
+	}
+}
+
+func TestListeners(t *testing.T) {
+	listeners, err := parseListeners([]byte("listeners:\n  - addr: 0.0.0.0:15000\n  - addr: 127.0.0.1:15001\n    allowlist: [127.0.0.1, 10.0.0.0/8, '::1']\n    wizardsOnly: true\n    denylist: [10.6.6.0/24]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 || listeners[0].Protocol != sshProtocol || !listeners[1].WizardsOnly {
+		t.Fatalf("got %+v, want two ssh listeners, the second wizards only", listeners)
+	}
+	for _, tc := range []struct {
+		listener *Listener
+		addr     string
+		want     bool
+	}{
+		{listener: listeners[0], addr: "192.168.1.1:4711", want: true},
+		{listener: listeners[1], addr: "127.0.0.1:4711", want: true},
+		{listener: listeners[1], addr: "10.1.2.3:4711", want: true},
+		{listener: listeners[1], addr: "[::1]:4711", want: true},
+		{listener: listeners[1], addr: "192.168.1.1:4711", want: false},
+		{listener: listeners[1], addr: "10.6.6.6:4711", want: false},
+	} {
+		addr, err := net.ResolveTCPAddr("tcp", tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tc.listener.Allows(addr); got != tc.want {
+			t.Errorf("%q.Allows(%q) = %v, want %v", tc.listener.Addr, tc.addr, got, tc.want)
+		}
+	}
+	for _, content := range []string{
+		"listeners: []",
+		"listeners:\n  - protocol: ssh",
+		"listeners:\n  - addr: 0.0.0.0:23\n    protocol: telnet",
+		"listeners:\n  - addr: 0.0.0.0:15000\n    allowlist: [localhost]",
+		"listeners:\n  - addr: 0.0.0.0:15000\n    denylist: [10.0.0.0/33]",
+	} {
+		if _, err := parseListeners([]byte(content)); err == nil {
+			t.Errorf("parseListeners(%q) = nil, want an error", content)
+		}
+	}
+}
+
+func TestBannerData(t *testing.T) {
+	g := &Game{started: time.Now().Add(-90 * time.Minute)}
+	tmpl, err := template.New(bannerSource).Parse(initialBanner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, g.bannerData("en", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "Up 1 hour") || !strings.Contains(got, "online") {
+		t.Errorf("got %q, want the uptime and players online", got)
+	}
+	g.SetHideStats(true)
+	if got := g.bannerData("en", time.Now()); got != (bannerData{}) {
+		t.Errorf("got %+v, want no statistics", got)
+	}
+	buf.Reset()
+	if err := tmpl.Execute(buf, g.bannerData("en", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Welcome!\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIPBans(t *testing.T) {
+	network, err := parseIPRange("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipBans.Set(network.String(), network)
+	defer ipBans.Del(network.String())
+	listener := &Listener{Addr: "127.0.0.1:15000"}
+	if err := listener.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"192.168.1.1:4711": false,
+		"192.168.2.1:4711": true,
+	} {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := admits(listener, tcpAddr); got != want {
+			t.Errorf("admits(%q) = %v, want %v", addr, got, want)
+		}
+	}
+	if _, err := parseIPRange("banana"); err == nil {
+		t.Errorf("got nil, want an error for an invalid IP")
+	}
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := append([]byte{}, proxyV2Signature...)
+	v2 = append(v2, 0x21, 0x11, 0, 12, 203, 0, 113, 7, 10, 0, 0, 1, 0x12, 0x67, 0x3a, 0x98)
+	for _, tc := range []struct {
+		header string
+		want   string
+	}{
+		{header: "PROXY TCP4 198.51.100.22 10.0.0.1 35646 15000\r\n", want: "198.51.100.22:35646"},
+		{header: "PROXY TCP6 2001:db8::1 ::1 35646 15000\r\n", want: "[2001:db8::1]:35646"},
+		{header: "PROXY UNKNOWN\r\n", want: ""},
+		{header: string(v2), want: "203.0.113.7:4711"},
+	} {
+		r := bufio.NewReader(strings.NewReader(tc.header + "SSH-2.0-client\r\n"))
+		addr, err := readProxyHeader(r)
+		if err != nil {
+			t.Fatalf("readProxyHeader(%q): %v", tc.header, err)
+		}
+		if got := fmt.Sprint(addr); (addr == nil && tc.want != "") || (addr != nil && got != tc.want) {
+			t.Errorf("readProxyHeader(%q) = %v, want %q", tc.header, addr, tc.want)
+		}
+		if rest, _ := r.ReadString('\n'); rest != "SSH-2.0-client\r\n" {
+			t.Errorf("got %q after the header, want the SSH banner", rest)
+		}
+	}
+	for _, header := range []string{
+		"SSH-2.0-client\r\n",
+		"PROXY TCP4 banana 10.0.0.1 35646 15000\r\n",
+	} {
+		if _, err := readProxyHeader(bufio.NewReader(strings.NewReader(header))); err == nil {
+			t.Errorf("readProxyHeader(%q) = nil, want an error", header)
+		}
+	}
+}
+
+func TestSessionRegistry(t *testing.T) {
+	first := &Connection{user: &storage.User{Object: "registry-test"}}
+	second := &Connection{user: first.user}
+	registerSession(first)
+	registerSession(second)
+	if got := envByObjectID.Get("registry-test"); got != first {
+		t.Errorf("got %p, want the first session %p", got, first)
+	}
+	second.readOnly.Store(true)
+	unregisterSession(first)
+	if got := envByObjectID.Get("registry-test"); got != second {
+		t.Errorf("got %p, want the remaining session %p", got, second)
+	}
+	if second.readOnly.Load() {
+		t.Errorf("got the remaining session read only, want it to run any command")
+	}
+	unregisterSession(second)
+	if envByObjectID.Has("registry-test") || sessionsByObjectID.Has("registry-test") {
+		t.Errorf("want no sessions left")
+	}
+	if _, err := ParseLoginPolicy("allow-read-only"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseLoginPolicy("banana"); err == nil {
+		t.Errorf("got nil, want an error for an unknown policy")
+	}
+}
+
+func TestEnforceLoginPolicy(t *testing.T) {
+	user := &storage.User{Object: "policy-test", LoginPolicy: string(DenyLogins)}
+	connections := []*Connection{}
+	for range 10 {
+		connections = append(connections, &Connection{
+			user: user,
+			term: term.NewTerminal(struct {
+				io.Reader
+				io.Writer
+			}{&bytes.Buffer{}, io.Discard}, ""),
+		})
+	}
+	proceeded := make(chan *Connection, len(connections))
+	for _, c := range connections {
+		go func() {
+			proceed, err := c.enforceLoginPolicy()
+			if err != nil {
+				t.Error(err)
+			}
+			if proceed {
+				proceeded <- c
+			} else {
+				proceeded <- nil
+			}
+		}()
+	}
+	var first *Connection
+	for range connections {
+		if c := <-proceeded; c != nil {
+			if first != nil {
+				t.Fatalf("got several sessions logged in, want the others denied")
+			}
+			first = c
+		}
+	}
+	if first == nil {
+		t.Fatalf("got all sessions denied, want one logged in")
+	}
+	if got := sessionsOf(user.Object); !slices.Equal(got, []*Connection{first}) {
+		t.Errorf("got sessions %+v, want only the one logged in", got)
+	}
+	unregisterSession(first)
+
+	user.LoginPolicy = string(ReadOnlyLogins)
+	for index, c := range connections[:2] {
+		if proceed, err := c.enforceLoginPolicy(); err != nil || !proceed {
+			t.Fatalf("got %v, %v, want the session to log in", proceed, err)
+		}
+		if got := c.readOnly.Load(); got != (index == 1) {
+			t.Errorf("got session %v read only %v, want only the second read only", index, got)
+		}
+	}
+	unregisterSession(connections[0])
+	if connections[1].readOnly.Load() {
+		t.Errorf("got the remaining session read only, want it to run any command")
+	}
+	unregisterSession(connections[1])
+}
+
+func TestAnalyticsCollector(t *testing.T) {
+	collector := newAnalyticsCollector()
+	collector.add(commandAnalytics, 1, "look")
+	collector.add(commandAnalytics, 1, "look")
+	collector.add(roomAnalytics, 1, "genesis")
+	counters := collector.drain()
+	slices.SortFunc(counters, func(a, b storage.AnalyticsCounter) int {
+		return strings.Compare(a.Kind, b.Kind)
+	})
+	if len(counters) != 2 || counters[0].Key != "look" || counters[0].Count != 2 || counters[1].Count != 1 {
+		t.Errorf("got %+v, want look twice and genesis once", counters)
+	}
+	if counters := collector.drain(); len(counters) != 0 {
+		t.Errorf("got %+v, want nothing after draining", counters)
+	}
+	monday := time.Date(2026, time.October, 12, 12, 0, 0, 0, time.UTC)
+	if got, want := analyticsPeriod(monday.Add(analyticsDay), analyticsDay), analyticsPeriod(monday, analyticsDay)+1; got != want {
+		t.Errorf("got day %v, want %v", got, want)
+	}
+}
+
+func TestCall(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/caller.js", `addCallback('start', ['emit'], async (msg) => {
+  try {
+    state.reply = await call(msg.Target, msg.Name, {Caller: msg.Caller}, msg.Timeout);
+  } catch (e) {
+    state.error = String(e);
+  }
+});
+addCallback('back', ['call'], (msg) => {
+  reply('back');
+});`)
+		storeSource(t, g, "/target.js", `addCallback('echo', ['call'], (msg) => {
+  state.echoed = true;
+  reply(msg);
+});
+addCallback('slow', ['call'], (msg) => {
+  state.slow = true;
+  const started = Date.now();
+  while (Date.now() - started < 150) {}
+  reply('slow');
+});
+addCallback('back', ['call'], async (msg) => {
+  try {
+    reply(await call(msg.Caller, 'back', {}, 1000));
+  } catch (e) {
+    reply(String(e));
+  }
+});`)
+		caller := emptyObject(t, g, genesisID)
+		caller.SourcePath = "/caller.js"
+		target := emptyObject(t, g, genesisID)
+		target.SourcePath = "/target.js"
+		for _, object := range []*structs.Object{caller, target} {
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+		}
+		start := func(name string, timeout int) (map[string]any, map[string]any) {
+			if err := g.loadRunSave(ctx, caller.Id, &AnyCall{
+				Name: "start",
+				Tag:  emitEventTag,
+				Content: map[string]any{
+					"Target":  target.Id,
+					"Name":    name,
+					"Caller":  caller.Id,
+					"Timeout": timeout,
+				},
+			}); err != nil {
+				t.Fatalf("starting %q: %v", name, err)
+			}
+			states := []map[string]any{}
+			for _, id := range []string{caller.Id, target.Id} {
+				object, err := g.storage.LoadObject(ctx, id, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				state := map[string]any{}
+				if err := goccy.Unmarshal([]byte(object.State), &state); err != nil {
+					t.Fatal(err)
+				}
+				states = append(states, state)
+			}
+			return states[0], states[1]
+		}
+
+		callerState, targetState := start("echo", 1000)
+		if reply, ok := callerState["reply"].(map[string]any); !ok || reply["Caller"] != caller.Id {
+			t.Errorf("got caller state %+v, want the echoed message as reply", callerState)
+		}
+		if targetState["echoed"] != true {
+			t.Errorf("got target state %+v, want it stored after replying", targetState)
+		}
+
+		// A call that times out terminates the target run without storing the target.
+		callerState, targetState = start("slow", 20)
+		if callerState["error"] != fmt.Sprintf("calling \"slow\" on #%s: Timeout", target.Id) {
+			t.Errorf("got caller state %+v, want a timeout", callerState)
+		}
+		if _, found := targetState["slow"]; found {
+			t.Errorf("got target state %+v, want the timed out run not stored", targetState)
+		}
+
+		// Calling back an object waiting for the reply fails right away instead of deadlocking.
+		started := time.Now()
+		callerState, _ = start("back", 1000)
+		if reply, _ := callerState["reply"].(string); !strings.Contains(reply, "waiting for") {
+			t.Errorf("got caller state %+v, want the call back rejected", callerState)
+		}
+		if lapsed := time.Since(started); lapsed > 500*time.Millisecond {
+			t.Errorf("took %v, want the call back rejected right away", lapsed)
+		}
+	})
+}
+
+func TestInstances(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/areas/crypt.yaml", `rooms:
+  entrance:
+    short: A crypt entrance
+    exits:
+      - name: down
+        to: hall
+  hall:
+    short: A crypt hall
+    exits:
+      - name: up
+        to: entrance
+`)
+		door := emptyObject(t, g, genesisID)
+		member := emptyObject(t, g, genesisID)
+		elsewhere := emptyObject(t, g, genesisID)
+		stranger := emptyObject(t, g, elsewhere.Id)
+		jsContextLocks.Lock(door.Id)
+		defer jsContextLocks.Unlock(door.Id)
+
+		if _, err := g.enterInstance(ctx, door, "/areas/crypt.yaml", []string{member.Id, stranger.Id}); err == nil {
+			t.Errorf("got nil, want error entering with an object from elsewhere")
+		}
+		if instances, err := g.storage.Instances(ctx); err != nil || len(instances) != 0 {
+			t.Errorf("got %+v, %v, want no instances built for a rejected party", instances, err)
+		}
+
+		key, err := g.enterInstance(ctx, door, "/areas/crypt.yaml", []string{member.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entrance := areaRoomID(key, "entrance")
+		if stored, err := g.storage.LoadObject(ctx, member.Id, nil); err != nil || stored.Location != entrance {
+			t.Fatalf("got %+v, %v, want %q in %q", stored, err, member.Id, entrance)
+		}
+		if again, err := g.enterInstance(ctx, door, "/areas/crypt.yaml", []string{member.Id}); err == nil || again != "" {
+			t.Errorf("got %q, %v, want error entering from inside the instance", again, err)
+		}
+
+		// Occupied instances, and new empty ones, are kept.
+		if err := g.sweepInstances(ctx, time.Now().Add(2*instanceGracePeriod)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.storage.LoadObject(ctx, entrance, nil); err != nil {
+			t.Errorf("got %v, want the occupied instance kept", err)
+		}
+		if err := g.moveObject(ctx, nil, member.Id, genesisID); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.sweepInstances(ctx, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.storage.LoadObject(ctx, entrance, nil); err != nil {
+			t.Errorf("got %v, want the new instance kept", err)
+		}
+
+		if err := g.sweepInstances(ctx, time.Now().Add(2*instanceGracePeriod)); err != nil {
+			t.Fatal(err)
+		}
+		for _, room := range []string{entrance, areaRoomID(key, "hall")} {
+			if _, err := g.storage.LoadObject(ctx, room, nil); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("got %v, want %q torn down", err, room)
+			}
+		}
+		if instances, err := g.storage.Instances(ctx); err != nil || len(instances) != 0 {
+			t.Errorf("got %+v, %v, want the empty instance deleted", instances, err)
+		}
+	})
+}
+
+func TestPlayerExportRoundTrip(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		user := &storage.User{Name: "exported", PasswordHash: "blapp"}
+		if err := g.createUser(ctx, user, nil); err != nil {
+			t.Fatal(err)
+		}
+		item := emptyObject(t, g, user.Object)
+		vault, err := g.loadVault(ctx, user.Object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chest := emptyObject(t, g, vault.Id)
+		emptyObject(t, g, chest.Id)
+		if err := g.modifyObject(ctx, nil, user.Object, func(o *structs.Object) error {
+			o.Titles = []string{"the Bold"}
+			o.Title = "the Bold"
+			o.Nicknames = map[string]string{item.Id: "trinket", "missing": "ghost"}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		recording := &storage.Recording{User: user.Id, Started: 1, Width: 80, Height: 24}
+		if err := g.storage.CreateRecording(ctx, recording); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.storage.AppendRecordingFrames(ctx, []storage.RecordingFrame{{Recording: recording.Id, At: 2, Output: "hello"}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.storage.CreateAPIToken(ctx, user, "bot"); err != nil {
+			t.Fatal(err)
+		}
+
+		exported, err := g.exportPlayer(ctx, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		js, err := goccy.Marshal(exported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		export := &PlayerExport{}
+		if err := goccy.Unmarshal(js, export); err != nil {
+			t.Fatal(err)
+		}
+		if len(export.APITokens) != 1 || export.APITokens[0].Name != "bot" || export.APITokens[0].Hash != "" {
+			t.Errorf("got tokens %+v, want the token without its hash", export.APITokens)
+		}
+
+		export.User.Name = "imported"
+		imported, err := g.importPlayer(ctx, export, "blepp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		object, err := g.storage.LoadObject(ctx, imported.Object, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if object.Title != "the Bold" || !slices.Equal(object.Titles, []string{"the Bold"}) {
+			t.Errorf("got title %q and titles %+v, want them imported", object.Title, object.Titles)
+		}
+		if len(object.Content) != 1 || len(object.Nicknames) != 1 {
+			t.Fatalf("got content %+v and nicknames %+v, want one item with its nickname", object.Content, object.Nicknames)
+		}
+		for id := range object.Content {
+			if object.Nicknames[id] != "trinket" {
+				t.Errorf("got nicknames %+v, want %q remapped to %q", object.Nicknames, item.Id, id)
+			}
+		}
+		importedVault, err := g.storage.LoadObject(ctx, vaultID(imported.Object), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(importedVault.Content) != 1 {
+			t.Fatalf("got vault content %+v, want the chest", importedVault.Content)
+		}
+		for id := range importedVault.Content {
+			importedChest, err := g.storage.LoadObject(ctx, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if importedChest.Location != importedVault.Id || len(importedChest.Content) != 1 {
+				t.Errorf("got chest %+v, want it in the vault with its content", importedChest)
+			}
+		}
+		recordings, err := g.storage.Recordings(ctx, imported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recordings) != 1 || recordings[0].Width != 80 {
+			t.Fatalf("got recordings %+v, want the exported recording", recordings)
+		}
+		if frames, err := g.storage.RecordingFrames(ctx, recordings[0].Id); err != nil || len(frames) != 1 || frames[0].Output != "hello" {
+			t.Errorf("got frames %+v, %v, want the exported frame", frames, err)
+		}
+		if tokens, err := g.storage.APITokens(ctx, imported); err != nil || len(tokens) != 0 {
+			t.Errorf("got tokens %+v, %v, want none imported", tokens, err)
+		}
+
+		genesis, err := g.storage.LoadObject(ctx, genesisID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := len(genesis.Content)
+		storeSource(t, g, "/conflict.js", "// here")
+		importWithVaultSource := func(source string) error {
+			export := &PlayerExport{}
+			if err := goccy.Unmarshal(js, export); err != nil {
+				t.Fatal(err)
+			}
+			export.User.Name = "failed"
+			export.Vault.Sources = map[string]string{"/conflict.js": source}
+			_, err := g.importPlayer(ctx, export, "blepp")
+			return err
+		}
+		if err := importWithVaultSource("// elsewhere"); err == nil || !strings.Contains(err.Error(), "different content") {
+			t.Fatalf("got %v, want the conflicting vault source to fail the import", err)
+		}
+		if _, err := g.storage.LoadUser(ctx, "failed"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v, want the user removed", err)
+		}
+		if genesis, err = g.storage.LoadObject(ctx, genesisID, nil); err != nil || len(genesis.Content) != before {
+			t.Errorf("got genesis %+v, %v, want the object removed", genesis, err)
+		}
+		if err := importWithVaultSource("// here"); err != nil {
+			t.Errorf("got %v, want the import to succeed when rerun", err)
+		}
+	})
+}
+
+func TestRerunSourceWaitsForRuns(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/counter.js", `state.runs = (state.runs || 0) + 1;
+setSerialEvents(['slow']);`)
+		object := emptyObject(t, g, genesisID)
+		object.SourcePath = "/counter.js"
+		if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+			t.Fatal(err)
+		}
+		load := func() string {
+			loaded, err := g.storage.LoadObject(ctx, object.Id, g.rerunSource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := g.storage.LoadObject(ctx, object.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.State != stored.State {
+				t.Errorf("loaded state %q, but stored %q", loaded.State, stored.State)
+			}
+			return stored.State
+		}
+		stored := func() string {
+			stored, err := g.storage.LoadObject(ctx, object.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return stored.State
+		}
+
+		if state := load(); state != `{"runs":1}` {
+			t.Errorf("got state %q, want the changed source rerun once", state)
+		}
+		if state := load(); state != `{"runs":1}` {
+			t.Errorf("got state %q, want the refreshed object not rerun", state)
+		}
+
+		// A run holding the lock past otherLockTimeout leaves the object to a queued refresh.
+		storeSource(t, g, "/counter.js", `state.runs = (state.runs || 0) + 10;
+setSerialEvents(['slow']);`)
+		jsContextLocks.Lock(object.Id)
+		started := time.Now()
+		if state := load(); state != `{"runs":1}` {
+			t.Errorf("got state %q, want the locked object left as it is", state)
+		}
+		if lapsed := time.Since(started); lapsed < otherLockTimeout {
+			t.Errorf("took %v, want the refresh to wait for the lock first", lapsed)
+		}
+		jsContextLocks.Unlock(object.Id)
+		waitFor(t, "the queued refresh", func() bool {
+			return stored() == `{"runs":11}`
+		})
+
+		// A run handling a serial event leaves the object to a queued refresh right away.
+		storeSource(t, g, "/counter.js", `state.runs = (state.runs || 0) + 100;
+setSerialEvents(['slow']);`)
+		jsContextLocks.Lock(object.Id)
+		serialRuns.Set(object.Id, true)
+		started = time.Now()
+		if state := load(); state != `{"runs":11}` {
+			t.Errorf("got state %q, want the serially running object left as it is", state)
+		}
+		if lapsed := time.Since(started); lapsed >= otherLockTimeout {
+			t.Errorf("took %v, want the refresh queued without waiting", lapsed)
+		}
+		serialRuns.Del(object.Id)
+		jsContextLocks.Unlock(object.Id)
+		waitFor(t, "the queued refresh", func() bool {
+			return stored() == `{"runs":111}`
+		})
+		if state := load(); state != `{"runs":111}` {
+			t.Errorf("got state %q, want the refreshed object not rerun", state)
+		}
+	})
+}
+
+func TestCreateCharacterObject(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		storeSource(t, g, "/items/sword.js", `setTags(['sword']);`)
+		storeSource(t, g, archetypesSource, fmt.Sprintf(`
+start: "#%s"
+archetypes:
+  warrior:
+    equipment:
+      - /items/sword.js
+  knight:
+    equipment:
+      - /items/sword.js
+      - /items/missing.js
+`, room.Id))
+		check := func(wantCharacters int, wantSwords int) {
+			t.Helper()
+			loaded, err := g.storage.LoadObject(ctx, room.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			swords, err := g.storage.ObjectIDsByTag(ctx, "sword")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(loaded.Content) != wantCharacters || len(swords) != wantSwords {
+				t.Errorf("got %v characters and %v swords, want %v and %v", len(loaded.Content), len(swords), wantCharacters, wantSwords)
+			}
+		}
+
+		knight := &storage.User{Name: "knight"}
+		if err := g.createCharacterObject(context.Background(), knight, &character{Name: "Lancelot", Archetype: "knight"}); err == nil {
+			t.Errorf("got nil, want an error for the missing equipment")
+		}
+		if knight.Object != "" {
+			t.Errorf("got user object %q, want none", knight.Object)
+		}
+		check(0, 0)
+
+		warrior := &storage.User{Name: "warrior"}
+		if err := g.createCharacterObject(context.Background(), warrior, &character{Name: "Conan", Archetype: "warrior"}); err != nil {
+			t.Fatal(err)
+		}
+		check(1, 1)
+	})
+}
+
+func TestAnnounce(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		puppet := emptyObject(t, g, room.Id)
+		user := emptyObject(t, g, genesisID)
+		connect := func(object string, puppet string) *bytes.Buffer {
+			output := &bytes.Buffer{}
+			c := &Connection{
+				user:   &storage.User{Object: object},
+				puppet: puppet,
+				term: term.NewTerminal(struct {
+					io.Reader
+					io.Writer
+				}{&bytes.Buffer{}, output}, ""),
+			}
+			c.width.Store(80)
+			registerSession(c)
+			t.Cleanup(func() { unregisterSession(c) })
+			return output
+		}
+		missing := connect("announce-missing", "")
+		possessing := connect(user.Id, puppet.Id)
+
+		g.announce(ctx, room.Id, "the end is near")
+		if got := possessing.String(); !strings.Contains(got, "the end is near") {
+			t.Errorf("got %q, want the announcement shown to the user whose puppet is in the room", got)
+		}
+		if got := missing.String(); got != "" {
+			t.Errorf("got %q, want nothing shown to the user without an object", got)
+		}
+		g.announce(ctx, "", "the end is here")
+		if got := missing.String(); !strings.Contains(got, "the end is here") {
+			t.Errorf("got %q, want the announcement shown to everyone", got)
+		}
+	})
+}
+
+func TestBanner(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, bannerSource, "")
+		if got, err := g.banner(ctx, "en"); err != nil || got != defaultBanner {
+			t.Errorf("got %q, %v, want %q for an empty banner source", got, err, defaultBanner)
+		}
+		storeSource(t, g, bannerSource, "Hello{{if .ShowStats}}, {{.Online}} online{{end}}\n")
+		if got, err := g.banner(ctx, "en"); err != nil || got != "Hello, 0 online" {
+			t.Errorf("got %q, %v, want the rendered banner", got, err)
+		}
+		storeSource(t, g, bannerSource, "Hello{{if}}")
+		if _, err := g.banner(ctx, "en"); err == nil {
+			t.Errorf("got nil, want an error for a broken banner")
+		}
+	})
+}
+
+func TestFetcherReserve(t *testing.T) {
+	f := newFetcher()
+	f.allowlist = []string{"*.example.com", "exact.org"}
+	for host, want := range map[string]bool{
+		"sub.example.com": true,
+		"a.b.example.com": true,
+		"evilexample.com": false,
+		"exact.org":       true,
+		"sub.exact.org":   false,
+		"other.com":       false,
+	} {
+		if got := f.reserve(host, "https://"+host+"/") == nil; got != want {
+			t.Errorf("got %v for %q, want %v", got, host, want)
+		}
+	}
+
+	f.recent = map[string][]time.Time{"idle": {time.Now().Add(-2 * time.Minute)}}
+	for i := 0; i < maxFetchesPerMinute; i++ {
+		if err := f.reserve("busy", "https://exact.org/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.reserve("busy", "https://exact.org/"); err == nil {
+		t.Error("got nil, want the fetch over the rate limit rejected")
+	}
+	if _, found := f.recent["idle"]; found {
+		t.Errorf("got %+v, want the idle entry pruned", f.recent)
+	}
+}
+
+func TestShop(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/sword.js", `setDescriptions([{short: 'sword'}]);`)
+		storeSource(t, g, "/shop.js", `if (!state.stocked) {
+  state.stocked = true;
+  setStock([{Name: 'sword', Source: '/sword.js', Price: 10, Quantity: 1}]);
+}
+addCallback('price', ['call'], (msg) => {
+  reply(msg.Action === 'buy' ? msg.Price - 2 : msg.Price);
+});`)
+		room := emptyObject(t, g, genesisID)
+		shop := emptyObject(t, g, room.Id)
+		shop.SourcePath = "/shop.js"
+		poor := emptyObject(t, g, room.Id)
+		customer := emptyObject(t, g, room.Id)
+		customer.Money = 20
+		for _, object := range []*structs.Object{shop, customer} {
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := g.loadRunSave(ctx, shop.Id, nil); err != nil {
+			t.Fatal(err)
+		}
+		money := func(id string) int64 {
+			t.Helper()
+			object, err := g.storage.LoadObject(ctx, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return object.Money
+		}
+
+		if _, _, err := g.buy(ctx, poor.Id, "sword"); err == nil || !strings.Contains(err.Error(), "can't afford") {
+			t.Errorf("got %v, want the poor customer rejected", err)
+		}
+		sword, price, err := g.buy(ctx, customer.Id, "sword")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price != 8 || money(customer.Id) != 12 || money(shop.Id) != 8 {
+			t.Errorf("got price %v, customer money %v and shop money %v, want the price call discount paid", price, money(customer.Id), money(shop.Id))
+		}
+		if bought, err := g.storage.LoadObject(ctx, sword.Id, nil); err != nil || bought.Location != customer.Id {
+			t.Errorf("got %+v, %v, want the sword carried by the customer", bought, err)
+		}
+		if _, _, err := g.buy(ctx, customer.Id, "sword"); err == nil || !strings.Contains(err.Error(), "sold out") {
+			t.Errorf("got %v, want the sword sold out", err)
+		}
+
+		if _, _, err := g.sell(ctx, customer.Id, "shield"); err == nil {
+			t.Error("got nil, want selling an item the customer doesn't carry rejected")
+		}
+		if _, price, err := g.sell(ctx, customer.Id, "sword"); err != nil || price != 5 {
+			t.Errorf("got %v, %v, want the sword sold for half its price", price, err)
+		}
+		if money(customer.Id) != 17 || money(shop.Id) != 3 {
+			t.Errorf("got customer money %v and shop money %v, want the sale paid", money(customer.Id), money(shop.Id))
+		}
+		if _, err := g.storage.LoadObject(ctx, sword.Id, nil); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v, want the sold sword removed", err)
+		}
+	})
+}
+
+func TestJSCommand(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/rock.js", `setDescriptions([{short: 'rock'}]);`)
+		storeSource(t, g, "/wand.js", `addCommand('zap', {
+  Help: 'Zaps a target.',
+  Arguments: [{Name: 'target', Kind: 'object'}, {Name: 'words', Kind: 'text', Optional: true}],
+}, (msg) => {
+  state.actor = msg.Actor;
+  state.target = msg.Args.target;
+  state.words = msg.Args.words || '';
+});`)
+		room := emptyObject(t, g, genesisID)
+		actor := emptyObject(t, g, room.Id)
+		rock := emptyObject(t, g, room.Id)
+		rock.SourcePath = "/rock.js"
+		wand := emptyObject(t, g, actor.Id)
+		wand.SourcePath = "/wand.js"
+		for _, object := range []*structs.Object{rock, wand} {
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.loadRunSave(ctx, object.Id, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c, output := testConnection(g, actor.Id)
+
+		if found, err := c.jsCommand("zap rock hello there"); !found || err != nil {
+			t.Fatalf("got %v, %v, want the command run", found, err)
+		}
+		loaded, err := g.storage.LoadObject(ctx, wand.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := map[string]string{}
+		if err := goccy.Unmarshal([]byte(loaded.State), &state); err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"actor": actor.Id, "target": rock.Id, "words": "hello there"}; !maps.Equal(state, want) {
+			t.Errorf("got state %+v, want %+v", state, want)
+		}
+
+		if found, err := c.jsCommand("zap"); !found || err == nil || !strings.Contains(err.Error(), "usage: zap <target> [words]") {
+			t.Errorf("got %v, %v, want the usage for a missing argument", found, err)
+		}
+		if found, err := c.jsCommand("zap ghost"); !found || err == nil || !strings.Contains(err.Error(), `no "ghost" here`) {
+			t.Errorf("got %v, %v, want an object argument that isn't there rejected", found, err)
+		}
+		if found, err := c.jsCommand("dance"); found || err != nil {
+			t.Errorf("got %v, %v, want commands nothing provides left alone", found, err)
+		}
+
+		if err := c.helpCommand(); err != nil {
+			t.Fatal(err)
+		}
+		if got := output.String(); !strings.Contains(got, "zap <target> [words]") || !strings.Contains(got, "Zaps a target.") {
+			t.Errorf("got %q, want the command listed by help", got)
+		}
+	})
+}
+
+func TestEffects(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/cursed.js", `addCallback('effectTick', ['emit'], (msg) => {
+  state.ticks = (state.ticks || 0) + 1;
+});
+addCallback('effectExpired', ['emit'], (msg) => {
+  state.expired = msg.Name;
+});`)
+		room := emptyObject(t, g, genesisID)
+		cursed := emptyObject(t, g, room.Id)
+		cursed.SourcePath = "/cursed.js"
+		if err := g.storage.StoreObject(ctx, nil, cursed); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.loadRunSave(ctx, cursed.Id, nil); err != nil {
+			t.Fatal(err)
+		}
+		var effect *structs.Effect
+		if err := g.modifyObject(ctx, nil, cursed.Id, func(o *structs.Object) error {
+			var err error
+			effect, err = g.applyEffect(ctx, o, &effectDefinition{
+				Name:         "hex",
+				Duration:     0.5,
+				SkillBonuses: map[string]float32{"swords": 5},
+				Immobile:     true,
+				TickInterval: 0.1,
+			})
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		load := func() *structs.Object {
+			t.Helper()
+			object, err := g.storage.LoadObject(ctx, cursed.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		if object := load(); object.SkillBonus("swords", time.Now()) != 5 || !object.Immobile(time.Now()) {
+			t.Errorf("got bonus %v and immobile %v, want the effect active", object.SkillBonus("swords", time.Now()), object.Immobile(time.Now()))
+		}
+		if err := g.moveObject(ctx, nil, cursed.Id, genesisID); err == nil || !strings.Contains(err.Error(), "immobile") {
+			t.Errorf("got %v, want the immobile object kept in place", err)
+		}
+		if object := load(); removeEffect(object, "missing") || len(object.Effects) != 1 {
+			t.Errorf("got effects %+v, want removing a missing effect to change nothing", object.Effects)
+		}
+
+		state := map[string]any{}
+		waitFor(t, "the effect to expire", func() bool {
+			if err := goccy.Unmarshal([]byte(load().State), &state); err != nil {
+				t.Fatal(err)
+			}
+			return state["expired"] == "hex"
+		})
+		if ticks, _ := state["ticks"].(float64); ticks < 2 {
+			t.Errorf("got %v ticks, want the effect to tick until it expired", state["ticks"])
+		}
+		if object := load(); len(object.Effects) != 0 || object.SkillBonus("swords", time.Now()) != 0 {
+			t.Errorf("got effects %+v, want the expired %q removed", object.Effects, effect.Id)
+		}
+		if err := g.moveObject(ctx, nil, cursed.Id, genesisID); err != nil {
+			t.Errorf("got %v, want the object free to move once the effect expired", err)
+		}
+	})
+}
+
+func TestHousing(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		sources, limit := furnitureSources.Load(), furnitureLimit.Load()
+		t.Cleanup(func() {
+			furnitureSources.Store(sources)
+			furnitureLimit.Store(limit)
+		})
+		storeSource(t, g, "/chair.js", `setDescriptions([{short: 'chair'}]);`)
+		house := emptyObject(t, g, genesisID)
+		house.HousePrice = 50
+		buyer := emptyObject(t, g, house.Id)
+		buyer.Money = 30
+		stranger := emptyObject(t, g, genesisID)
+		for _, object := range []*structs.Object{house, buyer} {
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := g.storage.StoreUser(ctx, &storage.User{Name: "stranger", Object: stranger.Id}, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := g.buyHouse(ctx, buyer.Id, house.Id); err == nil || !strings.Contains(err.Error(), "can't afford") {
+			t.Errorf("got %v, want the poor buyer rejected", err)
+		}
+		if err := g.modifyObject(ctx, nil, buyer.Id, func(o *structs.Object) error {
+			o.Money = 60
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.buyHouse(ctx, buyer.Id, house.Id); err != nil {
+			t.Fatal(err)
+		}
+		if loaded, err := g.storage.LoadObject(ctx, buyer.Id, nil); err != nil || loaded.Money != 10 {
+			t.Errorf("got %+v, %v, want the price paid", loaded, err)
+		}
+		if _, err := g.buyHouse(ctx, stranger.Id, house.Id); err == nil || !strings.Contains(err.Error(), "isn't for sale") {
+			t.Errorf("got %v, want the owned house not for sale", err)
+		}
+
+		if err := g.checkEntry(ctx, nil, stranger.Id, house.Id); err == nil {
+			t.Error("got nil, want strangers kept out")
+		}
+		if err := g.checkEntry(ctx, nil, buyer.Id, house.Id); err != nil {
+			t.Errorf("got %v, want the owner let in", err)
+		}
+		c, _ := testConnection(g, buyer.Id)
+		if err := c.houseCommand([]string{"guest", "stranger"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.checkEntry(ctx, nil, stranger.Id, house.Id); err != nil {
+			t.Errorf("got %v, want guests let in", err)
+		}
+
+		if err := c.houseCommand([]string{"furnish", "/chair.js"}); err == nil {
+			t.Error("got nil, want furnishing from sources that aren't furniture rejected")
+		}
+		furnitureSources.Store(&[]string{"/chair.js"})
+		furnitureLimit.Store(1)
+		if err := c.houseCommand([]string{"furnish", "/chair.js"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.houseCommand([]string{"furnish", "/chair.js"}); err == nil || !strings.Contains(err.Error(), "fully furnished") {
+			t.Errorf("got %v, want furnishing beyond the limit rejected", err)
+		}
+		loaded, err := g.storage.LoadObject(ctx, house.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := g.storage.LoadObjects(ctx, loaded.Content, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		furniture := 0
+		for _, item := range content {
+			if item.Flagged(structs.FurnitureTag) {
+				furniture++
+			}
+		}
+		if furniture != 1 {
+			t.Errorf("got %v pieces of furniture in %+v, want the one chair", furniture, content)
+		}
+	})
+}
+
+func TestDurability(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		storeSource(t, g, "/sword.js", `addCallback('itemBroken', ['emit'], (msg) => {
+  state.broken = msg.Id;
+});`)
+		room := emptyObject(t, g, genesisID)
+		sword := emptyObject(t, g, room.Id)
+		sword.SourcePath = "/sword.js"
+		sword.MaxDurability, sword.Durability, sword.DecayRate, sword.Decayed = 10, 10, 1, time.Now().UnixNano()
+		if err := g.storage.StoreObject(ctx, nil, sword); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.loadRunSave(ctx, sword.Id, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := g.changeDurability(ctx, nil, sword.Id, -4); err != nil || got < 5.99 || got > 6 {
+			t.Errorf("got %v, %v, want the sword worn to 6", got, err)
+		}
+		if got, err := g.changeDurability(ctx, nil, sword.Id, 100); err != nil || got != 10 {
+			t.Errorf("got %v, %v, want the sword repaired to its max", got, err)
+		}
+		if _, err := g.changeDurability(ctx, nil, room.Id, -1); err == nil {
+			t.Error("got nil, want wearing an object without durability rejected")
+		}
+		if got, err := g.changeDurability(ctx, nil, sword.Id, -20); err != nil || got != 0 {
+			t.Errorf("got %v, %v, want the sword broken", got, err)
+		}
+		waitFor(t, "the sword to learn it broke", func() bool {
+			object, err := g.storage.LoadObject(ctx, sword.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := map[string]any{}
+			if err := goccy.Unmarshal([]byte(object.State), &state); err != nil {
+				t.Fatal(err)
+			}
+			return state["broken"] == sword.Id
+		})
+
+		now := time.Now()
+		trash := emptyObject(t, g, room.Id)
+		trash.Tags = []string{structs.TrashTag}
+		trash.MaxDurability, trash.Durability, trash.DecayRate, trash.Decayed = 1, 1, 1, now.Add(-2*time.Hour).UnixNano()
+		if err := g.storage.StoreObject(ctx, nil, trash); err != nil {
+			t.Fatal(err)
+		}
+		loot := emptyObject(t, g, trash.Id)
+		if err := g.sweepDecay(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.storage.LoadObject(ctx, trash.Id, nil); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v, want the decayed trash removed", err)
+		}
+		if object, err := g.storage.LoadObject(ctx, loot.Id, nil); err != nil || object.Location != room.Id {
+			t.Errorf("got %+v, %v, want the content of the trash left in the room", object, err)
+		}
+		if object, err := g.storage.LoadObject(ctx, sword.Id, nil); err != nil {
+			t.Errorf("got %v, want the broken sword kept since it isn't trash", err)
+		} else if object.Durability != 0 {
+			t.Errorf("got durability %v, want the sword still broken", object.Durability)
+		}
+	})
+}
+
+func TestEquipment(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		wearer := emptyObject(t, g, room.Id)
+		item := func(short string, slot string) *structs.Object {
+			object := emptyObject(t, g, wearer.Id)
+			object.Descriptions = []structs.Description{{Short: short}}
+			object.Slot = slot
+			object.SkillBonuses = map[string]float32{"swords": 2}
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		sword := item("sword", weaponSlot)
+		helmet := item("helmet", "head")
+		c, output := testConnection(g, wearer.Id)
+		load := func() *structs.Object {
+			t.Helper()
+			object, err := g.storage.LoadObject(ctx, wearer.Id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+
+		if err := c.equipCommand("wear", "sword"); err == nil || !strings.Contains(err.Error(), "wield it instead") {
+			t.Errorf("got %v, want wearing a weapon rejected", err)
+		}
+		if err := c.equipCommand("wield", "helmet"); err == nil || !strings.Contains(err.Error(), "isn't a weapon") {
+			t.Errorf("got %v, want wielding armour rejected", err)
+		}
+		for verb, name := range map[string]string{"wield": "sword", "wear": "helmet"} {
+			if err := c.equipCommand(verb, name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if object := load(); object.Equipment[weaponSlot] != sword.Id || object.Equipment["head"] != helmet.Id || object.SkillBonus("swords", time.Now()) != 4 {
+			t.Errorf("got equipment %+v and bonus %v, want both items equipped", object.Equipment, object.SkillBonus("swords", time.Now()))
+		}
+		if err := c.equipCommand("wear", "helmet"); err == nil || !strings.Contains(err.Error(), "already equipped") {
+			t.Errorf("got %v, want wearing the helmet twice rejected", err)
+		}
+		if err := c.equipmentCommand(); err != nil {
+			t.Fatal(err)
+		}
+		if got := output.String(); !strings.Contains(got, "helmet") || !strings.Contains(got, weaponSlot) {
+			t.Errorf("got %q, want the equipment listed", got)
+		}
+
+		if err := c.equipCommand("remove", "helmet"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.equipCommand("remove", "helmet"); err == nil || !strings.Contains(err.Error(), "isn't equipped") {
+			t.Errorf("got %v, want removing the helmet twice rejected", err)
+		}
+		if err := g.moveObject(ctx, nil, sword.Id, room.Id); err != nil {
+			t.Fatal(err)
+		}
+		if object := load(); len(object.Equipment) != 0 || object.SkillBonus("swords", time.Now()) != 0 {
+			t.Errorf("got equipment %+v and bonus %v, want everything unequipped", object.Equipment, object.SkillBonus("swords", time.Now()))
+		}
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		user := &storage.User{Name: "recorded"}
+		if err := g.storage.StoreUser(ctx, user, false); err != nil {
+			t.Fatal(err)
+		}
+		output := &bytes.Buffer{}
+		r := &recorder{
+			ReadWriter: struct {
+				io.Reader
+				io.Writer
+			}{&bytes.Buffer{}, output},
+			storage: g.storage,
+		}
+		if _, err := r.Write([]byte("before\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		if _, found := r.recall(defaultRecallLines); found {
+			t.Error("got a recall, want none before recording")
+		}
+		if err := r.start(user, &fakeSession{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Write([]byte("\x1b[1mhello\x1b[0m\r\nworld\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		if got, found := r.recall(1); !found || got != "world" {
+			t.Errorf("got %q, %v, want the last line", got, found)
+		}
+		if got, found := r.recall(defaultRecallLines); !found || got != "hello\nworld" {
+			t.Errorf("got %q, %v, want the recorded lines without control sequences", got, found)
+		}
+		r.stop()
+		if _, found := r.recall(defaultRecallLines); found {
+			t.Error("got a recall, want none after recording")
+		}
+		if !strings.Contains(output.String(), "before") || !strings.Contains(output.String(), "world") {
+			t.Errorf("got %q, want everything passed on to the session", output.String())
+		}
+		recordings, err := g.storage.Recordings(ctx, user)
+		if err != nil || len(recordings) != 1 {
+			t.Fatalf("got %+v, %v, want one recording", recordings, err)
+		}
+		frames, err := g.storage.RecordingFrames(ctx, recordings[0].Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded := ""
+		for _, frame := range frames {
+			recorded += frame.Output
+		}
+		if recorded != "\x1b[1mhello\x1b[0m\r\nworld\r\n" {
+			t.Errorf("got %q, want only the output while recording stored", recorded)
+		}
+	})
+}
+
+func TestTrade(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		named := func(location string, short string, money int64) *structs.Object {
+			object := emptyObject(t, g, location)
+			object.Descriptions = []structs.Description{{Short: short}}
+			object.Money = money
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		alice := named(room.Id, "alice", 10)
+		bob := named(room.Id, "bob", 0)
+		gem := named(alice.Id, "gem", 0)
+		named(room.Id, "statue", 0)
+		connect := func(object *structs.Object) *Connection {
+			c, _ := testConnection(g, object.Id)
+			registerSession(c)
+			t.Cleanup(func() {
+				cancelTrade(object.Id)
+				unregisterSession(c)
+			})
+			return c
+		}
+		a, b := connect(alice), connect(bob)
+		run := func(c *Connection, args ...string) error {
+			t.Helper()
+			return c.tradeCommand(args)
+		}
+		check := func(wantAlice int64, wantBob int64, wantGemHolder string) {
+			t.Helper()
+			objects, err := g.storage.LoadObjects(ctx, map[string]bool{alice.Id: true, bob.Id: true, gem.Id: true}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if objects[alice.Id].Money != wantAlice || objects[bob.Id].Money != wantBob || objects[gem.Id].Location != wantGemHolder {
+				t.Errorf("got money %v and %v and the gem in %q, want %v and %v and it in %q", objects[alice.Id].Money, objects[bob.Id].Money, objects[gem.Id].Location, wantAlice, wantBob, wantGemHolder)
+			}
+		}
+
+		if err := run(a, "statue"); err == nil || !strings.Contains(err.Error(), "can't trade") {
+			t.Errorf("got %v, want trading with objects without users rejected", err)
+		}
+		if err := run(a, "bob"); err != nil {
+			t.Fatal(err)
+		}
+		if trades.Get(bob.Id) != trades.Get(alice.Id) {
+			t.Error("got bob outside the trade, want it open for both")
+		}
+		if err := run(a, "offer", "gem"); err != nil {
+			t.Fatal(err)
+		}
+		if err := run(b, "money", "20"); err != nil {
+			t.Fatal(err)
+		}
+		if err := run(a, "accept"); err != nil {
+			t.Fatal(err)
+		}
+		if err := run(b, "accept"); err == nil || !strings.Contains(err.Error(), "trade failed") {
+			t.Errorf("got %v, want bob unable to pay", err)
+		}
+		check(10, 0, alice.Id)
+		if _, trading := trades.GetHas(alice.Id); trading {
+			t.Error("got the failed trade still open, want it closed")
+		}
+
+		for _, step := range [][]string{{"bob"}, {"offer", "gem"}, {"money", "5"}, {"accept"}} {
+			if err := run(a, step...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := run(b, "money", "0"); err != nil {
+			t.Fatal(err)
+		}
+		if session := trades.Get(alice.Id); session.confirmed[0] {
+			t.Error("got alice's acceptance kept, want changed offers to need accepting again")
+		}
+		for _, c := range []*Connection{a, b} {
+			if err := run(c, "accept"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check(5, 5, bob.Id)
+	})
+}
+
+func TestDeath(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		sanctuary := emptyObject(t, g, genesisID)
+		sanctuary.Tags = []string{structs.SanctuaryTag}
+		if err := g.storage.StoreObject(ctx, nil, sanctuary); err != nil {
+			t.Fatal(err)
+		}
+		alive := func(location string) *structs.Object {
+			object := emptyObject(t, g, location)
+			object.MaxHealth, object.Health = 10, 10
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		monster := alive(room.Id)
+		loot := emptyObject(t, g, monster.Id)
+		hero := alive(room.Id)
+		if err := g.storage.StoreUser(ctx, &storage.User{Name: "hero", Object: hero.Id}, false); err != nil {
+			t.Fatal(err)
+		}
+		safe := alive(sanctuary.Id)
+		load := func(id string) *structs.Object {
+			t.Helper()
+			object, err := g.storage.LoadObject(ctx, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		corpses := func() []*structs.Object {
+			t.Helper()
+			content, err := g.storage.LoadObjects(ctx, load(room.Id).Content, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := []*structs.Object{}
+			for _, object := range content {
+				if object.Flagged(structs.TrashTag) {
+					result = append(result, object)
+				}
+			}
+			return result
+		}
+
+		if _, err := g.damage(ctx, hero, safe.Id, 5, hero.Id); err == nil || !strings.Contains(err.Error(), "sanctuary") {
+			t.Errorf("got %v, want damage in sanctuaries rejected", err)
+		}
+		if _, err := g.damage(ctx, hero, room.Id, 5, hero.Id); err == nil || !strings.Contains(err.Error(), "no vitals") {
+			t.Errorf("got %v, want damage to objects without vitals rejected", err)
+		}
+		if health, err := g.damage(ctx, hero, monster.Id, 4, hero.Id); err != nil || health != 6 {
+			t.Errorf("got %v, %v, want the monster hurt", health, err)
+		}
+		if len(corpses()) != 0 {
+			t.Error("got a corpse, want none before anything died")
+		}
+		if health, err := g.damage(ctx, hero, monster.Id, 10, hero.Id); err != nil || health != 0 {
+			t.Errorf("got %v, %v, want the monster dead", health, err)
+		}
+		found := corpses()
+		if len(found) != 1 || !found[0].Content[loot.Id] {
+			t.Fatalf("got corpses %+v, want one holding the loot of the monster", found)
+		}
+		if object := load(monster.Id); object.Health != 0 || len(object.Content) != 0 {
+			t.Errorf("got %+v, want the monster dead without its loot", object)
+		}
+
+		if _, err := g.damage(ctx, load(monster.Id), hero.Id, 20, monster.Id); err != nil {
+			t.Fatal(err)
+		}
+		if object := load(hero.Id); object.Health != 10 || object.Location != currentRespawnRoom() {
+			t.Errorf("got %+v, want the hero healed in the respawn room", object)
+		}
+		if len(corpses()) != 2 {
+			t.Errorf("got %+v, want a corpse of the hero too", corpses())
+		}
+	})
+}
+
+func TestTitlesAndNicknames(t *testing.T) {
+	ctx := juicemud.MakeMainContext(context.Background())
+	withGame(t, func(g *Game) {
+		room := emptyObject(t, g, genesisID)
+		named := func(short string) *structs.Object {
+			object := emptyObject(t, g, room.Id)
+			object.Descriptions = []structs.Description{{Short: short}}
+			if err := g.storage.StoreObject(ctx, nil, object); err != nil {
+				t.Fatal(err)
+			}
+			return object
+		}
+		arthur := named("arthur")
+		bob := named("bob")
+		if err := g.modifyObject(ctx, nil, arthur.Id, func(o *structs.Object) error {
+			o.Titles = []string{"the Bold"}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		c, output := testConnection(g, arthur.Id)
+		for _, connection := range []*Connection{c, {user: &storage.User{Object: bob.Id}}} {
+			registerSession(connection)
+			t.Cleanup(func() { unregisterSession(connection) })
+		}
+
+		if err := c.titleCommand([]string{"the", "Meek"}); err == nil || !strings.Contains(err.Error(), "haven't earned") {
+			t.Errorf("got %v, want unearned titles rejected", err)
+		}
+		if err := c.titleCommand([]string{"the", "bold"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.rememberCommand("bob"); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("got %v, want the usage without a nickname", err)
+		}
+		if err := c.rememberCommand("ghost as Casper"); err == nil || !strings.Contains(err.Error(), `no "ghost" here`) {
+			t.Errorf("got %v, want nicknaming what isn't there rejected", err)
+		}
+		if err := c.rememberCommand("bob as Robert"); err != nil {
+			t.Fatal(err)
+		}
+		output.Reset()
+		if err := c.whoCommand(); err != nil {
+			t.Fatal(err)
+		}
+		if got := output.String(); !strings.Contains(got, "arthur, the Bold\r\n") || !strings.Contains(got, "Robert\r\n") || strings.Contains(got, "bob") {
+			t.Errorf("got %q, want the chosen title and the nickname shown", got)
+		}
+
+		if err := c.forgetCommand("Roberta"); err == nil {
+			t.Error("got nil, want forgetting an unknown nickname rejected")
+		}
+		if err := c.forgetCommand("robert"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.titleCommand([]string{"none"}); err != nil {
+			t.Fatal(err)
+		}
+		output.Reset()
+		if err := c.whoCommand(); err != nil {
+			t.Fatal(err)
+		}
+		if got := output.String(); !strings.Contains(got, "arthur\r\n") || !strings.Contains(got, "bob\r\n") {
+			t.Errorf("got %q, want the plain names shown", got)
+		}
+	})
+}
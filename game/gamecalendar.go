@@ -0,0 +1,263 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"gopkg.in/yaml.v3"
+	"rogchap.com/v8go"
+)
+
+const (
+	gameHourEventType = "gameHour"
+)
+
+// GameMonth is a month of the game calendar.
+type GameMonth struct {
+	Name string `yaml:"name"`
+	Days int    `yaml:"days"`
+}
+
+// GameCalendar maps real time to dates in the game world, written in YAML (or JSON, which is
+// valid YAML) in the file given to the server with -calendar.
+//
+//	months:
+//	  - name: Frostmonth
+//	    days: 30
+//	  - name: Thawmonth
+//	    days: 30
+//	weekDays: [Moonday, Sunday, Starday]
+//	hoursPerDay: 24
+//	dawnHour: 6
+//	duskHour: 18
+//	speed: 4
+//	epoch: 2025-01-01T00:00:00Z
+//	epochYear: 1000
+type GameCalendar struct {
+	Months []GameMonth `yaml:"months"`
+	// WeekDays are the names of the days of the week, and their number is the length of the week.
+	WeekDays []string `yaml:"weekDays"`
+	// HoursPerDay is the number of hours in a game day.
+	HoursPerDay int `yaml:"hoursPerDay"`
+	// DawnHour and DuskHour are the hours when it gets light and dark.
+	DawnHour int `yaml:"dawnHour"`
+	DuskHour int `yaml:"duskHour"`
+	// Speed is how many game seconds pass each real second.
+	Speed float64 `yaml:"speed"`
+	// Epoch is when the game year EpochYear began.
+	Epoch     time.Time `yaml:"epoch"`
+	EpochYear int       `yaml:"epochYear"`
+}
+
+// GameDate is a date, and time of day, of the game calendar.
+type GameDate struct {
+	Year int
+	// Month is the 1-based number of the month.
+	Month     int
+	MonthName string
+	// Day is the 1-based day of the month.
+	Day int
+	// WeekDay is the 0-based day of the week.
+	WeekDay     int
+	WeekDayName string
+	Hour        int
+	Minute      int
+	// Daylight is whether the hour is between dawn and dusk.
+	Daylight bool
+}
+
+// defaultGameCalendar returns a calendar like the Gregorian one without leap years, where game
+// time passes like real time.
+func defaultGameCalendar() *GameCalendar {
+	return &GameCalendar{
+		Months: []GameMonth{
+			{Name: "January", Days: 31},
+			{Name: "February", Days: 28},
+			{Name: "March", Days: 31},
+			{Name: "April", Days: 30},
+			{Name: "May", Days: 31},
+			{Name: "June", Days: 30},
+			{Name: "July", Days: 31},
+			{Name: "August", Days: 31},
+			{Name: "September", Days: 30},
+			{Name: "October", Days: 31},
+			{Name: "November", Days: 30},
+			{Name: "December", Days: 31},
+		},
+		WeekDays:    []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+		HoursPerDay: 24,
+		DawnHour:    6,
+		DuskHour:    18,
+		Speed:       1,
+		Epoch:       time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		EpochYear:   1,
+	}
+}
+
+// LoadGameCalendar returns the calendar in the YAML file at path, with the fields it leaves
+// out taken from the default calendar.
+func LoadGameCalendar(path string) (*GameCalendar, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return parseGameCalendar(content)
+}
+
+func parseGameCalendar(content []byte) (*GameCalendar, error) {
+	result := defaultGameCalendar()
+	if err := yaml.Unmarshal(content, result); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := result.validate(); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+func (c *GameCalendar) validate() error {
+	if len(c.Months) == 0 {
+		return errors.New("calendars need months")
+	}
+	for _, month := range c.Months {
+		if month.Name == "" || month.Days <= 0 {
+			return errors.Errorf("months need a name and days, not %+v", month)
+		}
+	}
+	if len(c.WeekDays) == 0 {
+		return errors.New("calendars need week days")
+	}
+	if c.HoursPerDay <= 0 || c.Speed <= 0 {
+		return errors.New("calendars need positive hoursPerDay and speed")
+	}
+	if c.DawnHour < 0 || c.DuskHour < c.DawnHour || c.DuskHour > c.HoursPerDay {
+		return errors.Errorf("dawnHour %v and duskHour %v must be in order within the day", c.DawnHour, c.DuskHour)
+	}
+	return nil
+}
+
+// SetGameCalendar makes the game use calendar for game dates.
+func (g *Game) SetGameCalendar(calendar *GameCalendar) error {
+	if err := calendar.validate(); err != nil {
+		return juicemud.WithStack(err)
+	}
+	g.gameCalendar.Store(calendar)
+	return nil
+}
+
+// floorDiv returns a divided by b rounded down, and the non-negative remainder.
+func floorDiv(a int64, b int64) (int64, int64) {
+	quotient, remainder := a/b, a%b
+	if remainder < 0 {
+		quotient--
+		remainder += b
+	}
+	return quotient, remainder
+}
+
+func (c *GameCalendar) yearDays() int64 {
+	result := int64(0)
+	for _, month := range c.Months {
+		result += int64(month.Days)
+	}
+	return result
+}
+
+// Date returns the game date at t.
+func (c *GameCalendar) Date(t time.Time) GameDate {
+	gameSeconds := int64(float64(t.Sub(c.Epoch)) * c.Speed / float64(time.Second))
+	days, secondOfDay := floorDiv(gameSeconds, int64(c.HoursPerDay)*3600)
+	years, dayOfYear := floorDiv(days, c.yearDays())
+	_, weekDay := floorDiv(days, int64(len(c.WeekDays)))
+	result := GameDate{
+		Year:        c.EpochYear + int(years),
+		WeekDay:     int(weekDay),
+		WeekDayName: c.WeekDays[weekDay],
+		Hour:        int(secondOfDay / 3600),
+		Minute:      int(secondOfDay % 3600 / 60),
+	}
+	result.Daylight = result.Hour >= c.DawnHour && result.Hour < c.DuskHour
+	for index, month := range c.Months {
+		if dayOfYear < int64(month.Days) {
+			result.Month = index + 1
+			result.MonthName = month.Name
+			result.Day = int(dayOfYear) + 1
+			break
+		}
+		dayOfYear -= int64(month.Days)
+	}
+	return result
+}
+
+// Time returns the real time when the given game date and time of day begins.
+func (c *GameCalendar) Time(year int, month int, day int, hour int, minute int) (time.Time, error) {
+	if month < 1 || month > len(c.Months) {
+		return time.Time{}, errors.Errorf("month %v isn't between 1 and %v", month, len(c.Months))
+	}
+	if day < 1 || day > c.Months[month-1].Days {
+		return time.Time{}, errors.Errorf("day %v isn't between 1 and %v", day, c.Months[month-1].Days)
+	}
+	if hour < 0 || hour >= c.HoursPerDay || minute < 0 || minute >= 60 {
+		return time.Time{}, errors.Errorf("%02d:%02d isn't a time of day", hour, minute)
+	}
+	days := int64(year-c.EpochYear) * c.yearDays()
+	for _, previous := range c.Months[:month-1] {
+		days += int64(previous.Days)
+	}
+	days += int64(day - 1)
+	gameSeconds := days*int64(c.HoursPerDay)*3600 + int64(hour)*3600 + int64(minute)*60
+	return c.Epoch.Add(time.Duration(float64(gameSeconds) / c.Speed * float64(time.Second))), nil
+}
+
+// String returns d like "Monday, 3 March 12, 14:05".
+func (d GameDate) String() string {
+	return fmt.Sprintf("%s, %d %s %d, %02d:%02d", d.WeekDayName, d.Day, d.MonthName, d.Year, d.Hour, d.Minute)
+}
+
+// tickGameClock emits gameHour events with the game date to the objects subscribing to the
+// topic gameHour, when the game hour has changed since last, for day and night or weather.
+func (g *Game) tickGameClock(ctx context.Context, now time.Time, last *GameDate) error {
+	date := g.gameCalendar.Load().Date(now)
+	if last.MonthName != "" && date.Year == last.Year && date.Month == last.Month && date.Day == last.Day && date.Hour == last.Hour {
+		return nil
+	}
+	*last = date
+	subscribers, err := g.storage.ObjectIDsByTopic(ctx, gameHourEventType)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	at := g.storage.Queue().After(0)
+	for id := range subscribers {
+		if err := g.emitAny(ctx, at, id, gameHourEventType, &date); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// addGameCalendarCallbacks adds `getGameDate(ms?)`, which returns the JavaScript time, or now,
+// as `{Year, Month, MonthName, Day, WeekDay, WeekDayName, Hour, Minute, Daylight}` of the game
+// calendar.
+func (g *Game) addGameCalendarCallbacks(callbacks js.Callbacks) {
+	callbacks["getGameDate"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) > 1 || (len(args) == 1 && !args[0].IsNumber()) {
+			return rc.Throw("getGameDate takes [number?] arguments")
+		}
+		at := time.Now()
+		if len(args) > 0 {
+			at = time.UnixMilli(int64(args[0].Number()))
+		}
+		date := g.gameCalendar.Load().Date(at)
+		res, err := rc.JSFromGo(date)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", date, err)
+		}
+		return res
+	}
+}
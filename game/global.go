@@ -0,0 +1,98 @@
+package game
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// globalJSON returns the JSON of v for the global store, where null and undefined mean no value.
+func globalJSON(rc *js.RunContext, v *v8go.Value) (string, error) {
+	if v.IsNullOrUndefined() {
+		return "", nil
+	}
+	return v8go.JSONStringify(rc.Context(), v)
+}
+
+// globalWritable returns whether sources at sourcePath may write to namespace. Namespaces are
+// named after source directories, like "/quests", and only sources inside them may write to
+// them, so namespaces are protected by the same permissions as the sources.
+func globalWritable(namespace string, sourcePath string) bool {
+	if namespace == "/" || !path.IsAbs(namespace) || path.Clean(namespace) != namespace {
+		return false
+	}
+	return strings.HasPrefix(sourcePath, namespace+"/")
+}
+
+// addGlobalStoreCallbacks adds the callbacks of the global store. Every object can read every
+// namespace, but only write to the namespaces containing the source it runs.
+func (g *Game) addGlobalStoreCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	// Scripts can change their own SourcePath, so the permissions follow the source running.
+	sourcePath := object.SourcePath
+	callbacks["globalGet"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("globalGet takes [string, string] arguments")
+		}
+		value, err := g.storage.GetGlobal(ctx, args[0].String(), args[1].String())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return rc.Throw("trying to load %q from %q: %v", args[1].String(), args[0].String(), err)
+		}
+		res, err := v8go.JSONParse(rc.Context(), value)
+		if err != nil {
+			return rc.Throw("trying to parse %q: %v", value, err)
+		}
+		return res
+	}
+	callbacks["globalSet"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("globalSet takes [string, string, any] arguments")
+		}
+		if !globalWritable(args[0].String(), sourcePath) {
+			return rc.Throw("%q can't write to %q", sourcePath, args[0].String())
+		}
+		value, err := globalJSON(rc, args[2])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[2], err)
+		}
+		if err := g.storage.SetGlobal(ctx, args[0].String(), args[1].String(), value); err != nil {
+			return rc.Throw("trying to store %q in %q: %v", args[1].String(), args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["globalCAS"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 4 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("globalCAS takes [string, string, any, any] arguments")
+		}
+		if !globalWritable(args[0].String(), sourcePath) {
+			return rc.Throw("%q can't write to %q", sourcePath, args[0].String())
+		}
+		expected, err := globalJSON(rc, args[2])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[2], err)
+		}
+		value, err := globalJSON(rc, args[3])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[3], err)
+		}
+		swapped, err := g.storage.CASGlobal(ctx, args[0].String(), args[1].String(), &expected, value)
+		if err != nil {
+			return rc.Throw("trying to swap %q in %q: %v", args[1].String(), args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(swapped)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", swapped, err)
+		}
+		return res
+	}
+}
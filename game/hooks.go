@@ -0,0 +1,147 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	commandHookEventType = "commandHook"
+	// commandHookBudget is how long all hooks of a command may take together. Hooks that
+	// would run after it has passed are skipped.
+	commandHookBudget = 500 * time.Millisecond
+)
+
+// commandHookQuery is the message of commandHook calls.
+type commandHookQuery struct {
+	Actor string
+	Line  string
+}
+
+// commandHookReply is what commandHook handlers can reply with to replace the line of a
+// command, or veto it with a message. Handlers that don't reply only observe.
+type commandHookReply struct {
+	Line    *string
+	Veto    bool
+	Message string
+}
+
+// hookCommand runs the command hooks of the actor, what it carries and its location, in
+// priority order, on line. Returns the line after the hooks replaced it, or the message of
+// the veto of a hook, if one vetoed it. Hooks failing or timing out are skipped, so broken
+// hooks can't lock users out.
+func (g *Game) hookCommand(ctx context.Context, actor *structs.Object, line string) (string, *string, error) {
+	hookers := []*structs.Object{}
+	if actor.HasCallback(commandHookEventType, callEventTag) {
+		hookers = append(hookers, actor)
+	}
+	ids := map[string]bool{}
+	for id := range actor.Content {
+		ids[id] = true
+	}
+	if actor.Location != "" {
+		ids[actor.Location] = true
+	}
+	others, err := g.storage.LoadObjects(ctx, ids, nil)
+	if err != nil {
+		return "", nil, juicemud.WithStack(err)
+	}
+	for _, other := range others {
+		if other.HasCallback(commandHookEventType, callEventTag) {
+			hookers = append(hookers, other)
+		}
+	}
+	sort.Slice(hookers, func(i, j int) bool {
+		if hookers[i].CommandHookPriority != hookers[j].CommandHookPriority {
+			return hookers[i].CommandHookPriority < hookers[j].CommandHookPriority
+		}
+		return hookers[i].Id < hookers[j].Id
+	})
+	deadline := time.Now().Add(commandHookBudget)
+	for _, hooker := range hookers {
+		if time.Now().After(deadline) {
+			log.Printf("skipping command hooks of %q and later, the hooks of %q took longer than %v", hooker.Id, actor.Id, commandHookBudget)
+			break
+		}
+		message, err := goccy.Marshal(&commandHookQuery{Actor: actor.Id, Line: line})
+		if err != nil {
+			return "", nil, juicemud.WithStack(err)
+		}
+		rpc := &rpcCall{
+			call: structs.Call{
+				Name:    commandHookEventType,
+				Message: string(message),
+				Tag:     callEventTag,
+			},
+		}
+		if err := g.loadRunSave(ctx, hooker.Id, rpc); err != nil {
+			log.New(consoleByObjectID.Get(hooker.Id), "", 0).Printf("---- command hook failed ----\n%v", err)
+			continue
+		}
+		if rpc.reply == nil {
+			continue
+		}
+		reply := &commandHookReply{}
+		if err := goccy.Unmarshal([]byte(*rpc.reply), reply); err != nil {
+			log.New(consoleByObjectID.Get(hooker.Id), "", 0).Printf("---- command hook replied with invalid %s ----\n%v", *rpc.reply, err)
+			continue
+		}
+		if reply.Veto {
+			return line, &reply.Message, nil
+		}
+		if reply.Line != nil {
+			line = *reply.Line
+		}
+	}
+	return line, nil, nil
+}
+
+// addCommandHookCallbacks adds `addCommandHook(priority, handler)`, which makes handler see
+// the commands of users being, carrying or in the object before they run. Hooks run in
+// priority order, lowest first, and handlers can `reply({Line?, Veto?, Message?})` to replace
+// the line the next hooks and the command see, or veto the command showing Message. Each
+// hook has the usual run timeout, and all hooks of a command share a budget of 500ms.
+// Remove hooks with `removeCallback("commandHook")`.
+func addCommandHookCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["addCommandHook"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsNumber() || !args[1].IsFunction() {
+			return rc.Throw("addCommandHook takes [number, function] arguments")
+		}
+		if err := addCallback(rc, commandHookEventType, callEventTag, args[1]); err != nil {
+			return rc.Throw("trying to add command hook: %v", err)
+		}
+		object.CommandHookPriority = args[0].Integer()
+		return nil
+	}
+}
+
+// hookCommand runs the command hooks for line, and returns the line to run, or false if a hook
+// vetoed it.
+func (c *Connection) hookCommand(line string) (string, bool, error) {
+	actor, err := c.object()
+	if err != nil {
+		return "", false, juicemud.WithStack(err)
+	}
+	hooked, veto, err := c.game.hookCommand(c.sess.Context(), actor, line)
+	if err != nil {
+		return "", false, juicemud.WithStack(err)
+	}
+	if veto != nil {
+		if *veto != "" {
+			fmt.Fprintln(c.term, c.wrap(*veto))
+		}
+		return "", false, nil
+	}
+	return hooked, true, nil
+}
@@ -0,0 +1,366 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	// defaultFurnitureLimit is how many pieces of furniture houses hold unless changed by
+	// setFurnishing.
+	defaultFurnitureLimit = 10
+	// maxHouseDescription is the longest long description owners can give their houses.
+	maxHouseDescription = 2000
+)
+
+var (
+	furnitureSources atomic.Pointer[[]string]
+	furnitureLimit   atomic.Int64
+)
+
+func init() {
+	furnitureSources.Store(&[]string{})
+	furnitureLimit.Store(defaultFurnitureLimit)
+}
+
+// furnishing is how the furniture rules are given by JavaScript.
+type furnishing struct {
+	Sources []string
+	Limit   *int64
+}
+
+// house is how houses are shown to JavaScript.
+type house struct {
+	Owner  string
+	Guests []string
+	Price  int64
+}
+
+// checkEntry returns an error if the object with the given ID is a user not allowed to enter
+// destination, because it's a house that the user neither owns nor is a guest in.
+func (g *Game) checkEntry(ctx context.Context, running *structs.Object, id string, destination string) error {
+	room, err := g.loadObjectOrRunning(ctx, running, destination)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if room.Owner == "" || room.Owner == id || room.Guests[id] {
+		return nil
+	}
+	if user, err := g.isUser(ctx, id); err != nil {
+		return juicemud.WithStack(err)
+	} else if user {
+		return errors.Errorf("%q is private", destination)
+	}
+	return nil
+}
+
+// buyHouse makes the buyer with the given ID pay for, and own, the house with the given ID.
+func (g *Game) buyHouse(ctx context.Context, buyerID string, houseID string) (*structs.Object, error) {
+	room, err := g.storage.LoadObject(ctx, houseID, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if room.HousePrice <= 0 || room.Owner != "" {
+		return nil, errors.New("This place isn't for sale")
+	}
+	price := room.HousePrice
+	if err := g.modifyObject(ctx, nil, buyerID, func(buyer *structs.Object) error {
+		if buyer.Money < price {
+			return errors.Errorf("You can't afford %v", price)
+		}
+		buyer.Money -= price
+		return nil
+	}); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.modifyObject(ctx, nil, houseID, func(object *structs.Object) error {
+		if object.Owner != "" || object.HousePrice != price {
+			return errors.New("Someone else bought it first")
+		}
+		object.Owner = buyerID
+		object.Guests = map[string]bool{}
+		room = object
+		return nil
+	}); err != nil {
+		return nil, juicemud.WithStack(rollback(err, g.modifyObject(ctx, nil, buyerID, func(buyer *structs.Object) error {
+			buyer.Money += price
+			return nil
+		})))
+	}
+	return room, nil
+}
+
+// ownedHouse returns the location of the connected object, if owned by it.
+func (c *Connection) ownedHouse() (*structs.Object, error) {
+	object, err := c.object()
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if object.Location == "" {
+		return nil, errors.New("You don't own this place")
+	}
+	room, err := c.game.storage.LoadObject(c.sess.Context(), object.Location, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if room.Owner != object.Id {
+		return nil, errors.New("You don't own this place")
+	}
+	return room, nil
+}
+
+// houseCommand shows the house of the current location, buys it, or lets its owner describe,
+// furnish and manage the guests of it.
+func (c *Connection) houseCommand(args []string) error {
+	ctx := c.sess.Context()
+	usage := func() error {
+		fmt.Fprintln(c.term, "usage: house [buy|describe [text]|furnish [source]|discard [item]|guest [user]|unguest [user]]")
+		return nil
+	}
+	if len(args) == 0 {
+		return juicemud.WithStack(c.showHouse())
+	}
+	if args[0] == "buy" {
+		if len(args) != 1 {
+			return usage()
+		}
+		object, err := c.object()
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		room, err := c.game.buyHouse(ctx, object.Id, object.Location)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You buy %s for %v", shortDescription(room), room.HousePrice)))
+		return nil
+	}
+	if len(args) < 2 {
+		return usage()
+	}
+	room, err := c.ownedHouse()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	switch args[0] {
+	case "describe":
+		long := strings.Join(args[1:], " ")
+		if len(long) > maxHouseDescription {
+			return errors.Errorf("Descriptions can't be longer than %v characters", maxHouseDescription)
+		}
+		if err := c.game.modifyObject(ctx, nil, room.Id, func(object *structs.Object) error {
+			if len(object.Descriptions) == 0 {
+				object.Descriptions = []structs.Description{{Short: "a house"}}
+			}
+			object.Descriptions[0].Long = long
+			return nil
+		}); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, "You describe your house")
+	case "furnish":
+		if len(args) != 2 {
+			return usage()
+		}
+		if !slices.Contains(*furnitureSources.Load(), args[1]) {
+			return errors.Errorf("%q isn't furniture", args[1])
+		}
+		content, err := c.game.storage.LoadObjects(ctx, room.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		count := int64(0)
+		for _, item := range content {
+			if item.Flagged(structs.FurnitureTag) {
+				count++
+			}
+		}
+		if count >= furnitureLimit.Load() {
+			return errors.New("Your house is fully furnished")
+		}
+		// The furniture sources are chosen by the wizards, so the owner needn't be able to read them.
+		created, err := c.game.createObjectFromSource(juicemud.MakeMainContext(ctx), args[1], room.Id, "")
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if err := c.game.modifyObject(ctx, nil, created.Id, func(object *structs.Object) error {
+			object.Tags = append(object.Tags, structs.FurnitureTag)
+			return nil
+		}); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You furnish your house with %s", shortDescription(created))))
+	case "discard":
+		object, err := c.object()
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		content, err := c.game.storage.LoadObjects(ctx, room.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		for id, item := range content {
+			if !item.Flagged(structs.FurnitureTag) {
+				delete(content, id)
+			}
+		}
+		name := strings.Join(args[1:], " ")
+		item := matchObject(object, content, name)
+		if item == nil {
+			return errors.Errorf("There is no furniture %q here", name)
+		}
+		if len(item.Content) > 0 {
+			return errors.Errorf("Empty %s first", shortDescription(item))
+		}
+		if err := c.game.storage.DeleteObject(ctx, item.Id); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You discard %s", shortDescription(item))))
+	case "guest", "unguest":
+		if len(args) != 2 {
+			return usage()
+		}
+		user, err := c.game.storage.LoadUser(ctx, args[1])
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.Errorf("There is no user %q", args[1])
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if err := c.game.modifyObject(ctx, nil, room.Id, func(object *structs.Object) error {
+			if object.Guests == nil {
+				object.Guests = map[string]bool{}
+			}
+			if args[0] == "guest" {
+				object.Guests[user.Object] = true
+			} else {
+				delete(object.Guests, user.Object)
+			}
+			return nil
+		}); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if args[0] == "guest" {
+			fmt.Fprintf(c.term, "%s is now welcome in your house\n", user.Name)
+		} else {
+			fmt.Fprintf(c.term, "%s is no longer welcome in your house\n", user.Name)
+		}
+	default:
+		return usage()
+	}
+	return nil
+}
+
+func (c *Connection) showHouse() error {
+	ctx := c.sess.Context()
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if object.Location == "" {
+		fmt.Fprintln(c.term, "This isn't a house")
+		return nil
+	}
+	room, err := c.game.storage.LoadObject(ctx, object.Location, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if room.Owner == "" {
+		if room.HousePrice > 0 {
+			fmt.Fprintf(c.term, "This place is for sale for %v, `house buy` to buy it\n", room.HousePrice)
+		} else {
+			fmt.Fprintln(c.term, "This isn't a house")
+		}
+		return nil
+	}
+	if room.Owner != object.Id {
+		fmt.Fprintln(c.term, "This house is owned by someone else")
+		return nil
+	}
+	guests := []string{}
+	for id := range room.Guests {
+		user, err := c.game.storage.LoadUserByObject(ctx, id)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		guests = append(guests, user.Name)
+	}
+	sort.Strings(guests)
+	fmt.Fprintln(c.term, "This is your house")
+	if len(guests) > 0 {
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("Guests: %s", strings.Join(guests, ", "))))
+	}
+	fmt.Fprintf(c.term, "Furniture sources: %s\n", strings.Join(*furnitureSources.Load(), ", "))
+	return nil
+}
+
+// addFurnishingCallbacks adds `setFurnishing({Sources, Limit?})`, which sets the sources
+// owners can furnish their houses from, and how many pieces of furniture houses hold.
+func addFurnishingCallbacks(callbacks js.Callbacks) {
+	callbacks["setFurnishing"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setFurnishing takes [Object] arguments")
+		}
+		rules := furnishing{}
+		if err := rc.Copy(&rules, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to furnishing: %v", args[0], err)
+		}
+		if rules.Limit != nil && *rules.Limit < 0 {
+			return rc.Throw("furniture Limit must be non negative")
+		}
+		sources := slices.Clone(rules.Sources)
+		furnitureSources.Store(&sources)
+		if rules.Limit != nil {
+			furnitureLimit.Store(*rules.Limit)
+		}
+		return nil
+	}
+}
+
+// addHousingCallbacks adds `setHousePrice(price)`, which puts the object up for sale as a house
+// users can buy with `house buy`, or takes it off the market if price is 0, and
+// `getHouse(objectID)`, which returns the {Owner, Guests, Price} of a house. Only owners and
+// their guests can move users into houses.
+func (g *Game) addHousingCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setHousePrice"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsNumber() || args[0].Integer() < 0 {
+			return rc.Throw("setHousePrice takes [non negative number] arguments")
+		}
+		object.HousePrice = args[0].Integer()
+		return nil
+	}
+	callbacks["getHouse"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getHouse takes [string] arguments")
+		}
+		room, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		result := house{Owner: room.Owner, Guests: []string{}, Price: room.HousePrice}
+		for id := range room.Guests {
+			result.Guests = append(result.Guests, id)
+		}
+		sort.Strings(result.Guests)
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
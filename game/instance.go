@@ -0,0 +1,213 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	instanceSweepInterval = time.Minute
+	// instanceGracePeriod is how long new instances are kept while empty, to let their party
+	// arrive.
+	instanceGracePeriod = time.Minute
+)
+
+var (
+	// instanceLock stops concurrent enterInstance calls from building the same instance twice.
+	instanceLock sync.Mutex
+)
+
+// enterInstance moves the objects in party to the first room of the instance of the area at
+// template built for them, building it first if there is none. The rooms of instances get IDs
+// prefixed by the instance key, so they never collide with, or get the events of, the shared
+// world. The party can only be the running object, and objects in it or next to it, so that
+// scripts can't pull objects from anywhere into their instances. Returns the instance key.
+func (g *Game) enterInstance(ctx context.Context, running *structs.Object, template string, party []string) (string, error) {
+	party = slices.Compact(slices.Sorted(slices.Values(party)))
+	if len(party) == 0 {
+		return "", errors.Errorf("instances need a party")
+	}
+	for _, id := range party {
+		member, err := g.loadObjectOrRunning(ctx, running, id)
+		if err != nil {
+			return "", juicemud.WithStack(err)
+		}
+		if member.Id != running.Id && member.Location != running.Id && (running.Location == "" || member.Location != running.Location) {
+			return "", errors.Errorf("%q is neither in nor next to %q", id, running.Id)
+		}
+	}
+	partyJSON, err := goccy.Marshal(party)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	instance, rooms, err := func() (*storage.Instance, []string, error) {
+		instanceLock.Lock()
+		defer instanceLock.Unlock()
+
+		instance, err := g.storage.LoadInstance(ctx, template, string(partyJSON))
+		if err == nil {
+			rooms := []string{}
+			if err := goccy.Unmarshal([]byte(instance.Rooms), &rooms); err != nil {
+				return nil, nil, juicemud.WithStack(err)
+			}
+			return instance, rooms, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, juicemud.WithStack(err)
+		}
+		id, err := structs.NextObjectID()
+		if err != nil {
+			return nil, nil, juicemud.WithStack(err)
+		}
+		key := fmt.Sprintf("%s@%s", template, id)
+		rooms, err := g.buildArea(ctx, template, key)
+		if err != nil {
+			return nil, nil, juicemud.WithStack(err)
+		}
+		if len(rooms) == 0 {
+			return nil, nil, errors.Errorf("%q has no rooms", template)
+		}
+		roomsJSON, err := goccy.Marshal(rooms)
+		if err != nil {
+			return nil, nil, juicemud.WithStack(err)
+		}
+		instance = &storage.Instance{
+			Key:      key,
+			Template: template,
+			Party:    string(partyJSON),
+			Rooms:    string(roomsJSON),
+			Created:  time.Now().UnixNano(),
+		}
+		return instance, rooms, juicemud.WithStack(g.storage.StoreInstance(ctx, instance))
+	}()
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	for _, id := range party {
+		if err := g.moveObject(ctx, running, id, rooms[0]); err != nil {
+			return "", juicemud.WithStack(err)
+		}
+	}
+	return instance.Key, nil
+}
+
+// runInstanceSweeps tears down the instances without any of their party in their rooms every
+// instanceSweepInterval until ctx is done.
+func (g *Game) runInstanceSweeps(ctx context.Context) {
+	ticker := time.NewTicker(instanceSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.sweepInstances(ctx, time.Now()); err != nil {
+			log.Printf("trying to sweep instances: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+func (g *Game) sweepInstances(ctx context.Context, now time.Time) error {
+	instances, err := g.storage.Instances(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, instance := range instances {
+		if now.Sub(time.Unix(0, instance.Created)) < instanceGracePeriod {
+			continue
+		}
+		party, rooms := []string{}, []string{}
+		if err := goccy.Unmarshal([]byte(instance.Party), &party); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if err := goccy.Unmarshal([]byte(instance.Rooms), &rooms); err != nil {
+			return juicemud.WithStack(err)
+		}
+		occupied := false
+		for _, id := range party {
+			member, err := g.storage.LoadObject(ctx, id, nil)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return juicemud.WithStack(err)
+			}
+			if slices.Contains(rooms, member.Location) {
+				occupied = true
+				break
+			}
+		}
+		if occupied {
+			continue
+		}
+		for _, room := range rooms {
+			if err := g.tearDown(ctx, room); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		if err := g.storage.DeleteInstance(ctx, instance.Key); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// tearDown deletes the object with the given ID and everything in it, except users who are
+// moved to the respawn room.
+func (g *Game) tearDown(ctx context.Context, id string) error {
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for childID := range object.Content {
+		if user, err := g.isUser(ctx, childID); err != nil {
+			return juicemud.WithStack(err)
+		} else if user {
+			if err := g.moveObject(ctx, nil, childID, currentRespawnRoom()); err != nil {
+				return juicemud.WithStack(err)
+			}
+		} else if err := g.tearDown(ctx, childID); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return juicemud.WithStack(g.storage.DeleteObject(ctx, id))
+}
+
+// addInstanceCallbacks adds `enterInstance(templatePath, partyIDs?)`, which moves the party,
+// by default only the object, into its own instance of the area at templatePath and returns
+// the instance key. The party can only contain the object, and objects in it or next to it. Instances are torn down when none of their party is in them.
+func (g *Game) addInstanceCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["enterInstance"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsString() || (len(args) == 2 && !args[1].IsArray()) {
+			return rc.Throw("enterInstance takes [string, Array?] arguments")
+		}
+		party := []string{object.Id}
+		if len(args) == 2 {
+			if err := rc.Copy(&party, args[1]); err != nil {
+				return rc.Throw("trying to convert %v to []string: %v", args[1], err)
+			}
+		}
+		key, err := g.enterInstance(ctx, object, args[0].String(), party)
+		if err != nil {
+			return rc.Throw("trying to enter an instance of %q: %v", args[0].String(), err)
+		}
+		return rc.String(key)
+	}
+}
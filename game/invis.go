@@ -0,0 +1,65 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+const (
+	invisibilitySkill        = "perception"
+	defaultInvisibilityLevel = 100
+)
+
+// setInvisibility replaces the invisibility challenges of the object with the given ID.
+func (g *Game) setInvisibility(ctx context.Context, id string, challenges []structs.Challenge) error {
+	jsContextLocks.Lock(id)
+	defer jsContextLocks.Unlock(id)
+
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	object.Invisibility = challenges
+	return juicemud.WithStack(g.storage.StoreObject(ctx, nil, object))
+}
+
+// invisCommand toggles the invisibility of the object the connection acts as, making
+// it unnoticed by those failing a perception challenge of the given level.
+func (c *Connection) invisCommand(args []string) error {
+	level := float64(defaultInvisibilityLevel)
+	if len(args) == 1 {
+		var err error
+		if level, err = strconv.ParseFloat(args[0], 32); err != nil {
+			fmt.Fprintln(c.term, "usage: /invis [perception challenge level]")
+			return nil
+		}
+	} else if len(args) != 0 {
+		fmt.Fprintln(c.term, "usage: /invis [perception challenge level]")
+		return nil
+	}
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(object.Invisibility) > 0 && len(args) == 0 {
+		if err := c.game.setInvisibility(c.sess.Context(), object.Id, nil); err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, "You are visible")
+		return nil
+	}
+	if err := c.game.setInvisibility(c.sess.Context(), object.Id, []structs.Challenge{
+		{
+			Skill: invisibilitySkill,
+			Level: float32(level),
+		},
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "You are invisible to those failing a %s challenge of level %v\n", invisibilitySkill, level)
+	return nil
+}
@@ -0,0 +1,148 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+)
+
+var (
+	// ipBans are the banned CIDR ranges, by their storage.IPBan.Range.
+	ipBans = juicemud.NewSyncMap[string, *net.IPNet]()
+)
+
+// parseIPRange returns the CIDR range s, or the range of only the IP s.
+func parseIPRange(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("invalid IP %q", s)
+		}
+		if ip.To4() != nil {
+			s += "/32"
+		} else {
+			s += "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return network, nil
+}
+
+// loadIPBans bans the ranges banned in the storage.
+func (g *Game) loadIPBans(ctx context.Context) error {
+	bans, err := g.storage.IPBans(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, ban := range bans {
+		network, err := parseIPRange(ban.Range)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		ipBans.Set(ban.Range, network)
+	}
+	return nil
+}
+
+// ipBanned returns whether addr is in a banned range.
+func ipBanned(addr net.Addr) bool {
+	ip := net.ParseIP(remoteIP(addr))
+	if ip == nil {
+		return false
+	}
+	for network := range ipBans.Values() {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// admits returns whether connections from addr are let through listener.
+func admits(listener *Listener, addr net.Addr) bool {
+	return listener.Allows(addr) && !ipBanned(addr)
+}
+
+// ConnCallback returns a callback of the SSH server of listener closing connections it
+// doesn't admit before the SSH handshake, to cut off abusive hosts cheaply.
+func (g *Game) ConnCallback(listener *Listener) ssh.ConnCallback {
+	return func(ctx ssh.Context, conn net.Conn) net.Conn {
+		if !admits(listener, conn.RemoteAddr()) {
+			return nil
+		}
+		return conn
+	}
+}
+
+// ipBanCommand handles `/ipban [range] [reason]`, which bans an IP or CIDR range and
+// disconnects the sessions from it, or lists the bans, and `/ipunban range`.
+func (c *Connection) ipBanCommand(verb string, args []string) error {
+	ctx := c.sess.Context()
+	if verb == "/ipban" && len(args) == 0 {
+		bans, err := c.game.storage.IPBans(ctx)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		t := c.table("Range", "Reason", "Banned by", "Banned at")
+		for _, ban := range bans {
+			t.AddRow(ban.Range, ban.Reason, ban.BannedBy, time.Unix(0, ban.BannedAt).Format(time.DateTime))
+		}
+		t.Print()
+		return nil
+	}
+	if len(args) == 0 || (verb == "/ipunban" && len(args) != 1) {
+		fmt.Fprintf(c.term, "usage: /ipban [range] [reason], /ipunban [range]\n")
+		return nil
+	}
+	network, err := parseIPRange(args[0])
+	if err != nil {
+		fmt.Fprintln(c.term, err)
+		return nil
+	}
+	cidr := network.String()
+	if verb == "/ipunban" {
+		removed, err := c.game.storage.UnbanIP(ctx, cidr)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		ipBans.Del(cidr)
+		if removed {
+			fmt.Fprintf(c.term, "Unbanned %s\n", cidr)
+		} else {
+			fmt.Fprintf(c.term, "%s isn't banned\n", cidr)
+		}
+		return nil
+	}
+	if network.Contains(net.ParseIP(remoteIP(c.sess.RemoteAddr()))) {
+		fmt.Fprintf(c.term, "%s contains your own IP\n", cidr)
+		return nil
+	}
+	if err := c.game.storage.BanIP(ctx, &storage.IPBan{
+		Range:    cidr,
+		Reason:   strings.Join(args[1:], " "),
+		BannedBy: c.user.Name,
+		BannedAt: time.Now().UnixNano(),
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	ipBans.Set(cidr, network)
+	disconnected := 0
+	for other := range envByObjectID.Values() {
+		if network.Contains(net.ParseIP(remoteIP(other.sess.RemoteAddr()))) {
+			other.sess.Close()
+			disconnected++
+		}
+	}
+	fmt.Fprintf(c.term, "Banned %s, disconnecting %d sessions, /ipunban %s to undo\n", cidr, disconnected, cidr)
+	return nil
+}
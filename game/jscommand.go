@@ -0,0 +1,302 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/buildkite/shellwords"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	commandEventTag = "command"
+	// objectArgument is an object in the location or carried, given as e.g. "second red orb".
+	objectArgument = "object"
+	// directionArgument is an exit of the location.
+	directionArgument = "direction"
+	// textArgument is any text.
+	textArgument = "text"
+)
+
+var (
+	argumentKinds = []string{objectArgument, directionArgument, textArgument}
+	// commandNames are the sorted names of the commands available to all users.
+	commandNames = []string{}
+)
+
+func init() {
+	for _, command := range commands {
+		for name := range command.names {
+			if !command.wizard {
+				commandNames = append(commandNames, name)
+			}
+		}
+	}
+	sort.Strings(commandNames)
+}
+
+// commandSpec is how commands are declared by JavaScript.
+type commandSpec struct {
+	Help      string
+	Arguments []structs.CommandArgument
+}
+
+// commandMessage is the message of command events, with Args by argument name. Object
+// arguments are given as object IDs.
+type commandMessage struct {
+	Actor string
+	Line  string
+	Args  map[string]string
+}
+
+// commandEventType returns the event type of the command with the given name.
+func commandEventType(name string) string {
+	return "command:" + name
+}
+
+// commandUsage returns the usage line of command.
+func commandUsage(command *structs.Command) string {
+	parts := []string{command.Name}
+	for _, argument := range command.Arguments {
+		if argument.Optional {
+			parts = append(parts, fmt.Sprintf("[%s]", argument.Name))
+		} else {
+			parts = append(parts, fmt.Sprintf("<%s>", argument.Name))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// commandProviders returns the objects whose commands the actor can use, in priority order:
+// the actor itself, what it carries, its location, and the siblings in its location.
+func (g *Game) commandProviders(ctx context.Context, actor *structs.Object) ([]*structs.Object, *structs.Location, map[string]*structs.Object, error) {
+	carried, err := g.storage.LoadObjects(ctx, actor.Content, nil)
+	if err != nil {
+		return nil, nil, nil, juicemud.WithStack(err)
+	}
+	result := []*structs.Object{actor}
+	appendSorted := func(objects map[string]*structs.Object) {
+		ids := make([]string, 0, len(objects))
+		for id := range objects {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if objects[id].Detectable(actor) {
+				result = append(result, objects[id])
+			}
+		}
+	}
+	appendSorted(carried)
+	var location *structs.Location
+	if actor.Location != "" {
+		if location, err = g.loadLocation(ctx, actor.Location); err != nil {
+			return nil, nil, nil, juicemud.WithStack(err)
+		}
+		result = append(result, location.Container)
+		siblings := map[string]*structs.Object{}
+		for id, sibling := range location.Content {
+			if id != actor.Id {
+				siblings[id] = sibling
+			}
+		}
+		appendSorted(siblings)
+	}
+	return result, location, carried, nil
+}
+
+// parseArguments returns the arguments of command in words by name. All but the last
+// argument are single words, or quoted phrases, while the last argument gets the rest.
+func parseArguments(actor *structs.Object, location *structs.Location, carried map[string]*structs.Object, command *structs.Command, words []string) (map[string]string, error) {
+	result := map[string]string{}
+	for index, argument := range command.Arguments {
+		if len(words) == 0 {
+			if argument.Optional {
+				continue
+			}
+			return nil, errors.Errorf("usage: %s", commandUsage(command))
+		}
+		value := words[0]
+		words = words[1:]
+		if index == len(command.Arguments)-1 && len(words) > 0 {
+			value = strings.Join(append([]string{value}, words...), " ")
+			words = nil
+		}
+		switch argument.Kind {
+		case objectArgument:
+			candidates := map[string]*structs.Object{}
+			for id, object := range carried {
+				candidates[id] = object
+			}
+			if location != nil {
+				for id, object := range location.Content {
+					candidates[id] = object
+				}
+			}
+			object := matchObject(actor, candidates, value)
+			if object == nil {
+				return nil, errors.Errorf("There is no %q here", value)
+			}
+			value = object.Id
+		case directionArgument:
+			found := false
+			if location != nil {
+				_, exits := location.Container.Inspect(actor)
+				for _, exit := range exits {
+					if len(exit.Descriptions) > 0 && strings.EqualFold(exit.Descriptions[0].Short, value) {
+						value, found = exit.Descriptions[0].Short, true
+						break
+					}
+				}
+			}
+			if !found {
+				return nil, errors.Errorf("There is no exit %q here", value)
+			}
+		}
+		result[argument.Name] = value
+	}
+	if len(words) > 0 {
+		return nil, errors.Errorf("usage: %s", commandUsage(command))
+	}
+	return result, nil
+}
+
+// jsCommand runs the JavaScript command named by the first word of line, if any object
+// available to the connected object provides one. Returns whether one did.
+func (c *Connection) jsCommand(line string) (bool, error) {
+	ctx := c.sess.Context()
+	words, err := shellwords.SplitPosix(line)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	if len(words) == 0 {
+		return false, nil
+	}
+	actor, err := c.object()
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	providers, location, carried, err := c.game.commandProviders(ctx, actor)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	for _, provider := range providers {
+		index := slices.IndexFunc(provider.Commands, func(command structs.Command) bool {
+			return command.Name == words[0]
+		})
+		if index == -1 || !provider.HasCallback(commandEventType(words[0]), commandEventTag) {
+			continue
+		}
+		command := &provider.Commands[index]
+		args, err := parseArguments(actor, location, carried, command, words[1:])
+		if err != nil {
+			return true, juicemud.WithStack(err)
+		}
+		return true, juicemud.WithStack(c.game.loadRunSave(ctx, provider.Id, &AnyCall{
+			Name: commandEventType(command.Name),
+			Tag:  commandEventTag,
+			Content: &commandMessage{
+				Actor: actor.Id,
+				Line:  line,
+				Args:  args,
+			},
+		}))
+	}
+	return false, nil
+}
+
+// helpCommand lists the commands available to the connected object.
+func (c *Connection) helpCommand() error {
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("Commands: %s", strings.Join(commandNames, ", "))))
+	actor, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	providers, _, _, err := c.game.commandProviders(c.sess.Context(), actor)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	seen := map[string]bool{}
+	t := c.table("Command", "Help")
+	for _, provider := range providers {
+		for i := range provider.Commands {
+			command := &provider.Commands[i]
+			if seen[command.Name] || !provider.HasCallback(commandEventType(command.Name), commandEventTag) {
+				continue
+			}
+			seen[command.Name] = true
+			t.AddRow(commandUsage(command), command.Help)
+		}
+	}
+	if len(seen) > 0 {
+		t.Print()
+	}
+	return nil
+}
+
+// addCallback makes handler handle the events of eventType tagged tag, like the JavaScript
+// addCallback(eventType, [tag], handler).
+func addCallback(rc *js.RunContext, eventType string, tag string, handler *v8go.Value) error {
+	add, err := rc.Context().Global().Get("addCallback")
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	addFunction, err := add.AsFunction()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	tags, err := rc.JSFromGo([]string{tag})
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	_, err = addFunction.Call(rc.Context().Global(), rc.String(eventType), tags, handler)
+	return juicemud.WithStack(err)
+}
+
+// addCommandCallbacks adds `addCommand(name, {Help?, Arguments?: [{Name, Kind, Optional?}]},
+// handler)`, which lets users being, carrying, in or near the object run the command. Kind is
+// "object" for an object there, given to handler as its ID, "direction" for an exit, or
+// "text". All but the last argument are single words or quoted phrases, and the last gets
+// the rest of the line. The handler gets `{Actor, Line, Args}` with Args by name, and the
+// command is listed by `help`. Commands are added to commands, and like callbacks they only
+// last while the source keeps adding them.
+func addCommandCallbacks(commands *[]structs.Command, callbacks js.Callbacks) {
+	callbacks["addCommand"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsObject() || !args[2].IsFunction() {
+			return rc.Throw("addCommand takes [string, Object, function] arguments")
+		}
+		name := args[0].String()
+		if name == "" || strings.ContainsFunc(name, func(r rune) bool { return r == ' ' || r == '\t' }) {
+			return rc.Throw("command names must be single words, not %q", name)
+		}
+		spec := commandSpec{}
+		if err := rc.Copy(&spec, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to commandSpec: %v", args[1], err)
+		}
+		for _, argument := range spec.Arguments {
+			if argument.Name == "" || !slices.Contains(argumentKinds, argument.Kind) {
+				return rc.Throw("arguments need a Name and a Kind in %v", argumentKinds)
+			}
+		}
+		if err := addCallback(rc, commandEventType(name), commandEventTag, args[2]); err != nil {
+			return rc.Throw("trying to add callback for %q: %v", name, err)
+		}
+		*commands = slices.DeleteFunc(*commands, func(command structs.Command) bool {
+			return command.Name == name
+		})
+		*commands = append(*commands, structs.Command{
+			Name:      name,
+			Help:      spec.Help,
+			Arguments: spec.Arguments,
+		})
+		return nil
+	}
+}
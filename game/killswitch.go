@@ -0,0 +1,84 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+)
+
+var (
+	// disabledSources are the source paths whose scripts aren't run.
+	disabledSources = juicemud.NewSyncMap[string, bool]()
+)
+
+// loadDisabledSources makes the source paths disabled in the storage disabled.
+func (g *Game) loadDisabledSources(ctx context.Context) error {
+	sources, err := g.storage.DisabledSources(ctx)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, source := range sources {
+		disabledSources.Set(source.Path, true)
+	}
+	return nil
+}
+
+// skipDisabled returns whether the source of object is disabled, and logs the skipped call,
+// or run if call is nil, to the console of the object if so.
+func skipDisabled(object *structs.Object, call *structs.Call) bool {
+	if !disabledSources.Get(object.SourcePath) {
+		return false
+	}
+	name := "run"
+	if call != nil {
+		name = call.Name
+	}
+	log.New(consoleByObjectID.Get(object.Id), "", 0).Printf("---- %s is disabled, skipping %s ----", object.SourcePath, name)
+	return true
+}
+
+// disableSourceCommand handles `/disable-source [path]`, which stops all scripts of path
+// immediately, or lists the disabled paths, and `/enable-source path`.
+func (c *Connection) disableSourceCommand(verb string, args []string) error {
+	ctx := c.sess.Context()
+	if verb == "/disable-source" && len(args) == 0 {
+		sources, err := c.game.storage.DisabledSources(ctx)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		t := c.table("Path", "Disabled by", "Disabled at")
+		for _, source := range sources {
+			t.AddRow(source.Path, source.DisabledBy, time.Unix(0, source.DisabledAt).Format(time.DateTime))
+		}
+		t.Print()
+		return nil
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(c.term, "usage: %s [path]\n", verb)
+		return nil
+	}
+	if verb == "/enable-source" {
+		if err := c.game.storage.EnableSource(ctx, args[0]); err != nil {
+			return juicemud.WithStack(err)
+		}
+		disabledSources.Del(args[0])
+		fmt.Fprintf(c.term, "Enabled %s\n", args[0])
+		return nil
+	}
+	// Stop the scripts before storing, to not let them run while waiting for the database.
+	disabledSources.Set(args[0], true)
+	if err := c.game.storage.DisableSource(ctx, &storage.DisabledSource{
+		Path:       args[0],
+		DisabledBy: c.user.Name,
+		DisabledAt: time.Now().UnixNano(),
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "Disabled %s, /enable-source %s to undo\n", args[0], args[0])
+	return nil
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/zond/juicemud/js"
+	"rogchap.com/v8go"
+)
+
+var (
+	// languageFallbacks are the languages tried, in order, after each language and its more
+	// general forms.
+	languageFallbacks atomic.Pointer[map[string][]string]
+)
+
+func init() {
+	languageFallbacks.Store(&map[string][]string{})
+}
+
+// normalizeLanguage returns language as a lower case tag like "pt-br", without any encoding
+// or modifier like in the locale "pt_BR.UTF-8@euro".
+func normalizeLanguage(language string) string {
+	language, _, _ = strings.Cut(language, "@")
+	language, _, _ = strings.Cut(language, ".")
+	language = strings.ToLower(strings.ReplaceAll(language, "_", "-"))
+	if language == "c" || language == "posix" {
+		return ""
+	}
+	return language
+}
+
+// languageChain returns the languages to look for translations in, in order, for a viewer
+// preferring language: the language itself, its more general forms ("pt-br" is followed by
+// "pt"), and the fallbacks of each of them.
+func languageChain(language string) []string {
+	result := []string{}
+	fallbacks := *languageFallbacks.Load()
+	var add func(string)
+	add = func(language string) {
+		for ; language != ""; language, _, _ = cutLast(language, "-") {
+			if slices.Contains(result, language) {
+				continue
+			}
+			result = append(result, language)
+			for _, fallback := range fallbacks[language] {
+				add(fallback)
+			}
+		}
+	}
+	add(normalizeLanguage(language))
+	return result
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s string, sep string) (string, string, bool) {
+	if index := strings.LastIndex(s, sep); index != -1 {
+		return s[:index], s[index+len(sep):], true
+	}
+	return "", s, false
+}
+
+// sessionLanguage guesses the language of the session from the locale it sent, or returns an
+// empty string if it sent none.
+func sessionLanguage(sess ssh.Session) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		for _, env := range sess.Environ() {
+			if value, found := strings.CutPrefix(env, name+"="); found && value != "" {
+				return normalizeLanguage(value)
+			}
+		}
+	}
+	return ""
+}
+
+// languages returns the languages to show descriptions in to the user, most preferred first.
+func (c *Connection) languages() []string {
+	language := c.user.Language
+	if language == "" {
+		language = sessionLanguage(c.sess)
+	}
+	return languageChain(language)
+}
+
+// addLanguageCallbacks adds `setLanguageFallbacks({language: [fallbacks]})`, which makes
+// viewers preferring a language see translations to the fallbacks when there is none to
+// the language. Descriptions are translated by their `Translations: {language: {Short, Long}}`.
+func addLanguageCallbacks(callbacks js.Callbacks) {
+	callbacks["setLanguageFallbacks"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setLanguageFallbacks takes [Object] arguments")
+		}
+		fallbacks := map[string][]string{}
+		if err := rc.Copy(&fallbacks, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to map[string][]string: %v", args[0], err)
+		}
+		normalized := map[string][]string{}
+		for language, chain := range fallbacks {
+			language = normalizeLanguage(language)
+			for _, fallback := range chain {
+				normalized[language] = append(normalized[language], normalizeLanguage(fallback))
+			}
+		}
+		languageFallbacks.Store(&normalized)
+		return nil
+	}
+}
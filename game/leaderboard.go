@@ -0,0 +1,116 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	topScoreCount = 10
+)
+
+// scoreHolderNames returns the user names of the objects holding scores, or their short
+// descriptions if they aren't users.
+func (g *Game) scoreHolderNames(ctx context.Context, scores []storage.Score) (map[string]string, error) {
+	users, err := g.storage.Users(ctx)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	userNames := map[string]string{}
+	for _, user := range users {
+		userNames[user.Object] = user.Name
+	}
+	result := map[string]string{}
+	for _, score := range scores {
+		if name, found := userNames[score.Object]; found {
+			result[score.Object] = name
+			continue
+		}
+		result[score.Object] = score.Object
+		object, err := g.storage.LoadObject(ctx, score.Object, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if len(object.Descriptions) > 0 && object.Descriptions[0].Short != "" {
+			result[score.Object] = object.Descriptions[0].Short
+		}
+	}
+	return result, nil
+}
+
+func (c *Connection) topCommand(args []string) error {
+	usage := func() error {
+		fmt.Fprintf(c.term, "usage: top, top [board] [%s]\n", strings.Join(storage.ScoreWindows, "|"))
+		return nil
+	}
+	ctx := c.sess.Context()
+	if len(args) == 0 {
+		boards, err := c.game.storage.ScoreBoards(ctx)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if len(boards) == 0 {
+			fmt.Fprintln(c.term, "No leaderboards yet")
+			return nil
+		}
+		fmt.Fprintf(c.term, "Leaderboards: %s\n", strings.Join(boards, ", "))
+		return nil
+	}
+	if len(args) > 2 {
+		return usage()
+	}
+	window := storage.AllTimeScores
+	if len(args) == 2 {
+		if !slices.Contains(storage.ScoreWindows, args[1]) {
+			return usage()
+		}
+		window = args[1]
+	}
+	scores, err := c.game.storage.TopScores(ctx, args[0], window, time.Now(), topScoreCount)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(scores) == 0 {
+		fmt.Fprintf(c.term, "No %s scores on %q\n", window, args[0])
+		return nil
+	}
+	names, err := c.game.scoreHolderNames(ctx, scores)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "%s (%s)\n", args[0], window)
+	t := c.table("#", "Name", "Score")
+	for i, score := range scores {
+		t.AddRow(i+1, names[score.Object], score.Value)
+	}
+	t.Print()
+	return nil
+}
+
+// addLeaderboardCallbacks adds `reportScore(board, value)`, which reports value as the score
+// of the object on board.
+func (g *Game) addLeaderboardCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["reportScore"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsNumber() {
+			return rc.Throw("reportScore takes [string, number] arguments")
+		}
+		if err := g.storage.ReportScore(ctx, args[0].String(), object.Id, args[1].Number(), time.Now()); err != nil {
+			return rc.Throw("trying to report score to %q: %v", args[0].String(), err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/skills"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	skillImprovedEventType = "skillImproved"
+)
+
+// skillImproved is the message of skillImproved events.
+type skillImproved struct {
+	Skill string
+	From  float32
+	To    float32
+}
+
+// useSkill resolves skill of object against a challenge of level by target, and lets object
+// forget and learn from the use according to the definition of skill. Crossing a whole
+// level emits skillImproved to object.
+func (g *Game) useSkill(ctx context.Context, object *structs.Object, skill string, target string, level float32) (skills.Grade, error) {
+	now := time.Now()
+	definition := skills.Skills.Get(skill)
+	if object.Skills == nil {
+		object.Skills = map[string]structs.Skill{}
+	}
+	current := object.Skills[skill]
+	if current.LastUsed != 0 {
+		current.Practical = definition.Forgotten(current.Practical, current.Theoretical, now.Sub(time.Unix(0, current.LastUsed)))
+	}
+	object.Skills[skill] = current
+	challenge := structs.Challenge{
+		Skill: skill,
+		Level: level,
+	}
+	grade := challenge.Resolve(object, &structs.Object{Id: target})
+
+	from := current.Practical
+	current.Practical += definition.Improvement(current.Practical)
+	current.Theoretical = float32(math.Max(float64(current.Theoretical), float64(current.Practical)))
+	current.LastUsed = now.UnixNano()
+	object.Skills[skill] = current
+	if math.Floor(float64(current.Practical)) > math.Floor(float64(from)) {
+		if err := g.emitAny(ctx, g.storage.Queue().After(0), object.Id, skillImprovedEventType, &skillImproved{
+			Skill: skill,
+			From:  from,
+			To:    current.Practical,
+		}); err != nil {
+			return grade, juicemud.WithStack(err)
+		}
+	}
+	return grade, nil
+}
+
+// addLearningCallbacks adds `useSkill(skill, level, targetID?)`, which returns whether the
+// object succeeds with skill against a challenge of level, and improves the skill, and
+// `useSkillGraded(skill, level, targetID?)`, which does the same but returns how well it went,
+// "criticalFailure", "failure", "success" or "criticalSuccess".
+func (g *Game) addLearningCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	use := func(name string, result func(grade skills.Grade) any) func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		return func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+			args := info.Args()
+			if len(args) < 2 || len(args) > 3 || !args[0].IsString() || !args[1].IsNumber() || (len(args) == 3 && !args[2].IsString()) {
+				return rc.Throw("%s takes [string, number, string?] arguments", name)
+			}
+			target := ""
+			if len(args) == 3 {
+				target = args[2].String()
+			}
+			grade, err := g.useSkill(ctx, object, args[0].String(), target, float32(args[1].Number()))
+			if err != nil {
+				return rc.Throw("trying to use %q: %v", args[0].String(), err)
+			}
+			value := result(grade)
+			res, err := rc.JSFromGo(value)
+			if err != nil {
+				return rc.Throw("trying to convert %v to *v8go.Value: %v", value, err)
+			}
+			return res
+		}
+	}
+	callbacks["useSkill"] = use("useSkill", func(grade skills.Grade) any {
+		return grade.Succeeded()
+	})
+	callbacks["useSkillGraded"] = use("useSkillGraded", func(grade skills.Grade) any {
+		return grade.String()
+	})
+}
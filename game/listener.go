@@ -0,0 +1,161 @@
+package game
+
+import (
+	"net"
+	"os"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sshProtocol = "ssh"
+)
+
+// Listener is a port the server accepts connections on, with its own options.
+type Listener struct {
+	Addr string `yaml:"addr"`
+	// Protocol is what the listener speaks, and defaults to, and currently must be, "ssh".
+	Protocol string `yaml:"protocol"`
+	// Allowlist are the IPs and CIDR ranges allowed to connect, or empty to allow everyone.
+	Allowlist []string `yaml:"allowlist"`
+	// Denylist are the IPs and CIDR ranges not allowed to connect, even if in Allowlist.
+	Denylist []string `yaml:"denylist"`
+	// WizardsOnly makes the listener reject users that aren't wizards after they log in.
+	WizardsOnly bool `yaml:"wizardsOnly"`
+	// MOTD is shown to users logging in through the listener.
+	MOTD string `yaml:"motd"`
+	// ProxyProtocol makes the listener read the client address from a PROXY protocol v1 or v2
+	// header, for listeners behind TCP load balancers. It must only be set for listeners
+	// reachable only through the load balancers, since clients can send any header.
+	ProxyProtocol bool `yaml:"proxyProtocol"`
+
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+}
+
+// ListenerDefinitions are the listeners of the server, written in YAML (or JSON, which is
+// valid YAML) in the file given to the server with -listeners.
+//
+//	listeners:
+//	  - addr: 0.0.0.0:15000
+//	    motd: Welcome, adventurer!
+//	  - addr: 127.0.0.1:15001
+//	    allowlist: [127.0.0.1]
+//	    wizardsOnly: true
+type ListenerDefinitions struct {
+	Listeners []*Listener `yaml:"listeners"`
+}
+
+// LoadListeners returns the listeners in the YAML file at path.
+func LoadListeners(path string) ([]*Listener, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return parseListeners(content)
+}
+
+func parseListeners(content []byte) ([]*Listener, error) {
+	definitions := &ListenerDefinitions{}
+	if err := yaml.Unmarshal(content, definitions); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if len(definitions.Listeners) == 0 {
+		return nil, errors.New("no listeners defined")
+	}
+	for _, listener := range definitions.Listeners {
+		if err := listener.Prepare(); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	return definitions.Listeners, nil
+}
+
+// Prepare validates l and parses its allowlist and denylist.
+func (l *Listener) Prepare() error {
+	if l.Addr == "" {
+		return errors.New("listeners need an addr")
+	}
+	if l.Protocol == "" {
+		l.Protocol = sshProtocol
+	}
+	if l.Protocol != sshProtocol {
+		return errors.Errorf("listener %q has protocol %q, but only %q is supported", l.Addr, l.Protocol, sshProtocol)
+	}
+	l.allowed = nil
+	for _, entry := range l.Allowlist {
+		network, err := parseIPRange(entry)
+		if err != nil {
+			return errors.Wrapf(err, "listener %q allows invalid range %q", l.Addr, entry)
+		}
+		l.allowed = append(l.allowed, network)
+	}
+	l.denied = nil
+	for _, entry := range l.Denylist {
+		network, err := parseIPRange(entry)
+		if err != nil {
+			return errors.Wrapf(err, "listener %q denies invalid range %q", l.Addr, entry)
+		}
+		l.denied = append(l.denied, network)
+	}
+	return nil
+}
+
+// Allows returns whether the allowlist and denylist of l allow connections from addr.
+func (l *Listener) Allows(addr net.Addr) bool {
+	if len(l.allowed) == 0 && len(l.denied) == 0 {
+		return true
+	}
+	ip := net.ParseIP(remoteIP(addr))
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.denied {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(l.allowed) == 0 {
+		return true
+	}
+	for _, network := range l.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listen returns a network listener on the address of l.
+func (l *Listener) Listen() (net.Listener, error) {
+	result, err := net.Listen("tcp", l.Addr)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if l.ProxyProtocol {
+		result = proxyListener{Listener: result}
+	}
+	return result, nil
+}
+
+// remoteIP returns the IP part of addr.
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// SessionHandler returns a handler of the sessions of the SSH server of listener.
+func (g *Game) SessionHandler(listener *Listener) ssh.Handler {
+	return func(sess ssh.Session) {
+		if !admits(listener, sess.RemoteAddr()) {
+			return
+		}
+		g.handleSession(sess, listener)
+	}
+}
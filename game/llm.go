@@ -0,0 +1,256 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	llmTimeout           = 30 * time.Second
+	maxLLMPromptSize     = 4000
+	maxLLMResponseSize   = 1 << 16
+	defaultLLMMaxTokens  = 256
+	maxLLMMaxTokens      = 1024
+	llmQuotaWindow       = time.Hour
+	llmFilteredReplyText = "response filtered"
+)
+
+// LLMConfig configures the language model askLLM uses. The endpoint is expected to speak the
+// chat completions protocol most providers and local model servers offer.
+type LLMConfig struct {
+	// Endpoint is the URL of the chat completions endpoint, and askLLM is disabled if it's empty.
+	Endpoint string
+	Model    string
+	APIKey   string
+	// PerObjectQuota is how many requests each object may make per hour.
+	PerObjectQuota int
+	// Quota is how many requests all objects together may make per hour.
+	Quota int
+	// Blocklist are words that prompts and responses may not contain, case insensitively.
+	Blocklist []string
+}
+
+// llmClient performs the language model requests of objects, within the quotas of config.
+type llmClient struct {
+	mutex  sync.Mutex
+	config LLMConfig
+	recent map[string][]time.Time
+	total  []time.Time
+	client *http.Client
+}
+
+func newLLMClient() *llmClient {
+	return &llmClient{
+		recent: map[string][]time.Time{},
+		client: &http.Client{Timeout: llmTimeout},
+	}
+}
+
+// SetLLM configures askLLM, which stays disabled until config has an endpoint.
+func (g *Game) SetLLM(config LLMConfig) {
+	g.llm.mutex.Lock()
+	defer g.llm.mutex.Unlock()
+	blocklist := []string{}
+	for _, word := range config.Blocklist {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			blocklist = append(blocklist, word)
+		}
+	}
+	config.Blocklist = blocklist
+	g.llm.config = config
+}
+
+// withinWindow returns the times in ts that are less than llmQuotaWindow before now.
+func withinWindow(ts []time.Time, now time.Time) []time.Time {
+	result := []time.Time{}
+	for _, t := range ts {
+		if now.Sub(t) < llmQuotaWindow {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// blockedWord returns the first word of blocklist that text contains.
+func blockedWord(blocklist []string, text string) (string, bool) {
+	text = strings.ToLower(text)
+	for _, word := range blocklist {
+		if strings.Contains(text, word) {
+			return word, true
+		}
+	}
+	return "", false
+}
+
+// reserve checks whether objectID may ask prompt, and counts it against the quotas.
+func (l *llmClient) reserve(objectID string, prompt string) (LLMConfig, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.config.Endpoint == "" {
+		return LLMConfig{}, errors.New("askLLM is disabled")
+	}
+	if len(prompt) > maxLLMPromptSize {
+		return LLMConfig{}, errors.Errorf("prompts are at most %d bytes", maxLLMPromptSize)
+	}
+	if word, found := blockedWord(l.config.Blocklist, prompt); found {
+		return LLMConfig{}, errors.Errorf("prompt contains blocked word %q", word)
+	}
+	now := time.Now()
+	l.total = withinWindow(l.total, now)
+	recent := withinWindow(l.recent[objectID], now)
+	if len(recent) == 0 {
+		delete(l.recent, objectID)
+	} else {
+		l.recent[objectID] = recent
+	}
+	if l.config.Quota > 0 && len(l.total) >= l.config.Quota {
+		return LLMConfig{}, errors.Errorf("more than %d requests per hour", l.config.Quota)
+	}
+	if l.config.PerObjectQuota > 0 && len(recent) >= l.config.PerObjectQuota {
+		return LLMConfig{}, errors.Errorf("more than %d requests per hour from %q", l.config.PerObjectQuota, objectID)
+	}
+	l.total = append(l.total, now)
+	l.recent[objectID] = append(recent, now)
+	return l.config, nil
+}
+
+// llmOptions are the options of askLLM.
+type llmOptions struct {
+	System    string
+	MaxTokens int
+}
+
+type llmMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type llmRequest struct {
+	Model     string       `json:"model,omitempty"`
+	Messages  []llmMessage `json:"messages"`
+	MaxTokens int          `json:"max_tokens"`
+}
+
+type llmResponse struct {
+	Choices []struct {
+		Message llmMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// llmReply is the message of the events askLLM emits.
+type llmReply struct {
+	Prompt string
+	Text   string `json:",omitempty"`
+	Error  string `json:",omitempty"`
+}
+
+func (l *llmClient) ask(ctx context.Context, config LLMConfig, prompt string, options llmOptions) (string, error) {
+	request := &llmRequest{
+		Model:     config.Model,
+		MaxTokens: options.MaxTokens,
+	}
+	if options.System != "" {
+		request.Messages = append(request.Messages, llmMessage{Role: "system", Content: options.System})
+	}
+	request.Messages = append(request.Messages, llmMessage{Role: "user", Content: prompt})
+	body, err := goccy.Marshal(request)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxLLMResponseSize))
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("%s replied %v", config.Endpoint, resp.StatusCode)
+	}
+	response := &llmResponse{}
+	if err := goccy.Unmarshal(content, response); err != nil {
+		return "", errors.Wrapf(err, "trying to parse response from %s", config.Endpoint)
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.Errorf("%s replied without choices", config.Endpoint)
+	}
+	text := response.Choices[0].Message.Content
+	if _, found := blockedWord(config.Blocklist, text); found {
+		return "", errors.New(llmFilteredReplyText)
+	}
+	return text, nil
+}
+
+// addLLMCallbacks adds `askLLM(prompt, {System?, MaxTokens?}, event)`, which asks the
+// configured language model prompt in the background and emits event to the object with
+// `{Prompt, Text}`, or `{Prompt, Error}`. It throws when the server has no language model
+// configured, when the prompt contains blocked words, or when the object or the game has used
+// up the requests of the last hour. Responses with blocked words are replaced by errors.
+func (g *Game) addLLMCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["askLLM"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsObject() || !args[2].IsString() {
+			return rc.Throw("askLLM takes [string, Object, string] arguments")
+		}
+		prompt, event := args[0].String(), args[2].String()
+		options := llmOptions{}
+		if err := rc.Copy(&options, args[1]); err != nil {
+			return rc.Throw("trying to copy %v to a &llmOptions{}: %v", args[1], err)
+		}
+		if options.MaxTokens <= 0 {
+			options.MaxTokens = defaultLLMMaxTokens
+		}
+		options.MaxTokens = min(options.MaxTokens, maxLLMMaxTokens)
+		config, err := g.llm.reserve(object.Id, prompt)
+		if err != nil {
+			return rc.Throw("can't ask: %v", err)
+		}
+		objectID := object.Id
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			reply := &llmReply{Prompt: prompt}
+			text, err := g.llm.ask(ctx, config, prompt, options)
+			if err != nil {
+				reply.Error = err.Error()
+			} else {
+				reply.Text = text
+			}
+			message, err := goccy.Marshal(reply)
+			if err != nil {
+				log.Printf("trying to serialize %+v: %v", reply, err)
+				return
+			}
+			at := g.storage.Queue().After(0)
+			if err := g.emitJSON(ctx, at, objectID, event, string(message)); err != nil {
+				log.Printf("trying to emit %s to %s: %v", event, objectID, err)
+				return
+			}
+			traceEmitted(objectID, objectID, event, string(message), at)
+		}()
+		return nil
+	}
+}
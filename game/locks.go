@@ -0,0 +1,202 @@
+package game
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// checkLocks returns an error if the object with the given ID can't be moved to destination,
+// because destination is locked, or because it's in a locked container. Locked rooms can be
+// left, but not entered.
+func (g *Game) checkLocks(ctx context.Context, running *structs.Object, id string, destination string) error {
+	target, err := g.loadObjectOrRunning(ctx, running, destination)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if target.Locked {
+		return errors.Errorf("%q is locked", destination)
+	}
+	object, err := g.loadObjectOrRunning(ctx, running, id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if object.Location == "" {
+		return nil
+	}
+	container, err := g.loadObjectOrRunning(ctx, running, object.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if container.Locked && container.Location != "" {
+		return errors.Errorf("%q is locked", container.Id)
+	}
+	return nil
+}
+
+// changeLock gives the lock of the object with the given ID a new token, so that no existing
+// keys fit it. Returns the new token.
+func (g *Game) changeLock(ctx context.Context, running *structs.Object, id string) (string, error) {
+	token, err := structs.NextLockToken()
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if err := g.modifyObject(ctx, running, id, func(lock *structs.Object) error {
+		lock.LockToken = token
+		return nil
+	}); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return token, nil
+}
+
+// craftKey creates an object from sourcePath in location, fitting the lock of the object with
+// the given ID. Locks without a token get one first.
+func (g *Game) craftKey(ctx context.Context, running *structs.Object, lockID string, sourcePath string, location string) (*structs.Object, error) {
+	lock, err := g.loadObjectOrRunning(ctx, running, lockID)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	token := lock.LockToken
+	if token == "" {
+		if token, err = g.changeLock(ctx, running, lockID); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	key, err := g.createObjectFromSourceLater(ctx, sourcePath, location, "")
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.modifyObject(ctx, running, key.Id, func(key *structs.Object) error {
+		key.KeyToken = token
+		return nil
+	}); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return key, nil
+}
+
+// findKey returns the ID of the first object carried by the object with the given ID, in ID
+// order, that fits the lock of the object with lockID, or an empty string if none does.
+func (g *Game) findKey(ctx context.Context, running *structs.Object, holderID string, lockID string) (string, error) {
+	lock, err := g.loadObjectOrRunning(ctx, running, lockID)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	holder, err := g.loadObjectOrRunning(ctx, running, holderID)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	carried, err := g.storage.LoadObjects(ctx, holder.Content, nil)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	result := ""
+	for id, key := range carried {
+		if key.Fits(lock) && (result == "" || id < result) {
+			result = id
+		}
+	}
+	return result, nil
+}
+
+// addLockCallbacks adds lock and key handling, for doors and containers:
+//   - `craftKey(lockID, sourcePath, location)` creates a key fitting the lock of lockID, and
+//     returns a promise of its ID.
+//   - `changeLock(lockID)` makes all existing keys to the lock stop fitting it.
+//   - `fitsLock(keyID, lockID)` returns whether the key fits the lock.
+//   - `findKey(holderID, lockID)` returns the ID of a key to the lock carried by the holder,
+//     or null.
+//   - `setLocked(lockID, locked)` and `isLocked(lockID)`. Nothing can be moved into locked
+//     objects, or out of locked containers.
+//
+// Keys fit locks by tokens that only the engine knows, so copying the state or descriptions
+// of a key doesn't make another key.
+func (g *Game) addLockCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["craftKey"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsString() || !args[2].IsString() {
+			return rc.Throw("craftKey takes [string, string, string] arguments")
+		}
+		key, err := g.craftKey(ctx, object, args[0].String(), args[1].String(), args[2].String())
+		if err != nil {
+			return rc.Reject("trying to craft a key to %q from %q in %q: %v", args[0].String(), args[1].String(), args[2].String(), err)
+		}
+		return rc.Resolve(rc.String(key.Id))
+	}
+	callbacks["changeLock"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("changeLock takes [string] arguments")
+		}
+		if _, err := g.changeLock(ctx, object, args[0].String()); err != nil {
+			return rc.Throw("trying to change the lock of %q: %v", args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["fitsLock"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("fitsLock takes [string, string] arguments")
+		}
+		key, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		lock, err := g.loadObjectOrRunning(ctx, object, args[1].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[1].String(), err)
+		}
+		res, err := rc.JSFromGo(key.Fits(lock))
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", key.Fits(lock), err)
+		}
+		return res
+	}
+	callbacks["findKey"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("findKey takes [string, string] arguments")
+		}
+		key, err := g.findKey(ctx, object, args[0].String(), args[1].String())
+		if err != nil {
+			return rc.Throw("trying to find a key to %q carried by %q: %v", args[1].String(), args[0].String(), err)
+		}
+		if key == "" {
+			return v8go.Null(rc.Context().Isolate())
+		}
+		return rc.String(key)
+	}
+	callbacks["setLocked"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsBoolean() {
+			return rc.Throw("setLocked takes [string, boolean] arguments")
+		}
+		if err := g.modifyObject(ctx, object, args[0].String(), func(lock *structs.Object) error {
+			lock.Locked = args[1].Boolean()
+			return nil
+		}); err != nil {
+			return rc.Throw("trying to lock %q: %v", args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["isLocked"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("isLocked takes [string] arguments")
+		}
+		lock, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(lock.Locked)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", lock.Locked, err)
+		}
+		return res
+	}
+}
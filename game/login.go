@@ -0,0 +1,52 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zond/juicemud"
+)
+
+func formatAlert(message string) string {
+	return fmt.Sprintf("!!! %s !!!", message)
+}
+
+// alertWizards logs message and shows it to all connected wizards.
+func (g *Game) alertWizards(ctx context.Context, message string) error {
+	log.Print(message)
+	for c := range envByObjectID.Values() {
+		if wizard, err := g.storage.UserAccessToGroup(ctx, c.user, wizardsGroup); err != nil {
+			return juicemud.WithStack(err)
+		} else if wizard {
+			fmt.Fprintln(c.term, c.wrap(formatAlert(message)))
+		}
+	}
+	return nil
+}
+
+// recordLogin shows when and from where the user last logged in, records this login, and
+// alerts the wizards when an owner logs in from an IP they haven't used before.
+func (c *Connection) recordLogin() error {
+	ctx := c.sess.Context()
+	ip := remoteIP(c.sess.RemoteAddr())
+	now := time.Now()
+	c.lastLogin = c.user.LastLogin
+	if c.user.LastLogin != 0 {
+		fmt.Fprintf(c.term, "Last login from %s at %s\n\n", c.user.LastLoginIP, time.Unix(0, c.user.LastLogin).Format(time.DateTime))
+	}
+	c.user.LastLogin = now.UnixNano()
+	c.user.LastLoginIP = ip
+	if err := c.game.storage.StoreUser(ctx, c.user, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	newIP, err := c.game.storage.RecordLogin(ctx, c.user.Id, ip, now.UnixNano())
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if newIP && c.user.Owner {
+		return juicemud.WithStack(c.game.alertWizards(ctx, fmt.Sprintf("Owner %q logged in from the new IP %s", c.user.Name, ip)))
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package mapping lays out the room graph around a room on a grid, using the names of
+// the exits to decide where the rooms go, and renders it as ASCII or DOT.
+package mapping
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+type offset struct {
+	x, y int
+}
+
+// gridOffset returns the offset on the map grid, where north is towards negative y, of
+// exits with horizontal compass direction names.
+func gridOffset(name string) (offset, bool) {
+	p, found := structs.Direction(name)
+	if !found || p.Z != 0 {
+		return offset{}, false
+	}
+	return offset{int(p.X), int(-p.Y)}, true
+}
+
+// Loader loads the objects with the given IDs, skipping missing ones.
+type Loader func(ids map[string]bool) (map[string]*structs.Object, error)
+
+type Exit struct {
+	Name string
+	To   string
+}
+
+type Room struct {
+	Id    string
+	Short string
+	Exits []Exit
+	// Placed is true if the room got a grid position.
+	Placed bool
+	X, Y   int
+}
+
+type Graph struct {
+	Center string
+	Rooms  map[string]*Room
+}
+
+func exitName(exit structs.Exit) string {
+	if len(exit.Descriptions) == 0 {
+		return ""
+	}
+	return strings.ToLower(exit.Descriptions[0].Short)
+}
+
+// Build loads all rooms within radius exit hops from center and places them on a grid,
+// with center at 0, 0 and north towards negative Y.
+//
+// If center has coordinates, rooms with coordinates on the same level are placed according
+// to them. Other rooms are placed using the compass direction names of the exits leading to
+// them, and left unplaced if reached only via other exits or if their position is taken.
+func Build(center string, radius int, load Loader) (*Graph, error) {
+	result := &Graph{
+		Center: center,
+		Rooms:  map[string]*Room{},
+	}
+	positions := map[string]offset{center: {}}
+	occupied := map[offset]bool{{}: true}
+	var centerPoint *structs.Point
+	level := map[string]bool{center: true}
+	for depth := 0; depth <= radius && len(level) > 0; depth++ {
+		objects, err := load(level)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		ids := make(sort.StringSlice, 0, len(objects))
+		for id := range objects {
+			ids = append(ids, id)
+		}
+		sort.Sort(ids)
+		nextLevel := map[string]bool{}
+		for _, id := range ids {
+			object := objects[id]
+			room := &Room{
+				Id: id,
+			}
+			if len(object.Descriptions) > 0 {
+				room.Short = object.Descriptions[0].Short
+			}
+			if id == center {
+				if p, found := object.Point(); found {
+					centerPoint = &p
+				}
+			}
+			if p, found := object.Point(); found && centerPoint != nil && p.Z == centerPoint.Z {
+				pos := offset{int(p.X - centerPoint.X), int(centerPoint.Y - p.Y)}
+				positions[id] = pos
+				occupied[pos] = true
+			}
+			if pos, found := positions[id]; found {
+				room.Placed, room.X, room.Y = true, pos.x, pos.y
+			}
+			result.Rooms[id] = room
+			for _, exit := range object.Exits {
+				room.Exits = append(room.Exits, Exit{Name: exitName(exit), To: exit.Destination})
+				if _, found := level[exit.Destination]; !found && result.Rooms[exit.Destination] == nil && depth < radius {
+					nextLevel[exit.Destination] = true
+				}
+			}
+		}
+		for _, id := range ids {
+			room := result.Rooms[id]
+			if !room.Placed {
+				continue
+			}
+			for _, exit := range room.Exits {
+				dir, found := gridOffset(exit.Name)
+				if !found || !nextLevel[exit.To] {
+					continue
+				}
+				if _, found := positions[exit.To]; found {
+					continue
+				}
+				pos := offset{room.X + dir.x, room.Y + dir.y}
+				if occupied[pos] {
+					continue
+				}
+				occupied[pos] = true
+				positions[exit.To] = pos
+			}
+		}
+		level = nextLevel
+	}
+	return result, nil
+}
+
+// ASCII renders the placed rooms as a grid of "[ ]", with the center room as "[*]", rooms with
+// up or down exits as "[^]", "[v]" or "[x]", and the compass exits between them as lines.
+func (g *Graph) ASCII() string {
+	minX, minY, maxX, maxY := 0, 0, 0, 0
+	for _, room := range g.Rooms {
+		if room.Placed {
+			minX, minY = min(minX, room.X), min(minY, room.Y)
+			maxX, maxY = max(maxX, room.X), max(maxY, room.Y)
+		}
+	}
+	width := (maxX-minX)*4 + 3
+	height := (maxY-minY)*2 + 1
+	grid := make([][]rune, height)
+	for i := range grid {
+		grid[i] = []rune(strings.Repeat(" ", width))
+	}
+	set := func(col, row int, r rune) {
+		if row >= 0 && row < height && col >= 0 && col < width {
+			grid[row][col] = r
+		}
+	}
+	for _, room := range g.Rooms {
+		if !room.Placed {
+			continue
+		}
+		col, row := (room.X-minX)*4, (room.Y-minY)*2
+		up, down := false, false
+		for _, exit := range room.Exits {
+			switch exit.Name {
+			case "u", "up":
+				up = true
+			case "d", "down":
+				down = true
+			}
+			dir, found := gridOffset(exit.Name)
+			if !found {
+				continue
+			}
+			switch dir {
+			case offset{1, 0}:
+				set(col+3, row, '-')
+			case offset{-1, 0}:
+				set(col-1, row, '-')
+			case offset{0, 1}:
+				set(col+1, row+1, '|')
+			case offset{0, -1}:
+				set(col+1, row-1, '|')
+			case offset{1, 1}:
+				set(col+3, row+1, '\\')
+			case offset{-1, -1}:
+				set(col-1, row-1, '\\')
+			case offset{-1, 1}:
+				set(col-1, row+1, '/')
+			case offset{1, -1}:
+				set(col+3, row-1, '/')
+			}
+		}
+		marker := ' '
+		switch {
+		case room.Id == g.Center:
+			marker = '*'
+		case up && down:
+			marker = 'x'
+		case up:
+			marker = '^'
+		case down:
+			marker = 'v'
+		}
+		set(col, row, '[')
+		set(col+1, row, marker)
+		set(col+2, row, ']')
+	}
+	lines := make([]string, len(grid))
+	for i := range grid {
+		lines[i] = strings.TrimRight(string(grid[i]), " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// DOT renders all rooms, placed or not, as a Graphviz digraph with the exits as edges.
+func (g *Graph) DOT() string {
+	ids := make(sort.StringSlice, 0, len(g.Rooms))
+	for id := range g.Rooms {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	builder := &strings.Builder{}
+	fmt.Fprintln(builder, "digraph rooms {")
+	for _, id := range ids {
+		room := g.Rooms[id]
+		attrs := fmt.Sprintf("label=%q", fmt.Sprintf("%s\n#%s", room.Short, room.Id))
+		if room.Placed {
+			attrs += fmt.Sprintf(" pos=\"%d,%d!\"", room.X, -room.Y)
+		}
+		if id == g.Center {
+			attrs += " style=bold"
+		}
+		fmt.Fprintf(builder, "  %q [%s];\n", id, attrs)
+	}
+	for _, id := range ids {
+		for _, exit := range g.Rooms[id].Exits {
+			if _, found := g.Rooms[exit.To]; found {
+				fmt.Fprintf(builder, "  %q -> %q [label=%q];\n", id, exit.To, exit.Name)
+			}
+		}
+	}
+	fmt.Fprintln(builder, "}")
+	return builder.String()
+}
@@ -0,0 +1,52 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/zond/juicemud/structs"
+)
+
+func exit(name string, to string) structs.Exit {
+	return structs.Exit{
+		Descriptions: []structs.Description{{Short: name}},
+		Destination:  to,
+	}
+}
+
+func TestBuild(t *testing.T) {
+	world := map[string]*structs.Object{
+		"a": {Id: "a", Exits: []structs.Exit{exit("east", "b"), exit("s", "c")}},
+		"b": {Id: "b", Exits: []structs.Exit{exit("west", "a"), exit("up", "d")}},
+		"c": {Id: "c", Exits: []structs.Exit{exit("north", "a")}},
+		"d": {Id: "d", Exits: []structs.Exit{exit("down", "b")}},
+	}
+	loaded := map[string]int{}
+	graph, err := Build("a", 1, func(ids map[string]bool) (map[string]*structs.Object, error) {
+		result := map[string]*structs.Object{}
+		for id := range ids {
+			loaded[id]++
+			if object, found := world[id]; found {
+				result[id] = object
+			}
+		}
+		return result, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := graph.Rooms["d"]; found {
+		t.Errorf("got d, want it outside radius")
+	}
+	for id, count := range loaded {
+		if count != 1 {
+			t.Errorf("loaded %q %v times, want 1", id, count)
+		}
+	}
+	if b := graph.Rooms["b"]; !b.Placed || b.X != 1 || b.Y != 0 {
+		t.Errorf("got %+v, want b at 1, 0", b)
+	}
+	want := "[*]-[^]\n |\n[ ]\n"
+	if got := graph.ASCII(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+var (
+	ordinalWords = map[string]int{
+		"first":   1,
+		"second":  2,
+		"third":   3,
+		"fourth":  4,
+		"fifth":   5,
+		"sixth":   6,
+		"seventh": 7,
+		"eighth":  8,
+		"ninth":   9,
+		"tenth":   10,
+	}
+)
+
+// parseOrdinal returns the number of an ordinal like "second", "2nd" or "2", or 0 if word isn't
+// one.
+func parseOrdinal(word string) int {
+	if n, found := ordinalWords[word]; found {
+		return n
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if trimmed, found := strings.CutSuffix(word, suffix); found {
+			word = trimmed
+			break
+		}
+	}
+	if n, err := strconv.Atoi(word); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// parseMatchPhrase returns the ordinal a phrase like "second red orb" or "2.orb" starts with,
+// or 0 if it has none, and the keywords of the rest of it.
+func parseMatchPhrase(phrase string) (int, []string) {
+	keywords := storage.Keywords(phrase)
+	if len(keywords) > 1 {
+		if ordinal := parseOrdinal(keywords[0]); ordinal > 0 {
+			return ordinal, keywords[1:]
+		}
+	}
+	return 0, keywords
+}
+
+// matchObjects returns the objects, in ID order, with a description detected by viewer whose
+// short description and tags contain all keywords of phrase. Tags thus work as adjectives not
+// mentioned in the short description. If phrase starts with an ordinal, like "second red orb",
+// only that one of the matches is returned.
+func matchObjects(viewer *structs.Object, objects map[string]*structs.Object, phrase string) []*structs.Object {
+	ordinal, keywords := parseMatchPhrase(phrase)
+	if len(keywords) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	result := []*structs.Object{}
+	for _, id := range ids {
+		object := objects[id]
+		if !object.Detectable(viewer) {
+			continue
+		}
+		desc := structs.Descriptions(object.Descriptions).Detect(object, viewer)
+		if desc == nil {
+			continue
+		}
+		if containsKeywords(strings.Join(append([]string{desc.Short}, desc.Tags...), " "), keywords) {
+			result = append(result, object)
+		}
+	}
+	if ordinal > 0 {
+		if ordinal > len(result) {
+			return nil
+		}
+		return result[ordinal-1 : ordinal]
+	}
+	return result
+}
+
+// matchObject returns the first of matchObjects(viewer, objects, name), or nil if there is none.
+func matchObject(viewer *structs.Object, objects map[string]*structs.Object, name string) *structs.Object {
+	if matches := matchObjects(viewer, objects, name); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// containsKeywords returns whether short contains all keywords.
+func containsKeywords(short string, keywords []string) bool {
+	described := storage.Keywords(short)
+	return !slices.ContainsFunc(keywords, func(keyword string) bool {
+		return !slices.Contains(described, keyword)
+	})
+}
+
+// addMatchCallbacks adds `matchObjects(phrase, candidateIDs)`, which returns the IDs of the
+// candidates the object would mean by a phrase like "second red orb", using the same matching
+// as the commands of users.
+func (g *Game) addMatchCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["matchObjects"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsArray() {
+			return rc.Throw("matchObjects takes [string, Array] arguments")
+		}
+		ids := []string{}
+		if err := rc.Copy(&ids, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to []string: %v", args[1], err)
+		}
+		candidates := map[string]*structs.Object{}
+		for _, id := range ids {
+			candidate, err := g.loadObjectOrRunning(ctx, object, id)
+			if err != nil {
+				return rc.Throw("trying to load %q: %v", id, err)
+			}
+			candidates[id] = candidate
+		}
+		result := []string{}
+		for _, match := range matchObjects(object, candidates, args[0].String()) {
+			result = append(result, match.Id)
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
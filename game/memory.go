@@ -0,0 +1,64 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	defaultLargestStates = 20
+)
+
+// memoryCommand handles `/stats memory [count]`, showing the heap usage of each isolate and
+// the count objects with the largest states.
+func (c *Connection) memoryCommand(args []string) error {
+	limit := defaultLargestStates
+	if len(args) > 0 {
+		var err error
+		if limit, err = strconv.Atoi(args[0]); err != nil || limit <= 0 {
+			fmt.Fprintln(c.term, "usage: /stats memory [count]")
+			return nil
+		}
+	}
+	t := c.table("Isolate", "Used heap", "Total heap", "Heap limit", "Malloced", "External")
+	for index, stats := range js.GetHeapStatistics() {
+		t.AddRow(index, stats.UsedHeapSize, stats.TotalHeapSize, stats.HeapSizeLimit, stats.MallocedMemory, stats.ExternalMemory)
+	}
+	t.Print()
+	fmt.Fprintln(c.term)
+	sizes, err := c.game.storage.LargestStates(c.sess.Context(), limit)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	t = c.table("Object", "Source", "State bytes")
+	for _, size := range sizes {
+		t.AddRow(size.Object, size.SourcePath, size.Size)
+	}
+	t.Print()
+	return nil
+}
+
+// addMemoryCallbacks adds `getStateSize()`, returning the size in bytes of the state of the
+// object as it would be stored now, so objects can notice their state growing without bounds.
+func addMemoryCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["getStateSize"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		state, err := rc.Context().Global().Get("state")
+		if err != nil {
+			return rc.Throw("trying to get state of %q: %v", object.Id, err)
+		}
+		serialized, err := v8go.JSONStringify(rc.Context(), state)
+		if err != nil {
+			return rc.Throw("trying to serialize state of %q: %v", object.Id, err)
+		}
+		res, err := rc.JSFromGo(len(serialized))
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", len(serialized), err)
+		}
+		return res
+	}
+}
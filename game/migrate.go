@@ -0,0 +1,66 @@
+package game
+
+import (
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// stateMigration is the state version a source declares, and how it migrates older states.
+type stateMigration struct {
+	declared bool
+	version  int64
+	migrate  *v8go.Function
+}
+
+// beforeCall migrates the state of object, if the source declared another state version than
+// the object has, before any callback sees it.
+func (m *stateMigration) beforeCall(object *structs.Object) func(rc *js.RunContext) error {
+	return func(rc *js.RunContext) error {
+		if !m.declared || m.version == object.StateVersion || m.migrate == nil {
+			return nil
+		}
+		state, err := rc.State()
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		oldVersion, err := rc.JSFromGo(object.StateVersion)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		migrated, err := m.migrate.Call(rc.Context().Global(), oldVersion, state)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if migrated != nil && !migrated.IsUndefined() {
+			return juicemud.WithStack(rc.SetState(migrated))
+		}
+		return nil
+	}
+}
+
+// addMigrationCallbacks adds `setStateVersion(version, onMigrate?)`, which declares the
+// version of the state format of the source. When an object with a state of another version
+// runs, `onMigrate(oldVersion, state)` gets to convert the state before any callback runs, by
+// changing it or returning a replacement. If the migration throws, the run fails and the
+// state is kept as it was. Objects start at version 0.
+func addMigrationCallbacks(migration *stateMigration, callbacks js.Callbacks) {
+	callbacks["setStateVersion"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsNumber() || (len(args) == 2 && !args[1].IsFunction()) {
+			return rc.Throw("setStateVersion takes [number, function?] arguments")
+		}
+		migration.declared = true
+		migration.version = args[0].Integer()
+		migration.migrate = nil
+		if len(args) == 2 {
+			migrate, err := args[1].AsFunction()
+			if err != nil {
+				return rc.Throw("trying to convert %v to function: %v", args[1], err)
+			}
+			migration.migrate = migrate
+		}
+		return nil
+	}
+}
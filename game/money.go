@@ -0,0 +1,91 @@
+package game
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// transferMoney atomically moves amount money between the objects with the given IDs. The
+// running object, if involved, gets the same change, since it's stored when the run finishes.
+func (g *Game) transferMoney(ctx context.Context, running *structs.Object, from string, to string, amount int64) error {
+	others := []string{}
+	for _, id := range []string{from, to} {
+		if running == nil || id != running.Id {
+			others = append(others, id)
+		}
+	}
+	slices.Sort(others)
+	for _, id := range others {
+		jsContextLocks.Lock(id)
+		defer jsContextLocks.Unlock(id)
+	}
+	if running != nil && from == running.Id && running.Money < amount {
+		return errors.Wrapf(storage.ErrInsufficientFunds, "%q has %v", from, running.Money)
+	}
+	if err := g.storage.TransferMoney(ctx, from, to, amount); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if running != nil {
+		switch running.Id {
+		case from:
+			running.Money -= amount
+		case to:
+			running.Money += amount
+		}
+	}
+	return nil
+}
+
+// addMoneyCallbacks adds `getMoney(objectID)`, `giveMoney(objectID, amount)` which moves amount
+// money from the object to another, and `createMoney(objectID, amount)` which adds amount, or
+// removes it if negative, from nowhere.
+func (g *Game) addMoneyCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["getMoney"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getMoney takes [string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(holder.Money)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", holder.Money, err)
+		}
+		return res
+	}
+	callbacks["giveMoney"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsNumber() {
+			return rc.Throw("giveMoney takes [string, number] arguments")
+		}
+		if err := g.transferMoney(ctx, object, object.Id, args[0].String(), args[1].Integer()); err != nil {
+			return rc.Throw("trying to give %v to %q: %v", args[1].Integer(), args[0].String(), err)
+		}
+		return nil
+	}
+	callbacks["createMoney"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsNumber() {
+			return rc.Throw("createMoney takes [string, number] arguments")
+		}
+		if err := g.modifyObject(ctx, object, args[0].String(), func(target *structs.Object) error {
+			if target.Money+args[1].Integer() < 0 {
+				return errors.Wrapf(storage.ErrInsufficientFunds, "%q has %v", target.Id, target.Money)
+			}
+			target.Money += args[1].Integer()
+			return nil
+		}); err != nil {
+			return rc.Throw("trying to create %v for %q: %v", args[1].Integer(), args[0].String(), err)
+		}
+		return nil
+	}
+}
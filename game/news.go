@@ -0,0 +1,155 @@
+package game
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+)
+
+const (
+	motdSource = "/motd.txt"
+	// newsListed is how many of the latest news entries `news` lists.
+	newsListed = 20
+)
+
+// motd returns the message of the day in motdSource, or empty if there is none.
+func (g *Game) motd(ctx context.Context) (string, error) {
+	source, _, err := g.storage.LoadSource(ctx, motdSource)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return strings.TrimSpace(string(source)), nil
+}
+
+// showLoginNews shows the message of the day and how many news entries were posted since the
+// user last logged in.
+func (c *Connection) showLoginNews() error {
+	motd, err := c.game.motd(c.sess.Context())
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if motd != "" {
+		fmt.Fprintf(c.term, "%s\n\n", c.wrap(motd))
+	}
+	unread, err := c.game.storage.CountNewsSince(c.sess.Context(), c.lastLogin)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if unread > 0 {
+		fmt.Fprintf(c.term, "There are %d news since you last logged in, type `news` to read them.\n\n", unread)
+	}
+	return nil
+}
+
+// newsCommand lists the latest news, flagging those posted since the user last logged in, or
+// shows the entry with the ID in args.
+func (c *Connection) newsCommand(args []string) error {
+	if len(args) == 0 {
+		entries, err := c.game.storage.ListNews(c.sess.Context(), newsListed)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintln(c.term, "No news")
+			return nil
+		}
+		t := c.table("Id", "Posted", "New", "Title")
+		for _, entry := range entries {
+			unread := ""
+			if entry.Posted > c.lastLogin {
+				unread = "*"
+			}
+			t.AddRow(entry.Id, time.Unix(0, entry.Posted).Format(time.DateTime), unread, entry.Title)
+		}
+		t.Print()
+		return nil
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if len(args) != 1 || err != nil {
+		fmt.Fprintln(c.term, "usage: news [id?]")
+		return nil
+	}
+	entry, err := c.game.storage.LoadNews(c.sess.Context(), id)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(c.term, "No news %v\n", id)
+		return nil
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "%s\nPosted by %s at %s\n\n%s\n", entry.Title, entry.Author, time.Unix(0, entry.Posted).Format(time.DateTime), c.wrap(entry.Body))
+	return nil
+}
+
+// readNewsBody reads lines of news body until a line with only a period.
+func (c *Connection) readNewsBody() (string, error) {
+	fmt.Fprintln(c.term, "Enter the news, end with a line with only a period:")
+	lines := []string{}
+	for {
+		line, err := c.term.ReadLine()
+		if err != nil {
+			return "", juicemud.WithStack(err)
+		}
+		if line == "." {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// newsEditCommand posts, edits and removes news entries.
+func (c *Connection) newsEditCommand(args []string) error {
+	flags := flag.NewFlagSet("/news-edit", flag.ContinueOnError)
+	flags.SetOutput(c.term)
+	edit := flags.Int64("id", 0, "Replace the news entry with this ID")
+	remove := flags.Int64("remove", 0, "Remove the news entry with this ID")
+	if err := flags.Parse(args); err != nil {
+		return nil
+	}
+	if *remove != 0 {
+		if removed, err := c.game.storage.RemoveNews(c.sess.Context(), *remove); err != nil {
+			return juicemud.WithStack(err)
+		} else if removed {
+			fmt.Fprintf(c.term, "Removed news %v\n", *remove)
+		} else {
+			fmt.Fprintf(c.term, "No news %v\n", *remove)
+		}
+		return nil
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(c.term, "usage: /news-edit [-id id] [title], /news-edit -remove [id]")
+		return nil
+	}
+	entry := &storage.News{}
+	if *edit != 0 {
+		var err error
+		if entry, err = c.game.storage.LoadNews(c.sess.Context(), *edit); errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(c.term, "No news %v\n", *edit)
+			return nil
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	body, err := c.readNewsBody()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	entry.Title = strings.Join(flags.Args(), " ")
+	entry.Body = body
+	entry.Author = c.user.Name
+	entry.Posted = time.Now().UnixNano()
+	if err := c.game.storage.StoreNews(c.sess.Context(), entry); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "Posted news %v\n", entry.Id)
+	return nil
+}
@@ -0,0 +1,229 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/digest"
+	"github.com/zond/juicemud/storage"
+
+	goccy "github.com/goccy/go-json"
+)
+
+// PlayerExport is everything the server stores about a player, for them to keep or to move
+// their account to another server. The server has no mail system, so there is no mail to export.
+type PlayerExport struct {
+	// User is the user record, without password hash.
+	User storage.User
+	// Logins are the IPs the user has logged in from.
+	Logins []storage.UserLogin
+	// Achievements are the achievements the object of the user has unlocked.
+	Achievements []storage.AchievementUnlock
+	// Recordings are the recorded sessions of the user.
+	Recordings []RecordedSession
+	// APITokens are the API tokens of the user, without the hashes of their secrets.
+	APITokens []storage.APIToken
+	// Archive is the object of the user, with its titles and the nicknames it gave others, and
+	// everything it carries.
+	Archive *Archive
+	// Vault is the bank vault of the object of the user and everything in it, or nil if it has
+	// none.
+	Vault *Archive
+}
+
+// RecordedSession is a recorded session of a user, with its output.
+type RecordedSession struct {
+	Recording storage.Recording
+	Frames    []storage.RecordingFrame
+}
+
+// exportPlayer returns everything stored about user.
+func (g *Game) exportPlayer(ctx context.Context, user *storage.User) (*PlayerExport, error) {
+	result := &PlayerExport{User: *user}
+	result.User.PasswordHash = ""
+	var err error
+	if result.Logins, err = g.storage.UserLogins(ctx, user.Id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if result.Achievements, err = g.storage.AchievementUnlocks(ctx, user.Object); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	recordings, err := g.storage.Recordings(ctx, user)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	for _, recording := range recordings {
+		frames, err := g.storage.RecordingFrames(ctx, recording.Id)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		result.Recordings = append(result.Recordings, RecordedSession{Recording: recording, Frames: frames})
+	}
+	if result.APITokens, err = g.storage.APITokens(ctx, user); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	for i := range result.APITokens {
+		result.APITokens[i].Hash = ""
+	}
+	if result.Archive, err = g.exportArchive(ctx, user.Object, -1); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if _, err := g.storage.LoadObject(ctx, vaultID(user.Object), nil); err == nil {
+		if result.Vault, err = g.exportArchive(ctx, vaultID(user.Object), -1); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// importPlayer creates a user, with passwordHash, and its object in genesis from export.
+// Achievements not defined in this world are skipped, API tokens are dropped since their secrets
+// aren't exported, and the login history starts over. The user is created first, so that
+// importing the same export again fails before creating anything, and everything created is
+// removed if the import fails.
+func (g *Game) importPlayer(ctx context.Context, export *PlayerExport, passwordHash string) (*storage.User, error) {
+	if export.Archive == nil {
+		return nil, errors.New("export has no archive")
+	}
+	if err := export.Archive.validate(); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if export.Vault != nil {
+		if err := export.Vault.validate(); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	if _, err := g.storage.LoadUser(ctx, export.User.Name); err == nil {
+		return nil, errors.Errorf("user %q already exists", export.User.Name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, juicemud.WithStack(err)
+	}
+	user := export.User
+	user.Id = 0
+	user.Owner = false
+	user.PasswordHash = passwordHash
+	user.Object = ""
+	if err := g.storage.StoreUser(ctx, &user, false); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if err := g.importPlayerData(ctx, export, &user); err != nil {
+		err = rollback(err, g.storage.DeleteUser(ctx, &user))
+		if user.Object != "" {
+			err = rollback(err, g.tearDown(ctx, user.Object))
+			err = rollback(err, g.tearDown(ctx, vaultID(user.Object)))
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// importPlayerData creates the object, achievement unlocks, vault and recordings of the newly
+// created user from export, and connects the object to user.
+func (g *Game) importPlayerData(ctx context.Context, export *PlayerExport, user *storage.User) error {
+	object, err := g.importArchive(ctx, export.Archive, genesisID)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	user.Object = object.Id
+	if err := g.storage.StoreUser(ctx, user, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, unlock := range export.Achievements {
+		if _, _, err := g.storage.GrantAchievement(ctx, object.Id, unlock.Achievement); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return juicemud.WithStack(err)
+		}
+	}
+	if export.Vault != nil {
+		vault, err := g.loadVault(ctx, object.Id)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		for _, content := range export.Vault.contents() {
+			if _, err := g.importArchive(ctx, content, vault.Id); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+	}
+	// Recordings are exported most recent first.
+	for i := len(export.Recordings) - 1; i >= 0; i-- {
+		recording := export.Recordings[i].Recording
+		recording.Id = 0
+		recording.User = user.Id
+		if err := g.storage.CreateRecording(ctx, &recording); err != nil {
+			return juicemud.WithStack(err)
+		}
+		frames := slices.Clone(export.Recordings[i].Frames)
+		for j := range frames {
+			frames[j].Id = 0
+			frames[j].Recording = recording.Id
+		}
+		if err := g.storage.AppendRecordingFrames(ctx, frames); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// exportMeCommand shows everything stored about the user as JSON.
+func (c *Connection) exportMeCommand() error {
+	export, err := c.game.exportPlayer(c.sess.Context(), c.user)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	js, err := goccy.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "%s\n\nThis is everything stored about you. To move to another server, save it and send it to an owner there.\n", js)
+	return nil
+}
+
+// importPlayerCommand handles `/import-player path`, which creates the user in the player
+// export at path with a new password, for owners only.
+func (c *Connection) importPlayerCommand(args []string) error {
+	if !c.user.Owner {
+		fmt.Fprintln(c.term, "Only owners can import players.")
+		return nil
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(c.term, "usage: /import-player [path]")
+		return nil
+	}
+	js, _, err := c.game.storage.LoadSource(c.sess.Context(), args[0])
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	export := &PlayerExport{}
+	if err := goccy.Unmarshal(js, export); err != nil {
+		return errors.Wrapf(err, "%q is not a player export", args[0])
+	}
+	if export.User.Name == "" {
+		return errors.Errorf("%q is not a player export", args[0])
+	}
+	fmt.Fprintf(c.term, "Enter new password for %q:\n", export.User.Name)
+	password, err := c.term.ReadPassword("> ")
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, "Repeat new password:")
+	verification, err := c.term.ReadPassword("> ")
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if password != verification {
+		fmt.Fprintln(c.term, "Passwords don't match!")
+		return nil
+	}
+	user, err := c.game.importPlayer(c.sess.Context(), export, digest.ComputeHA1(export.User.Name, juicemud.DAVAuthRealm, password))
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "Imported %q as #%s\n", user.Name, user.Object)
+	return nil
+}
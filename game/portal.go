@@ -0,0 +1,198 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	portalEventType          = "portal"
+	portalDepartureEventType = "portalDeparture"
+	portalArrivalEventType   = "portalArrival"
+	// maxTeleports is how many portals an object can pass through within teleportWindow, so
+	// that portals leading into each other can't bounce objects around forever.
+	maxTeleports   = 3
+	teleportWindow = 10 * time.Second
+)
+
+var (
+	// teleports are the recent times each object passed through a portal, by object ID.
+	teleports = juicemud.NewSyncMap[string, *[]time.Time]()
+)
+
+// portalQuery is the message of portal calls, which reply with the ID of the destination of
+// Traveller, or null to refuse it.
+type portalQuery struct {
+	Traveller string
+	Portal    string
+}
+
+// portalTransit is the message of portalDeparture and portalArrival events.
+type portalTransit struct {
+	Object      string
+	Portal      string
+	Source      string
+	Destination string
+}
+
+// recordTeleport records that the object with the given ID passes through a portal at now, or
+// returns an error if it already passed through maxTeleports within teleportWindow.
+func recordTeleport(id string, now time.Time) error {
+	var err error
+	teleports.WithLock(id, func() {
+		recent := []time.Time{}
+		if previous := teleports.Get(id); previous != nil {
+			for _, at := range *previous {
+				if now.Sub(at) < teleportWindow {
+					recent = append(recent, at)
+				}
+			}
+		}
+		if len(recent) >= maxTeleports {
+			err = errors.Errorf("%q passed through %v portals within %v", id, maxTeleports, teleportWindow)
+			return
+		}
+		recent = append(recent, now)
+		teleports.Set(id, &recent)
+	})
+	return err
+}
+
+// enterPortal moves the traveller with the given ID through the portal with the given ID,
+// to the destination the portal replies with to a portal call, and emits portalDeparture to
+// the source and portalArrival to the destination. The traveller must be next to the portal.
+func (g *Game) enterPortal(ctx context.Context, running *structs.Object, travellerID string, portalID string) (string, error) {
+	traveller, err := g.loadObjectOrRunning(ctx, running, travellerID)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	portal, err := g.loadObjectOrRunning(ctx, running, portalID)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if !portal.HasCallback(portalEventType, callEventTag) {
+		return "", errors.Errorf("%q isn't a portal", portalID)
+	}
+	if traveller.Location == "" || traveller.Location != portal.Location {
+		return "", errors.Errorf("%q isn't next to %q", travellerID, portalID)
+	}
+	message, err := goccy.Marshal(&portalQuery{Traveller: travellerID, Portal: portalID})
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	reply, err := g.callObject(ctx, portalID, portalEventType, string(message))
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	var destination *string
+	if err := goccy.Unmarshal([]byte(reply), &destination); err != nil {
+		return "", errors.Wrapf(err, "%q replied with a non string destination %s", portalID, reply)
+	}
+	if destination == nil || *destination == "" {
+		return "", errors.Errorf("%q refuses %q", portalID, travellerID)
+	}
+	source := traveller.Location
+	if *destination == source || *destination == portalID || *destination == travellerID {
+		return "", errors.Errorf("%q can't lead %q to %q", portalID, travellerID, *destination)
+	}
+	if err := recordTeleport(travellerID, time.Now()); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if err := g.relocate(ctx, running, travellerID, *destination); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	transit := &portalTransit{
+		Object:      travellerID,
+		Portal:      portalID,
+		Source:      source,
+		Destination: *destination,
+	}
+	at := g.storage.Queue().After(0)
+	if err := g.emitAny(ctx, at, source, portalDepartureEventType, transit); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if err := g.emitAny(ctx, at, *destination, portalArrivalEventType, transit); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return *destination, nil
+}
+
+// enterCommand moves the connected object through the portal matching name in its location.
+func (c *Connection) enterCommand(name string) error {
+	ctx := c.sess.Context()
+	if name == "" {
+		return errors.New("usage: enter <portal>")
+	}
+	traveller, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if traveller.Location == "" {
+		return errors.Errorf("There is no %q here", name)
+	}
+	location, err := c.game.loadLocation(ctx, traveller.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	portals := map[string]*structs.Object{}
+	for id, object := range location.Content {
+		if object.HasCallback(portalEventType, callEventTag) {
+			portals[id] = object
+		}
+	}
+	portal := matchObject(traveller, portals, name)
+	if portal == nil {
+		return errors.Errorf("There is no portal %q here", name)
+	}
+	if err := c.game.checkTutorialGate(ctx, traveller); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if _, err := c.game.enterPortal(ctx, nil, traveller.Id, portal.Id); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You enter %s.", shortDescription(portal))))
+	return juicemud.WithStack(c.describeLong())
+}
+
+// addPortalCallbacks adds `addPortal(handler)`, which makes the object a portal users can
+// `enter`. The handler gets `{Traveller, Portal}` calls and replies with the ID of the
+// destination, or null to refuse. The source gets a portalDeparture and the destination a
+// portalArrival event, both with `{Object, Portal, Source, Destination}`, for visual effects.
+// Nothing can pass through more than 3 portals within 10 seconds. Also adds
+// `enterPortal(travellerID, portalID)`, returning a promise of the destination, for NPCs.
+// Portals can't be entered from their own runs, since they would have to call themselves.
+func (g *Game) addPortalCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["addPortal"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsFunction() {
+			return rc.Throw("addPortal takes [function] arguments")
+		}
+		if err := addCallback(rc, portalEventType, callEventTag, args[0]); err != nil {
+			return rc.Throw("trying to add portal: %v", err)
+		}
+		return nil
+	}
+	callbacks["enterPortal"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("enterPortal takes [string, string] arguments")
+		}
+		if args[1].String() == object.Id {
+			return rc.Throw("portals can't be entered from their own runs")
+		}
+		destination, err := g.enterPortal(ctx, object, args[0].String(), args[1].String())
+		if err != nil {
+			return rc.Reject("trying to move %q through %q: %v", args[0].String(), args[1].String(), err)
+		}
+		return rc.Resolve(rc.String(destination))
+	}
+}
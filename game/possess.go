@@ -0,0 +1,73 @@
+package game
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+)
+
+var (
+	puppeteerByObjectID = juicemud.NewSyncMap[string, *Connection]()
+)
+
+// actor returns the ID of the object the connection acts as, the possessed object if any,
+// otherwise the object of the user.
+func (c *Connection) actor() string {
+	if c.puppet != "" {
+		return c.puppet
+	}
+	return c.user.Object
+}
+
+// possess makes the connection act as id, and show the console output of id, until released.
+func (c *Connection) possess(id string) error {
+	if id == c.user.Object {
+		return errors.New("can't possess yourself")
+	}
+	if envByObjectID.Has(id) {
+		return errors.Errorf("#%s is connected", id)
+	}
+	if _, err := c.game.storage.LoadObject(c.sess.Context(), id, nil); errors.Is(err, os.ErrNotExist) {
+		return errors.Errorf("#%s not found", id)
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if !puppeteerByObjectID.Swap(id, nil, c) {
+		return errors.Errorf("#%s is already possessed", id)
+	}
+	c.release()
+	c.puppet = id
+	addConsole(id, c.term)
+	return nil
+}
+
+// release makes the connection act as the object of the user again.
+func (c *Connection) release() bool {
+	if c.puppet == "" {
+		return false
+	}
+	delConsole(c.puppet, c.term)
+	puppeteerByObjectID.Del(c.puppet)
+	c.puppet = ""
+	return true
+}
+
+func (c *Connection) possessCommand(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(c.term, "usage: /possess #[id]")
+		return nil
+	}
+	id, ok := parseObjectRef(args[0])
+	if !ok {
+		fmt.Fprintln(c.term, "usage: /possess #[id]")
+		return nil
+	}
+	if err := c.possess(id); err != nil {
+		fmt.Fprintf(c.term, "Can't possess #%s: %v\n", id, err)
+		return nil
+	}
+	fmt.Fprintf(c.term, "Possessing #%s, /release to return to yourself\n\n", id)
+	return juicemud.WithStack(c.describeLong())
+}
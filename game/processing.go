@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/zond/juicemud"
@@ -20,6 +22,12 @@ import (
 
 const (
 	defaultReactionDelay = 100 * time.Millisecond
+	// otherLockTimeout is how long a run waits for the lock of another object it changes.
+	otherLockTimeout = time.Second
+)
+
+var (
+	ErrObjectBusy = errors.New("object busy")
 )
 
 func addGetSetPair(name string, source any, callbacks js.Callbacks) {
@@ -58,9 +66,17 @@ func (g *Game) emitJSONIf(ctx context.Context, at structs.Timestamp, object *str
 }
 
 func (g *Game) emitJSON(ctx context.Context, at structs.Timestamp, id string, name string, json string) error {
+	return juicemud.WithStack(g.emitJSONWithPriority(ctx, at, id, name, json, 0))
+}
+
+// emitJSONWithPriority emits an event that is delivered before events with lower priority
+// due at the same time. Events to the same object are always delivered one at a time, ordered
+// by time, then priority, then emit order.
+func (g *Game) emitJSONWithPriority(ctx context.Context, at structs.Timestamp, id string, name string, json string, priority int64) error {
 	return juicemud.WithStack(g.storage.Queue().Push(ctx, &structs.Event{
-		At:     uint64(at),
-		Object: id,
+		At:       uint64(at),
+		Object:   id,
+		Priority: priority,
 		Call: structs.Call{
 			Name:    name,
 			Message: json,
@@ -69,15 +85,6 @@ func (g *Game) emitJSON(ctx context.Context, at structs.Timestamp, id string, na
 	}))
 }
 
-func (g *Game) emitJSONToNeighbourhoodIf(ctx context.Context, at structs.Timestamp, n *structs.Neighbourhood, name string, json string) error {
-	for _, obj := range n.All() {
-		if err := g.emitJSONIf(ctx, at, obj, name, json); err != nil {
-			return juicemud.WithStack(err)
-		}
-	}
-	return nil
-}
-
 type movement struct {
 	Object      string
 	Source      string
@@ -97,12 +104,70 @@ func (g *Game) emitMovementToNeighbourhood(ctx context.Context, bigM *storage.Mo
 	if err != nil {
 		return juicemud.WithStack(err)
 	}
+	if bigM.Destination != "" && envByObjectID.Has(bigM.Object.Id) {
+		g.countAnalytics(roomAnalytics, analyticsDay, bigM.Destination)
+	}
 	at := g.storage.Queue().After(defaultReactionDelay)
-	return juicemud.WithStack(g.emitJSONToNeighbourhoodIf(ctx, at, n, movementEventType, string(json)))
+	for _, obj := range n.All() {
+		// Invisible objects move without anyone but those noticing them knowing.
+		if !bigM.Object.Detectable(obj) {
+			continue
+		}
+		if err := g.emitJSONIf(ctx, at, obj, movementEventType, string(json)); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
 }
 
+// serialRuns contains the IDs of objects currently handling one of their SerialEvents.
+var serialRuns = juicemud.NewSyncMap[string, bool]()
+
+// rerunSource reruns and stores objects loaded after their source changed. Rerunning an object
+// locked by a run would interleave with it, so it waits for the lock, unless the run is handling
+// one of the SerialEvents of the object or the lock isn't released in time. Those objects are
+// left as they are, and rerun by a queued refresh event instead.
 func (g *Game) rerunSource(ctx context.Context, object *structs.Object) error {
-	return g.run(ctx, object, nil)
+	if serialRuns.Has(object.Id) {
+		return juicemud.WithStack(g.refreshLater(ctx, object.Id))
+	}
+	if err := lockOther(object.Id); errors.Is(err, ErrObjectBusy) {
+		return juicemud.WithStack(g.refreshLater(ctx, object.Id))
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	defer jsContextLocks.Unlock(object.Id)
+	// The object might have been stored since it was loaded.
+	current, err := g.storage.LoadObject(ctx, object.Id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	*object = *current
+	return juicemud.WithStack(g.refresh(ctx, object))
+}
+
+// refreshLater queues a refresh event rerunning id if its source changed.
+func (g *Game) refreshLater(ctx context.Context, id string) error {
+	return juicemud.WithStack(g.storage.Queue().Push(ctx, &structs.Event{
+		At:     uint64(g.storage.Queue().After(0)),
+		Object: id,
+		Call: structs.Call{
+			Name: refreshEventTag,
+			Tag:  refreshEventTag,
+		},
+	}))
+}
+
+// refresh reruns and stores the locked object, unless it already runs its current source.
+func (g *Game) refresh(ctx context.Context, object *structs.Object) error {
+	t, err := g.storage.SourceModTime(ctx, object.SourcePath)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if object.SourceModTime >= t {
+		return nil
+	}
+	return juicemud.WithStack(g.runSave(ctx, object, nil))
 }
 
 func (g *Game) loadLocation(ctx context.Context, id string) (*structs.Location, error) {
@@ -137,7 +202,32 @@ func (g *Game) loadNeighbourhood(ctx context.Context, object *structs.Object) (*
 	return result, nil
 }
 
-func (g *Game) addGlobalCallbacks(_ context.Context, callbacks js.Callbacks) {
+func sortedIDsToJS(rc *js.RunContext, ids map[string]bool) *v8go.Value {
+	sortedIDs := make(sort.StringSlice, 0, len(ids))
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Sort(sortedIDs)
+	res, err := rc.JSFromGo(sortedIDs)
+	if err != nil {
+		return rc.Throw("trying to convert %v to *v8go.Value: %v", sortedIDs, err)
+	}
+	return res
+}
+
+func (g *Game) addGlobalCallbacks(ctx context.Context, callbacks js.Callbacks) {
+	addFactionCallbacks(callbacks)
+	addEquipmentSlotCallbacks(callbacks)
+	addRespawnCallbacks(callbacks)
+	addTableCallbacks(callbacks)
+	addFurnishingCallbacks(callbacks)
+	addLanguageCallbacks(callbacks)
+	addAttributeConfigCallbacks(callbacks)
+	addSocialConfigCallbacks(callbacks)
+	addSpokenLanguageCallbacks(callbacks)
+	g.addFormatCallbacks(ctx, callbacks)
+	g.addGameCalendarCallbacks(callbacks)
+	g.addBankCallbacks(ctx, callbacks)
 	callbacks["getSkills"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 		args := info.Args()
 		if len(args) != 0 {
@@ -175,6 +265,32 @@ func (g *Game) addGlobalCallbacks(_ context.Context, callbacks js.Callbacks) {
 		}
 		return res
 	}
+	callbacks["findByTag"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("findByTag takes [string] arguments")
+		}
+		ids, err := g.storage.ObjectIDsByTag(ctx, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to find objects tagged %q: %v", args[0].String(), err)
+		}
+		return sortedIDsToJS(rc, ids)
+	}
+	callbacks["findObjects"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("findObjects takes [Object] arguments")
+		}
+		query := map[string]string{}
+		if err := rc.Copy(&query, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to map[string]string: %v", args[0], err)
+		}
+		ids, err := g.storage.FindObjectIDs(ctx, query)
+		if err != nil {
+			return rc.Throw("trying to find objects matching %+v: %v", query, err)
+		}
+		return sortedIDsToJS(rc, ids)
+	}
 	callbacks["setSkill"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 		args := info.Args()
 		if len(args) != 2 || !args[0].IsString() || !args[1].IsObject() {
@@ -189,6 +305,30 @@ func (g *Game) addGlobalCallbacks(_ context.Context, callbacks js.Callbacks) {
 	}
 }
 
+// addCreateObjectCallbacks adds `createObject(sourcePath, locationID, creationParams?)`, which
+// resolves to the ID of a new object running the source in the location. The new object runs
+// for the first time in a queued event, so it isn't set up by its source yet when this resolves.
+func (g *Game) addCreateObjectCallbacks(ctx context.Context, callbacks js.Callbacks) {
+	callbacks["createObject"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 2 || len(args) > 3 || !args[0].IsString() || !args[1].IsString() || (len(args) == 3 && !args[2].IsObject()) {
+			return rc.Throw("createObject takes [string, string, Object?] arguments")
+		}
+		params := ""
+		if len(args) == 3 {
+			var err error
+			if params, err = v8go.JSONStringify(rc.Context(), args[2]); err != nil {
+				return rc.Throw("trying to serialize %v: %v", args[2], err)
+			}
+		}
+		created, err := g.createObjectFromSourceLater(ctx, args[0].String(), args[1].String(), params)
+		if err != nil {
+			return rc.Reject("trying to create object from %q in %q: %v", args[0].String(), args[1].String(), err)
+		}
+		return rc.Resolve(rc.String(created.Id))
+	}
+}
+
 func (g *Game) addObjectCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
 	addGetSetPair("Location", &object.Location, callbacks)
 	addGetSetPair("Content", &object.Content, callbacks)
@@ -196,6 +336,9 @@ func (g *Game) addObjectCallbacks(ctx context.Context, object *structs.Object, c
 	addGetSetPair("Descriptions", &object.Descriptions, callbacks)
 	addGetSetPair("Exits", &object.Exits, callbacks)
 	addGetSetPair("SourcePath", &object.SourcePath, callbacks)
+	addGetSetPair("Tags", &object.Tags, callbacks)
+	addGetSetPair("Coordinates", &object.Coordinates, callbacks)
+	addGetSetPair("SerialEvents", &object.SerialEvents, callbacks)
 	callbacks["setTimeout"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 		args := info.Args()
 		if len(args) != 3 || !args[1].IsString() {
@@ -206,9 +349,11 @@ func (g *Game) addObjectCallbacks(ctx context.Context, object *structs.Object, c
 			return rc.Throw("trying to serialize %v: %v", args[2], err)
 		}
 		delay := time.Duration(args[0].Integer()) * time.Millisecond
-		if err := g.emitJSON(ctx, g.storage.Queue().After(delay), object.Id, args[1].String(), message); err != nil {
+		at := g.storage.Queue().After(delay)
+		if err := g.emitJSON(ctx, at, object.Id, args[1].String(), message); err != nil {
 			return rc.Throw("trying to enqueue %v for %v: %v", message, object.Id, err)
 		}
+		traceEmitted(object.Id, object.Id, args[1].String(), message, at)
 		return nil
 	}
 	callbacks["setInterval"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
@@ -217,35 +362,204 @@ func (g *Game) addObjectCallbacks(ctx context.Context, object *structs.Object, c
 	}
 	callbacks["emit"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 		args := info.Args()
-		if len(args) != 3 || !args[0].IsString() || !args[1].IsString() {
-			return rc.Throw("emit takes [string, string, any] arguments")
+		if len(args) < 3 || len(args) > 4 || !args[0].IsString() || !args[1].IsString() || (len(args) == 4 && !args[3].IsNumber()) {
+			return rc.Throw("emit takes [string, string, any, int?] arguments")
 		}
 		message, err := v8go.JSONStringify(rc.Context(), args[2])
 		if err != nil {
 			return rc.Throw("trying to serialize %v: %v", args[2], err)
 		}
-		if err := g.emitJSON(ctx, g.storage.Queue().After(defaultReactionDelay), args[0].String(), args[1].String(), message); err != nil {
+		priority := int64(0)
+		if len(args) == 4 {
+			priority = args[3].Integer()
+		}
+		at := g.storage.Queue().After(defaultReactionDelay)
+		if err := g.emitJSONWithPriority(ctx, at, args[0].String(), args[1].String(), message, priority); err != nil {
 			return rc.Throw("trying to enqueue %v for %v: %v", message, args[0].String(), err)
 		}
+		traceEmitted(object.Id, args[0].String(), args[1].String(), message, at)
+		return nil
+	}
+	callbacks["emitToRadius"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 4 || len(args) > 5 || !args[0].IsString() || !args[1].IsNumber() || !args[2].IsString() || (len(args) == 5 && !args[4].IsArray()) {
+			return rc.Throw("emitToRadius takes [string, int, string, any, Array?] arguments")
+		}
+		message, err := v8go.JSONStringify(rc.Context(), args[3])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[3], err)
+		}
+		challenges := []structs.Challenge{}
+		if len(args) == 5 {
+			if err := rc.Copy(&challenges, args[4]); err != nil {
+				return rc.Throw("trying to copy %v to []structs.Challenge: %v", args[4], err)
+			}
+		}
+		at := g.storage.Queue().After(defaultReactionDelay)
+		if err := g.emitToRadius(ctx, at, args[0].String(), int(args[1].Integer()), args[2].String(), message, challenges); err != nil {
+			return rc.Throw("trying to emit %v around %v: %v", message, args[0].String(), err)
+		}
+		traceEmitted(object.Id, fmt.Sprintf("%s+%d", args[0].String(), args[1].Integer()), args[2].String(), message, at)
+		return nil
+	}
+	callbacks["subscribe"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("subscribe takes [string] arguments")
+		}
+		if !slices.Contains(object.Subscriptions, args[0].String()) {
+			object.Subscriptions = append(object.Subscriptions, args[0].String())
+		}
+		return nil
+	}
+	callbacks["unsubscribe"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("unsubscribe takes [string] arguments")
+		}
+		object.Subscriptions = slices.DeleteFunc(object.Subscriptions, func(topic string) bool {
+			return topic == args[0].String()
+		})
+		return nil
+	}
+	callbacks["publishTopic"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() {
+			return rc.Throw("publishTopic takes [string, any] arguments")
+		}
+		message, err := v8go.JSONStringify(rc.Context(), args[1])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[1], err)
+		}
+		subscribers, err := g.storage.ObjectIDsByTopic(ctx, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load subscribers to %q: %v", args[0].String(), err)
+		}
+		at := g.storage.Queue().After(defaultReactionDelay)
+		for subscriber := range subscribers {
+			if err := g.emitJSON(ctx, at, subscriber, args[0].String(), message); err != nil {
+				return rc.Throw("trying to enqueue %v for %v: %v", message, subscriber, err)
+			}
+			traceEmitted(object.Id, subscriber, args[0].String(), message, at)
+		}
 		return nil
 	}
+	g.addCreateObjectCallbacks(ctx, callbacks)
+	callbacks["moveObject"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("moveObject takes [string, string] arguments")
+		}
+		if err := g.checkSteal(ctx, object, args[0].String()); err != nil {
+			return rc.Reject("trying to move %q to %q: %v", args[0].String(), args[1].String(), err)
+		}
+		if err := g.relocate(ctx, object, args[0].String(), args[1].String()); err != nil {
+			return rc.Reject("trying to move %q to %q: %v", args[0].String(), args[1].String(), err)
+		}
+		return rc.Resolve(v8go.Undefined(rc.Context().Isolate()))
+	}
+	callbacks["dig"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 3 || !args[0].IsString() || (len(args) > 1 && !args[1].IsString()) || (len(args) > 2 && !args[2].IsString()) {
+			return rc.Throw("dig takes [string, string?, string?] arguments")
+		}
+		destinationID, sourcePath := "", ""
+		if len(args) > 1 {
+			destinationID = args[1].String()
+		}
+		if len(args) > 2 {
+			sourcePath = args[2].String()
+		}
+		destination, err := g.dig(ctx, object, args[0].String(), destinationID, sourcePath)
+		if err != nil {
+			return rc.Throw("trying to dig %q from %q: %v", args[0].String(), object.Id, err)
+		}
+		return rc.String(destination.Id)
+	}
 	callbacks["getNeighbourhood"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 		object, err := g.storage.LoadObject(ctx, object.Id, g.rerunSource)
 		if err != nil {
-			return rc.Throw("trying to load Object: %v", err)
+			return rc.Reject("trying to load Object: %v", err)
 		}
 		neighbourhood, err := g.loadNeighbourhood(ctx, object)
 		if err != nil {
-			return rc.Throw("trying to load Object neighbourhood: %v", err)
+			return rc.Reject("trying to load Object neighbourhood: %v", err)
 		}
+		neighbourhood.Redact()
 		val, err := rc.JSFromGo(neighbourhood)
 		if err != nil {
-			return rc.Throw("trying to convert %v to *v8go.Value: %v", neighbourhood, err)
+			return rc.Reject("trying to convert %v to *v8go.Value: %v", neighbourhood, err)
 		}
-		return val
+		return rc.Resolve(val)
 	}
 }
 
+// lockOther locks id while the running object is locked. Waiting for the lock would deadlock if
+// the run holding it waits for the running object in turn, so it gives up with ErrObjectBusy
+// after otherLockTimeout.
+func lockOther(id string) error {
+	deadline := time.Now().Add(otherLockTimeout)
+	for !jsContextLocks.TryLock(id) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%q: %w", id, ErrObjectBusy)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// lockContext locks id, and gives up when ctx is done.
+func lockContext(ctx context.Context, id string) error {
+	if ctx.Done() == nil {
+		jsContextLocks.Lock(id)
+		return nil
+	}
+	for !jsContextLocks.TryLock(id) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%q: %w", id, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// moveObject moves id to destination, and unequips it from its previous location. Moving the
+// running object itself only updates it, since it's stored when the run finishes.
+func (g *Game) moveObject(ctx context.Context, running *structs.Object, id string, destination string) error {
+	if running != nil && id == running.Id {
+		if running.Immobile(time.Now()) {
+			return fmt.Errorf("%q is immobile", id)
+		}
+		oldLocation := running.Location
+		running.Location = destination
+		return juicemud.WithStack(g.unequipMoved(ctx, running, oldLocation, id))
+	}
+	oldLocation, err := func() (string, error) {
+		if running == nil {
+			jsContextLocks.Lock(id)
+		} else if err := lockOther(id); err != nil {
+			return "", juicemud.WithStack(err)
+		}
+		defer jsContextLocks.Unlock(id)
+
+		object, err := g.storage.LoadObject(ctx, id, nil)
+		if err != nil {
+			return "", juicemud.WithStack(err)
+		}
+		if object.Immobile(time.Now()) {
+			return "", fmt.Errorf("%q is immobile", id)
+		}
+		oldLocation := object.Location
+		object.Location = destination
+		return oldLocation, juicemud.WithStack(g.storage.StoreObject(ctx, &oldLocation, object))
+	}()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.unequipMoved(ctx, running, oldLocation, id))
+}
+
 type Caller interface {
 	Call() (*structs.Call, error)
 }
@@ -281,6 +595,12 @@ Some events we should send to objects:
 - transmitted: Object lost Content.
 */
 func (g *Game) run(ctx context.Context, object *structs.Object, caller Caller) error {
+	return juicemud.WithStack(g.runWithParams(ctx, object, caller, ""))
+}
+
+// runWithParams runs the object source, exposing creationParams (if not empty) as the
+// `creationParams` global.
+func (g *Game) runWithParams(ctx context.Context, object *structs.Object, caller Caller, creationParams string) error {
 	var call *structs.Call
 	if caller != nil {
 		var err error
@@ -295,6 +615,9 @@ func (g *Game) run(ctx context.Context, object *structs.Object, caller Caller) e
 			return nil
 		}
 	}
+	if skipDisabled(object, call) {
+		return nil
+	}
 
 	sid := string(object.Id)
 	source, modTime, err := g.storage.LoadSource(ctx, object.SourcePath)
@@ -305,14 +628,74 @@ func (g *Game) run(ctx context.Context, object *structs.Object, caller Caller) e
 	callbacks := js.Callbacks{}
 	g.addGlobalCallbacks(ctx, callbacks)
 	g.addObjectCallbacks(ctx, object, callbacks)
+	g.addGlobalStoreCallbacks(ctx, object, callbacks)
+	g.addRPCCallbacks(ctx, object, caller, callbacks)
+	g.addTransactionCallbacks(ctx, object, callbacks)
+	g.addFetchCallbacks(ctx, object, callbacks)
+	g.addLLMCallbacks(ctx, object, callbacks)
+	g.addAnnounceCallbacks(ctx, object, callbacks)
+	g.addAchievementCallbacks(ctx, object, callbacks)
+	g.addLeaderboardCallbacks(ctx, object, callbacks)
+	g.addReputationCallbacks(ctx, object, callbacks)
+	g.addLearningCallbacks(ctx, object, callbacks)
+	g.addTeachingCallbacks(ctx, object, callbacks)
+	g.addEffectCallbacks(ctx, object, callbacks)
+	g.addEquipmentCallbacks(ctx, object, callbacks)
+	g.addDurabilityCallbacks(ctx, object, callbacks)
+	g.addVitalsCallbacks(ctx, object, callbacks)
+	g.addInstanceCallbacks(ctx, object, callbacks)
+	g.addMoneyCallbacks(ctx, object, callbacks)
+	addShopCallbacks(object, callbacks)
+	g.addHousingCallbacks(ctx, object, callbacks)
+	g.addLockCallbacks(ctx, object, callbacks)
+	addAmbienceCallbacks(object, callbacks)
+	g.addMatchCallbacks(ctx, object, callbacks)
+	addDetailCallbacks(object, callbacks)
+	addSenseCallbacks(object, callbacks)
+	commands := []structs.Command{}
+	addCommandCallbacks(&commands, callbacks)
+	addCommandHookCallbacks(object, callbacks)
+	addTerrainCallbacks(object, callbacks)
+	g.addPortalCallbacks(ctx, object, callbacks)
+	g.addQueryCallbacks(ctx, object, callbacks)
+	g.addPublicStateCallbacks(ctx, object, callbacks)
+	g.addAttributeCallbacks(ctx, object, callbacks)
+	g.addTutorialCallbacks(ctx, object, callbacks)
+	addScoreCallbacks(callbacks)
+	g.addTitleCallbacks(ctx, object, callbacks)
+	addPronounCallbacks(object, callbacks)
+	g.addSpeechCallbacks(ctx, object, callbacks)
+	addDialogueCallbacks(object, callbacks)
+	g.addConversationCallbacks(ctx, object, callbacks)
+	g.addServiceCallbacks(ctx, object, callbacks)
+	g.addTrashCallbacks(ctx, object, callbacks)
+	g.addDespawnCallbacks(ctx, object, callbacks)
+	addMemoryCallbacks(object, callbacks)
+	migration := &stateMigration{}
+	addMigrationCallbacks(migration, callbacks)
+	var schema *stateSchema
+	addStateSchemaCallbacks(&schema, callbacks)
+	addCooldownCallbacks(object, callbacks)
+	addRandomCallbacks(object, callbacks)
 	target := js.Target{
-		Source:    string(source),
-		Origin:    object.SourcePath,
-		State:     object.State,
-		Callbacks: callbacks,
-		Console:   consoleByObjectID.Get(sid),
+		Source:         string(source),
+		Origin:         object.SourcePath,
+		State:          object.State,
+		CreationParams: creationParams,
+		Callbacks:      callbacks,
+		Console:        consoleByObjectID.Get(sid),
+		Affinity:       sid,
+		BeforeCall:     migration.beforeCall(object),
+	}
+	event := loadEvent
+	if call != nil {
+		event = call.Name
 	}
+	started := time.Now()
 	res, err := target.Run(ctx, call, 200*time.Millisecond)
+	lapsed := time.Since(started)
+	recordScriptRun(object.SourcePath, event, lapsed, err != nil)
+	checkSlowRun(object, event, call, lapsed, err)
 	if err != nil {
 		jserr := &v8go.JSError{}
 		if errors.As(err, &jserr) {
@@ -320,8 +703,15 @@ func (g *Game) run(ctx context.Context, object *structs.Object, caller Caller) e
 		}
 		return juicemud.WithStack(err)
 	}
+	if err := validateState(object, schema, res.State); err != nil {
+		return juicemud.WithStack(err)
+	}
 	object.State = res.State
 	object.Callbacks = res.Callbacks
+	object.Commands = commands
+	if migration.declared {
+		object.StateVersion = migration.version
+	}
 	object.SourceModTime = modTime
 	return nil
 }
@@ -331,17 +721,50 @@ func (g *Game) runSave(ctx context.Context, object *structs.Object, caller Calle
 	if err := g.run(ctx, object, caller); err != nil {
 		return juicemud.WithStack(err)
 	}
+	// Whoever gave up on the run when ctx was done doesn't expect it to be stored.
+	if err := ctx.Err(); err != nil {
+		return juicemud.WithStack(err)
+	}
 	return juicemud.WithStack(g.storage.StoreObject(ctx, &oldLocation, object))
 }
 
 func (g *Game) loadRunSave(ctx context.Context, id string, caller Caller) error {
 	sid := string(id)
-	jsContextLocks.Lock(sid)
+	if err := lockContext(ctx, sid); err != nil {
+		return juicemud.WithStack(err)
+	}
 	defer jsContextLocks.Unlock(sid)
 
 	object, err := g.storage.LoadObject(ctx, id, nil)
 	if err != nil {
 		return juicemud.WithStack(err)
 	}
+	var call *structs.Call
+	if caller != nil {
+		if call, err = caller.Call(); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if call.Tag == initEventTag {
+			oldLocation := object.Location
+			if err := g.runWithParams(ctx, object, nil, call.Message); err != nil {
+				return juicemud.WithStack(err)
+			}
+			return juicemud.WithStack(g.storage.StoreObject(ctx, &oldLocation, object))
+		}
+		if call.Tag == refreshEventTag {
+			return juicemud.WithStack(g.refresh(ctx, object))
+		}
+		// Refreshes of objects handling serial events are queued instead of waiting for the
+		// handler, see rerunSource.
+		if slices.Contains(object.SerialEvents, call.Name) {
+			serialRuns.Set(sid, true)
+			defer serialRuns.Del(sid)
+		}
+	}
+	if shouldRun, err := g.handleEffectEvent(ctx, object, call); err != nil {
+		return juicemud.WithStack(err)
+	} else if !shouldRun {
+		return nil
+	}
 	return juicemud.WithStack(g.runSave(ctx, object, caller))
 }
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+)
+
+const (
+	// proxyHeaderTimeout is how long a connection may take to send its PROXY protocol header.
+	proxyHeaderTimeout = 5 * time.Second
+	// maxProxyV1Header is the longest valid PROXY protocol v1 header.
+	maxProxyV1Header = 107
+)
+
+var (
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// readProxyHeader reads a PROXY protocol v1 or v2 header from r, and returns the client
+// address it contains, or nil if the header is for a connection not proxied for a client,
+// like a health check.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	peeked, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if bytes.Equal(peeked, proxyV2Signature) {
+		return readProxyV2Header(r)
+	}
+	if !bytes.HasPrefix(peeked, []byte("PROXY ")) {
+		return nil, errors.New("missing PROXY protocol header")
+	}
+	return readProxyV1Header(r)
+}
+
+func readProxyV1Header(r *bufio.Reader) (net.Addr, error) {
+	line := []byte{}
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= maxProxyV1Header {
+			return nil, errors.New("too long PROXY protocol v1 header")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		line = append(line, b)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, errors.Errorf("invalid PROXY protocol v1 source in %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readProxyV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	versionCommand, family := header[12], header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if versionCommand>>4 != 2 {
+		return nil, errors.Errorf("invalid PROXY protocol v2 version %v", versionCommand>>4)
+	}
+	switch versionCommand & 0xf {
+	case 0:
+		// LOCAL, sent by the proxy itself.
+		return nil, nil
+	case 1:
+		// PROXY, sent for a client.
+	default:
+		return nil, errors.Errorf("invalid PROXY protocol v2 command %v", versionCommand&0xf)
+	}
+	switch family >> 4 {
+	case 1:
+		if len(payload) < 12 {
+			return nil, errors.New("too short PROXY protocol v2 IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 2:
+		if len(payload) < 36 {
+			return nil, errors.New("too short PROXY protocol v2 IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	default:
+		// Unix sockets and unspecified families have no client IP.
+		return nil, nil
+	}
+}
+
+// proxyConn is a connection starting with a PROXY protocol header, read on first use.
+type proxyConn struct {
+	net.Conn
+	reader *bufio.Reader
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (p *proxyConn) readHeader() {
+	p.once.Do(func() {
+		if p.err = p.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); p.err != nil {
+			return
+		}
+		if p.remote, p.err = readProxyHeader(p.reader); p.err != nil {
+			return
+		}
+		p.err = p.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+// RemoteAddr returns the client address from the PROXY protocol header, or the address of
+// the proxy if the header has none.
+func (p *proxyConn) RemoteAddr() net.Addr {
+	if p.readHeader(); p.remote != nil {
+		return p.remote
+	}
+	return p.Conn.RemoteAddr()
+}
+
+func (p *proxyConn) Read(b []byte) (int, error) {
+	if p.readHeader(); p.err != nil {
+		return 0, p.err
+	}
+	return p.reader.Read(b)
+}
+
+// proxyListener accepts connections starting with a PROXY protocol header.
+type proxyListener struct {
+	net.Listener
+}
+
+func (p proxyListener) Accept() (net.Conn, error) {
+	conn, err := p.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+// publicState returns the top level fields of the state of object that it declared public.
+func publicState(object *structs.Object) (map[string]any, error) {
+	result := map[string]any{}
+	if len(object.PublicKeys) == 0 || object.State == "" {
+		return result, nil
+	}
+	state := map[string]any{}
+	if err := goccy.Unmarshal([]byte(object.State), &state); err != nil {
+		return nil, errors.Wrapf(err, "trying to parse state of %q", object.Id)
+	}
+	for _, key := range object.PublicKeys {
+		if value, found := state[key]; found {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// addPublicStateCallbacks adds `setPublicKeys(keys)`, which declares which top level fields of
+// the state of the object other objects can read, and `getPublicState(objectID)`, which
+// returns a copy of those fields of the state of the object as it was last saved. Changes to the
+// copy don't affect the object, and fields not declared public are left out.
+func (g *Game) addPublicStateCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setPublicKeys"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setPublicKeys takes [Array] arguments")
+		}
+		result := []string{}
+		if err := rc.Copy(&result, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []string: %v", args[0], err)
+		}
+		object.PublicKeys = result
+		return nil
+	}
+	callbacks["getPublicState"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getPublicState takes [string] arguments")
+		}
+		target, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		state, err := publicState(target)
+		if err != nil {
+			return rc.Throw("trying to get public state of %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(state)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", state, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"context"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// queryResult is what queryObjects returns.
+type queryResult struct {
+	IDs []string
+	// Cursor is the cursor of the next page, empty after the last page.
+	Cursor string
+}
+
+// queryArguments are the arguments of queryObjects.
+type queryArguments struct {
+	Filter storage.ObjectFilter
+	Cursor string
+	Limit  int
+}
+
+// privileged returns whether the source of object can only be written by wizards.
+func (g *Game) privileged(ctx context.Context, object *structs.Object) (bool, error) {
+	file, err := g.storage.LoadFile(ctx, object.SourcePath)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	_, writer, err := g.storage.FileGroups(ctx, file)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	return writer.Name == wizardsGroup, nil
+}
+
+// addQueryCallbacks adds `queryObjects({Filter: {SourcePath?, Location?, Tags?,
+// ModifiedAfter?, ModifiedBefore?, Index?}, Cursor?, Limit?})`, returning `{IDs, Cursor}` with
+// the IDs of a page of matching objects, and the Cursor to pass for the next page, if any.
+// Modification times are Unix nanoseconds. Since queries can scan all objects, only objects
+// whose source only wizards can write can query.
+func (g *Game) addQueryCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["queryObjects"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("queryObjects takes [Object] arguments")
+		}
+		if privileged, err := g.privileged(ctx, object); err != nil {
+			return rc.Throw("trying to check if %q is privileged: %v", object.Id, err)
+		} else if !privileged {
+			return rc.Throw("only objects with sources written by %s can query objects", wizardsGroup)
+		}
+		query := queryArguments{}
+		if err := rc.Copy(&query, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to queryArguments: %v", args[0], err)
+		}
+		objects, cursor, err := g.storage.QueryObjects(ctx, &query.Filter, query.Cursor, query.Limit)
+		if err != nil {
+			return rc.Throw("trying to query objects matching %+v: %v", query.Filter, err)
+		}
+		result := queryResult{IDs: make([]string, 0, len(objects)), Cursor: cursor}
+		for _, found := range objects {
+			result.IDs = append(result.IDs, found.Id)
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"context"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+const (
+	// radiusChallengeAttenuation is added to the level of each challenge of a ranged emit
+	// per exit hop between the origin and the receiver.
+	radiusChallengeAttenuation = 10
+)
+
+// emitToRadius emits the event to location, its content, and the rooms (with content) within
+// depth exit hops from it. Receivers have to pass all challenges, with levels increased by
+// radiusChallengeAttenuation per hop, against the origin location to receive the event.
+func (g *Game) emitToRadius(ctx context.Context, at structs.Timestamp, location string, depth int, name string, json string, challenges []structs.Challenge) error {
+	origin, err := g.storage.LoadObject(ctx, location, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	visited := map[string]bool{location: true}
+	level := map[string]*structs.Object{location: origin}
+	for distance := 0; distance <= depth && len(level) > 0; distance++ {
+		attenuated := make([]structs.Challenge, len(challenges))
+		for i, challenge := range challenges {
+			attenuated[i] = challenge
+			attenuated[i].Level += float32(distance * radiusChallengeAttenuation)
+		}
+		nextIDs := map[string]bool{}
+		for _, room := range level {
+			content, err := g.storage.LoadObjects(ctx, room.Content, nil)
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			content[room.Id] = room
+			for _, receiver := range content {
+				if !receiver.HasCallback(name, emitEventTag) {
+					continue
+				}
+				if !passesAll(attenuated, receiver, origin) {
+					continue
+				}
+				if err := g.emitJSON(ctx, at, receiver.Id, name, json); err != nil {
+					return juicemud.WithStack(err)
+				}
+			}
+			for _, exit := range room.Exits {
+				if !visited[exit.Destination] {
+					visited[exit.Destination] = true
+					nextIDs[exit.Destination] = true
+				}
+			}
+		}
+		if distance == depth {
+			break
+		}
+		if level, err = g.storage.LoadObjects(ctx, nextIDs, nil); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func passesAll(challenges []structs.Challenge, challenger *structs.Object, target *structs.Object) bool {
+	for _, challenge := range challenges {
+		if !challenge.Check(challenger, target) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package game
+
+import (
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// addRandomCallbacks adds `random(seed?)`, which returns a pseudo-random number in [0, 1) from
+// the generator of the object, after reseeding it with seed if given. The generator is stored
+// with the object, so the same seed always gives the same sequence.
+func addRandomCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["random"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) > 1 || (len(args) == 1 && !args[0].IsNumber()) {
+			return rc.Throw("random takes [number?] arguments")
+		}
+		if len(args) == 1 {
+			object.Seed(uint64(args[0].Integer()))
+		}
+		result, err := object.Random()
+		if err != nil {
+			return rc.Throw("trying to generate random number: %v", err)
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"context"
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+)
+
+const (
+	recallBufferSize       = 64 << 10
+	recordingFlushSize     = 32 << 10
+	recordingFlushInterval = 5 * time.Second
+	defaultRecallLines     = 50
+)
+
+var (
+	escapeSequencePattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+)
+
+// recorder sits between the terminal and the session, and stores everything sent to the
+// session while recording, so that it can be recalled or exported.
+type recorder struct {
+	io.ReadWriter
+	storage *storage.Storage
+
+	mutex       sync.Mutex
+	ctx         context.Context
+	recording   *storage.Recording
+	pending     []storage.RecordingFrame
+	pendingSize int
+	lastFlush   time.Time
+	recent      []byte
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	written, err := r.ReadWriter.Write(b)
+	if written > 0 {
+		r.record(b[:written])
+	}
+	return written, err
+}
+
+func (r *recorder) record(b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.recording == nil {
+		return
+	}
+	r.recent = append(r.recent, b...)
+	if len(r.recent) > 2*recallBufferSize {
+		r.recent = slices.Clone(r.recent[len(r.recent)-recallBufferSize:])
+	}
+	r.pending = append(r.pending, storage.RecordingFrame{
+		Recording: r.recording.Id,
+		At:        time.Now().UnixNano(),
+		Output:    string(b),
+	})
+	r.pendingSize += len(b)
+	if r.pendingSize > recordingFlushSize || time.Since(r.lastFlush) > recordingFlushInterval {
+		r.flush()
+	}
+}
+
+func (r *recorder) flush() {
+	if len(r.pending) > 0 {
+		if err := r.storage.AppendRecordingFrames(r.ctx, r.pending); err != nil {
+			log.Printf("trying to store frames of recording %v: %v", r.recording.Id, err)
+		}
+	}
+	r.pending = nil
+	r.pendingSize = 0
+	r.lastFlush = time.Now()
+}
+
+// start starts recording sess for user, unless already recording.
+func (r *recorder) start(user *storage.User, sess ssh.Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.recording != nil {
+		return nil
+	}
+	pty, _, _ := sess.Pty()
+	recording := &storage.Recording{
+		User:    user.Id,
+		Started: time.Now().UnixNano(),
+		Width:   int64(pty.Window.Width),
+		Height:  int64(pty.Window.Height),
+	}
+	// Recordings are flushed when sessions end, so they mustn't be canceled with them.
+	ctx := context.WithoutCancel(sess.Context())
+	if err := r.storage.CreateRecording(ctx, recording); err != nil {
+		return juicemud.WithStack(err)
+	}
+	r.ctx = ctx
+	r.recording = recording
+	r.lastFlush = time.Now()
+	return nil
+}
+
+// stop stores what is left of the recording, and stops recording.
+func (r *recorder) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.recording == nil {
+		return
+	}
+	r.flush()
+	r.recording = nil
+	r.recent = nil
+}
+
+// recall returns the last lines of text sent to the session while recording, without
+// terminal control sequences.
+func (r *recorder) recall(lines int) (string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.recording == nil {
+		return "", false
+	}
+	recent := r.recent
+	if len(recent) > recallBufferSize {
+		recent = recent[len(recent)-recallBufferSize:]
+	}
+	text := escapeSequencePattern.ReplaceAllString(string(recent), "")
+	text = strings.ReplaceAll(text, "\r", "")
+	split := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(split) > lines {
+		split = split[len(split)-lines:]
+	}
+	return strings.Join(split, "\n"), true
+}
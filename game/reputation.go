@@ -0,0 +1,113 @@
+package game
+
+import (
+	"context"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// adjustReputation adds delta to the reputation of the object with the given ID with faction,
+// and returns the new reputation.
+func (g *Game) adjustReputation(ctx context.Context, running *structs.Object, id string, faction string, delta float32) (float32, error) {
+	var result float32
+	if err := g.modifyObject(ctx, running, id, func(object *structs.Object) error {
+		if object.Reputation == nil {
+			object.Reputation = map[string]float32{}
+		}
+		object.Reputation[faction] += delta
+		result = object.Reputation[faction]
+		return nil
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// loadObjectOrRunning returns the running object if id is its ID, and otherwise loads the object.
+func (g *Game) loadObjectOrRunning(ctx context.Context, running *structs.Object, id string) (*structs.Object, error) {
+	if running != nil && id == running.Id {
+		return running, nil
+	}
+	object, err := g.storage.LoadObject(ctx, id, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return object, nil
+}
+
+// addFactionCallbacks adds `setFaction(name, {Hostile, Friendly})` and `getFactions()`, which
+// define the reputation thresholds used by getStanding and by challenges with a Faction.
+func addFactionCallbacks(callbacks js.Callbacks) {
+	callbacks["setFaction"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsObject() {
+			return rc.Throw("setFaction takes [string, Object] arguments")
+		}
+		faction := structs.DefaultFaction
+		if err := rc.Copy(&faction, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to structs.Faction{}: %v", args[1], err)
+		}
+		if faction.Hostile >= faction.Friendly {
+			return rc.Throw("factions must have a Hostile threshold below the Friendly threshold")
+		}
+		structs.Factions.Set(args[0].String(), faction)
+		return nil
+	}
+	callbacks["getFactions"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		res, err := rc.JSFromGo(structs.Factions)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", structs.Factions, err)
+		}
+		return res
+	}
+}
+
+// addReputationCallbacks adds `getReputation(objectID, faction)`, `adjustReputation(objectID,
+// faction, delta)` and `getStanding(objectID, faction)`, which returns whether faction considers
+// the object "hostile", "neutral" or "friendly".
+func (g *Game) addReputationCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["getReputation"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("getReputation takes [string, string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(holder.Reputation[args[1].String()])
+		if err != nil {
+			return rc.Throw("trying to convert reputation to *v8go.Value: %v", err)
+		}
+		return res
+	}
+	callbacks["adjustReputation"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsString() || !args[2].IsNumber() {
+			return rc.Throw("adjustReputation takes [string, string, number] arguments")
+		}
+		reputation, err := g.adjustReputation(ctx, object, args[0].String(), args[1].String(), float32(args[2].Number()))
+		if err != nil {
+			return rc.Throw("trying to adjust the reputation of %q with %q: %v", args[0].String(), args[1].String(), err)
+		}
+		res, err := rc.JSFromGo(reputation)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", reputation, err)
+		}
+		return res
+	}
+	callbacks["getStanding"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsString() {
+			return rc.Throw("getStanding takes [string, string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		return rc.String(holder.Standing(args[1].String()))
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	callEventTag = "call"
+)
+
+// callersKey is the context key of the IDs of the objects waiting for the call being run.
+type callersKey struct{}
+
+func callersOf(ctx context.Context) []string {
+	callers, _ := ctx.Value(callersKey{}).([]string)
+	return callers
+}
+
+// rpcCall is a call to an object that expects the handler to reply(value).
+type rpcCall struct {
+	call  structs.Call
+	reply *string
+}
+
+func (r *rpcCall) Call() (*structs.Call, error) {
+	return &r.call, nil
+}
+
+// callObject synchronously runs the handler of targetID tagged `call` for name, and returns
+// the JSON value the handler replied with. It gives up, without storing targetID, when ctx is
+// done. Since it's mostly called from callbacks of other runs, the handler doesn't wait for a
+// free isolate.
+func (g *Game) callObject(ctx context.Context, targetID string, name string, message string) (string, error) {
+	ctx = js.InRun(ctx)
+	rpc := &rpcCall{
+		call: structs.Call{
+			Name:    name,
+			Message: message,
+			Tag:     callEventTag,
+		},
+	}
+	if err := g.loadRunSave(ctx, targetID, rpc); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	if rpc.reply == nil {
+		return "", errors.Errorf("#%s didn't reply to %q", targetID, name)
+	}
+	return *rpc.reply, nil
+}
+
+// addRPCCallbacks adds `call(targetId, event, msg, timeoutMs)` returning a promise of the reply of
+// the target, and `reply(value)` answering such a call when handling one.
+//
+// Calls are run synchronously in a separate JS run and must finish within both timeoutMs and the
+// remaining time of the calling run. A call that doesn't is terminated without storing the
+// target. Calls to the calling object, or to objects waiting for it to reply, are rejected since
+// they would deadlock.
+func (g *Game) addRPCCallbacks(ctx context.Context, object *structs.Object, caller Caller, callbacks js.Callbacks) {
+	callbacks["reply"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 {
+			return rc.Throw("reply takes [any] arguments")
+		}
+		rpc, ok := caller.(*rpcCall)
+		if !ok {
+			return rc.Throw("reply is only available when handling a call")
+		}
+		if rpc.reply != nil {
+			return rc.Throw("already replied")
+		}
+		value, err := v8go.JSONStringify(rc.Context(), args[0])
+		if err != nil {
+			return rc.Throw("trying to serialize %v: %v", args[0], err)
+		}
+		rpc.reply = &value
+		return nil
+	}
+	callbacks["call"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 4 || !args[0].IsString() || !args[1].IsString() || !args[3].IsNumber() {
+			return rc.Throw("call takes [string, string, any, int] arguments")
+		}
+		targetID, name := args[0].String(), args[1].String()
+		if targetID == object.Id {
+			return rc.Reject("can't call #%s from itself", targetID)
+		}
+		callers := callersOf(ctx)
+		if slices.Contains(callers, targetID) {
+			return rc.Reject("can't call #%s, it's waiting for #%s to reply", targetID, object.Id)
+		}
+		message, err := v8go.JSONStringify(rc.Context(), args[2])
+		if err != nil {
+			return rc.Reject("trying to serialize %v: %v", args[2], err)
+		}
+		callCtx := context.WithValue(ctx, callersKey{}, append(slices.Clone(callers), object.Id))
+		callCtx, cancel := context.WithTimeout(callCtx, time.Duration(args[3].Integer())*time.Millisecond)
+		defer cancel()
+		traceEmitted(object.Id, targetID, name, message, g.storage.Queue().After(0))
+		// callObject gives up when callCtx is done, so waiting for it never outlasts the timeout
+		// for long, and tells if the target was stored.
+		reply, err := g.callObject(callCtx, targetID, name, message)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return rc.Reject("calling %q on #%s: %v", name, targetID, js.ErrTimeout)
+		} else if err != nil {
+			return rc.Reject("calling %q on #%s: %v", name, targetID, err)
+		}
+		value, err := v8go.JSONParse(rc.Context(), reply)
+		if err != nil {
+			return rc.Reject("trying to parse reply %q: %v", reply, err)
+		}
+		return rc.Resolve(value)
+	}
+}
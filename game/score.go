@@ -0,0 +1,163 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/skills"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	scoreEventType = "score"
+)
+
+// scoreSection is a custom section of the score sheet, replied by the score handler of users.
+type scoreSection struct {
+	Title string
+	// Rows are the label and value of each row.
+	Rows [][]any
+}
+
+// printSkills shows the skills of object, with the practical level as it is now that some of
+// it may be forgotten, including bonuses from effects.
+func (c *Connection) printSkills(object *structs.Object, now time.Time) {
+	if len(object.Skills) == 0 {
+		return
+	}
+	names := make([]string, 0, len(object.Skills))
+	for name := range object.Skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	t := c.table("Skill", "Practical", "Theoretical", "Bonus")
+	for _, name := range names {
+		skill := object.Skills[name]
+		practical := skill.Practical
+		if skill.LastUsed != 0 {
+			practical = skills.Skills.Get(name).Forgotten(skill.Practical, skill.Theoretical, now.Sub(time.Unix(0, skill.LastUsed)))
+		}
+		t.AddRow(name, fmt.Sprintf("%.1f", practical), fmt.Sprintf("%.1f", skill.Theoretical), fmt.Sprintf("%+.1f", object.SkillBonus(name, now)))
+	}
+	t.Print()
+	fmt.Fprintln(c.term)
+}
+
+// printAttributes shows the derived attributes of object.
+func (c *Connection) printAttributes(object *structs.Object, now time.Time) {
+	attributes := skills.Attributes.Clone()
+	if len(attributes) == 0 {
+		return
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	t := c.table("Attribute", "Value")
+	for _, name := range names {
+		value, _ := object.Attribute(name, now)
+		t.AddRow(name, fmt.Sprintf("%.1f", value))
+	}
+	t.Print()
+	fmt.Fprintln(c.term)
+}
+
+// printEffects shows the active effects of object.
+func (c *Connection) printEffects(object *structs.Object, now time.Time) {
+	t := c.table("Effect", "Description", "Remaining")
+	active := 0
+	for _, effect := range object.Effects {
+		if !effect.Active(now) {
+			continue
+		}
+		active++
+		remaining := "permanent"
+		if effect.Expires != 0 {
+			remaining = time.Unix(0, effect.Expires).Sub(now).Round(time.Second).String()
+		}
+		t.AddRow(effect.Name, effect.Description, remaining)
+	}
+	if active == 0 {
+		return
+	}
+	t.Print()
+	fmt.Fprintln(c.term)
+}
+
+// scoreSections returns the custom sections the score handler of object replies with, if it
+// has one.
+func (c *Connection) scoreSections(object *structs.Object) ([]scoreSection, error) {
+	if !object.HasCallback(scoreEventType, callEventTag) {
+		return nil, nil
+	}
+	reply, err := c.game.callObject(c.sess.Context(), object.Id, scoreEventType, "{}")
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := []scoreSection{}
+	if err := goccy.Unmarshal([]byte(reply), &result); err != nil {
+		return nil, errors.Wrapf(err, "%q replied with invalid score sections %s", object.Id, reply)
+	}
+	return result, nil
+}
+
+// scoreCommand shows the score sheet of the connected object, with its title, vitals, money,
+// skills, attributes, effects, and the custom sections of its score handler.
+func (c *Connection) scoreCommand() error {
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	now := time.Now()
+	fmt.Fprintf(c.term, "** %s **\n\n", shortDescription(object))
+	t := c.table("", "")
+	if object.Title != "" {
+		t.AddRow("Title", object.Title)
+	}
+	if object.MaxHealth > 0 {
+		t.AddRow("Health", fmt.Sprintf("%.0f/%.0f", object.Health, object.MaxHealth))
+	}
+	t.AddRow("Money", object.Money)
+	t.Print()
+	fmt.Fprintln(c.term)
+	c.printSkills(object, now)
+	c.printAttributes(object, now)
+	c.printEffects(object, now)
+	sections, err := c.scoreSections(object)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, section := range sections {
+		t := c.table(section.Title, "")
+		for _, row := range section.Rows {
+			t.AddRow(row...)
+		}
+		t.Print()
+		fmt.Fprintln(c.term)
+	}
+	return nil
+}
+
+// addScoreCallbacks adds `addScoreSections(handler)`, which lets the object add sections to
+// its `score` sheet. The handler gets `{}` calls and replies with `[{Title, Rows}]`, where
+// Rows are `[label, value]` pairs.
+func addScoreCallbacks(callbacks js.Callbacks) {
+	callbacks["addScoreSections"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsFunction() {
+			return rc.Throw("addScoreSections takes [function] arguments")
+		}
+		if err := addCallback(rc, scoreEventType, callEventTag, args[0]); err != nil {
+			return rc.Throw("trying to add score sections: %v", err)
+		}
+		return nil
+	}
+}
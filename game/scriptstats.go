@@ -0,0 +1,170 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zond/juicemud"
+)
+
+const (
+	// scriptSamples is how many of the most recent run durations of each source and event
+	// the percentiles are computed from.
+	scriptSamples = 256
+	// loadEvent is the event of runs without a call, like creation and source reloads.
+	loadEvent = "(load)"
+)
+
+var (
+	// scriptTimings are the run statistics by source and event.
+	scriptTimings = juicemud.NewSyncMap[scriptKey, *scriptTiming]()
+	// scriptStatsColumns are what the script run statistics can be sorted by.
+	scriptStatsColumns = []string{"total", "runs", "errors", "mean", "p50", "p95", "p99", "max"}
+)
+
+type scriptKey struct {
+	source string
+	event  string
+}
+
+// scriptTiming is the run statistics of an event in a source.
+type scriptTiming struct {
+	mutex   sync.Mutex
+	runs    uint64
+	errors  uint64
+	total   time.Duration
+	max     time.Duration
+	samples []time.Duration
+	next    int
+}
+
+func (s *scriptTiming) record(d time.Duration, failed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.runs++
+	if failed {
+		s.errors++
+	}
+	s.total += d
+	s.max = max(s.max, d)
+	if len(s.samples) < scriptSamples {
+		s.samples = append(s.samples, d)
+	} else {
+		s.samples[s.next] = d
+		s.next = (s.next + 1) % scriptSamples
+	}
+}
+
+// scriptSummary is a snapshot of a scriptTiming.
+type scriptSummary struct {
+	source string
+	event  string
+	runs   uint64
+	errors uint64
+	total  time.Duration
+	max    time.Duration
+	mean   time.Duration
+	p50    time.Duration
+	p95    time.Duration
+	p99    time.Duration
+}
+
+func (s *scriptTiming) summary(key scriptKey) scriptSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sorted := slices.Clone(s.samples)
+	slices.Sort(sorted)
+	result := scriptSummary{
+		source: key.source,
+		event:  key.event,
+		runs:   s.runs,
+		errors: s.errors,
+		total:  s.total,
+		max:    s.max,
+		p50:    percentile(sorted, 50),
+		p95:    percentile(sorted, 95),
+		p99:    percentile(sorted, 99),
+	}
+	if s.runs > 0 {
+		result.mean = s.total / time.Duration(s.runs)
+	}
+	return result
+}
+
+// value returns the value of the column of the summary, for sorting.
+func (s *scriptSummary) value(column string) time.Duration {
+	switch column {
+	case "runs":
+		return time.Duration(s.runs)
+	case "errors":
+		return time.Duration(s.errors)
+	case "mean":
+		return s.mean
+	case "p50":
+		return s.p50
+	case "p95":
+		return s.p95
+	case "p99":
+		return s.p99
+	case "max":
+		return s.max
+	}
+	return s.total
+}
+
+// percentile returns the nearest rank p percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.5)
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// recordScriptRun records that a run of the event of source took d.
+func recordScriptRun(source string, event string, d time.Duration, failed bool) {
+	key := scriptKey{source: source, event: event}
+	timing, found := scriptTimings.GetHas(key)
+	if !found {
+		scriptTimings.Swap(key, nil, &scriptTiming{})
+		timing = scriptTimings.Get(key)
+	}
+	timing.record(d, failed)
+}
+
+// printScriptTimings shows the run statistics of the events of all sources, or those whose
+// path contains filter, sorted by column, descending.
+func (c *Connection) printScriptTimings(column string, filter string) {
+	summaries := []scriptSummary{}
+	for key, timing := range scriptTimings.Clone() {
+		if strings.Contains(key.source, filter) {
+			summaries = append(summaries, timing.summary(key))
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if a, b := summaries[i].value(column), summaries[j].value(column); a != b {
+			return a > b
+		}
+		if summaries[i].source != summaries[j].source {
+			return summaries[i].source < summaries[j].source
+		}
+		return summaries[i].event < summaries[j].event
+	})
+	var total time.Duration
+	for _, summary := range summaries {
+		total += summary.total
+	}
+	t := c.table("Source", "Event", "Runs", "Errors", "Total", "Share", "Mean", "p50", "p95", "p99", "Max")
+	for _, summary := range summaries {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(summary.total) / float64(total)
+		}
+		t.AddRow(summary.source, summary.event, summary.runs, summary.errors, summary.total, fmt.Sprintf("%.1f%%", share), summary.mean, summary.p50, summary.p95, summary.p99, summary.max)
+	}
+	t.Print()
+}
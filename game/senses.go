@@ -0,0 +1,135 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	smellSense  = "smell"
+	listenSense = "listen"
+	touchSense  = "touch"
+)
+
+var (
+	// senses are the sensory channels besides sight, each with a command of the same name.
+	senses = []string{smellSense, listenSense, touchSense}
+	// nothingSensed is what each sense shows when nothing is sensed.
+	nothingSensed = map[string]string{
+		smellSense:  "You smell nothing special",
+		listenSense: "You hear nothing special",
+		touchSense:  "You feel nothing special",
+	}
+)
+
+// sense returns the long description of object in the sensory channel, detected by viewer,
+// or an empty string if there is none.
+func sense(object *structs.Object, viewer *structs.Object, channel string) string {
+	if !object.Detectable(viewer) {
+		return ""
+	}
+	desc := structs.Descriptions(object.Senses[channel]).Detect(object, viewer)
+	if desc == nil {
+		return ""
+	}
+	return desc.Long
+}
+
+// senseCommand shows what the user senses through channel of the object matching name, or of
+// the location and everything in it if name is empty.
+func (c *Connection) senseCommand(channel string, name string) error {
+	ctx := c.sess.Context()
+	viewer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if viewer.Location == "" {
+		fmt.Fprintln(c.term, nothingSensed[channel])
+		return nil
+	}
+	location, err := c.game.loadLocation(ctx, viewer.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	sensed := []string{}
+	if name != "" {
+		candidates, err := c.game.storage.LoadObjects(ctx, viewer.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		for id, object := range location.Content {
+			candidates[id] = object
+		}
+		target := matchObject(viewer, candidates, name)
+		if target == nil {
+			return errors.Errorf("There is no %q here", name)
+		}
+		if text := sense(target, viewer, channel); text != "" {
+			sensed = append(sensed, text)
+		}
+	} else {
+		if text := sense(location.Container, viewer, channel); text != "" {
+			sensed = append(sensed, text)
+		}
+		ids := make([]string, 0, len(location.Content))
+		for id := range location.Content {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if text := sense(location.Content[id], viewer, channel); text != "" {
+				sensed = append(sensed, text)
+			}
+		}
+	}
+	if len(sensed) == 0 {
+		fmt.Fprintln(c.term, nothingSensed[channel])
+		return nil
+	}
+	for _, text := range sensed {
+		fmt.Fprintln(c.term, c.wrap(text))
+	}
+	return nil
+}
+
+// addSenseCallbacks adds `setSenses({smell|listen|touch: [{Long, Challenges?}]})`, which
+// gives the object descriptions in the other sensory channels than sight, shown by the
+// commands of the same names. Like descriptions, the first one whose challenges the user
+// passes is shown, so a faint sound can need a hearing skill. Also adds `getSenses()`.
+func addSenseCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setSenses"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setSenses takes [Object] arguments")
+		}
+		channels := map[string][]structs.Description{}
+		if err := rc.Copy(&channels, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to map[string][]structs.Description: %v", args[0], err)
+		}
+		for channel := range channels {
+			if !slices.Contains(senses, channel) {
+				return rc.Throw("%q isn't one of the senses %v", channel, senses)
+			}
+		}
+		object.Senses = channels
+		return nil
+	}
+	callbacks["getSenses"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		channels := object.Senses
+		if channels == nil {
+			channels = map[string][]structs.Description{}
+		}
+		res, err := rc.JSFromGo(channels)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", channels, err)
+		}
+		return res
+	}
+}
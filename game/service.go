@@ -0,0 +1,111 @@
+package game
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+// recreateService creates a new object for service if its object is missing, from the source
+// and in the location it was registered with, and returns whether it did.
+func (g *Game) recreateService(ctx context.Context, service *storage.Service) (bool, error) {
+	if _, err := g.storage.LoadObject(ctx, service.Object, nil); err == nil {
+		return false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, juicemud.WithStack(err)
+	}
+	location := service.Location
+	if location != "" {
+		if _, err := g.storage.LoadObject(ctx, location, nil); errors.Is(err, os.ErrNotExist) {
+			location = genesisID
+		} else if err != nil {
+			return false, juicemud.WithStack(err)
+		}
+	}
+	object, err := g.createObjectFromSource(ctx, service.Source, location, "")
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	// The new object may have registered itself already, and then this changes nothing.
+	service.Object = object.Id
+	return true, juicemud.WithStack(g.storage.RegisterService(ctx, service))
+}
+
+// recreateServices recreates the services whose objects are missing, and returns how it went
+// for each recreated or failing service.
+func (g *Game) recreateServices(ctx context.Context) ([]bootResult, error) {
+	services, err := g.storage.Services(ctx)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	results := []bootResult{}
+	for i := range services {
+		service := &services[i]
+		started := time.Now()
+		recreated, err := g.recreateService(ctx, service)
+		if err == nil && !recreated {
+			continue
+		}
+		result := bootResult{Path: "service " + service.Name, Duration: time.Since(started)}
+		if err != nil {
+			log.Printf("trying to recreate service %q: %v", service.Name, err)
+			log.Println(juicemud.StackTrace(err))
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// addServiceCallbacks adds `getService(name)`, which returns the ID of the object registered
+// as the service, or null if there is none, and unless object is nil `registerService(name)`,
+// which makes the object the service. Only one object can be each service, and if it goes
+// missing it is recreated from its source when the server starts.
+func (g *Game) addServiceCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["getService"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getService takes [string] arguments")
+		}
+		service, err := g.storage.LoadService(ctx, args[0].String())
+		if errors.Is(err, os.ErrNotExist) {
+			return v8go.Null(rc.Context().Isolate())
+		} else if err != nil {
+			return rc.Throw("trying to load service %q: %v", args[0].String(), err)
+		}
+		if object == nil || service.Object != object.Id {
+			if _, err := g.storage.LoadObject(ctx, service.Object, nil); errors.Is(err, os.ErrNotExist) {
+				return v8go.Null(rc.Context().Isolate())
+			} else if err != nil {
+				return rc.Throw("trying to load %q: %v", service.Object, err)
+			}
+		}
+		return rc.String(service.Object)
+	}
+	if object == nil {
+		return
+	}
+	callbacks["registerService"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() || args[0].String() == "" {
+			return rc.Throw("registerService takes [non-empty string] arguments")
+		}
+		if err := g.storage.RegisterService(ctx, &storage.Service{
+			Name:     args[0].String(),
+			Object:   object.Id,
+			Source:   object.SourcePath,
+			Location: object.Location,
+		}); err != nil {
+			return rc.Throw("trying to register service %q: %v", args[0].String(), err)
+		}
+		return nil
+	}
+}
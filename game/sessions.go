@@ -0,0 +1,189 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+)
+
+// LoginPolicy is what happens when a user logs in while already connected.
+type LoginPolicy string
+
+const (
+	// TakeoverLogins makes the new session take over the old one.
+	TakeoverLogins LoginPolicy = "takeover"
+	// DenyLogins refuses the new session.
+	DenyLogins LoginPolicy = "deny"
+	// AllowLogins lets the sessions run side by side.
+	AllowLogins LoginPolicy = "allow"
+	// ReadOnlyLogins lets the new session run side by side with the old one, but only
+	// run the readOnlyCommands.
+	ReadOnlyLogins LoginPolicy = "allow-read-only"
+)
+
+var (
+	loginPolicies = map[LoginPolicy]bool{
+		TakeoverLogins: true,
+		DenyLogins:     true,
+		AllowLogins:    true,
+		ReadOnlyLogins: true,
+	}
+	// readOnlyCommands are the commands read only sessions can run.
+	readOnlyCommands = m("l", "look", "who", "help", "news", "achievements", "top", "events")
+	// sessionsByObjectID are the connections of each user object, in the order they logged
+	// in. The first of them is the one in envByObjectID.
+	sessionsByObjectID = juicemud.NewSyncMap[string, *[]*Connection]()
+)
+
+// ParseLoginPolicy returns the policy named s.
+func ParseLoginPolicy(s string) (LoginPolicy, error) {
+	if policy := LoginPolicy(s); loginPolicies[policy] {
+		return policy, nil
+	}
+	names := []string{}
+	for policy := range loginPolicies {
+		names = append(names, string(policy))
+	}
+	sort.Strings(names)
+	return "", errors.Errorf("login policy %q isn't one of %s", s, strings.Join(names, ", "))
+}
+
+// SetLoginPolicy makes policy the login policy of users without one of their own.
+func (g *Game) SetLoginPolicy(policy LoginPolicy) error {
+	if !loginPolicies[policy] {
+		return errors.Errorf("unknown login policy %q", policy)
+	}
+	g.loginPolicy.Store(string(policy))
+	return nil
+}
+
+// sessionsOf returns the connections of the user object with id.
+func sessionsOf(id string) []*Connection {
+	if sessions := sessionsByObjectID.Get(id); sessions != nil {
+		return append([]*Connection{}, *sessions...)
+	}
+	return nil
+}
+
+// registerSession adds c to the connections of its user object.
+func registerSession(c *Connection) {
+	sessionsByObjectID.WithLock(c.user.Object, func() {
+		addSession(c)
+	})
+}
+
+// addSession adds c to the connections of its user object, which must be locked in
+// sessionsByObjectID.
+func addSession(c *Connection) {
+	id := c.user.Object
+	sessions := append(sessionsOf(id), c)
+	sessionsByObjectID.Set(id, &sessions)
+	envByObjectID.Set(id, sessions[0])
+}
+
+// unregisterSession removes c from the connections of its user object.
+func unregisterSession(c *Connection) {
+	id := c.user.Object
+	sessionsByObjectID.WithLock(id, func() {
+		sessions := []*Connection{}
+		for _, session := range sessionsOf(id) {
+			if session != c {
+				sessions = append(sessions, session)
+			}
+		}
+		if len(sessions) == 0 {
+			sessionsByObjectID.Del(id)
+			envByObjectID.Del(id)
+		} else {
+			sessionsByObjectID.Set(id, &sessions)
+			envByObjectID.Set(id, sessions[0])
+			sessions[0].readOnly.Store(false)
+		}
+	})
+}
+
+// loginPolicy returns the login policy of the user, or of the server if the user has none.
+func (c *Connection) loginPolicy() LoginPolicy {
+	if c.user.LoginPolicy != "" {
+		return LoginPolicy(c.user.LoginPolicy)
+	}
+	return LoginPolicy(c.game.loginPolicy.Load().(string))
+}
+
+// enforceLoginPolicy applies the login policy to the user if they are already connected, and
+// registers the session unless it may not continue. Checking and registering happen at once, so
+// that sessions logging in at the same time can't all find the user not connected.
+func (c *Connection) enforceLoginPolicy() (bool, error) {
+	policy := c.loginPolicy()
+	connected := false
+	sessionsByObjectID.WithLock(c.user.Object, func() {
+		connected = len(sessionsOf(c.user.Object)) > 0
+		if connected && policy == DenyLogins {
+			return
+		}
+		c.readOnly.Store(connected && policy == ReadOnlyLogins)
+		addSession(c)
+	})
+	if !connected {
+		return true, nil
+	}
+	switch policy {
+	case DenyLogins:
+		fmt.Fprintln(c.term, "You are already connected in another session.")
+		return false, nil
+	case AllowLogins:
+		return true, nil
+	case ReadOnlyLogins:
+		fmt.Fprint(c.term, "You are already connected in another session, so this one is read only.\n\n")
+		return true, nil
+	default:
+		// Taking over waits for the other sessions to unregister, so it can't hold the lock.
+		return true, juicemud.WithStack(c.takeOver())
+	}
+}
+
+// loginPolicyCommand handles `/login-policy username [policy or default]`, which shows or sets
+// the login policy of a user, for owners only.
+func (c *Connection) loginPolicyCommand(args []string) error {
+	if !c.user.Owner {
+		fmt.Fprintln(c.term, "Only owners can change login policies.")
+		return nil
+	}
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintln(c.term, "usage: /login-policy [username] [takeover, deny, allow, allow-read-only or default]")
+		return nil
+	}
+	user, err := c.game.storage.LoadUser(c.sess.Context(), args[0])
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(c.term, "No user %q\n", args[0])
+		return nil
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(args) == 1 {
+		if user.LoginPolicy == "" {
+			fmt.Fprintf(c.term, "%s uses the server login policy %s\n", user.Name, c.game.loginPolicy.Load())
+		} else {
+			fmt.Fprintf(c.term, "%s uses the login policy %s\n", user.Name, user.LoginPolicy)
+		}
+		return nil
+	}
+	user.LoginPolicy = ""
+	if args[1] != "default" {
+		policy, err := ParseLoginPolicy(args[1])
+		if err != nil {
+			fmt.Fprintln(c.term, err)
+			return nil
+		}
+		user.LoginPolicy = string(policy)
+	}
+	if err := c.game.storage.StoreUser(c.sess.Context(), user, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintf(c.term, "Login policy of %s set to %s\n", user.Name, args[1])
+	return nil
+}
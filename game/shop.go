@@ -0,0 +1,320 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	priceEventType = "price"
+	buyAction      = "buy"
+	sellAction     = "sell"
+)
+
+// priceQuery is the message of price calls to shopkeepers, which can reply with another price
+// than the suggested Price.
+type priceQuery struct {
+	Action   string
+	Customer string
+	Name     string
+	Source   string
+	Price    int64
+}
+
+// stockView is how stock is shown to JavaScript, with a missing Quantity meaning unlimited.
+type stockView struct {
+	Name     string
+	Source   string
+	Price    int64
+	Quantity *int64
+}
+
+// findShop returns the first object with stock, in ID order, detected by customer in its
+// location, or nil if there is none.
+func (g *Game) findShop(ctx context.Context, customer *structs.Object) (*structs.Object, error) {
+	if customer.Location == "" {
+		return nil, nil
+	}
+	room, err := g.storage.LoadObject(ctx, customer.Location, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	neighbours, err := g.storage.LoadObjects(ctx, room.Content, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	ids := make([]string, 0, len(neighbours))
+	for id := range neighbours {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if shop := neighbours[id]; len(shop.Stock) > 0 && shop.Detectable(customer) {
+			return shop, nil
+		}
+	}
+	return nil, nil
+}
+
+// price returns what shop asks for, or offers for, item. Shops sell at the price of the item
+// and buy at half of it, unless their price call handler replies with another price.
+func (g *Game) price(ctx context.Context, shop *structs.Object, customer string, action string, item *structs.StockItem) (int64, error) {
+	price := item.Price
+	if action == sellAction {
+		price /= 2
+	}
+	if !shop.HasCallback(priceEventType, callEventTag) {
+		return price, nil
+	}
+	message, err := goccy.Marshal(&priceQuery{
+		Action:   action,
+		Customer: customer,
+		Name:     item.Name,
+		Source:   item.Source,
+		Price:    price,
+	})
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	reply, err := g.callObject(ctx, shop.Id, priceEventType, string(message))
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	var result float64
+	if err := goccy.Unmarshal([]byte(reply), &result); err != nil {
+		return 0, errors.Wrapf(err, "%q replied with a non numeric price %s", shop.Id, reply)
+	}
+	if result < 0 {
+		return 0, errors.Errorf("%q replied with a negative price %v", shop.Id, result)
+	}
+	return int64(math.Round(result)), nil
+}
+
+// rollback returns err, annotated with undoErr if undoing what err interrupted failed too.
+func rollback(err error, undoErr error) error {
+	if undoErr != nil {
+		return errors.Wrapf(err, "and rolling back failed with %v", undoErr)
+	}
+	return err
+}
+
+// buy makes the customer with the given ID pay for the stock item matching name of the shop in
+// its location, and creates it in the customer, atomically. Returns the new item and the price
+// paid.
+func (g *Game) buy(ctx context.Context, customerID string, name string) (*structs.Object, int64, error) {
+	customer, err := g.storage.LoadObject(ctx, customerID, nil)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	shop, err := g.findShop(ctx, customer)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	if shop == nil {
+		return nil, 0, errors.New("There is no shop here")
+	}
+	keywords := storage.Keywords(name)
+	index := slices.IndexFunc(shop.Stock, func(item structs.StockItem) bool {
+		return len(keywords) > 0 && containsKeywords(item.Name, keywords)
+	})
+	if index == -1 {
+		return nil, 0, errors.Errorf("%s doesn't sell %q", shortDescription(shop), name)
+	}
+	item := shop.Stock[index]
+	price, err := g.price(ctx, shop, customerID, buyAction, &item)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	bought, err := structs.MakeObject(ctx)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	bought.SourcePath = item.Source
+	bought.Location = customerID
+	if err := g.run(ctx, bought, nil); err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	if err := lockSorted([]string{shop.Id, customerID}, func() error {
+		return juicemud.WithStack(g.storage.Buy(ctx, shop.Id, customerID, price, bought))
+	}); errors.Is(err, storage.ErrSoldOut) {
+		return nil, 0, errors.Errorf("%s is sold out", item.Name)
+	} else if errors.Is(err, storage.ErrInsufficientFunds) {
+		return nil, 0, errors.Errorf("You can't afford %s", item.Name)
+	} else if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	return bought, price, nil
+}
+
+// sell makes the shop in the location of the seller with the given ID pay for the carried
+// item matching name, and removes the item, atomically. Returns the sold item and the price
+// paid.
+func (g *Game) sell(ctx context.Context, sellerID string, name string) (*structs.Object, int64, error) {
+	seller, err := g.storage.LoadObject(ctx, sellerID, nil)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	shop, err := g.findShop(ctx, seller)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	if shop == nil {
+		return nil, 0, errors.New("There is no shop here")
+	}
+	carried, err := g.storage.LoadObjects(ctx, seller.Content, nil)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	sold := matchObject(seller, carried, name)
+	if sold == nil {
+		return nil, 0, errors.Errorf("You don't carry %q", name)
+	}
+	index := slices.IndexFunc(shop.Stock, func(item structs.StockItem) bool {
+		return item.Source == sold.SourcePath
+	})
+	if index == -1 {
+		return nil, 0, errors.Errorf("%s doesn't buy %s", shortDescription(shop), shortDescription(sold))
+	}
+	if len(sold.Content) > 0 {
+		return nil, 0, errors.Errorf("Empty %s first", shortDescription(sold))
+	}
+	item := shop.Stock[index]
+	price, err := g.price(ctx, shop, sellerID, sellAction, &item)
+	if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	if err := lockSorted([]string{shop.Id, sellerID, sold.Id}, func() error {
+		return juicemud.WithStack(g.storage.Sell(ctx, shop.Id, sellerID, sold.Id, item.Source, price, func(seller *structs.Object) {
+			unequip(seller, sold.Id)
+		}))
+	}); errors.Is(err, storage.ErrInsufficientFunds) {
+		return nil, 0, errors.Errorf("%s can't afford %s", shortDescription(shop), shortDescription(sold))
+	} else if err != nil {
+		return nil, 0, juicemud.WithStack(err)
+	}
+	return sold, price, nil
+}
+
+// lockSorted runs f holding the locks of ids, taken in sorted order so that it can't deadlock
+// with others doing the same.
+func lockSorted(ids []string, f func() error) error {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	for _, id := range ids {
+		jsContextLocks.Lock(id)
+		defer jsContextLocks.Unlock(id)
+	}
+	return f()
+}
+
+func (c *Connection) listCommand() error {
+	ctx := c.sess.Context()
+	customer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	shop, err := c.game.findShop(ctx, customer)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if shop == nil {
+		fmt.Fprintln(c.term, "There is no shop here")
+		return nil
+	}
+	t := c.table("Item", "Price", "Stock")
+	for i := range shop.Stock {
+		item := &shop.Stock[i]
+		price, err := c.game.price(ctx, shop, customer.Id, buyAction, item)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		quantity := "plenty"
+		if item.Quantity >= 0 {
+			quantity = fmt.Sprint(item.Quantity)
+		}
+		t.AddRow(item.Name, price, quantity)
+	}
+	t.Print()
+	fmt.Fprintf(c.term, "You have %v money\n", customer.Money)
+	return nil
+}
+
+// shopCommand buys or sells the item matching name.
+func (c *Connection) shopCommand(verb string, name string) error {
+	if name == "" {
+		fmt.Fprintf(c.term, "usage: %s [item]\n", verb)
+		return nil
+	}
+	ctx := c.sess.Context()
+	if verb == buyAction {
+		bought, price, err := c.game.buy(ctx, c.actor(), name)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You buy %s for %v", shortDescription(bought), price)))
+		return nil
+	}
+	sold, price, err := c.game.sell(ctx, c.actor(), name)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You sell %s for %v", shortDescription(sold), price)))
+	return nil
+}
+
+// addShopCallbacks adds `setStock([{Name, Source, Price, Quantity?}])`, which makes the object
+// a shop selling objects created from Source and buying objects running Source, and
+// `getStock()`. Shops are asked for prices with a `price` call, if they handle it.
+func addShopCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setStock"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setStock takes [Array] arguments")
+		}
+		views := []stockView{}
+		if err := rc.Copy(&views, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []stockView: %v", args[0], err)
+		}
+		stock := []structs.StockItem{}
+		for _, view := range views {
+			if view.Name == "" || view.Source == "" || view.Price < 0 {
+				return rc.Throw("stock needs Name, Source and a non negative Price")
+			}
+			item := structs.StockItem{Name: view.Name, Source: view.Source, Price: view.Price, Quantity: -1}
+			if view.Quantity != nil {
+				item.Quantity = max(0, *view.Quantity)
+			}
+			stock = append(stock, item)
+		}
+		object.Stock = stock
+		return nil
+	}
+	callbacks["getStock"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		result := []stockView{}
+		for _, item := range object.Stock {
+			view := stockView{Name: item.Name, Source: item.Source, Price: item.Price}
+			if item.Quantity >= 0 {
+				view.Quantity = &item.Quantity
+			}
+			result = append(result, view)
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
@@ -15,6 +15,8 @@ import (
 
 var (
 	Skills = juicemud.NewSyncMap[string, Skill]()
+	// Attributes are the attributes derived from skills, by name.
+	Attributes = juicemud.NewSyncMap[string, *Attribute]()
 )
 
 type SkillDuration float32
@@ -43,6 +45,143 @@ type Skill struct {
 	// 1 - 0.5^8 ~= 0.996.
 	// TL;DR Recharge is when the skill is freely usable again. 0 means immediately.
 	Recharge SkillDuration
+	// Skills might improve with use.
+	// Each use improves the practical level by XP, adjusted by the Curve for the
+	// current level. 0 means the skill doesn't improve with use.
+	XP float32
+	// Curve is the name of the formula, registered with SetCurve, used to adjust XP.
+	// Empty means DiminishingCurve.
+	Curve string
+	// Skills might be forgotten when not used.
+	// This takes the shape of the practical level above half the theoretical level
+	// halving every n seconds without use.
+	// The Forget of a skill is n seconds. 0 means never.
+	Forget SkillDuration
+	// Skills might belong to a Parent skill, like "swords" to "melee".
+	// Users without the skill use it at their level in the parent minus ParentPenalty,
+	// and users without the parent use it at their level in the skill minus ParentPenalty.
+	Parent        string
+	ParentPenalty float32
+}
+
+// EffectiveLevel returns the level at which a user knowing the skills known knows skill, by
+// falling back on the parent skills, or the child skills, of skills the user doesn't know.
+func EffectiveLevel(skill string, known func(skill string) (float32, bool)) float32 {
+	level, _ := effectiveLevel(skill, known, map[string]bool{})
+	return level
+}
+
+func effectiveLevel(skill string, known func(skill string) (float32, bool), visited map[string]bool) (float32, bool) {
+	if visited[skill] {
+		return 0, false
+	}
+	visited[skill] = true
+	if level, found := known(skill); found {
+		return level, true
+	}
+	result, found := float32(0), false
+	if definition, ok := Skills.GetHas(skill); ok && definition.Parent != "" {
+		if level, ok := effectiveLevel(definition.Parent, known, visited); ok {
+			result, found = level-definition.ParentPenalty, true
+		}
+	}
+	for name, child := range Skills.Clone() {
+		if child.Parent != skill {
+			continue
+		}
+		if level, ok := known(name); ok && (!found || level-child.ParentPenalty > result) {
+			result, found = level-child.ParentPenalty, true
+		}
+	}
+	return result, found
+}
+
+// Attribute is a value derived from the levels of skills, like carrying capacity from strength.
+type Attribute struct {
+	// Base is the value without any skills.
+	Base float32
+	// Weights are how much each level of each skill adds to the value.
+	Weights map[string]float32
+}
+
+// Value returns the value of the attribute for a user with the skill levels given by level.
+func (a Attribute) Value(level func(skill string) float32) float32 {
+	result := a.Base
+	for skill, weight := range a.Weights {
+		result += weight * level(skill)
+	}
+	return result
+}
+
+// Improvement returns how much one use improves the skill at level.
+func (s Skill) Improvement(level float32) float32 {
+	if s.XP == 0 {
+		return 0
+	}
+	name := s.Curve
+	if name == "" {
+		name = DiminishingCurve
+	}
+	curve, found := GetCurve(name)
+	if !found {
+		return s.XP
+	}
+	return curve(level, s.XP)
+}
+
+// Forgotten returns what practical is after the skill hasn't been used for unused.
+func (s Skill) Forgotten(practical float32, theoretical float32, unused time.Duration) float32 {
+	floor := theoretical / 2
+	if s.Forget == 0 || practical <= floor || unused <= 0 {
+		return practical
+	}
+	return floor + (practical-floor)*float32(math.Pow(0.5, float64(unused)/float64(s.Forget.Nanoseconds())))
+}
+
+// Curve returns how much a use improves a skill at level, given the XP of the skill.
+type Curve func(level float32, xp float32) float32
+
+const (
+	LinearCurve      = "linear"
+	DiminishingCurve = "diminishing"
+	ExponentialCurve = "exponential"
+)
+
+var (
+	// curves are the formulas skills can use to improve, by name. Register more
+	// with SetCurve.
+	curves     = map[string]Curve{}
+	curvesLock sync.RWMutex
+)
+
+// SetCurve makes curve the formula named name.
+func SetCurve(name string, curve Curve) {
+	curvesLock.Lock()
+	defer curvesLock.Unlock()
+	curves[name] = curve
+}
+
+// GetCurve returns the formula named name, and whether there is one.
+func GetCurve(name string) (Curve, bool) {
+	curvesLock.RLock()
+	defer curvesLock.RUnlock()
+	curve, found := curves[name]
+	return curve, found
+}
+
+func init() {
+	// Every use gives the same improvement.
+	SetCurve(LinearCurve, func(level float32, xp float32) float32 {
+		return xp
+	})
+	// Improvement is halved at level 10, a third at level 20, and so on.
+	SetCurve(DiminishingCurve, func(level float32, xp float32) float32 {
+		return xp * 10 / (10 + float32(math.Max(0, float64(level))))
+	})
+	// Improvement is halved for every 10 levels.
+	SetCurve(ExponentialCurve, func(level float32, xp float32) float32 {
+		return xp * float32(math.Pow(0.5, math.Max(0, float64(level))/10))
+	})
 }
 
 type Use struct {
@@ -103,10 +242,68 @@ type Application struct {
 }
 
 func (s Application) Check() bool {
+	return s.Margin() > 0
+}
+
+// Margin returns by how much the application succeeded, between -1 and 1, where positive
+// margins are successes.
+func (s Application) Margin() float32 {
 	// Success likelihood is ELO with 10 instead of 400 as "90% likely to win delta".
 	// success := float64(skillUses.recharge(s.use)) / (1.0 + math.Pow(10, float64(s.challenge-s.level)*0.1))
 	success := skillUses.recharge(s.Use) / float32(1.0+math.Pow(10, float64(s.Level-s.Challenge)*0.1))
-	return s.Use.RNG(s.Target).Float32() > success
+	return s.Use.RNG(s.Target).Float32() - success
+}
+
+// Resolve returns the grade of the application.
+func (s Application) Resolve() Grade {
+	return GradeOf(s.Margin())
+}
+
+// Grade is how well an application of a skill went.
+type Grade int
+
+const (
+	CriticalFailure Grade = iota
+	Failure
+	Success
+	CriticalSuccess
+)
+
+const (
+	// CriticalMargin is the margin beyond which successes and failures are critical.
+	CriticalMargin = 0.4
+)
+
+// GradeOf returns the grade of an application that succeeded by margin.
+func GradeOf(margin float32) Grade {
+	switch {
+	case margin < -CriticalMargin:
+		return CriticalFailure
+	case margin <= 0:
+		return Failure
+	case margin <= CriticalMargin:
+		return Success
+	}
+	return CriticalSuccess
+}
+
+// Succeeded returns whether g is a success.
+func (g Grade) Succeeded() bool {
+	return g >= Success
+}
+
+func (g Grade) String() string {
+	switch g {
+	case CriticalFailure:
+		return "criticalFailure"
+	case Failure:
+		return "failure"
+	case Success:
+		return "success"
+	case CriticalSuccess:
+		return "criticalSuccess"
+	}
+	return fmt.Sprintf("Grade(%d)", int(g))
 }
 
 type globalSkillUses struct {
@@ -126,3 +126,88 @@ func TestLevel(t *testing.T) {
 		t.Errorf("wanted 0.9, got %v", at)
 	}
 }
+
+func TestImprovement(t *testing.T) {
+	for _, tc := range []struct {
+		skill Skill
+		level float32
+		want  float32
+	}{
+		{Skill{}, 0, 0},
+		{Skill{XP: 2, Curve: LinearCurve}, 50, 2},
+		{Skill{XP: 2}, 0, 2},
+		{Skill{XP: 2}, 10, 1},
+		{Skill{XP: 2, Curve: ExponentialCurve}, 20, 0.5},
+	} {
+		if got := tc.skill.Improvement(tc.level); got != tc.want {
+			t.Errorf("%+v at %v: got %v, want %v", tc.skill, tc.level, got, tc.want)
+		}
+	}
+}
+
+func TestForgotten(t *testing.T) {
+	s := Skill{
+		Forget: 10,
+	}
+	if got := s.Forgotten(30, 40, 10*time.Second); got != 25 {
+		t.Errorf("got %v, want 25", got)
+	}
+	if got := s.Forgotten(15, 40, time.Hour); got != 15 {
+		t.Errorf("got %v, want 15", got)
+	}
+	if got := (Skill{}).Forgotten(30, 40, time.Hour); got != 30 {
+		t.Errorf("got %v, want 30", got)
+	}
+}
+
+func TestGradeOf(t *testing.T) {
+	for _, tc := range []struct {
+		margin float32
+		want   Grade
+	}{
+		{-1, CriticalFailure},
+		{-0.5, CriticalFailure},
+		{-0.4, Failure},
+		{0, Failure},
+		{0.1, Success},
+		{0.4, Success},
+		{0.5, CriticalSuccess},
+	} {
+		if got := GradeOf(tc.margin); got != tc.want {
+			t.Errorf("GradeOf(%v): got %v, want %v", tc.margin, got, tc.want)
+		}
+	}
+	if Failure.Succeeded() || !Success.Succeeded() || !CriticalSuccess.Succeeded() {
+		t.Errorf("wrong Succeeded")
+	}
+}
+
+func TestEffectiveLevel(t *testing.T) {
+	Skills.Set("melee", Skill{})
+	Skills.Set("swords", Skill{Parent: "melee", ParentPenalty: 5})
+	Skills.Set("axes", Skill{Parent: "melee", ParentPenalty: 3})
+	for _, tc := range []struct {
+		known map[string]float32
+		skill string
+		want  float32
+	}{
+		{map[string]float32{"swords": 12}, "swords", 12},
+		{map[string]float32{"melee": 10}, "swords", 5},
+		{map[string]float32{"swords": 12}, "melee", 7},
+		{map[string]float32{"swords": 12, "axes": 11}, "melee", 8},
+		{map[string]float32{"axes": 11}, "swords", 3},
+		{map[string]float32{}, "swords", 0},
+	} {
+		got := EffectiveLevel(tc.skill, func(skill string) (float32, bool) {
+			level, found := tc.known[skill]
+			return level, found
+		})
+		if got != tc.want {
+			t.Errorf("%v in %q: got %v, want %v", tc.known, tc.skill, got, tc.want)
+		}
+	}
+	attribute := Attribute{Base: 10, Weights: map[string]float32{"melee": 2}}
+	if got := attribute.Value(func(skill string) float32 { return 3 }); got != 16 {
+		t.Errorf("got %v, want 16", got)
+	}
+}
@@ -0,0 +1,182 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud/structs"
+	"golang.org/x/term"
+	"rogchap.com/v8go"
+)
+
+const (
+	defaultSlowThreshold     = 50 * time.Millisecond
+	defaultCriticalThreshold = 150 * time.Millisecond
+	// maxSlowRuns is how many of the most recent slow runs are kept.
+	maxSlowRuns = 100
+	// maxAlertMessage is how much of the message of a call alerts show.
+	maxAlertMessage = 200
+)
+
+var (
+	// slowThreshold is the nanoseconds after which runs are listed as slow.
+	slowThreshold atomic.Int64
+	// criticalThreshold is the nanoseconds after which runs alert the console of the object
+	// and the subscribed wizards.
+	criticalThreshold atomic.Int64
+	slowRuns          = &slowRunLog{}
+	slowAlerts        = &alertSubscribers{}
+)
+
+func init() {
+	slowThreshold.Store(int64(defaultSlowThreshold))
+	criticalThreshold.Store(int64(defaultCriticalThreshold))
+}
+
+// slowRun is a run that took longer than the slow threshold.
+type slowRun struct {
+	At       time.Time
+	Object   string
+	Source   string
+	Event    string
+	Duration time.Duration
+	Error    string
+}
+
+// slowRunLog is the most recent slow runs.
+type slowRunLog struct {
+	mutex sync.Mutex
+	runs  []slowRun
+}
+
+func (s *slowRunLog) add(run slowRun) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.runs = append(s.runs, run)
+	if len(s.runs) > maxSlowRuns {
+		s.runs = s.runs[len(s.runs)-maxSlowRuns:]
+	}
+}
+
+// recent returns the slow runs, most recent first.
+func (s *slowRunLog) recent() []slowRun {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([]slowRun, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		result = append(result, s.runs[i])
+	}
+	return result
+}
+
+// alertSubscribers are the terminals of the wizards subscribed to critical slow run alerts.
+type alertSubscribers struct {
+	mutex  sync.Mutex
+	fanout *Fanout
+}
+
+func (a *alertSubscribers) subscribe(t *term.Terminal) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.fanout = a.fanout.Push(t)
+}
+
+func (a *alertSubscribers) unsubscribe(t *term.Terminal) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.fanout = a.fanout.Drop(t)
+}
+
+func (a *alertSubscribers) Write(b []byte) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.fanout.Write(b)
+}
+
+// checkSlowRun records the run of event by object if it took longer than the slow threshold,
+// and alerts about it if it took longer than the critical threshold. Alerts show the stack of
+// the callback if it failed, and the message of the call otherwise.
+func checkSlowRun(object *structs.Object, event string, call *structs.Call, d time.Duration, runErr error) {
+	if d < time.Duration(slowThreshold.Load()) {
+		return
+	}
+	run := slowRun{
+		At:       time.Now(),
+		Object:   object.Id,
+		Source:   object.SourcePath,
+		Event:    event,
+		Duration: d,
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	slowRuns.add(run)
+	if d < time.Duration(criticalThreshold.Load()) {
+		return
+	}
+	alert := fmt.Sprintf("---- #%s (%s) took %v handling %s ----\n", object.Id, object.SourcePath, d, event)
+	jserr := &v8go.JSError{}
+	if errors.As(runErr, &jserr) {
+		alert += fmt.Sprintf("%s\n%s\n%s\n", jserr.Location, jserr.Message, jserr.StackTrace)
+	} else if runErr != nil {
+		alert += fmt.Sprintf("%v\n", runErr)
+	} else if call != nil {
+		message := call.Message
+		if len(message) > maxAlertMessage {
+			message = message[:maxAlertMessage] + "..."
+		}
+		alert += fmt.Sprintf("message: %s\n", message)
+	}
+	fmt.Fprint(consoleByObjectID.Get(object.Id), alert)
+	fmt.Fprint(slowAlerts, alert)
+}
+
+// slowCommand handles `/stats perf slow [threshold|critical [duration]]|[alerts on|off]`.
+func (c *Connection) slowCommand(args []string) error {
+	usage := "usage: /stats perf slow [threshold|critical [duration]]|[alerts on|off]"
+	if len(args) == 0 {
+		fmt.Fprintf(c.term, "Slow threshold %v, critical threshold %v\n\n", time.Duration(slowThreshold.Load()), time.Duration(criticalThreshold.Load()))
+		t := c.table("At", "Object", "Source", "Event", "Duration", "Error")
+		for _, run := range slowRuns.recent() {
+			t.AddRow(run.At.Format(time.DateTime), run.Object, run.Source, run.Event, run.Duration, run.Error)
+		}
+		t.Print()
+		return nil
+	}
+	switch args[0] {
+	case "threshold", "critical":
+		threshold := &slowThreshold
+		if args[0] == "critical" {
+			threshold = &criticalThreshold
+		}
+		if len(args) == 1 {
+			fmt.Fprintln(c.term, time.Duration(threshold.Load()))
+			return nil
+		}
+		d, err := time.ParseDuration(args[1])
+		if err != nil || d <= 0 {
+			fmt.Fprintln(c.term, usage)
+			return nil
+		}
+		threshold.Store(int64(d))
+		fmt.Fprintf(c.term, "Set %s threshold to %v\n", args[0], d)
+	case "alerts":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			fmt.Fprintln(c.term, usage)
+			return nil
+		}
+		if args[1] == "on" {
+			slowAlerts.subscribe(c.term)
+			fmt.Fprintln(c.term, "Subscribed to critical slow run alerts")
+		} else {
+			slowAlerts.unsubscribe(c.term)
+			fmt.Fprintln(c.term, "Unsubscribed from critical slow run alerts")
+		}
+	default:
+		fmt.Fprintln(c.term, usage)
+	}
+	return nil
+}
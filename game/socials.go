@@ -0,0 +1,311 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"gopkg.in/yaml.v3"
+	"rogchap.com/v8go"
+)
+
+const (
+	socialsSource   = "/socials.yaml"
+	socialEventType = "social"
+	defaultPronouns = "they"
+	initialSocials  = `# The socials everyone can perform, like "smile" or "smile bob". Messages can use
+# %n for the name of the actor, %t for the name of the target, %e/%m/%s for the
+# subject/object/possessive pronoun of the actor and %E/%M/%S for those of the
+# target. %N and %T are capitalized names, and %% is a percent sign.
+socials:
+  smile:
+    self: You smile.
+    others: "%N smiles."
+    selfTarget: You smile at %t.
+    target: "%N smiles at you."
+    othersTarget: "%N smiles at %t."
+  nod:
+    self: You nod.
+    others: "%N nods."
+    selfTarget: You nod at %t.
+    target: "%N nods at you."
+    othersTarget: "%N nods at %t."
+  wave:
+    self: You wave.
+    others: "%N waves."
+    selfTarget: You wave at %t.
+    target: "%N waves at you."
+    othersTarget: "%N waves at %t."
+  bow:
+    self: You bow.
+    others: "%N bows."
+    selfTarget: You bow before %t.
+    target: "%N bows before you."
+    othersTarget: "%N bows before %t."
+  laugh:
+    self: You laugh.
+    others: "%N laughs."
+    selfTarget: You laugh at %t.
+    target: "%N laughs at you."
+    othersTarget: "%N laughs at %t."
+  grin:
+    self: You grin.
+    others: "%N grins."
+    selfTarget: You grin at %t.
+    target: "%N grins at you."
+    othersTarget: "%N grins at %t."
+  shrug:
+    self: You shrug.
+    others: "%N shrugs."
+  sigh:
+    self: You sigh.
+    others: "%N sighs."
+  hug:
+    selfTarget: You hug %t.
+    target: "%N hugs you."
+    othersTarget: "%N hugs %t."
+  pat:
+    selfTarget: You pat %t on %S head.
+    target: "%N pats you on your head."
+    othersTarget: "%N pats %t on %S head."
+`
+)
+
+var (
+	// socials are the socials everyone can perform, by name.
+	socials = juicemud.NewSyncMap[string, Social]()
+	// pronounSets are the subject, object and possessive pronouns of each kind of pronouns.
+	pronounSets = map[string][3]string{
+		"he":   {"he", "him", "his"},
+		"she":  {"she", "her", "her"},
+		"they": {"they", "them", "their"},
+		"it":   {"it", "it", "its"},
+	}
+)
+
+// Social is an emote with messages for everyone involved. Socials without target messages
+// can't be performed at anyone, and socials without untargeted messages need a target.
+type Social struct {
+	Self         string `yaml:"self"`
+	Others       string `yaml:"others"`
+	SelfTarget   string `yaml:"selfTarget"`
+	Target       string `yaml:"target"`
+	OthersTarget string `yaml:"othersTarget"`
+}
+
+type socialDefinitions struct {
+	Socials map[string]Social `yaml:"socials"`
+}
+
+// socialPerformed is the message of social events.
+type socialPerformed struct {
+	Social string
+	Actor  string
+	Target string
+}
+
+// socialParty is someone taking part in a social, as a viewer sees them.
+type socialParty struct {
+	Name     string
+	Pronouns string
+}
+
+func (s socialParty) pronoun(index int) string {
+	set, found := pronounSets[s.Pronouns]
+	if !found {
+		set = pronounSets[defaultPronouns]
+	}
+	return set[index]
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// composeSocial replaces the substitution codes of template with the names and pronouns of
+// actor and target.
+func composeSocial(template string, actor socialParty, target socialParty) string {
+	result := &strings.Builder{}
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			result.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'n':
+			result.WriteString(actor.Name)
+		case 'N':
+			result.WriteString(capitalize(actor.Name))
+		case 't':
+			result.WriteString(target.Name)
+		case 'T':
+			result.WriteString(capitalize(target.Name))
+		case 'e':
+			result.WriteString(actor.pronoun(0))
+		case 'm':
+			result.WriteString(actor.pronoun(1))
+		case 's':
+			result.WriteString(actor.pronoun(2))
+		case 'E':
+			result.WriteString(target.pronoun(0))
+		case 'M':
+			result.WriteString(target.pronoun(1))
+		case 'S':
+			result.WriteString(target.pronoun(2))
+		case '%':
+			result.WriteByte('%')
+		default:
+			result.WriteByte('%')
+			result.WriteByte(template[i])
+		}
+	}
+	return result.String()
+}
+
+// loadSocials adds the socials defined in socialsSource, if it exists.
+func (g *Game) loadSocials(ctx context.Context) error {
+	if _, err := g.storage.LoadFile(ctx, socialsSource); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return juicemud.WithStack(err)
+	}
+	content, _, err := g.storage.LoadSource(ctx, socialsSource)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	definitions := &socialDefinitions{}
+	if err := yaml.Unmarshal(content, definitions); err != nil {
+		return errors.Wrapf(err, "trying to parse %q", socialsSource)
+	}
+	for name, social := range definitions.Socials {
+		socials.Set(name, social)
+	}
+	return nil
+}
+
+// partyAs returns object as viewer sees it in socials.
+func partyAs(viewer *structs.Object, object *structs.Object) socialParty {
+	name, found := viewer.Nicknames[object.Id]
+	if !found {
+		name = shortDescription(object)
+	}
+	return socialParty{Name: name, Pronouns: object.Pronouns}
+}
+
+// socialCommand performs the social named by the first word of line, at the target named by
+// the rest of it if any, and returns whether there was such a social.
+func (c *Connection) socialCommand(line string) (bool, error) {
+	name, targetName, _ := strings.Cut(strings.TrimSpace(line), " ")
+	targetName = strings.TrimSpace(targetName)
+	social, found := socials.GetHas(name)
+	if !found {
+		return false, nil
+	}
+	ctx := c.sess.Context()
+	actor, err := c.object()
+	if err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	if actor.Location == "" {
+		return true, errors.New("There is nobody here")
+	}
+	location, err := c.game.loadLocation(ctx, actor.Location)
+	if err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	var target *structs.Object
+	self, others, toTarget := social.Self, social.Others, ""
+	if targetName != "" {
+		if social.SelfTarget == "" {
+			return true, errors.Errorf("You can't %s at anyone", name)
+		}
+		if target = matchObject(actor, location.Content, targetName); target == nil {
+			return true, errors.Errorf("There is no %q here", targetName)
+		}
+		self, others, toTarget = social.SelfTarget, social.OthersTarget, social.Target
+	} else if social.Self == "" {
+		return true, errors.Errorf("%s whom?", capitalize(name))
+	}
+	compose := func(viewer *structs.Object, template string) string {
+		targetParty := socialParty{}
+		if target != nil {
+			targetParty = partyAs(viewer, target)
+		}
+		return composeSocial(template, partyAs(viewer, actor), targetParty)
+	}
+	fmt.Fprintln(c.term, c.wrap(compose(actor, self)))
+	for id, viewer := range location.Content {
+		if id == actor.Id || !actor.Detectable(viewer) {
+			continue
+		}
+		template := others
+		if target != nil && id == target.Id {
+			template = toTarget
+		}
+		if other, found := envByObjectID.GetHas(id); found && template != "" {
+			fmt.Fprintln(other.term, other.wrap(compose(viewer, template)))
+		}
+	}
+	performed := &socialPerformed{Social: name, Actor: actor.Id}
+	recipients := []string{actor.Location}
+	if target != nil {
+		performed.Target = target.Id
+		recipients = append(recipients, target.Id)
+	}
+	at := c.game.storage.Queue().After(0)
+	for _, id := range recipients {
+		if err := c.game.emitAny(ctx, at, id, socialEventType, performed); err != nil {
+			return true, juicemud.WithStack(err)
+		}
+	}
+	return true, nil
+}
+
+// addSocialConfigCallbacks adds `addSocial(name, {Self?, Others?, SelfTarget?, Target?,
+// OthersTarget?})`, which lets everyone perform the social. Performed socials send social events
+// with `{Social, Actor, Target}` to the location and the target.
+func addSocialConfigCallbacks(callbacks js.Callbacks) {
+	callbacks["addSocial"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsObject() {
+			return rc.Throw("addSocial takes [string, Object] arguments")
+		}
+		social := Social{}
+		if err := rc.Copy(&social, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to Social: %v", args[1], err)
+		}
+		if social.Self == "" && social.SelfTarget == "" {
+			return rc.Throw("socials need a self or selfTarget message")
+		}
+		socials.Set(args[0].String(), social)
+		return nil
+	}
+}
+
+// addPronounCallbacks adds `setPronouns(pronouns)`, which sets the pronouns socials use for the
+// object, one of "he", "she", "they" and "it".
+func addPronounCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setPronouns"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("setPronouns takes [string] arguments")
+		}
+		if _, found := pronounSets[args[0].String()]; !found {
+			return rc.Throw("%q isn't he, she, they or it", args[0].String())
+		}
+		object.Pronouns = args[0].String()
+		return nil
+	}
+}
@@ -0,0 +1,263 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/skills"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	heardEventType            = "heard"
+	defaultLanguageDifficulty = 10
+)
+
+var (
+	// spokenLanguages are the difficulty of understanding each spoken language, by the name of
+	// the skill used to speak and understand it.
+	spokenLanguages = juicemud.NewSyncMap[string, float32]()
+)
+
+// speechHeard is the message of heard events, with the text as the listener understood it.
+type speechHeard struct {
+	Speaker  string
+	Language string
+	Text     string
+	// Grade is how well the listener understood the language, empty when it's not spoken in any.
+	Grade string
+}
+
+func speechHash(parts ...string) uint64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// garbleWord replaces the letters of word with other letters, the same ones every time the
+// word is garbled.
+func garbleWord(word string) string {
+	rng := rand.New(rand.NewPCG(speechHash(strings.ToLower(word)), 0))
+	result := &strings.Builder{}
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			result.WriteRune(r)
+			continue
+		}
+		garbled := rune('a' + rng.IntN(26))
+		if unicode.IsUpper(r) {
+			garbled = unicode.ToUpper(garbled)
+		}
+		result.WriteRune(garbled)
+	}
+	return result.String()
+}
+
+// comprehend returns text as a listener who understood its language with grade hears it:
+// successes hear all of it, failures hear some words as gibberish, and critical failures
+// hear only gibberish. The same listener garbles the same words every time.
+func comprehend(text string, grade skills.Grade, listener string) string {
+	if grade.Succeeded() {
+		return text
+	}
+	words := strings.Fields(text)
+	for i, word := range words {
+		if grade == skills.CriticalFailure || speechHash(listener, strings.ToLower(word))%2 == 1 {
+			words[i] = garbleWord(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// describeSpeech returns what listener sees when hearing speaker.
+func describeSpeech(listener *structs.Object, speaker *structs.Object, heard *speechHeard) string {
+	name := "Someone"
+	if speaker.Detectable(listener) {
+		name = capitalize(partyAs(listener, speaker).Name)
+	}
+	in := ""
+	if heard.Language != "" {
+		in = " in " + heard.Language
+	}
+	return fmt.Sprintf("%s says%s, \"%s\"", name, in, heard.Text)
+}
+
+// speak makes everyone in the location of speaker hear text in the language speaker is
+// speaking, as well as they understand it, which also trains their skill in the language.
+func (g *Game) speak(ctx context.Context, running *structs.Object, speaker *structs.Object, text string) error {
+	if speaker.Location == "" {
+		return nil
+	}
+	location, err := g.loadLocation(ctx, speaker.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	difficulty, found := spokenLanguages.GetHas(speaker.Speaking)
+	if !found {
+		difficulty = defaultLanguageDifficulty
+	}
+	at := g.storage.Queue().After(0)
+	for id, listener := range location.Content {
+		if id == speaker.Id {
+			continue
+		}
+		heard := &speechHeard{Speaker: speaker.Id, Language: speaker.Speaking, Text: text}
+		if speaker.Speaking != "" {
+			var grade skills.Grade
+			if err := g.modifyObject(ctx, running, id, func(o *structs.Object) error {
+				var err error
+				grade, err = g.useSkill(ctx, o, speaker.Speaking, speaker.Id, difficulty)
+				return err
+			}); err != nil {
+				return juicemud.WithStack(err)
+			}
+			heard.Text = comprehend(text, grade, id)
+			heard.Grade = grade.String()
+		}
+		if other, found := envByObjectID.GetHas(id); found {
+			fmt.Fprintln(other.term, other.wrap(describeSpeech(listener, speaker, heard)))
+		}
+		if err := g.emitAny(ctx, at, id, heardEventType, heard); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// sayCommand handles `say [text]` unless the connected object has a say command of its own,
+// and returns whether line was a say command.
+func (c *Connection) sayCommand(line string) (bool, error) {
+	command, text, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if command != "say" {
+		return false, nil
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return true, errors.New("usage: say [text]")
+	}
+	speaker, err := c.object()
+	if err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	in := ""
+	if speaker.Speaking != "" {
+		in = " in " + speaker.Speaking
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You say%s, \"%s\"", in, text)))
+	return true, juicemud.WithStack(c.game.speak(c.sess.Context(), nil, speaker, text))
+}
+
+// knownLanguages returns the spoken languages object has any skill in at now.
+func knownLanguages(object *structs.Object, now time.Time) []string {
+	result := []string{}
+	for language := range spokenLanguages.Keys() {
+		if object.SkillLevel(language, now) > 0 {
+			result = append(result, language)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// speakCommand handles `speak [language|none]`, listing the spoken languages the connected
+// object knows, or choosing the one it says things in.
+func (c *Connection) speakCommand(args []string) error {
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	known := knownLanguages(object, time.Now())
+	if len(args) == 0 {
+		if len(known) == 0 {
+			fmt.Fprintln(c.term, "You don't know any languages")
+			return nil
+		}
+		t := c.table("Language", "Speaking")
+		for _, language := range known {
+			speaking := ""
+			if language == object.Speaking {
+				speaking = "*"
+			}
+			t.AddRow(language, speaking)
+		}
+		t.Print()
+		return nil
+	}
+	language := strings.Join(args, " ")
+	if language == "none" {
+		language = ""
+	} else if i := sort.SearchStrings(known, language); i == len(known) || known[i] != language {
+		return errors.Errorf("You don't know how to speak %q", language)
+	}
+	if err := c.game.modifyObject(c.sess.Context(), nil, object.Id, func(o *structs.Object) error {
+		o.Speaking = language
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if language == "" {
+		fmt.Fprintln(c.term, "You no longer speak any particular language")
+	} else {
+		fmt.Fprintf(c.term, "You now speak %s\n", language)
+	}
+	return nil
+}
+
+// addSpokenLanguageCallbacks adds `addSpokenLanguage(skill, difficulty?)`, which makes skill a
+// language objects can speak, and listeners need to succeed with against difficulty, default
+// 10, to understand.
+func addSpokenLanguageCallbacks(callbacks js.Callbacks) {
+	callbacks["addSpokenLanguage"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsString() || args[0].String() == "" || (len(args) == 2 && !args[1].IsNumber()) {
+			return rc.Throw("addSpokenLanguage takes [non-empty string, number?] arguments")
+		}
+		difficulty := float32(defaultLanguageDifficulty)
+		if len(args) == 2 {
+			difficulty = float32(args[1].Number())
+		}
+		spokenLanguages.Set(args[0].String(), difficulty)
+		return nil
+	}
+}
+
+// addSpeechCallbacks adds `setSpeaking(language)`, which makes the object speak language, or
+// no particular language if it's empty, and `speak(text)`, which makes everyone in the location
+// of the object hear text. Listeners get heard events with `{Speaker, Language, Text, Grade}`,
+// where Text is what they understood.
+func (g *Game) addSpeechCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setSpeaking"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("setSpeaking takes [string] arguments")
+		}
+		if language := args[0].String(); language != "" && !spokenLanguages.Has(language) {
+			return rc.Throw("%q isn't a spoken language", language)
+		}
+		object.Speaking = args[0].String()
+		return nil
+	}
+	callbacks["speak"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("speak takes [string] arguments")
+		}
+		if err := g.speak(ctx, object, object, args[0].String()); err != nil {
+			return rc.Throw("trying to speak: %v", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,256 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+
+	goccy "github.com/goccy/go-json"
+)
+
+const (
+	// maxStateViolations is how many of the most recent state violations are kept.
+	maxStateViolations = 100
+)
+
+var (
+	stateViolations = &stateViolationLog{}
+	schemaTypes     = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+)
+
+// stateSchema is the subset of JSON schema that states can be validated against.
+type stateSchema struct {
+	// Type is a type name, or a list of them.
+	Type                 any                     `json:"type"`
+	Properties           map[string]*stateSchema `json:"properties"`
+	Required             []string                `json:"required"`
+	AdditionalProperties *bool                   `json:"additionalProperties"`
+	Items                *stateSchema            `json:"items"`
+	Enum                 []any                   `json:"enum"`
+	Minimum              *float64                `json:"minimum"`
+	Maximum              *float64                `json:"maximum"`
+}
+
+// types returns the type names the schema allows, or nil if it allows any.
+func (s *stateSchema) types() ([]string, error) {
+	switch t := s.Type.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{t}, nil
+	case []any:
+		result := []string{}
+		for _, name := range t {
+			if str, ok := name.(string); ok {
+				result = append(result, str)
+			} else {
+				return nil, errors.Errorf("type %v isn't a string", name)
+			}
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("type %v isn't a string or a list of strings", s.Type)
+}
+
+// check returns an error if the schema uses unknown types.
+func (s *stateSchema) check() error {
+	types, err := s.types()
+	if err != nil {
+		return err
+	}
+	for _, name := range types {
+		if !slices.Contains(schemaTypes, name) {
+			return errors.Errorf("%q isn't one of the types %v", name, schemaTypes)
+		}
+	}
+	for _, property := range s.Properties {
+		if err := property.check(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check()
+	}
+	return nil
+}
+
+// typeOf returns the JSON schema type name of a value decoded from JSON.
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validate returns an error describing where value, at path, violates the schema.
+func (s *stateSchema) validate(path string, value any) error {
+	types, err := s.types()
+	if err != nil {
+		return err
+	}
+	actual := typeOf(value)
+	if len(types) > 0 && !slices.Contains(types, actual) && !(actual == "integer" && slices.Contains(types, "number")) {
+		return errors.Errorf("%s is %s, not %s", path, actual, strings.Join(types, " or "))
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool {
+		return reflect.DeepEqual(allowed, value)
+	}) {
+		return errors.Errorf("%s is %v, not one of %v", path, value, s.Enum)
+	}
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return errors.Errorf("%s is %v, less than %v", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return errors.Errorf("%s is %v, more than %v", path, v, *s.Maximum)
+		}
+	case []any:
+		if s.Items != nil {
+			for index, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, index), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, found := v[name]; !found {
+				return errors.Errorf("%s.%s is missing", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, found := s.Properties[name]
+			if !found {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return errors.Errorf("%s.%s isn't allowed", path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, v[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stateViolation is a run whose state didn't match the schema of its source.
+type stateViolation struct {
+	At     time.Time
+	Object string
+	Source string
+	Error  string
+}
+
+// stateViolationLog is the most recent state violations.
+type stateViolationLog struct {
+	mutex      sync.Mutex
+	violations []stateViolation
+}
+
+func (s *stateViolationLog) add(violation stateViolation) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.violations = append(s.violations, violation)
+	if len(s.violations) > maxStateViolations {
+		s.violations = s.violations[len(s.violations)-maxStateViolations:]
+	}
+}
+
+// recent returns the state violations, most recent first.
+func (s *stateViolationLog) recent() []stateViolation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([]stateViolation, 0, len(s.violations))
+	for i := len(s.violations) - 1; i >= 0; i-- {
+		result = append(result, s.violations[i])
+	}
+	return result
+}
+
+// validateState returns an error if state, the JSON state object got from a run, violates
+// schema, and records and logs the violation to the console of object.
+func validateState(object *structs.Object, schema *stateSchema, state string) error {
+	if schema == nil {
+		return nil
+	}
+	var value any
+	if err := goccy.Unmarshal([]byte(state), &value); err != nil {
+		return errors.Wrapf(err, "trying to parse state of %q", object.Id)
+	}
+	if err := schema.validate("state", value); err != nil {
+		stateViolations.add(stateViolation{
+			At:     time.Now(),
+			Object: object.Id,
+			Source: object.SourcePath,
+			Error:  err.Error(),
+		})
+		log.New(consoleByObjectID.Get(object.Id), "", 0).Printf("---- state rejected by schema ----\n%v", err)
+		return errors.Wrapf(err, "state of %q violates the schema of %s", object.Id, object.SourcePath)
+	}
+	return nil
+}
+
+// printStateViolations shows the most recent state violations.
+func (c *Connection) printStateViolations() {
+	t := c.table("At", "Object", "Source", "Error")
+	for _, violation := range stateViolations.recent() {
+		t.AddRow(violation.At.Format(time.DateTime), violation.Object, violation.Source, violation.Error)
+	}
+	t.Print()
+}
+
+// addStateSchemaCallbacks adds `setStateSchema(schema)`, which makes the engine validate the
+// state against schema after every run, and reject runs whose state violates it, keeping the
+// state from before the run. Violations are logged to the console of the object and listed by
+// `/stats errors`. Schemas support the JSON schema keywords type, properties, required,
+// additionalProperties (as a boolean), items, enum, minimum and maximum, so a schema with
+// `additionalProperties: false` catches typos like `state.cout`.
+func addStateSchemaCallbacks(schema **stateSchema, callbacks js.Callbacks) {
+	callbacks["setStateSchema"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsObject() {
+			return rc.Throw("setStateSchema takes [Object] arguments")
+		}
+		result := &stateSchema{}
+		if err := rc.Copy(result, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to stateSchema: %v", args[0], err)
+		}
+		if err := result.check(); err != nil {
+			return rc.Throw("invalid state schema: %v", err)
+		}
+		*schema = result
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/lang"
+)
+
+var (
+	statsCommands = map[string]func(c *Connection, args []string) error{
+		"storage": func(c *Connection, args []string) error {
+			count, err := c.game.storage.CountObjects(c.sess.Context())
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			fmt.Fprintf(c.term, "%s stored\n\n", lang.Declare(int(count), "objects"))
+			stats, err := c.game.storage.IndexStats(c.sess.Context())
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			t := c.table("Index", "Entries", "Distinct values")
+			for _, stat := range stats {
+				t.AddRow(stat.Kind, stat.Entries, stat.DistinctValues)
+			}
+			t.Print()
+			return nil
+		},
+		"cache": func(c *Connection, args []string) error {
+			stats := c.game.storage.ObjectCache().Stats()
+			hitRate := 0.0
+			if total := stats.Hits + stats.Misses; total > 0 {
+				hitRate = 100 * float64(stats.Hits) / float64(total)
+			}
+			t := c.table("Cache", "Entries", "Size", "Hits", "Misses", "Hit rate", "Evictions")
+			t.AddRow("objects", stats.Entries, stats.Size, stats.Hits, stats.Misses, fmt.Sprintf("%.1f%%", hitRate), stats.Evictions)
+			t.Print()
+			return nil
+		},
+		"scripts": func(c *Connection, args []string) error {
+			column, filter := "total", ""
+			if len(args) > 0 {
+				column = args[0]
+			}
+			if len(args) > 1 {
+				filter = args[1]
+			}
+			if len(args) > 2 || !slices.Contains(scriptStatsColumns, column) {
+				fmt.Fprintf(c.term, "usage: /stats scripts [column] [source filter] (column is %s)\n", lang.Enumerator{Operator: "or"}.Do(scriptStatsColumns...))
+				return nil
+			}
+			stats := js.GetScriptStats()
+			t := c.table("Compiled scripts", "Count")
+			t.AddRow("reused in isolate", stats.Hits)
+			t.AddRow("from code cache", stats.CodeCacheHits)
+			t.AddRow("from scratch", stats.Misses)
+			t.AddRow("code cache rejected", stats.CodeCacheRejects)
+			t.Print()
+			fmt.Fprintln(c.term)
+			c.printScriptTimings(column, filter)
+			return nil
+		},
+		"boot": func(c *Connection, args []string) error {
+			c.printBootResults()
+			return nil
+		},
+		"errors": func(c *Connection, args []string) error {
+			c.printStateViolations()
+			return nil
+		},
+		"memory": func(c *Connection, args []string) error {
+			return juicemud.WithStack(c.memoryCommand(args))
+		},
+		"perf": func(c *Connection, args []string) error {
+			if len(args) == 0 || args[0] != "slow" {
+				fmt.Fprintln(c.term, "usage: /stats perf slow")
+				return nil
+			}
+			return juicemud.WithStack(c.slowCommand(args[1:]))
+		},
+		"deadletters": func(c *Connection, args []string) error {
+			count, err := c.game.storage.CountDeadLetters(c.sess.Context())
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			letters, err := c.game.storage.DeadLetters(c.sess.Context(), 20)
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			t := c.table("Id", "Object", "Event", "Attempts", "Failed", "Error")
+			for _, letter := range letters {
+				t.AddRow(letter.Id, letter.Object, letter.Name, letter.Attempts, time.Unix(0, letter.FailedAt).Format(time.DateTime), letter.Error)
+			}
+			t.Print()
+			fmt.Fprintf(c.term, "%s, showing the %d most recent\n", lang.Declare(int(count), "dead letters"), len(letters))
+			return nil
+		},
+	}
+)
+
+func (c *Connection) stats(args []string) error {
+	if len(args) > 0 {
+		if cmd, found := statsCommands[args[0]]; found {
+			return juicemud.WithStack(cmd(c, args[1:]))
+		}
+	}
+	names := make(sort.StringSlice, 0, len(statsCommands))
+	for name := range statsCommands {
+		names = append(names, name)
+	}
+	sort.Sort(names)
+	fmt.Fprintf(c.term, "usage: /stats %s\n", lang.Enumerator{Pattern: "[%s]", Operator: "or"}.Do(names...))
+	return nil
+}
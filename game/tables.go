@@ -0,0 +1,105 @@
+package game
+
+import (
+	"math/rand/v2"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"rogchap.com/v8go"
+)
+
+const (
+	// maxTableDepth limits how deep tables can nest, to stop tables rolling each other forever.
+	maxTableDepth = 16
+)
+
+var (
+	// tables are the weighted tables scripts roll for loot and encounters.
+	tables = juicemud.NewSyncMap[string, *rollableTable]()
+)
+
+// tableEntry is an entry of a weighted table. Entries with Table roll that table instead of
+// returning Value, and each point of luck adds Luck to the Weight of the entry.
+type tableEntry struct {
+	Value  string
+	Table  string
+	Weight float64
+	Luck   float64
+}
+
+// rollableTable is a weighted table of entries.
+type rollableTable []tableEntry
+
+// rollTable returns the value of a random entry of the table with the given name, or "" if that
+// entry has no value, with the chance of each entry proportional to its weight given luck.
+func rollTable(name string, luck float64, random func() float64) (string, error) {
+	for depth := 0; depth < maxTableDepth; depth++ {
+		t, found := tables.GetHas(name)
+		if !found {
+			return "", errors.Errorf("no table %q", name)
+		}
+		weights := make([]float64, len(*t))
+		sum := 0.0
+		for i, entry := range *t {
+			weights[i] = max(0, entry.Weight+luck*entry.Luck)
+			sum += weights[i]
+		}
+		if sum == 0 {
+			return "", nil
+		}
+		r := random() * sum
+		var chosen *tableEntry
+		for i := range *t {
+			if chosen = &(*t)[i]; r < weights[i] {
+				break
+			}
+			r -= weights[i]
+		}
+		if chosen.Table == "" {
+			return chosen.Value, nil
+		}
+		name = chosen.Table
+	}
+	return "", errors.Errorf("tables nested deeper than %v", maxTableDepth)
+}
+
+// addTableCallbacks adds `registerTable(name, [{Value?, Table?, Weight, Luck?}])`, and
+// `rollTable(name, luck?)` which returns the value of a random entry, or null if it has none.
+func addTableCallbacks(callbacks js.Callbacks) {
+	callbacks["registerTable"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsString() || !args[1].IsArray() {
+			return rc.Throw("registerTable takes [string, Array] arguments")
+		}
+		t := rollableTable{}
+		if err := rc.Copy(&t, args[1]); err != nil {
+			return rc.Throw("trying to convert %v to []tableEntry: %v", args[1], err)
+		}
+		for _, entry := range t {
+			if entry.Weight < 0 {
+				return rc.Throw("table entries can't have negative weights")
+			}
+		}
+		tables.Set(args[0].String(), &t)
+		return nil
+	}
+	callbacks["rollTable"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) < 1 || len(args) > 2 || !args[0].IsString() || (len(args) == 2 && !args[1].IsNumber()) {
+			return rc.Throw("rollTable takes [string, number?] arguments")
+		}
+		luck := 0.0
+		if len(args) == 2 {
+			luck = args[1].Number()
+		}
+		value, err := rollTable(args[0].String(), luck, rand.Float64)
+		if err != nil {
+			return rc.Throw("trying to roll %q: %v", args[0].String(), err)
+		}
+		if value == "" {
+			return v8go.Null(rc.Context().Isolate())
+		}
+		return rc.String(value)
+	}
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zond/juicemud"
+)
+
+const (
+	sessionTakenOverEventType = "sessionTakenOver"
+	// takeoverTimeout is how long a new session waits for the sessions it takes over to end.
+	takeoverTimeout = 5 * time.Second
+)
+
+// takeOver detaches the sessions the user is already connected with, if any, so that this
+// connection continues them instead of being refused or running alongside them, and emits
+// sessionTakenOver to the object of the user.
+func (c *Connection) takeOver() error {
+	var previous any
+	for _, old := range sessionsOf(c.user.Object) {
+		if old == c {
+			continue
+		}
+		if previous == nil {
+			previous = old.sess.RemoteAddr()
+		}
+		fmt.Fprintln(old.term, c.wrap(fmt.Sprintf("This session was taken over by a new connection from %s.", remoteIP(c.sess.RemoteAddr()))))
+		old.sess.Close()
+		select {
+		case <-old.done:
+		case <-time.After(takeoverTimeout):
+		}
+	}
+	if previous == nil {
+		return nil
+	}
+	fmt.Fprintf(c.term, "Took over your session from %v.\n\n", previous)
+	return juicemud.WithStack(c.game.loadRunSave(c.sess.Context(), c.user.Object, &AnyCall{
+		Name: sessionTakenOverEventType,
+		Tag:  emitEventTag,
+		Content: map[string]any{
+			"remote":   c.sess.RemoteAddr(),
+			"previous": previous,
+			"username": c.user.Name,
+			"object":   c.user.Object,
+		},
+	}))
+}
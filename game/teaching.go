@@ -0,0 +1,98 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/game/skills"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	// defaultTeachingRecharge is how long a teacher has to wait before teaching a skill to
+	// the same student again, for skills without a Recharge.
+	defaultTeachingRecharge = time.Minute
+)
+
+var (
+	// lastTeachings are the times teachers last taught skills to students.
+	lastTeachings = juicemud.NewSyncMap[string, time.Time]()
+)
+
+// teachSkill raises the theoretical and practical level of skill of the student with the given
+// ID by at most amount, and never above the theoretical level of teacher. Returns the raise.
+func (g *Game) teachSkill(ctx context.Context, teacher *structs.Object, studentID string, skill string, amount float32) (float32, error) {
+	if studentID == teacher.Id {
+		return 0, errors.Errorf("%q can't teach itself", teacher.Id)
+	}
+	if amount <= 0 {
+		return 0, errors.Errorf("can't teach %v of %q", amount, skill)
+	}
+	recharge := skills.Skills.Get(skill).Recharge.Duration()
+	if recharge == 0 {
+		recharge = defaultTeachingRecharge
+	}
+	key := fmt.Sprintf("%s.%s.%s", teacher.Id, studentID, skill)
+	now := time.Now()
+	if last, found := lastTeachings.GetHas(key); found && now.Sub(last) < recharge {
+		return 0, errors.Errorf("%q can teach %q to %q again in %v", teacher.Id, skill, studentID, recharge-now.Sub(last))
+	}
+
+	jsContextLocks.Lock(studentID)
+	defer jsContextLocks.Unlock(studentID)
+
+	student, err := g.storage.LoadObject(ctx, studentID, nil)
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	if student.Skills == nil {
+		student.Skills = map[string]structs.Skill{}
+	}
+	known := student.Skills[skill]
+	limit := teacher.Skills[skill].Theoretical
+	if known.Theoretical >= limit {
+		return 0, errors.Errorf("%q knows no more of %q than %q", teacher.Id, skill, studentID)
+	}
+	raise := min(amount, limit-known.Theoretical)
+	from := known.Practical
+	known.Theoretical += raise
+	known.Practical = min(known.Practical+raise, known.Theoretical)
+	student.Skills[skill] = known
+	if err := g.storage.StoreObject(ctx, nil, student); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	lastTeachings.Set(key, now)
+	if err := g.emitAny(ctx, g.storage.Queue().After(0), studentID, skillImprovedEventType, &skillImproved{
+		Skill: skill,
+		From:  from,
+		To:    known.Practical,
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return raise, nil
+}
+
+// addTeachingCallbacks adds `teachSkill(targetID, skill, amount)`, which lets the object teach
+// skill to the target and returns how much was taught.
+func (g *Game) addTeachingCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["teachSkill"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 3 || !args[0].IsString() || !args[1].IsString() || !args[2].IsNumber() {
+			return rc.Throw("teachSkill takes [string, string, number] arguments")
+		}
+		raise, err := g.teachSkill(ctx, object, args[0].String(), args[1].String(), float32(args[2].Number()))
+		if err != nil {
+			return rc.Throw("trying to teach %q to %q: %v", args[1].String(), args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(raise)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", raise, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,243 @@
+package game
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	drowningEventType = "drowning"
+
+	swimMovement  = "swim"
+	flyMovement   = "fly"
+	climbMovement = "climb"
+
+	// drowningSweepInterval is how often objects in water that can't swim lose health.
+	drowningSweepInterval = 5 * time.Second
+	// drowningGrace is how long objects can stay in water without swimming before they drown.
+	drowningGrace = 30 * time.Second
+	// drowningDamage is the health lost every drowningSweepInterval after drowningGrace.
+	drowningDamage = 10
+)
+
+// terrain is how a terrain type affects movement into rooms of that type.
+type terrain struct {
+	// requires is a list of movements, any of which is needed to enter.
+	requires []string
+	// drowns objects that can't swim.
+	drowns bool
+	// slowdown multiplies the travel time of exits into the terrain, for objects that can't fly.
+	slowdown float32
+}
+
+var (
+	// terrains are the terrain types rooms can have, besides the default empty one.
+	terrains = map[string]terrain{
+		"water":  {drowns: true},
+		"swamp":  {slowdown: 2},
+		"cliff":  {requires: []string{climbMovement, flyMovement}},
+		"chasm":  {requires: []string{flyMovement}},
+		"forest": {slowdown: 1.5},
+	}
+	movements = []string{swimMovement, flyMovement, climbMovement}
+	// drownings are when the objects in water that can't swim entered it, by object ID.
+	drownings = juicemud.NewSyncMap[string, time.Time]()
+)
+
+// drowningMessage is the message of drowning events.
+type drowningMessage struct {
+	Location string
+	// Seconds is how long the object has been drowning.
+	Seconds float64
+	Health  float32
+}
+
+// canMove returns whether object has any of the wanted movements.
+func canMove(object *structs.Object, wanted ...string) bool {
+	for _, movement := range wanted {
+		if slices.Contains(object.Movements, movement) {
+			return true
+		}
+	}
+	return false
+}
+
+// drowns returns whether object drowns in destination.
+func drowns(object *structs.Object, destination *structs.Object) bool {
+	return terrains[destination.Terrain].drowns && !canMove(object, swimMovement)
+}
+
+// travelFactor returns what the travel time of object into destination is multiplied by.
+func travelFactor(object *structs.Object, destination *structs.Object) float32 {
+	if t := terrains[destination.Terrain]; t.slowdown > 0 && !canMove(object, flyMovement) {
+		return t.slowdown
+	}
+	return 1
+}
+
+// checkTerrain returns an error if the object with the given ID lacks the movements the
+// terrain of destination requires.
+func (g *Game) checkTerrain(ctx context.Context, running *structs.Object, id string, destination string) error {
+	target, err := g.loadObjectOrRunning(ctx, running, destination)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	t := terrains[target.Terrain]
+	if len(t.requires) == 0 {
+		return nil
+	}
+	object, err := g.loadObjectOrRunning(ctx, running, id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if !canMove(object, t.requires...) {
+		return errors.Errorf("%q can't enter the %s of %q without one of %v", id, target.Terrain, destination, t.requires)
+	}
+	return nil
+}
+
+// enterTerrain starts the drowning of the object with the given ID if it can't swim in
+// destination, which it just moved to.
+func (g *Game) enterTerrain(ctx context.Context, running *structs.Object, id string, destination string) error {
+	target, err := g.loadObjectOrRunning(ctx, running, destination)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	object, err := g.loadObjectOrRunning(ctx, running, id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if drowns(object, target) {
+		drownings.Swap(id, time.Time{}, time.Now())
+	}
+	return nil
+}
+
+// runDrownings damages the drowning objects every drowningSweepInterval.
+func (g *Game) runDrownings(ctx context.Context) {
+	ticker := time.NewTicker(drowningSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.sweepDrownings(ctx, time.Now()); err != nil {
+			log.Printf("trying to sweep drownings: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+// sweepDrownings forgets the drownings of objects that left the water or learned to swim, and
+// damages, and emits drowning to, those that have drowned for longer than drowningGrace.
+func (g *Game) sweepDrownings(ctx context.Context, now time.Time) error {
+	for id, since := range drownings.Clone() {
+		object, err := g.storage.LoadObject(ctx, id, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			drownings.Del(id)
+			continue
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if object.Location == "" {
+			drownings.Del(id)
+			continue
+		}
+		location, err := g.storage.LoadObject(ctx, object.Location, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if !drowns(object, location) {
+			drownings.Del(id)
+			continue
+		}
+		if now.Sub(since) < drowningGrace {
+			continue
+		}
+		health := object.Health
+		if object.MaxHealth > 0 {
+			if health, err = g.damage(ctx, nil, id, drowningDamage, location.Id); err != nil {
+				log.Printf("trying to drown %q in %q: %v", id, location.Id, err)
+				continue
+			}
+		}
+		if err := g.emitAny(ctx, g.storage.Queue().After(0), id, drowningEventType, &drowningMessage{
+			Location: location.Id,
+			Seconds:  now.Sub(since).Seconds(),
+			Health:   health,
+		}); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if health == 0 && object.MaxHealth > 0 {
+			drownings.Del(id)
+		}
+	}
+	return nil
+}
+
+// addTerrainCallbacks adds `setTerrain(terrain)`, which makes the engine check the movements
+// of everything entering the object against its terrain, and `getTerrain()`. Terrains are:
+//   - "" for plain ground.
+//   - "water", where objects that can't swim drown, and lose health after 30 seconds.
+//   - "swamp" and "forest", which slow down travel for objects that can't fly.
+//   - "cliff", which needs climbing or flying to enter.
+//   - "chasm", which needs flying to enter.
+//
+// Also adds `setMovements([movement])` and `getMovements()`, for the movements of the object
+// among "swim", "fly" and "climb". Objects drowning get drowning events with
+// `{Location, Seconds, Health}`.
+func addTerrainCallbacks(object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setTerrain"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("setTerrain takes [string] arguments")
+		}
+		if _, found := terrains[args[0].String()]; args[0].String() != "" && !found {
+			return rc.Throw("%q isn't a terrain", args[0].String())
+		}
+		object.Terrain = args[0].String()
+		return nil
+	}
+	callbacks["getTerrain"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		return rc.String(object.Terrain)
+	}
+	callbacks["setMovements"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setMovements takes [Array] arguments")
+		}
+		result := []string{}
+		if err := rc.Copy(&result, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []string: %v", args[0], err)
+		}
+		for _, movement := range result {
+			if !slices.Contains(movements, movement) {
+				return rc.Throw("%q isn't one of the movements %v", movement, movements)
+			}
+		}
+		object.Movements = result
+		return nil
+	}
+	callbacks["getMovements"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		result := object.Movements
+		if result == nil {
+			result = []string{}
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
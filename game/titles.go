@@ -0,0 +1,229 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	maxNickname = 30
+)
+
+// titles is the result of getTitles.
+type titles struct {
+	Titles []string
+	Title  string
+}
+
+// titleCommand handles `title [title|none]`, listing the earned titles of the connected object,
+// or choosing the one shown after its name.
+func (c *Connection) titleCommand(args []string) error {
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if len(args) == 0 {
+		if len(object.Titles) == 0 {
+			fmt.Fprintln(c.term, "You haven't earned any titles")
+			return nil
+		}
+		t := c.table("Title", "Chosen")
+		for _, title := range object.Titles {
+			chosen := ""
+			if title == object.Title {
+				chosen = "*"
+			}
+			t.AddRow(title, chosen)
+		}
+		t.Print()
+		return nil
+	}
+	title := strings.Join(args, " ")
+	if title == "none" {
+		title = ""
+	} else if i := slices.IndexFunc(object.Titles, func(earned string) bool {
+		return strings.EqualFold(earned, title)
+	}); i == -1 {
+		return errors.Errorf("You haven't earned the title %q", title)
+	} else {
+		title = object.Titles[i]
+	}
+	if err := c.game.modifyObject(c.sess.Context(), nil, object.Id, func(o *structs.Object) error {
+		o.Title = title
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if title == "" {
+		fmt.Fprintln(c.term, "You no longer show a title")
+	} else {
+		fmt.Fprintf(c.term, "You are now known as %s, %s\n", shortDescription(object), title)
+	}
+	return nil
+}
+
+// rememberCommand handles `remember [target] as [nickname]`, making the connected object see
+// target as nickname.
+func (c *Connection) rememberCommand(line string) error {
+	usage := errors.New("usage: remember [target] as [nickname]")
+	name, nickname, found := strings.Cut(line, " as ")
+	name, nickname = strings.TrimSpace(name), strings.TrimSpace(nickname)
+	if !found || name == "" || nickname == "" {
+		return usage
+	}
+	if len([]rune(nickname)) > maxNickname {
+		return errors.Errorf("Nicknames are at most %d characters", maxNickname)
+	}
+	ctx := c.sess.Context()
+	viewer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if viewer.Location == "" {
+		return errors.Errorf("There is no %q here", name)
+	}
+	location, err := c.game.loadLocation(ctx, viewer.Location)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	target := matchObject(viewer, location.Content, name)
+	if target == nil || target.Id == viewer.Id {
+		return errors.Errorf("There is no %q here", name)
+	}
+	if err := c.game.modifyObject(ctx, nil, viewer.Id, func(o *structs.Object) error {
+		if o.Nicknames == nil {
+			o.Nicknames = map[string]string{}
+		}
+		o.Nicknames[target.Id] = nickname
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You will remember %s as %s.", shortDescription(target), nickname)))
+	return nil
+}
+
+// forgetCommand handles `forget [nickname]`, removing the nickname the connected object gave
+// something.
+func (c *Connection) forgetCommand(nickname string) error {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" {
+		return errors.New("usage: forget [nickname]")
+	}
+	viewer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	forgotten := false
+	if err := c.game.modifyObject(c.sess.Context(), nil, viewer.Id, func(o *structs.Object) error {
+		for id, name := range o.Nicknames {
+			if strings.EqualFold(name, nickname) {
+				delete(o.Nicknames, id)
+				forgotten = true
+			}
+		}
+		return nil
+	}); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if !forgotten {
+		return errors.Errorf("You don't remember anyone as %q", nickname)
+	}
+	fmt.Fprintf(c.term, "You forget about %s\n", nickname)
+	return nil
+}
+
+// whoCommand lists the connected users, as the connected object sees them.
+func (c *Connection) whoCommand() error {
+	viewer, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	ids := map[string]bool{}
+	for id := range envByObjectID.Keys() {
+		ids[id] = true
+	}
+	objects, err := c.game.storage.LoadObjects(c.sess.Context(), ids, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	languages := c.languages()
+	names := []string{}
+	for _, object := range objects {
+		if !object.Detectable(viewer) || len(object.Descriptions) == 0 {
+			continue
+		}
+		object.Translate(languages)
+		object.Personalize(viewer)
+		names = append(names, object.Descriptions[0].Short)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(c.term, name)
+	}
+	fmt.Fprintf(c.term, "%d connected\n", len(names))
+	return nil
+}
+
+// addTitleCallbacks adds `grantTitle(objectID, title)` and `revokeTitle(objectID, title)`, which
+// give and take titles objects can choose to show after their name with the `title` command,
+// and `getTitles(objectID)`, returning `{Titles, Title}` with the earned and chosen titles.
+func (g *Game) addTitleCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	change := func(name string, grant bool) func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		return func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+			args := info.Args()
+			if len(args) != 2 || !args[0].IsString() || !args[1].IsString() || args[1].String() == "" {
+				return rc.Throw("%s takes [string, non-empty string] arguments", name)
+			}
+			title := args[1].String()
+			if err := g.modifyObject(ctx, object, args[0].String(), func(o *structs.Object) error {
+				if grant {
+					if !slices.Contains(o.Titles, title) {
+						o.Titles = append(o.Titles, title)
+					}
+					return nil
+				}
+				o.Titles = slices.DeleteFunc(o.Titles, func(earned string) bool {
+					return earned == title
+				})
+				if o.Title == title {
+					o.Title = ""
+				}
+				return nil
+			}); err != nil {
+				return rc.Throw("trying to change titles of %q: %v", args[0].String(), err)
+			}
+			return nil
+		}
+	}
+	callbacks["grantTitle"] = change("grantTitle", true)
+	callbacks["revokeTitle"] = change("revokeTitle", false)
+	callbacks["getTitles"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getTitles takes [string] arguments")
+		}
+		holder, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		result := titles{Titles: holder.Titles, Title: holder.Title}
+		if result.Titles == nil {
+			result.Titles = []string{}
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+	"golang.org/x/term"
+)
+
+var (
+	tracesByObjectID = juicemud.NewSyncMap[string, *Fanout]()
+)
+
+func addTrace(id string, term *term.Terminal) {
+	tracesByObjectID.WithLock(id, func() {
+		tracesByObjectID.Set(id, tracesByObjectID.Get(id).Push(term))
+	})
+}
+
+func delTrace(id string, term *term.Terminal) {
+	tracesByObjectID.WithLock(id, func() {
+		tracesByObjectID.Set(id, tracesByObjectID.Get(id).Drop(term))
+	})
+}
+
+// delTraces removes term from the traces of all objects, and returns the IDs it was tracing.
+func delTraces(term *term.Terminal) []string {
+	result := []string{}
+	for id, fanout := range tracesByObjectID.Clone() {
+		if fanout != nil && (*fanout)[term] {
+			delTrace(id, term)
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func traceReceived(ev *structs.Event, started time.Time, err error) {
+	fanout := tracesByObjectID.Get(ev.Object)
+	if fanout == nil || len(*fanout) == 0 {
+		return
+	}
+	late := started.Sub(time.Unix(0, int64(ev.At)))
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+	fmt.Fprintf(fanout, "#%s <- %s %s (%v after due, handled in %v: %s)\n", ev.Object, ev.Call.Name, ev.Call.Message, late, time.Since(started), result)
+}
+
+func traceEmitted(from string, to string, name string, message string, at structs.Timestamp) {
+	fanout := tracesByObjectID.Get(from)
+	if fanout == nil || len(*fanout) == 0 {
+		return
+	}
+	fmt.Fprintf(fanout, "#%s -> #%s %s %s (due in %v)\n", from, to, name, message, time.Until(time.Unix(0, int64(at))))
+}
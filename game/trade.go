@@ -0,0 +1,256 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage"
+)
+
+const (
+	tradeCompletedEventType = "tradeCompleted"
+)
+
+var (
+	// trades are the ongoing trades by the IDs of their parties.
+	trades = juicemud.NewSyncMap[string, *tradeSession]()
+)
+
+// tradeSession is a trade between two users, completed when both have confirmed the current
+// offers.
+type tradeSession struct {
+	mutex     sync.Mutex
+	offers    [2]storage.TradeOffer
+	confirmed [2]bool
+}
+
+// tradeCompleted is the message of tradeCompleted events.
+type tradeCompleted struct {
+	Offers []storage.TradeOffer
+}
+
+// party returns the index of the offer of id, and the ID of the other party.
+func (t *tradeSession) party(id string) (int, string) {
+	if t.offers[0].Party == id {
+		return 0, t.offers[1].Party
+	}
+	return 1, t.offers[0].Party
+}
+
+// tell prints message to the connection of the object with the given ID, if it has one.
+func tell(id string, message string) {
+	if c, found := envByObjectID.GetHas(id); found {
+		fmt.Fprintln(c.term, c.wrap(message))
+	}
+}
+
+// cancelTrade ends the trade of the object with the given ID, if any.
+func cancelTrade(id string) {
+	t, found := trades.GetHas(id)
+	if !found {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, other := t.party(id)
+	trades.Del(id)
+	trades.Del(other)
+	tell(other, "The trade was cancelled")
+}
+
+// completeTrade atomically swaps the offers of t, and emits tradeCompleted to both parties.
+func (g *Game) completeTrade(ctx context.Context, t *tradeSession) error {
+	ids := []string{t.offers[0].Party, t.offers[1].Party}
+	for _, offer := range t.offers {
+		ids = append(ids, offer.Items...)
+	}
+	slices.Sort(ids)
+	if err := func() error {
+		for _, id := range ids {
+			jsContextLocks.Lock(id)
+			defer jsContextLocks.Unlock(id)
+		}
+		return juicemud.WithStack(g.storage.Trade(ctx, t.offers[0], t.offers[1]))
+	}(); err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, offer := range t.offers {
+		for _, item := range offer.Items {
+			if err := g.unequipMoved(ctx, nil, offer.Party, item); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+	}
+	at := g.storage.Queue().After(0)
+	message := &tradeCompleted{Offers: t.offers[:]}
+	for _, offer := range t.offers {
+		if err := g.emitAny(ctx, at, offer.Party, tradeCompletedEventType, message); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// tradeCommand opens a trade with the user matching a name, or changes, shows, confirms or
+// cancels the current trade.
+func (c *Connection) tradeCommand(args []string) error {
+	ctx := c.sess.Context()
+	usage := func() error {
+		fmt.Fprintln(c.term, "usage: trade [user|offer [item]|money [amount]|accept|cancel]")
+		return nil
+	}
+	t, trading := trades.GetHas(c.actor())
+	if len(args) == 0 {
+		if !trading {
+			return usage()
+		}
+		return juicemud.WithStack(c.showTrade(t))
+	}
+	if !trading {
+		if len(args) != 1 {
+			return usage()
+		}
+		return juicemud.WithStack(c.openTrade(args[0]))
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if trades.Get(c.actor()) != t {
+		return errors.New("The trade was cancelled")
+	}
+	me, other := t.party(c.actor())
+	switch args[0] {
+	case "cancel":
+		trades.Del(c.actor())
+		trades.Del(other)
+		fmt.Fprintln(c.term, "You cancel the trade")
+		tell(other, "The trade was cancelled")
+		return nil
+	case "accept":
+		t.confirmed[me] = true
+		if !t.confirmed[1-me] {
+			fmt.Fprintln(c.term, "You accept the trade, waiting for the other party")
+			tell(other, "The other party accepts the trade")
+			return nil
+		}
+		trades.Del(c.actor())
+		trades.Del(other)
+		if err := c.game.completeTrade(ctx, t); err != nil {
+			tell(other, "The trade failed")
+			return errors.Wrap(err, "The trade failed")
+		}
+		fmt.Fprintln(c.term, "The trade is completed")
+		tell(other, "The trade is completed")
+		return nil
+	case "offer":
+		if len(args) != 2 {
+			return usage()
+		}
+		object, err := c.object()
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		carried, err := c.game.storage.LoadObjects(ctx, object.Content, nil)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		item := matchObject(object, carried, args[1])
+		if item == nil {
+			return errors.Errorf("You don't carry %q", args[1])
+		}
+		if !slices.Contains(t.offers[me].Items, item.Id) {
+			t.offers[me].Items = append(t.offers[me].Items, item.Id)
+		}
+		tell(other, fmt.Sprintf("The other party offers %s", shortDescription(item)))
+	case "money":
+		if len(args) != 2 {
+			return usage()
+		}
+		amount, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount < 0 {
+			return usage()
+		}
+		t.offers[me].Money = amount
+		tell(other, fmt.Sprintf("The other party offers %v money", amount))
+	default:
+		return usage()
+	}
+	t.confirmed = [2]bool{}
+	return juicemud.WithStack(c.showTrade(t))
+}
+
+func (c *Connection) openTrade(name string) error {
+	ctx := c.sess.Context()
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	room, err := c.game.storage.LoadObject(ctx, object.Location, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	neighbours, err := c.game.storage.LoadObjects(ctx, room.Content, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	delete(neighbours, object.Id)
+	other := matchObject(object, neighbours, name)
+	if other == nil {
+		return errors.Errorf("There is no %q here", name)
+	}
+	if !envByObjectID.Has(other.Id) {
+		return errors.Errorf("%s can't trade", shortDescription(other))
+	}
+	t := &tradeSession{
+		offers: [2]storage.TradeOffer{{Party: object.Id}, {Party: other.Id}},
+	}
+	if !trades.Swap(object.Id, nil, t) {
+		return errors.New("You are already trading")
+	}
+	if !trades.Swap(other.Id, nil, t) {
+		trades.Del(object.Id)
+		return errors.Errorf("%s is already trading", shortDescription(other))
+	}
+	fmt.Fprintln(c.term, c.wrap(fmt.Sprintf("You open a trade with %s", shortDescription(other))))
+	tell(other.Id, fmt.Sprintf("%s opens a trade with you, see `trade`", shortDescription(object)))
+	return nil
+}
+
+func (c *Connection) showTrade(t *tradeSession) error {
+	ids := map[string]bool{}
+	for _, offer := range t.offers {
+		for _, item := range offer.Items {
+			ids[item] = true
+		}
+	}
+	items, err := c.game.storage.LoadObjects(c.sess.Context(), ids, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	me, _ := t.party(c.actor())
+	tbl := c.table("Party", "Offer", "Accepted")
+	for index, offer := range t.offers {
+		party := "Them"
+		if index == me {
+			party = "You"
+		}
+		names := []any{fmt.Sprintf("%v money", offer.Money)}
+		for _, id := range offer.Items {
+			if item, found := items[id]; found {
+				names = append(names, shortDescription(item))
+			} else {
+				names = append(names, id)
+			}
+		}
+		for _, name := range names {
+			tbl.AddRow(party, name, t.confirmed[index])
+			party = ""
+		}
+	}
+	tbl.Print()
+	return nil
+}
@@ -0,0 +1,171 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/storage"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	maxTransactionAttempts = 3
+	transactionBackoff     = 10 * time.Millisecond
+)
+
+// transact runs fn with an object mapping each of ids to its state, and atomically stores the
+// states fn leaves in it. The running object is represented by its `state` global, which is
+// copied, and replaced after the others have been stored.
+//
+// Objects running concurrently, or changing state between the load and the store, make the
+// attempt fail with storage.ErrStateConflict without storing anything.
+func (g *Game) transact(ctx context.Context, rc *js.RunContext, object *structs.Object, ids []string, fn *v8go.Function) (*v8go.Value, error) {
+	others := []string{}
+	for _, id := range ids {
+		if id != object.Id && !slices.Contains(others, id) {
+			others = append(others, id)
+		}
+	}
+	slices.Sort(others)
+	for index, id := range others {
+		if !jsContextLocks.TryLock(id) {
+			for _, locked := range others[:index] {
+				jsContextLocks.Unlock(locked)
+			}
+			return nil, juicemud.WithStack(storage.ErrStateConflict)
+		}
+	}
+	defer func() {
+		for _, id := range others {
+			jsContextLocks.Unlock(id)
+		}
+	}()
+
+	expected := map[string]string{}
+	states, err := v8go.NewObjectTemplate(rc.Context().Isolate()).NewInstance(rc.Context())
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	for _, id := range others {
+		other, err := g.storage.LoadObject(ctx, id, nil)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		expected[id] = other.State
+		stateJSON := other.State
+		if stateJSON == "" {
+			stateJSON = "{}"
+		}
+		state, err := v8go.JSONParse(rc.Context(), stateJSON)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if err := states.Set(id, state); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	if slices.Contains(ids, object.Id) {
+		// Copying the state makes failed attempts leave it untouched.
+		current, err := rc.State()
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		currentJSON, err := v8go.JSONStringify(rc.Context(), current)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		state, err := v8go.JSONParse(rc.Context(), currentJSON)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if err := states.Set(object.Id, state); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+
+	result, err := fn.Call(rc.Context().Global(), states)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+
+	updates := []storage.StateUpdate{}
+	for _, id := range others {
+		state, err := states.Get(id)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		stateJSON, err := v8go.JSONStringify(rc.Context(), state)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		expectedJSON := expected[id]
+		updates = append(updates, storage.StateUpdate{
+			Object:   id,
+			Expected: &expectedJSON,
+			State:    stateJSON,
+		})
+	}
+	if slices.Contains(ids, object.Id) {
+		state, err := states.Get(object.Id)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		stateJSON, err := v8go.JSONStringify(rc.Context(), state)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		updates = append(updates, storage.StateUpdate{
+			Object: object.Id,
+			State:  stateJSON,
+		})
+	}
+	if err := g.storage.UpdateStates(ctx, updates); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	if slices.Contains(ids, object.Id) {
+		state, err := states.Get(object.Id)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if err := rc.SetState(state); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	return result, nil
+}
+
+// addTransactionCallbacks adds `transaction(ids, fn)`, which calls fn with an object mapping
+// each of ids to its state and atomically stores the states fn leaves in it. Conflicting
+// attempts are retried, discarding the changes fn made, and transaction throws if they keep
+// conflicting. Returns the return value of fn.
+func (g *Game) addTransactionCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["transaction"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 2 || !args[0].IsArray() || !args[1].IsFunction() {
+			return rc.Throw("transaction takes [[]string, function] arguments")
+		}
+		ids := []string{}
+		if err := rc.Copy(&ids, args[0]); err != nil {
+			return rc.Throw("trying to copy %v to a &[]string{}: %v", args[0], err)
+		}
+		fn, err := args[1].AsFunction()
+		if err != nil {
+			return rc.Throw("trying to cast %v to *v8go.Function: %v", args[1], err)
+		}
+		for attempt := 1; ; attempt++ {
+			result, err := g.transact(ctx, rc, object, ids, fn)
+			if err == nil {
+				return result
+			}
+			if !errors.Is(err, storage.ErrStateConflict) || attempt == maxTransactionAttempts {
+				return rc.Throw("transaction on %v failed: %v", ids, err)
+			}
+			time.Sleep(time.Duration(attempt) * transactionBackoff)
+		}
+	}
+}
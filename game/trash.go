@@ -0,0 +1,138 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	trashSweepInterval = time.Hour
+)
+
+// SetTrashRetention sets how long removed objects can be restored before they are gone.
+func (g *Game) SetTrashRetention(retention time.Duration) {
+	g.trashRetention.Store(int64(retention))
+}
+
+// removeObject moves the object with the given ID, and its content, to the trash. Objects
+// containing users, or the running object, can't be removed. Returns the number of removed
+// objects.
+func (g *Game) removeObject(ctx context.Context, running *structs.Object, id string) (int, error) {
+	tree, err := g.storage.ObjectTree(ctx, id)
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	for _, object := range tree {
+		if running != nil && (object.Id == running.Id || object.Location == running.Id) {
+			return 0, errors.Errorf("%q can't remove itself or its content", running.Id)
+		}
+		if user, err := g.isUser(ctx, object.Id); err != nil {
+			return 0, juicemud.WithStack(err)
+		} else if user {
+			return 0, errors.Errorf("%q is a user", object.Id)
+		}
+	}
+	count, err := g.storage.TrashObject(ctx, id)
+	if err != nil {
+		return count, juicemud.WithStack(err)
+	}
+	return count, nil
+}
+
+// runTrashSweeps permanently removes the objects that have been in the trash longer than the
+// trash retention every trashSweepInterval.
+func (g *Game) runTrashSweeps(ctx context.Context) {
+	ticker := time.NewTicker(trashSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		before := time.Now().Add(-time.Duration(g.trashRetention.Load())).UnixNano()
+		if _, err := g.storage.ExpireTrash(ctx, before); err != nil {
+			log.Printf("trying to expire trash: %v", err)
+			log.Println(juicemud.StackTrace(err))
+		}
+	}
+}
+
+// trashCommand handles `/remove #id`, `/trash` and `/restore #id`.
+func (c *Connection) trashCommand(verb string, args []string) error {
+	ctx := c.sess.Context()
+	switch verb {
+	case "/trash":
+		trashed, err := c.game.storage.TrashedObjects(ctx)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		retention := time.Duration(c.game.trashRetention.Load())
+		t := c.table("Id", "Short", "Location", "Removed", "Expires")
+		for _, entry := range trashed {
+			removed := time.Unix(0, entry.TrashedAt)
+			t.AddRow(entry.Object, entry.Short, entry.Location, removed.Format(time.DateTime), removed.Add(retention).Format(time.DateTime))
+		}
+		t.Print()
+		return nil
+	case "/remove", "/restore":
+		usage := fmt.Sprintf("usage: %s #[id]", verb)
+		if len(args) != 1 {
+			fmt.Fprintln(c.term, usage)
+			return nil
+		}
+		id, ok := parseObjectRef(args[0])
+		if !ok {
+			fmt.Fprintln(c.term, usage)
+			return nil
+		}
+		if verb == "/remove" {
+			if id == c.actor() {
+				return errors.New("You can't remove yourself")
+			}
+			count, err := c.game.removeObject(ctx, nil, id)
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			fmt.Fprintf(c.term, "Moved %d objects to the trash, /restore #%s to undo\n", count, id)
+			return nil
+		}
+		count, err := c.game.storage.RestoreObject(ctx, id)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		fmt.Fprintf(c.term, "Restored %d objects\n", count)
+		return nil
+	}
+	return nil
+}
+
+// addTrashCallbacks adds `removeObject(id)`, which moves the object and its content to the
+// trash, where wizards can `/restore` them until the trash retention has passed. Returns a
+// promise of the number of removed objects. Objects can't remove themselves, their content,
+// or users.
+func (g *Game) addTrashCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["removeObject"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("removeObject takes [string] arguments")
+		}
+		count, err := g.removeObject(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Reject("trying to remove %q: %v", args[0].String(), err)
+		}
+		res, err := rc.JSFromGo(count)
+		if err != nil {
+			return rc.Reject("trying to convert %v to *v8go.Value: %v", count, err)
+		}
+		return rc.Resolve(res)
+	}
+}
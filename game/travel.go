@@ -0,0 +1,202 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+const (
+	departingEventType  = "departing"
+	arrivedEventType    = "arrived"
+	exitFailedEventType = "exitFailed"
+)
+
+var (
+	// travels are the ongoing travels by the ID of the traveller.
+	travels = juicemud.NewSyncMap[string, *travel]()
+)
+
+// travel is a move through an exit with a travel time, which can be cancelled until it's done.
+type travel struct {
+	timer *time.Timer
+}
+
+// travelPhase is the message of departing and arrived events.
+type travelPhase struct {
+	Exit        string
+	Source      string
+	Destination string
+	// Duration is the travel time in seconds.
+	Duration float64
+}
+
+// exitFailed is the message of exitFailed events, with Grade being how badly the traveller
+// failed the challenge, "failure" or "criticalFailure".
+type exitFailed struct {
+	Traveller string
+	Exit      string
+	Grade     string
+	Message   string
+}
+
+// cancelTravel stops the travel of the object with the given ID, and returns whether it was
+// travelling.
+func cancelTravel(id string) bool {
+	t, found := travels.GetHas(id)
+	if !found {
+		return false
+	}
+	t.timer.Stop()
+	return travels.CompareAndDelete(id, t)
+}
+
+// relocate moves the object with the given ID to destination, if housing, locks and terrain
+// allow it.
+func (g *Game) relocate(ctx context.Context, running *structs.Object, id string, destination string) error {
+	if err := g.checkEntry(ctx, running, id, destination); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.checkLocks(ctx, running, id, destination); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.checkTerrain(ctx, running, id, destination); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if err := g.moveObject(ctx, running, id, destination); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.enterTerrain(ctx, running, id, destination))
+}
+
+// traverse moves the traveller with the given ID from source through exit, checking the same
+// rules as moveObject calls from JavaScript, and emits arrived to it.
+func (g *Game) traverse(ctx context.Context, travellerID string, source string, exit *structs.Exit) error {
+	traveller, err := g.storage.LoadObject(ctx, travellerID, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if traveller.Location != source {
+		return errors.Errorf("%q is no longer in %q", travellerID, source)
+	}
+	if err := g.relocate(ctx, nil, travellerID, exit.Destination); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(g.emitAny(ctx, g.storage.Queue().After(0), travellerID, arrivedEventType, &travelPhase{
+		Exit:        exit.Descriptions[0].Short,
+		Source:      source,
+		Destination: exit.Destination,
+		Duration:    float64(exit.TravelTime),
+	}))
+}
+
+// exitCommand moves the connected object through the exit of its location named line, if any,
+// and returns whether there was one. Exits with a travel time emit departing, show their
+// travel message, and move the object when the time has passed unless `stop` cancels it.
+func (c *Connection) exitCommand(line string) (bool, error) {
+	ctx := c.sess.Context()
+	traveller, err := c.object()
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	if traveller.Location == "" {
+		return false, nil
+	}
+	location, err := c.game.storage.LoadObject(ctx, traveller.Location, nil)
+	if err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	name := strings.TrimSpace(line)
+	_, exits := location.Inspect(traveller)
+	var exit *structs.Exit
+	for i := range exits {
+		if strings.EqualFold(exits[i].Descriptions[0].Short, name) {
+			exit = &exits[i]
+			break
+		}
+	}
+	if exit == nil {
+		return false, nil
+	}
+	if err := c.game.checkTutorialGate(ctx, traveller); err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	for i := range exit.UseChallenges {
+		if grade := exit.UseChallenges[i].Resolve(traveller, location); !grade.Succeeded() {
+			message := exit.UseChallenges[i].Message
+			if message == "" {
+				message = fmt.Sprintf("You can't go %s", name)
+			}
+			// Both the traveller and the location get to decide the consequences of failing.
+			failed := &exitFailed{Traveller: traveller.Id, Exit: name, Grade: grade.String(), Message: message}
+			at := c.game.storage.Queue().After(0)
+			for _, id := range []string{traveller.Id, location.Id} {
+				if err := c.game.emitAny(ctx, at, id, exitFailedEventType, failed); err != nil {
+					return true, juicemud.WithStack(err)
+				}
+			}
+			return true, errors.New(message)
+		}
+	}
+	destination, err := c.game.storage.LoadObject(ctx, exit.Destination, nil)
+	if err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	if t := terrains[destination.Terrain]; len(t.requires) > 0 && !canMove(traveller, t.requires...) {
+		return true, errors.Errorf("You can't go %s without being able to %s", name, strings.Join(t.requires, " or "))
+	}
+	travelTime := exit.TravelTime * travelFactor(traveller, destination)
+	if travelTime <= 0 {
+		if err := c.game.traverse(ctx, traveller.Id, location.Id, exit); err != nil {
+			return true, juicemud.WithStack(err)
+		}
+		return true, juicemud.WithStack(c.describeLong())
+	}
+	duration := time.Duration(float64(travelTime) * float64(time.Second))
+	t := &travel{}
+	if !travels.Swap(traveller.Id, nil, t) {
+		return true, errors.New("You are already on your way, `stop` first")
+	}
+	t.timer = time.AfterFunc(duration, func() {
+		if !travels.CompareAndDelete(traveller.Id, t) {
+			return
+		}
+		if err := c.game.traverse(ctx, traveller.Id, location.Id, exit); err != nil {
+			fmt.Fprintln(c.term, err)
+			return
+		}
+		if err := c.describeLong(); err != nil {
+			log.Printf("trying to describe the destination of %q: %v", traveller.Id, err)
+		}
+	})
+	if err := c.game.emitAny(ctx, c.game.storage.Queue().After(0), traveller.Id, departingEventType, &travelPhase{
+		Exit:        exit.Descriptions[0].Short,
+		Source:      location.Id,
+		Destination: exit.Destination,
+		Duration:    float64(travelTime),
+	}); err != nil {
+		return true, juicemud.WithStack(err)
+	}
+	message := exit.TravelMessage
+	if message == "" {
+		message = fmt.Sprintf("You head %s", exit.Descriptions[0].Short)
+	}
+	fmt.Fprintln(c.term, c.wrap(message+"..."))
+	return true, nil
+}
+
+// stopCommand cancels the travel of the connected object.
+func (c *Connection) stopCommand() error {
+	if cancelTravel(c.actor()) {
+		fmt.Fprintln(c.term, "You stop")
+	} else {
+		fmt.Fprintln(c.term, "You aren't going anywhere")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/js"
+	"github.com/zond/juicemud/lang"
+	"github.com/zond/juicemud/structs"
+	"rogchap.com/v8go"
+)
+
+const (
+	firstLoginEventType = "firstLogin"
+)
+
+// missingCommands returns the commands required to leave location that traveller hasn't used.
+func missingCommands(traveller *structs.Object, location *structs.Object) []string {
+	result := []string{}
+	for _, command := range location.RequiredCommands {
+		if !slices.Contains(traveller.UsedCommands, command) {
+			result = append(result, command)
+		}
+	}
+	return result
+}
+
+// checkTutorialGate returns an error if traveller hasn't used all the commands its location
+// requires before leaving it.
+func (g *Game) checkTutorialGate(ctx context.Context, traveller *structs.Object) error {
+	if traveller.Location == "" {
+		return nil
+	}
+	location, err := g.storage.LoadObject(ctx, traveller.Location, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if missing := missingCommands(traveller, location); len(missing) > 0 {
+		return errors.Errorf("You need to try %s before leaving", lang.Enumerator{Pattern: "`%s`", Operator: "and"}.Do(missing...))
+	}
+	return nil
+}
+
+// recordTutorialCommand remembers that the connected object used command, if its location
+// requires it before leaving.
+func (c *Connection) recordTutorialCommand(command string) error {
+	ctx := c.sess.Context()
+	object, err := c.object()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if object.Location == "" || slices.Contains(object.UsedCommands, command) {
+		return nil
+	}
+	location, err := c.game.storage.LoadObject(ctx, object.Location, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if !slices.Contains(location.RequiredCommands, command) {
+		return nil
+	}
+	return juicemud.WithStack(c.game.modifyObject(ctx, nil, object.Id, func(o *structs.Object) error {
+		if !slices.Contains(o.UsedCommands, command) {
+			o.UsedCommands = append(o.UsedCommands, command)
+		}
+		return nil
+	}))
+}
+
+// addTutorialCallbacks adds `setRequiredCommands(commands)`, which keeps objects from leaving
+// the object through exits or portals until they have used all the commands, and
+// `getUsedCommands(objectID)`, which returns the required commands the object has used.
+func (g *Game) addTutorialCallbacks(ctx context.Context, object *structs.Object, callbacks js.Callbacks) {
+	callbacks["setRequiredCommands"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsArray() {
+			return rc.Throw("setRequiredCommands takes [Array] arguments")
+		}
+		result := []string{}
+		if err := rc.Copy(&result, args[0]); err != nil {
+			return rc.Throw("trying to convert %v to []string: %v", args[0], err)
+		}
+		object.RequiredCommands = result
+		return nil
+	}
+	callbacks["getUsedCommands"] = func(rc *js.RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+		args := info.Args()
+		if len(args) != 1 || !args[0].IsString() {
+			return rc.Throw("getUsedCommands takes [string] arguments")
+		}
+		user, err := g.loadObjectOrRunning(ctx, object, args[0].String())
+		if err != nil {
+			return rc.Throw("trying to load %q: %v", args[0].String(), err)
+		}
+		result := user.UsedCommands
+		if result == nil {
+			result = []string{}
+		}
+		res, err := rc.JSFromGo(result)
+		if err != nil {
+			return rc.Throw("trying to convert %v to *v8go.Value: %v", result, err)
+		}
+		return res
+	}
+}
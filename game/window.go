@@ -0,0 +1,42 @@
+package game
+
+import (
+	"log"
+
+	"github.com/zond/juicemud/lang"
+)
+
+// watchWindow keeps the terminal, and the width text is wrapped to, in sync with the
+// window size the client reports, until the session ends.
+func (c *Connection) watchWindow() {
+	pty, windows, isPty := c.sess.Pty()
+	if !isPty {
+		return
+	}
+	c.resize(pty.Window.Width, pty.Window.Height)
+	go func() {
+		for {
+			select {
+			case <-c.sess.Context().Done():
+				return
+			case window, ok := <-windows:
+				if !ok {
+					return
+				}
+				c.resize(window.Width, window.Height)
+			}
+		}
+	}()
+}
+
+func (c *Connection) resize(width int, height int) {
+	c.width.Store(int64(width))
+	if err := c.term.SetSize(width, height); err != nil {
+		log.Printf("trying to resize terminal to %vx%v: %v", width, height, err)
+	}
+}
+
+// wrap wraps s to the width of the terminal, if known.
+func (c *Connection) wrap(s string) string {
+	return lang.Wrap(s, int(c.width.Load()))
+}
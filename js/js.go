@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"runtime"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/zond/juicemud"
 	"github.com/zond/juicemud/structs"
 	"rogchap.com/v8go"
@@ -16,33 +16,21 @@ import (
 )
 
 const (
-	stateName = "state"
+	stateName          = "state"
+	creationParamsName = "creationParams"
 )
 
-var (
-	machines chan *machine
-)
-
-func init() {
-	machines = make(chan *machine, runtime.NumCPU())
-	for i := 0; i < runtime.NumCPU(); i++ {
-		m, err := newMachine()
-		if err != nil {
-			log.Panic(err)
-		}
-		machines <- m
-	}
-}
-
 type machine struct {
 	iso                    *v8go.Isolate
 	vctx                   *v8go.Context
 	unableToGenerateString *v8go.Value
+	scripts                map[string]compiledScript
 }
 
 func newMachine() (*machine, error) {
 	m := &machine{
-		iso: v8go.NewIsolate(),
+		iso:     v8go.NewIsolate(),
+		scripts: map[string]compiledScript{},
 	}
 	var err error
 	if m.vctx = v8go.NewContext(m.iso); err != nil {
@@ -57,11 +45,18 @@ func newMachine() (*machine, error) {
 type Callbacks map[string]func(rc *RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value
 
 type Target struct {
-	Source    string
-	Origin    string
-	State     string
-	Callbacks Callbacks
-	Console   io.Writer
+	Source string
+	Origin string
+	State  string
+	// CreationParams is JSON exposed as a frozen global `creationParams` during
+	// this run. It's only set during the first run of newly created objects.
+	CreationParams string
+	Callbacks      Callbacks
+	Console        io.Writer
+	// Affinity makes runs with the same value prefer the same isolate.
+	Affinity string
+	// BeforeCall, if set, runs after the source and before the callback, within the timeout.
+	BeforeCall func(rc *RunContext) error
 }
 
 type Result struct {
@@ -111,10 +106,42 @@ func (rc *RunContext) String(s string) *v8go.Value {
 	return rc.m.unableToGenerateString
 }
 
+// State returns the `state` global of the run.
+func (rc *RunContext) State() (*v8go.Value, error) {
+	res, err := rc.m.vctx.Global().Get(stateName)
+	return res, juicemud.WithStack(err)
+}
+
+// SetState replaces the `state` global of the run.
+func (rc *RunContext) SetState(v *v8go.Value) error {
+	return juicemud.WithStack(rc.m.vctx.Global().Set(stateName, v))
+}
+
 func (rc *RunContext) Throw(format string, args ...any) *v8go.Value {
 	return rc.Context().Isolate().ThrowException(rc.String(fmt.Sprintf(format, args...)))
 }
 
+// Resolve returns a promise resolved with v, for callbacks returning promises.
+func (rc *RunContext) Resolve(v *v8go.Value) *v8go.Value {
+	resolver, err := v8go.NewPromiseResolver(rc.Context())
+	if err != nil {
+		return rc.Throw("trying to create promise: %v", err)
+	}
+	resolver.Resolve(v)
+	return resolver.GetPromise().Value
+}
+
+// Reject returns a promise rejected with a message created from format and args, for callbacks
+// returning promises.
+func (rc *RunContext) Reject(format string, args ...any) *v8go.Value {
+	resolver, err := v8go.NewPromiseResolver(rc.Context())
+	if err != nil {
+		return rc.Throw("trying to create promise: %v", err)
+	}
+	resolver.Reject(rc.String(fmt.Sprintf(format, args...)))
+	return resolver.GetPromise().Value
+}
+
 func addJSCallback(rc *RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
 	args := info.Args()
 	if len(args) == 3 && args[0].IsString() && args[1].IsArray() && args[2].IsFunction() {
@@ -233,11 +260,40 @@ func (rc *RunContext) prepareV8Context(timeout *time.Duration) error {
 		return juicemud.WithStack(err)
 	}
 
+	if err := rc.setCreationParams(); err != nil {
+		return juicemud.WithStack(err)
+	}
+
 	return nil
 }
 
+func (rc *RunContext) setCreationParams() error {
+	// Machines are reused between runs, so the global has to be reset even when
+	// there are no params for this run.
+	if rc.t.CreationParams == "" {
+		return juicemud.WithStack(rc.m.vctx.Global().Set(creationParamsName, v8go.Undefined(rc.m.iso)))
+	}
+	paramsValue, err := v8go.JSONParse(rc.m.vctx, rc.t.CreationParams)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	objectValue, err := rc.m.vctx.Global().Get("Object")
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	objectObject, err := objectValue.AsObject()
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if _, err := objectObject.MethodCall("freeze", paramsValue); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(rc.m.vctx.Global().Set(creationParamsName, paramsValue))
+}
+
 var (
-	ErrTimeout = fmt.Errorf("Timeout")
+	ErrTimeout        = fmt.Errorf("Timeout")
+	ErrPendingPromise = fmt.Errorf("callback returned a promise that never settled")
 )
 
 type result struct {
@@ -245,7 +301,8 @@ type result struct {
 	err   error
 }
 
-func (rc *RunContext) withTimeout(_ context.Context, f func() (*v8go.Value, error), timeout *time.Duration) (*v8go.Value, error) {
+// withTimeout runs f, and terminates it if it doesn't finish within timeout or before ctx is done.
+func (rc *RunContext) withTimeout(ctx context.Context, f func() (*v8go.Value, error), timeout *time.Duration) (*v8go.Value, error) {
 	results := make(chan result, 1)
 	go func() {
 		t := time.Now()
@@ -260,12 +317,26 @@ func (rc *RunContext) withTimeout(_ context.Context, f func() (*v8go.Value, erro
 	case <-time.After(*timeout):
 		rc.m.iso.TerminateExecution()
 		return nil, juicemud.WithStack(ErrTimeout)
+	case <-ctx.Done():
+		rc.m.iso.TerminateExecution()
+		return nil, juicemud.WithStack(ctx.Err())
 	}
 }
 
+// Run runs the source of t, and then the callback for call if t has one, terminating them when
+// timeout has lapsed or ctx is done.
 func (t Target) Run(ctx context.Context, call *structs.Call, timeout time.Duration) (*Result, error) {
-	m := <-machines
-	defer func() { machines <- m }()
+	p := getPool()
+	m, index, err := p.get(t.Affinity, isInRun(ctx))
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	defer p.put(index)
+
+	// Waiting for an isolate might have outlasted ctx.
+	if err := ctx.Err(); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
 
 	rc := &RunContext{
 		m: m,
@@ -281,11 +352,23 @@ func (t Target) Run(ctx context.Context, call *structs.Call, timeout time.Durati
 	}
 
 	if _, err := rc.withTimeout(ctx, func() (*v8go.Value, error) {
-		return rc.m.vctx.RunScript(t.Source, t.Origin)
+		script, err := rc.m.compile(t.Source, t.Origin)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		return script.Run(rc.m.vctx)
 	}, &timeout); err != nil {
 		return nil, juicemud.WithStack(err)
 	}
 
+	if t.BeforeCall != nil {
+		if _, err := rc.withTimeout(ctx, func() (*v8go.Value, error) {
+			return nil, t.BeforeCall(rc)
+		}, &timeout); err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+
 	if call == nil {
 		return rc.collectResult(nil)
 	}
@@ -312,6 +395,8 @@ func (t Target) Run(ctx context.Context, call *structs.Call, timeout time.Durati
 	}
 
 	if val, err := rc.withTimeout(ctx, func() (*v8go.Value, error) {
+		// Running the microtasks here lets async callbacks finish within the timeout.
+		defer rc.m.vctx.PerformMicrotaskCheckpoint()
 		if val != nil {
 			return jsCB.Call(rc.m.vctx.Global(), val)
 		} else {
@@ -324,10 +409,33 @@ func (t Target) Run(ctx context.Context, call *structs.Call, timeout time.Durati
 	}
 }
 
+// settle returns the result of value if it's a settled promise, and value otherwise.
+// Since engine callbacks resolve their promises before returning, async callbacks awaiting
+// only them have settled once the microtasks have run.
+func (rc *RunContext) settle(value *v8go.Value) (*v8go.Value, error) {
+	if value == nil || !value.IsPromise() {
+		return value, nil
+	}
+	promise, err := value.AsPromise()
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	switch promise.State() {
+	case v8go.Fulfilled:
+		return promise.Result(), nil
+	case v8go.Rejected:
+		return nil, errors.Errorf("callback rejected: %v", promise.Result())
+	}
+	return nil, juicemud.WithStack(ErrPendingPromise)
+}
+
 func (rc *RunContext) collectResult(value *v8go.Value) (*Result, error) {
+	value, err := rc.settle(value)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
 	rc.r.Value = "{}"
-	if value != nil && !value.IsNull() {
-		var err error
+	if value != nil && !value.IsNull() && !value.IsUndefined() {
 		if rc.r.Value, err = v8go.JSONStringify(rc.m.vctx, value); err != nil {
 			return nil, juicemud.WithStack(err)
 		}
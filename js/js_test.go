@@ -198,3 +198,83 @@ addCallback("test", [], (arg) => {
 		b.Fatalf("got %q, want \"20\"", result)
 	}
 }
+
+func TestCreationParams(t *testing.T) {
+	ctx := context.Background()
+	target := Target{
+		Source: `
+if (creationParams) {
+  state.name = creationParams.name;
+  try {
+    creationParams.name = 'changed';
+  } catch (e) {}
+  state.after = creationParams.name;
+} else {
+  state.rerun = true;
+}
+`,
+		Origin:         "TestCreationParams",
+		State:          "{}",
+		CreationParams: "{\"name\": \"goblin\"}",
+	}
+	res, err := target.Run(ctx, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"name\":\"goblin\",\"after\":\"goblin\"}"; res.State != want {
+		t.Errorf("got %q, want %q", res.State, want)
+	}
+	target.State = "{}"
+	target.CreationParams = ""
+	if res, err = target.Run(ctx, nil, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"rerun\":true}"; res.State != want {
+		t.Errorf("got %q, want %q", res.State, want)
+	}
+}
+
+func TestAsyncCallback(t *testing.T) {
+	ctx := context.Background()
+	target := Target{
+		Source: `
+addCallback("test", [], async (arg) => {
+  const a = await double(arg.c);
+  const b = await double(a);
+  state.b = b;
+  return {b: b};
+});
+addCallback("fail", [], async (arg) => {
+  await double(-1);
+});
+`,
+		Origin: "TestAsyncCallback",
+		State:  "{}",
+		Callbacks: map[string]func(*RunContext, *v8go.FunctionCallbackInfo) *v8go.Value{
+			"double": func(rc *RunContext, info *v8go.FunctionCallbackInfo) *v8go.Value {
+				n := info.Args()[0].Integer()
+				if n < 0 {
+					return rc.Reject("negative")
+				}
+				res, err := rc.JSFromGo(n * 2)
+				if err != nil {
+					return rc.Throw("%v", err)
+				}
+				return rc.Resolve(res)
+			},
+		},
+	}
+	res, err := target.Run(ctx, &structs.Call{Name: "test", Message: "{\"c\": 3}"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"b\":12}"; res.State != want {
+		t.Errorf("got %q, want %q", res.State, want)
+	}
+	if want := "{\"b\":12}"; res.Value != want {
+		t.Errorf("got %q, want %q", res.Value, want)
+	}
+	if _, err := target.Run(ctx, &structs.Call{Name: "fail", Message: "{}"}, time.Second); err == nil {
+		t.Errorf("got no error for rejected callback")
+	}
+}
@@ -0,0 +1,150 @@
+package js
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/zond/juicemud"
+	"rogchap.com/v8go"
+)
+
+var (
+	poolSize    = runtime.NumCPU()
+	poolOnce    sync.Once
+	machinePool *pool
+)
+
+var (
+	ErrPoolExhausted = fmt.Errorf("no isolates left for runs started by other runs")
+)
+
+// SetPoolSize sets the number of isolates that scripts run in concurrently. It has to be
+// called before the first run to have any effect.
+func SetPoolSize(size int) {
+	if size > 0 {
+		poolSize = size
+	}
+}
+
+type inRunKey struct{}
+
+// InRun returns a context for runs started by callbacks of another run, which holds on to its
+// isolate until they finish. Such runs get a new isolate instead of waiting for one to be free,
+// since waiting could deadlock.
+func InRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inRunKey{}, true)
+}
+
+func isInRun(ctx context.Context) bool {
+	inRun, _ := ctx.Value(inRunKey{}).(bool)
+	return inRun
+}
+
+func getPool() *pool {
+	poolOnce.Do(func() {
+		machinePool = &pool{
+			cond:     sync.NewCond(&sync.Mutex{}),
+			machines: make([]*machine, poolSize),
+			busy:     make([]bool, poolSize),
+			size:     poolSize,
+		}
+		for i := range machinePool.machines {
+			m, err := newMachine()
+			if err != nil {
+				log.Panic(err)
+			}
+			machinePool.machines[i] = m
+		}
+	})
+	return machinePool
+}
+
+// pool hands out isolates to runs. Runs with the same affinity get the same isolate when
+// it's free, so that they reuse what the isolate has already compiled and cached, and any
+// free isolate otherwise.
+//
+// The order of the runs of an object is not up to the pool, but to the object lock held while
+// running it, and to the per object event dispatch.
+//
+// Runs started by callbacks of other runs add isolates to the pool when none are free, see InRun.
+// The added isolates are handed out like the others once they are put back, and at most as many
+// are added as the pool started with.
+type pool struct {
+	cond     *sync.Cond
+	machines []*machine
+	busy     []bool
+	size     int
+}
+
+func (p *pool) get(affinity string, grow bool) (*machine, int, error) {
+	h := fnv.New32a()
+	h.Write([]byte(affinity))
+
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+	preferred := int(h.Sum32() % uint32(len(p.machines)))
+	for {
+		if !p.busy[preferred] {
+			p.busy[preferred] = true
+			return p.machines[preferred], preferred, nil
+		}
+		for index, busy := range p.busy {
+			if !busy {
+				p.busy[index] = true
+				return p.machines[index], index, nil
+			}
+		}
+		if grow {
+			if len(p.machines) >= 2*p.size {
+				return nil, 0, juicemud.WithStack(ErrPoolExhausted)
+			}
+			m, err := newMachine()
+			if err != nil {
+				return nil, 0, juicemud.WithStack(err)
+			}
+			p.machines = append(p.machines, m)
+			p.busy = append(p.busy, true)
+			return m, len(p.machines) - 1, nil
+		}
+		p.cond.Wait()
+	}
+}
+
+func (p *pool) put(index int) {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+	p.busy[index] = false
+	// Broadcast, since waiters in getIndex only want a particular isolate.
+	p.cond.Broadcast()
+}
+
+// getIndex waits for the isolate with the given index to be free, and returns it.
+func (p *pool) getIndex(index int) *machine {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+	for p.busy[index] {
+		p.cond.Wait()
+	}
+	p.busy[index] = true
+	return p.machines[index]
+}
+
+// GetHeapStatistics returns the heap statistics of each isolate, waiting for each to finish
+// its current run.
+func GetHeapStatistics() []v8go.HeapStatistics {
+	p := getPool()
+	p.cond.L.Lock()
+	size := len(p.machines)
+	p.cond.L.Unlock()
+	result := make([]v8go.HeapStatistics, size)
+	for index := range result {
+		m := p.getIndex(index)
+		result[index] = m.iso.GetHeapStatistics()
+		p.put(index)
+	}
+	return result
+}
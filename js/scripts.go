@@ -0,0 +1,82 @@
+package js
+
+import (
+	"crypto/sha256"
+	"sync/atomic"
+
+	"github.com/zond/juicemud"
+	"rogchap.com/v8go"
+)
+
+var (
+	codeCacheByOrigin = juicemud.NewSyncMap[string, *cachedCode]()
+	scriptHits        uint64
+	codeCacheHits     uint64
+	scriptMisses      uint64
+	codeCacheRejects  uint64
+)
+
+// ScriptStats counts how the scripts of runs were compiled.
+type ScriptStats struct {
+	// Hits were already compiled in the isolate of the run.
+	Hits uint64
+	// CodeCacheHits were compiled using code cached when another isolate compiled the same source.
+	CodeCacheHits uint64
+	// Misses were compiled from scratch.
+	Misses uint64
+	// CodeCacheRejects had cached code that V8 rejected, and were compiled from scratch.
+	CodeCacheRejects uint64
+}
+
+func GetScriptStats() ScriptStats {
+	return ScriptStats{
+		Hits:             atomic.LoadUint64(&scriptHits),
+		CodeCacheHits:    atomic.LoadUint64(&codeCacheHits),
+		Misses:           atomic.LoadUint64(&scriptMisses),
+		CodeCacheRejects: atomic.LoadUint64(&codeCacheRejects),
+	}
+}
+
+// cachedCode is the code V8 compiled for the source with the given hash.
+type cachedCode struct {
+	hash  [sha256.Size]byte
+	bytes []byte
+}
+
+// compiledScript is a script compiled in the isolate of a machine for the source with the given hash.
+type compiledScript struct {
+	hash   [sha256.Size]byte
+	script *v8go.UnboundScript
+}
+
+// compile returns the script for source in the isolate of the machine, reusing what the
+// machine, or any other machine, has already compiled for the same source. Only the latest
+// source of each origin is kept, so edited sources don't accumulate.
+func (m *machine) compile(source string, origin string) (*v8go.UnboundScript, error) {
+	hash := sha256.Sum256([]byte(source))
+	if compiled, found := m.scripts[origin]; found && compiled.hash == hash {
+		atomic.AddUint64(&scriptHits, 1)
+		return compiled.script, nil
+	}
+	if cached, found := codeCacheByOrigin.GetHas(origin); found && cached.hash == hash {
+		data := &v8go.CompilerCachedData{Bytes: cached.bytes}
+		script, err := m.iso.CompileUnboundScript(source, origin, v8go.CompileOptions{CachedData: data})
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if !data.Rejected {
+			atomic.AddUint64(&codeCacheHits, 1)
+			m.scripts[origin] = compiledScript{hash: hash, script: script}
+			return script, nil
+		}
+		atomic.AddUint64(&codeCacheRejects, 1)
+	}
+	atomic.AddUint64(&scriptMisses, 1)
+	script, err := m.iso.CompileUnboundScript(source, origin, v8go.CompileOptions{})
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	codeCacheByOrigin.Set(origin, &cachedCode{hash: hash, bytes: script.CreateCodeCache().Bytes})
+	m.scripts[origin] = compiledScript{hash: hash, script: script}
+	return script, nil
+}
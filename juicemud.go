@@ -1,9 +1,11 @@
 package juicemud
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"iter"
 	"sync"
 	"sync/atomic"
@@ -39,6 +41,73 @@ const (
 	DAVAuthRealm = "WebDAV"
 )
 
+const (
+	// CompressionEnv is the environment variable SSH clients set to CompressionZlib to get
+	// their terminal output as a zlib stream, escaped with NewlineEscaper.
+	CompressionEnv  = "JUICEMUD_COMPRESSION"
+	CompressionZlib = "zlib"
+)
+
+const (
+	newlineEscape = 0x7d
+	escapeMask    = 0x20
+)
+
+type newlineEscaper struct {
+	w io.Writer
+}
+
+func (n newlineEscaper) Write(b []byte) (int, error) {
+	escaped := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == '\n' || c == newlineEscape {
+			escaped = append(escaped, newlineEscape, c^escapeMask)
+		} else {
+			escaped = append(escaped, c)
+		}
+	}
+	if _, err := n.w.Write(escaped); err != nil {
+		return 0, WithStack(err)
+	}
+	return len(b), nil
+}
+
+// NewlineEscaper returns a writer writing to w without newlines, by replacing them and the escape
+// byte with the escape byte followed by a masked copy. SSH servers rewrite the newlines in the
+// output of sessions with a terminal, which would corrupt binary output like compressed streams.
+func NewlineEscaper(w io.Writer) io.Writer {
+	return newlineEscaper{w: w}
+}
+
+type newlineUnescaper struct {
+	r *bufio.Reader
+}
+
+func (n newlineUnescaper) Read(b []byte) (int, error) {
+	read := 0
+	// Return what's already read instead of blocking for more.
+	for read < len(b) && (read == 0 || n.r.Buffered() > 0) {
+		c, err := n.r.ReadByte()
+		if err != nil {
+			return read, err
+		}
+		if c == newlineEscape {
+			if c, err = n.r.ReadByte(); err != nil {
+				return read, err
+			}
+			c ^= escapeMask
+		}
+		b[read] = c
+		read++
+	}
+	return read, nil
+}
+
+// NewlineUnescaper returns a reader reading what NewlineEscaper wrote to r.
+func NewlineUnescaper(r io.Reader) io.Reader {
+	return newlineUnescaper{r: bufio.NewReader(r)}
+}
+
 type stackTracer interface {
 	StackTrace() errors.StackTrace
 }
@@ -186,6 +255,17 @@ func (s *SyncMap[K, V]) Swap(key K, oldValue V, newValue V) bool {
 	return false
 }
 
+// CompareAndDelete deletes the value of key if it's oldValue, and returns whether it was.
+func (s *SyncMap[K, V]) CompareAndDelete(key K, oldValue V) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value, found := s.m[key]; found && value == oldValue {
+		delete(s.m, key)
+		return true
+	}
+	return false
+}
+
 func (l *SyncMap[K, V]) WithLock(key K, f func()) {
 	l.Lock(key)
 	defer l.Unlock(key)
@@ -209,6 +289,19 @@ func (l *SyncMap[K, V]) Lock(key K) {
 	}
 }
 
+// TryLock locks key and returns true if it isn't already locked, and returns false otherwise.
+func (l *SyncMap[K, V]) TryLock(key K) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, found := l.locks[key]; found {
+		return false
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	l.locks[key] = wg
+	return true
+}
+
 func (l *SyncMap[K, V]) Unlock(key K) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
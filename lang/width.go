@@ -0,0 +1,62 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Width returns the number of terminal columns s occupies, counting wide (e.g. CJK and
+// emoji) characters as two columns and combining marks as none.
+func Width(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// ASCII returns s with accents removed from accented letters, and all other characters
+// outside of ASCII replaced by '?', for terminals that can't show anything else.
+func ASCII(s string) string {
+	builder := &strings.Builder{}
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r == utf8.RuneError:
+			builder.WriteRune('?')
+		case unicode.Is(unicode.Mn, r):
+		case r > unicode.MaxASCII:
+			builder.WriteRune('?')
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// Wrap breaks the lines of s between words so that no line is wider than width columns,
+// unless it contains a single word wider than that. A width below 1 leaves s as it is.
+func Wrap(s string, width int) string {
+	if width < 1 {
+		return s
+	}
+	builder := &strings.Builder{}
+	for index, line := range strings.Split(s, "\n") {
+		if index > 0 {
+			builder.WriteByte('\n')
+		}
+		lineWidth := 0
+		for _, word := range strings.Fields(line) {
+			wordWidth := Width(word)
+			if lineWidth > 0 && lineWidth+1+wordWidth > width {
+				builder.WriteByte('\n')
+				lineWidth = 0
+			} else if lineWidth > 0 {
+				builder.WriteByte(' ')
+				lineWidth++
+			}
+			builder.WriteString(word)
+			lineWidth += wordWidth
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,57 @@
+package lang
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want int
+	}{
+		{"door", 4},
+		{"café", 4},
+		{"cafe\u0301", 4},
+		{"門", 2},
+		{"門 door", 7},
+		{"🚪", 2},
+	} {
+		if got := Width(tc.s); got != tc.want {
+			t.Errorf("Width(%q) = %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestASCII(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want string
+	}{
+		{"door", "door"},
+		{"café", "cafe"},
+		{"cafe\u0301", "cafe"},
+		{"Ångström", "Angstrom"},
+		{"門 door", "? door"},
+	} {
+		if got := ASCII(tc.s); got != tc.want {
+			t.Errorf("ASCII(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, tc := range []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"a small room", 0, "a small room"},
+		{"a small room", 20, "a small room"},
+		{"a small room", 7, "a small\nroom"},
+		{"a small room", 3, "a\nsmall\nroom"},
+		{"a small room\n\nwith a door", 7, "a small\nroom\n\nwith a\ndoor"},
+		{"門門 門門", 5, "門門\n門門"},
+	} {
+		if got := Wrap(tc.s, tc.width); got != tc.want {
+			t.Errorf("Wrap(%q, %v) = %q, want %q", tc.s, tc.width, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// juicemud-loadtest connects bots to a running juicemud server, has them run a weighted mix
+// of commands, and reports the latency of each command.
+//
+// Each bot creates a new user, so don't point it at a server with real players. Build it with
+// `go build -o juicemud-loadtest ./loadtest`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rodaine/table"
+	"github.com/zond/juicemud/client"
+)
+
+type weightedCommand struct {
+	command string
+	weight  int
+}
+
+// parseMix parses comma separated command=weight pairs, where the weight defaults to 1.
+func parseMix(s string) ([]weightedCommand, error) {
+	result := []weightedCommand{}
+	for _, part := range strings.Split(s, ",") {
+		command, weightString, found := strings.Cut(part, "=")
+		weight := 1
+		if found {
+			var err error
+			if weight, err = strconv.Atoi(weightString); err != nil || weight < 1 {
+				return nil, errors.Errorf("invalid weight in %q", part)
+			}
+		}
+		if command = strings.TrimSpace(command); command == "" {
+			return nil, errors.Errorf("empty command in %q", s)
+		}
+		result = append(result, weightedCommand{command: command, weight: weight})
+	}
+	return result, nil
+}
+
+func pick(mix []weightedCommand) string {
+	total := 0
+	for _, wc := range mix {
+		total += wc.weight
+	}
+	n := rand.IntN(total)
+	for _, wc := range mix {
+		if n < wc.weight {
+			return wc.command
+		}
+		n -= wc.weight
+	}
+	return mix[len(mix)-1].command
+}
+
+type results struct {
+	mutex     sync.Mutex
+	latencies map[string][]time.Duration
+	failures  map[string]int
+}
+
+func (r *results) add(command string, latency time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil {
+		r.failures[command]++
+	} else {
+		r.latencies[command] = append(r.latencies[command], latency)
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func (r *results) print() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	commands := []string{}
+	for command := range r.latencies {
+		commands = append(commands, command)
+	}
+	for command := range r.failures {
+		if _, found := r.latencies[command]; !found {
+			commands = append(commands, command)
+		}
+	}
+	sort.Strings(commands)
+	t := table.New("Command", "Count", "Failures", "p50", "p90", "p99", "Max").WithWriter(os.Stdout)
+	for _, command := range commands {
+		latencies := r.latencies[command]
+		slices.Sort(latencies)
+		t.AddRow(command, len(latencies), r.failures[command], percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99), percentile(latencies, 1))
+	}
+	t.Print()
+}
+
+func runBot(addr string, compressed bool, username string, mix []weightedCommand, deadline time.Time, think time.Duration, timeout time.Duration, res *results) error {
+	dial := client.Dial
+	if compressed {
+		dial = client.DialCompressed
+	}
+	c, err := dial(addr)
+	if err != nil {
+		return errors.Wrapf(err, "connecting %s", username)
+	}
+	defer c.Close()
+	c.Timeout = timeout
+	if err := c.CreateUser(username, username); err != nil {
+		return errors.Wrapf(err, "creating %s", username)
+	}
+	for time.Now().Before(deadline) {
+		command := pick(mix)
+		started := time.Now()
+		_, err := c.SendCommand(command)
+		res.add(command, time.Since(started), err)
+		if err != nil {
+			return errors.Wrapf(err, "%s running %q", username, command)
+		}
+		if think > 0 {
+			time.Sleep(think/2 + rand.N(think))
+		}
+	}
+	return nil
+}
+
+func main() {
+	addr := flag.String("ssh", "127.0.0.1:15000", "The SSH address of the server")
+	compressed := flag.Bool("compressed", false, "Whether the bots ask the server to compress their output")
+	bots := flag.Int("bots", 10, "How many bots to connect")
+	duration := flag.Duration("duration", time.Minute, "How long to run the bots")
+	think := flag.Duration("think", time.Second, "Average delay between the commands of a bot")
+	timeout := flag.Duration("timeout", 10*time.Second, "How long to wait for responses before failing a bot")
+	mixString := flag.String("mix", "look=5,north,south,east,west", "Comma separated command=weight pairs to run")
+	prefix := flag.String("prefix", fmt.Sprintf("bot%d", time.Now().Unix()), "Username prefix of the bots")
+
+	flag.Parse()
+
+	mix, err := parseMix(*mixString)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res := &results{
+		latencies: map[string][]time.Duration{},
+		failures:  map[string]int{},
+	}
+	deadline := time.Now().Add(*duration)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < *bots; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runBot(*addr, *compressed, fmt.Sprintf("%s_%d", *prefix, i), mix, deadline, *think, *timeout, res); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	wg.Wait()
+	res.print()
+}
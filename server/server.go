@@ -8,16 +8,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gliderlabs/ssh"
 	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/api"
 	"github.com/zond/juicemud/crypto"
 	"github.com/zond/juicemud/dav"
 	"github.com/zond/juicemud/digest"
 	"github.com/zond/juicemud/fs"
 	"github.com/zond/juicemud/game"
+	"github.com/zond/juicemud/js"
 	"github.com/zond/juicemud/storage"
 
 	gossh "golang.org/x/crypto/ssh"
@@ -67,14 +71,31 @@ func (r *responseWriter) WriteHeader(status int) {
 }
 
 func main() {
-	sshIface := flag.String("ssh", "127.0.0.1:15000", "Where to listen to SSH connections")
+	sshIface := flag.String("ssh", "127.0.0.1:15000", "Where to listen to SSH connections, ignored if -listeners is set")
+	listenersPath := flag.String("listeners", "", "YAML file with the SSH listeners, each with an addr, and optionally an allowlist and denylist of IPs and CIDR ranges, wizardsOnly, a motd and proxyProtocol")
 	httpsIface := flag.String("https", "127.0.0.1:8081", "Where to listen to HTTPS connections for WebDAV")
 	httpIface := flag.String("http", "127.0.0.1:8080", "Where to listen to HTTP connections for WebDAV")
 	hostname := flag.String("hostname", "", "Hostname for HTTPS certificate signatures, will use -https value if empty")
 	dir := flag.String("dir", filepath.Join(os.Getenv("HOME"), ".juicemud"), "Where to save database and settings")
+	jsIsolates := flag.Int("js_isolates", runtime.NumCPU(), "How many JavaScript isolates to run object scripts in concurrently")
+	fetchAllowlist := flag.String("fetch_allowlist", "", "Comma separated hosts that scripts may httpFetch, *.example.com allows all subdomains of example.com")
+	objectCache := flag.Int("object_cache", storage.DefaultObjectCacheSize, "How many recently used objects to keep in memory")
+	trashRetention := flag.Duration("trash_retention", storage.DefaultTrashRetention, "How long objects removed with /remove or removeObject can be restored with /restore")
+	serveAPI := flag.Bool("api", false, "Whether to serve the token authenticated JSON API under /api/ over HTTPS, tokens are created with /apitoken")
+	llmEndpoint := flag.String("llm_endpoint", "", "Chat completions URL scripts may askLLM, askLLM is disabled if empty, the API key is read from $JUICEMUD_LLM_API_KEY")
+	llmModel := flag.String("llm_model", "", "Model to ask at -llm_endpoint")
+	llmObjectQuota := flag.Int("llm_object_quota", 20, "How many askLLM requests each object may make per hour")
+	llmQuota := flag.Int("llm_quota", 200, "How many askLLM requests all objects together may make per hour")
+	llmBlocklist := flag.String("llm_blocklist", "", "Comma separated words askLLM prompts and responses may not contain")
+	hideStats := flag.Bool("hide_stats", false, "Whether to hide uptime, players online and the last reboot from the banner in /banner.txt shown before login")
+	loginPolicy := flag.String("login_policy", string(game.TakeoverLogins), "What happens when users log in while already connected, takeover, deny, allow or allow-read-only, owners can override it per user with /login-policy")
+	analytics := flag.Bool("analytics", false, "Whether to collect anonymous totals of command usage, room visits and returning players, shown with /analytics")
+	calendar := flag.String("calendar", "", "YAML file with the game calendar, with month names, week days, hours per day, speed and epoch, defaults to a Gregorian calendar in real time")
 
 	flag.Parse()
 
+	js.SetPoolSize(*jsIsolates)
+
 	if *hostname == "" {
 		*hostname = *httpsIface
 	}
@@ -121,48 +142,115 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	store.ObjectCache().Resize(*objectCache)
 	g, err := game.New(ctx, store)
 	if err != nil {
 		log.Println(juicemud.StackTrace(err))
 		log.Fatal(err)
 	}
+	g.SetFetchAllowlist(strings.Split(*fetchAllowlist, ","))
+	g.SetTrashRetention(*trashRetention)
+	g.SetHideStats(*hideStats)
+	g.SetAnalytics(*analytics)
+	policy, err := game.ParseLoginPolicy(*loginPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := g.SetLoginPolicy(policy); err != nil {
+		log.Fatal(err)
+	}
+	if *calendar != "" {
+		gameCalendar, err := game.LoadGameCalendar(*calendar)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := g.SetGameCalendar(gameCalendar); err != nil {
+			log.Fatal(err)
+		}
+	}
+	g.SetLLM(game.LLMConfig{
+		Endpoint:       *llmEndpoint,
+		Model:          *llmModel,
+		APIKey:         os.Getenv("JUICEMUD_LLM_API_KEY"),
+		PerObjectQuota: *llmObjectQuota,
+		Quota:          *llmQuota,
+		Blocklist:      strings.Split(*llmBlocklist, ","),
+	})
 
-	sshServer := &ssh.Server{
-		Addr:    *sshIface,
-		Handler: g.HandleSession,
+	listeners := []*game.Listener{{Addr: *sshIface}}
+	if *listenersPath != "" {
+		if listeners, err = game.LoadListeners(*listenersPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	sshServers := []func() error{}
+	for _, listener := range listeners {
+		if err := listener.Prepare(); err != nil {
+			log.Fatal(err)
+		}
+		sshServer := &ssh.Server{
+			Addr:         listener.Addr,
+			Handler:      g.SessionHandler(listener),
+			ConnCallback: g.ConnCallback(listener),
+		}
+		sshServer.AddHostKey(signer)
+		ln, err := listener.Listen()
+		if err != nil {
+			log.Fatal(err)
+		}
+		sshServers = append(sshServers, func() error {
+			return sshServer.Serve(ln)
+		})
+		log.Printf("Serving SSH on %q with public key %q", listener.Addr, fingerprint)
 	}
-	sshServer.AddHostKey(signer)
-	log.Printf("Serving SSH on %q with public key %q", *sshIface, fingerprint)
 
 	fs := &fs.Fs{
 		Storage: store,
 	}
 	dav := dav.New(fs)
 	auth := digest.NewDigestAuth(juicemud.DAVAuthRealm, store).Wrap(dav)
-	logger := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		t := time.Now()
-		ww := &responseWriter{backend: w, status: http.StatusOK}
-		sb := &sizeBody{backend: r.Body}
-		r.Body = sb
-		auth.ServeHTTP(ww, r)
-		lapsed := time.Since(t)
-		log.Printf("%s\t%s\t%s\t%v\t%vb in\t%vb out\t%s", r.RemoteAddr, r.Method, r.URL, ww.status, sb.size, ww.size, lapsed)
-	})
+	logger := func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := time.Now()
+			ww := &responseWriter{backend: w, status: http.StatusOK}
+			sb := &sizeBody{backend: r.Body}
+			r.Body = sb
+			handler.ServeHTTP(ww, r)
+			lapsed := time.Since(t)
+			log.Printf("%s\t%s\t%s\t%v\t%vb in\t%vb out\t%s", r.RemoteAddr, r.Method, r.URL, ww.status, sb.size, ww.size, lapsed)
+		})
+	}
 
+	httpsHandler := http.Handler(auth)
+	if *serveAPI {
+		mux := http.NewServeMux()
+		mux.Handle("/api/", api.New(store))
+		mux.Handle("/", auth)
+		httpsHandler = mux
+	}
 	httpsServer := &http.Server{
 		Addr:    *httpsIface,
-		Handler: logger,
+		Handler: logger(httpsHandler),
 	}
 	log.Printf("Serving HTTPS on %q with public key %q", *httpsIface, fingerprint)
+	if *serveAPI {
+		log.Printf("Serving API on %q", *httpsIface)
+	}
 
 	httpServer := &http.Server{
 		Addr:    *httpIface,
-		Handler: logger,
+		Handler: logger(auth),
 	}
 	log.Printf("Serving HTTP on %q", *httpIface)
 
 	wg := sync.WaitGroup{}
-	wg.Add(2)
+	wg.Add(2 + len(sshServers))
+	for _, serve := range sshServers {
+		go func() {
+			defer wg.Done()
+			log.Fatal(serve())
+		}()
+	}
 	go func() {
 		defer wg.Done()
 		log.Fatal(httpsServer.ListenAndServeTLS(crypto.HTTPSCertPath, crypto.PrivKeyPath))
@@ -172,6 +260,5 @@ func main() {
 		log.Fatal(httpServer.ListenAndServe())
 	}()
 
-	log.Fatal(sshServer.ListenAndServe())
 	wg.Wait()
 }
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+var (
+	ErrAchievementTaken = fmt.Errorf("achievement defined by another object")
+)
+
+// Achievement is the definition of something objects can achieve.
+type Achievement struct {
+	Id          int64  `sqly:"pkey,autoinc"`
+	Key         string `sqly:"unique"`
+	Name        string
+	Description string
+	// Object, if not empty, is the ID of an object judging the criteria of the achievement,
+	// and the only object allowed to grant it.
+	Object string
+}
+
+// AchievementUnlock records that Object unlocked the achievement with key Achievement.
+type AchievementUnlock struct {
+	Id          int64  `sqly:"pkey,autoinc"`
+	Object      string `sqly:"index"`
+	Achievement string `sqly:"uniqueWith(Object)"`
+	Unlocked    int64
+}
+
+func loadAchievement(ctx context.Context, db sqlx.QueryerContext, key string) (*Achievement, error) {
+	result := &Achievement{}
+	if err := getSQL(ctx, db, result, "SELECT * FROM Achievement WHERE Key = ?", key); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// LoadAchievement returns the achievement with key, or os.ErrNotExist if there is none.
+func (s *Storage) LoadAchievement(ctx context.Context, key string) (*Achievement, error) {
+	return loadAchievement(ctx, s.sql, key)
+}
+
+// StoreAchievement creates or replaces the achievement with the key of achievement. Unless definer
+// is empty, achievements whose Object is another object than definer aren't replaced, and
+// ErrAchievementTaken is returned.
+func (s *Storage) StoreAchievement(ctx context.Context, achievement *Achievement, definer string) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		current, err := loadAchievement(ctx, tx, achievement.Key)
+		if err == nil {
+			if definer != "" && current.Object != "" && current.Object != definer {
+				return errors.Wrapf(ErrAchievementTaken, "%q belongs to %q", current.Key, current.Object)
+			}
+			achievement.Id = current.Id
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return juicemud.WithStack(err)
+		}
+		return juicemud.WithStack(tx.Upsert(ctx, achievement, true))
+	}))
+}
+
+// Achievements returns all achievement definitions, ordered by name.
+func (s *Storage) Achievements(ctx context.Context) ([]Achievement, error) {
+	result := []Achievement{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM Achievement ORDER BY Name, Key"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// AchievementUnlocks returns the achievements object has unlocked, ordered by when.
+func (s *Storage) AchievementUnlocks(ctx context.Context, object string) ([]AchievementUnlock, error) {
+	result := []AchievementUnlock{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM AchievementUnlock WHERE Object = ? ORDER BY Unlocked", object); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// GrantAchievement unlocks the achievement with key for object, and returns the achievement
+// and whether it was unlocked now rather than before.
+func (s *Storage) GrantAchievement(ctx context.Context, object string, key string) (*Achievement, bool, error) {
+	var achievement *Achievement
+	unlocked := false
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		var err error
+		if achievement, err = loadAchievement(ctx, tx, key); err != nil {
+			return juicemud.WithStack(err)
+		}
+		var count int64
+		if err := getSQL(ctx, tx, &count, "SELECT COUNT(*) FROM AchievementUnlock WHERE Object = ? AND Achievement = ?", object, key); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if count > 0 {
+			return nil
+		}
+		unlocked = true
+		return juicemud.WithStack(tx.Upsert(ctx, &AchievementUnlock{
+			Object:      object,
+			Achievement: key,
+			Unlocked:    time.Now().UnixNano(),
+		}, false))
+	}); err != nil {
+		return nil, false, juicemud.WithStack(err)
+	}
+	return achievement, unlocked, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// AnalyticsCounter counts how many times something of Kind, like a command or a room visit,
+// identified by Key happened during Period. It records no users, only totals.
+type AnalyticsCounter struct {
+	Id     int64  `sqly:"pkey,autoinc"`
+	Kind   string `sqly:"index"`
+	Period int64  `sqly:"index"`
+	Key    string
+	Count  int64
+}
+
+// AddAnalytics adds the counts of counters to the stored counters with the same kind,
+// period and key.
+func (s *Storage) AddAnalytics(ctx context.Context, counters []AnalyticsCounter) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		for _, counter := range counters {
+			current := &AnalyticsCounter{}
+			err := getSQL(ctx, tx, current, "SELECT * FROM AnalyticsCounter WHERE Kind = ? AND Period = ? AND Key = ?", counter.Kind, counter.Period, counter.Key)
+			if errors.Is(err, os.ErrNotExist) {
+				counter.Id = 0
+				if err := tx.Upsert(ctx, &counter, false); err != nil {
+					return juicemud.WithStack(err)
+				}
+				continue
+			} else if err != nil {
+				return juicemud.WithStack(err)
+			}
+			current.Count += counter.Count
+			if err := tx.Upsert(ctx, current, true); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
+// AnalyticsTotals returns the counts of kind during the periods from and up to, but not
+// including, to summed by key, largest first.
+func (s *Storage) AnalyticsTotals(ctx context.Context, kind string, from int64, to int64) ([]AnalyticsCounter, error) {
+	result := []AnalyticsCounter{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT Kind, Key, SUM(Count) AS Count FROM AnalyticsCounter WHERE Kind = ? AND Period >= ? AND Period < ? GROUP BY Kind, Key ORDER BY Count DESC, Key", kind, from, to); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// AnalyticsCounters returns the counters of kind during the periods from and up to, but not
+// including, to, ordered by key and period.
+func (s *Storage) AnalyticsCounters(ctx context.Context, kind string, from int64, to int64) ([]AnalyticsCounter, error) {
+	result := []AnalyticsCounter{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM AnalyticsCounter WHERE Kind = ? AND Period >= ? AND Period < ? ORDER BY Key, Period", kind, from, to); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// APIToken lets the holder of a secret act as User in the HTTP API. Only the hash
+// of the secret is stored.
+type APIToken struct {
+	Id      int64 `sqly:"pkey,autoinc"`
+	User    int64 `sqly:"index"`
+	Name    string
+	Hash    string `sqly:"unique"`
+	Created int64
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken creates a token named name for user, and returns the secret to present
+// to the API. The secret can't be recovered later.
+func (s *Storage) CreateAPIToken(ctx context.Context, user *User, name string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		return juicemud.WithStack(tx.Upsert(ctx, &APIToken{
+			User:    user.Id,
+			Name:    name,
+			Hash:    hashAPIToken(secret),
+			Created: time.Now().UnixNano(),
+		}, false))
+	}); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return secret, nil
+}
+
+// APITokens returns the tokens of user.
+func (s *Storage) APITokens(ctx context.Context, user *User) ([]APIToken, error) {
+	result := []APIToken{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM APIToken WHERE User = ? ORDER BY Id", user.Id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// DeleteAPIToken revokes the token with the given ID belonging to user.
+func (s *Storage) DeleteAPIToken(ctx context.Context, user *User, id int64) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM APIToken WHERE Id = ? AND User = ?", id, user.Id)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return juicemud.WithStack(err)
+		} else if affected == 0 {
+			return errors.Wrapf(os.ErrNotExist, "no token %v", id)
+		}
+		return nil
+	}))
+}
+
+// LoadAPITokenUser returns the user the token with the given secret belongs to, or
+// os.ErrNotExist if there is no such token.
+func (s *Storage) LoadAPITokenUser(ctx context.Context, secret string) (*User, error) {
+	user := &User{}
+	if err := getSQL(ctx, s.sql, user, "SELECT User.* FROM User JOIN APIToken ON APIToken.User = User.Id WHERE APIToken.Hash = ?", hashAPIToken(secret)); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return user, nil
+}
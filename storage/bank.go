@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage/dbm"
+	"github.com/zond/juicemud/structs"
+)
+
+var (
+	ErrVaultFull = fmt.Errorf("vault full")
+)
+
+// BankTransfer is a deposit of Item or Money from Customer into Vault, or a withdrawal from Vault
+// to Customer, for which Customer pays Fee to Bank.
+type BankTransfer struct {
+	Bank     string
+	Customer string
+	Vault    string
+	Withdraw bool
+	Item     string
+	Money    int64
+	Fee      int64
+	// Capacity is how many items Vault can hold.
+	Capacity int64
+}
+
+// Bank atomically makes the customer of transfer pay the fee to the bank, and moves the money or
+// item between the customer and the vault, calling unequip with the customer when depositing an
+// item. Changes nothing if the customer lacks the money for the fee or the deposit, the vault
+// lacks the money for the withdrawal, the item isn't where it's moved from, or the vault is full.
+func (s *Storage) Bank(ctx context.Context, transfer *BankTransfer, unequip func(customer *structs.Object)) error {
+	if transfer.Money < 0 || transfer.Fee < 0 {
+		return errors.Errorf("can't bank negative amounts")
+	}
+	from, to := transfer.Customer, transfer.Vault
+	if transfer.Withdraw {
+		from, to = to, from
+	}
+	var movement *Movement
+	pairs := []dbm.Proc{
+		s.objects.SProc(transfer.Bank, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", transfer.Bank)
+			}
+			value.Money += transfer.Fee
+			return value, nil
+		}),
+	}
+	for _, id := range []string{from, to} {
+		pairs = append(pairs, s.objects.SProc(id, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", id)
+			}
+			if value.Content == nil {
+				value.Content = map[string]bool{}
+			}
+			if id == transfer.Customer {
+				if value.Money < transfer.Fee {
+					return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", id, value.Money)
+				}
+				value.Money -= transfer.Fee
+			}
+			if id == from {
+				if value.Money < transfer.Money {
+					return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", id, value.Money)
+				}
+				value.Money -= transfer.Money
+				if transfer.Item != "" {
+					if !value.Content[transfer.Item] {
+						return nil, errors.Errorf("%q doesn't carry %q", id, transfer.Item)
+					}
+					delete(value.Content, transfer.Item)
+					if id == transfer.Customer {
+						unequip(value)
+					}
+				}
+			} else {
+				value.Money += transfer.Money
+				if transfer.Item != "" {
+					if id == transfer.Vault && int64(len(value.Content)) >= transfer.Capacity {
+						return nil, errors.Wrapf(ErrVaultFull, "%q holds %v items", id, len(value.Content))
+					}
+					value.Content[transfer.Item] = true
+				}
+			}
+			return value, nil
+		}))
+	}
+	if transfer.Item != "" {
+		pairs = append(pairs, s.objects.SProc(transfer.Item, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", transfer.Item)
+			}
+			if value.Location != from {
+				return nil, errors.Errorf("%q isn't carried by %q", transfer.Item, from)
+			}
+			value.Location = to
+			movement = &Movement{Object: value, Source: from, Destination: to}
+			return value, nil
+		}))
+	}
+	if err := s.objects.Proc(pairs, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	if movement != nil {
+		return juicemud.WithStack(s.movementHandler(ctx, movement))
+	}
+	return nil
+}
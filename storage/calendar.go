@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// WorldEvent is a scheduled world event, announced to interested objects when it
+// starts and when it ends.
+type WorldEvent struct {
+	Id          int64 `sqly:"pkey,autoinc"`
+	Name        string
+	Description string
+	// Object, if not empty, is the ID of an object hooking the event, that is told about
+	// it starting and ending even without subscribing.
+	Object  string
+	Start   int64 `sqly:"index"`
+	End     int64
+	Started bool
+	Ended   bool
+}
+
+func (s *Storage) StoreWorldEvent(ctx context.Context, event *WorldEvent) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		return juicemud.WithStack(tx.Upsert(ctx, event, true))
+	}))
+}
+
+func (s *Storage) DeleteWorldEvent(ctx context.Context, id int64) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM WorldEvent WHERE Id = ?", id)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return juicemud.WithStack(err)
+		} else if affected == 0 {
+			return errors.Wrapf(os.ErrNotExist, "no world event %v", id)
+		}
+		return nil
+	}))
+}
+
+// UpcomingWorldEvents returns the events that haven't ended yet, ordered by start.
+func (s *Storage) UpcomingWorldEvents(ctx context.Context) ([]WorldEvent, error) {
+	result := []WorldEvent{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM WorldEvent WHERE Ended = 0 ORDER BY Start, Id"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// DueWorldEvents returns the events that should have started or ended at now, but aren't
+// marked as such, ordered by start.
+func (s *Storage) DueWorldEvents(ctx context.Context, now int64) ([]WorldEvent, error) {
+	result := []WorldEvent{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM WorldEvent WHERE (Started = 0 AND Start <= ?) OR (Ended = 0 AND End <= ?) ORDER BY Start, Id", now, now); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage/dbm"
+)
+
+func (s *Storage) hashes() []dbm.Hash {
+	return []dbm.Hash{s.sources, s.modTimes, s.objects.Hash, s.queueTree.Hash}
+}
+
+// Compact rewrites all databases to reclaim the space used by removed and overwritten data.
+func (s *Storage) Compact(ctx context.Context) error {
+	for _, hash := range s.hashes() {
+		if err := hash.Rebuild(); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	if _, err := s.sql.ExecContext(ctx, "VACUUM"); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return nil
+}
+
+func (s *Storage) Close() error {
+	s.queue.Close()
+	for _, hash := range s.hashes() {
+		if err := hash.Close(); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return juicemud.WithStack(s.sql.Close())
+}
+
+// Orphan is an object whose Location points at an object that doesn't exist.
+type Orphan struct {
+	Object   string
+	Location string
+}
+
+// FindOrphans scans all objects for ones located in objects that don't exist.
+func (s *Storage) FindOrphans(_ context.Context) ([]Orphan, error) {
+	result := []Orphan{}
+	for object, err := range s.objects.Each() {
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if object.Location == "" {
+			continue
+		}
+		if _, err := s.objects.Get(object.Location); errors.Is(err, os.ErrNotExist) {
+			result = append(result, Orphan{Object: object.Id, Location: object.Location})
+		} else if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+	}
+	return result, nil
+}
+
+// RepairOrphan moves an orphaned object to newLocation. Since the old location doesn't
+// exist, this can't be done using StoreObject.
+func (s *Storage) RepairOrphan(_ context.Context, id string, newLocation string) error {
+	object, err := s.objects.Get(id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	if _, err := s.objects.Get(object.Location); !errors.Is(err, os.ErrNotExist) {
+		return errors.Errorf("%q is not orphaned, location %q exists", id, object.Location)
+	}
+	return juicemud.WithStack(s.forceMove(id, newLocation))
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+const (
+	// ConversationMemoryQuota is the maximum number of keys an object remembers about each
+	// subject. Remembering more forgets the keys closest to expiring.
+	ConversationMemoryQuota = 64
+)
+
+// ConversationMemory is a JSON value an object remembers about a subject, like an NPC
+// remembering what a player told it, until Expires.
+type ConversationMemory struct {
+	Id      int64  `sqly:"pkey,autoinc"`
+	Object  string `sqly:"index"`
+	Subject string `sqly:"index"`
+	Key     string
+	Value   string
+	Expires int64 `sqly:"index"`
+}
+
+func loadConversationMemory(ctx context.Context, db sqlx.QueryerContext, object string, subject string, key string) (*ConversationMemory, error) {
+	result := &ConversationMemory{}
+	if err := getSQL(ctx, db, result, "SELECT * FROM ConversationMemory WHERE Object = ? AND Subject = ? AND Key = ?", object, subject, key); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// Remember makes object remember the JSON value of key about subject until the Unix
+// nanoseconds expires, or forget it if value is empty.
+func (s *Storage) Remember(ctx context.Context, object string, subject string, key string, value string, expires int64) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		current, err := loadConversationMemory(ctx, tx, object, subject, key)
+		if errors.Is(err, os.ErrNotExist) {
+			current = &ConversationMemory{Object: object, Subject: subject, Key: key}
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if value == "" {
+			if current.Id != 0 {
+				if _, err := tx.ExecContext(ctx, "DELETE FROM ConversationMemory WHERE Id = ?", current.Id); err != nil {
+					return juicemud.WithStack(err)
+				}
+			}
+			return nil
+		}
+		current.Value = value
+		current.Expires = expires
+		if err := tx.Upsert(ctx, current, true); err != nil {
+			return juicemud.WithStack(err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM ConversationMemory WHERE Id IN (
+  SELECT Id FROM ConversationMemory WHERE Object = ? AND Subject = ?
+  ORDER BY Expires DESC, Id DESC LIMIT -1 OFFSET ?)`, object, subject, ConversationMemoryQuota)
+		return juicemud.WithStack(err)
+	}))
+}
+
+// Recall returns the JSON values object remembers about subject by key, skipping those
+// expired before the Unix nanoseconds now.
+func (s *Storage) Recall(ctx context.Context, object string, subject string, now int64) (map[string]string, error) {
+	memories := []ConversationMemory{}
+	if err := sqlx.SelectContext(ctx, s.sql, &memories, "SELECT * FROM ConversationMemory WHERE Object = ? AND Subject = ? AND Expires >= ?", object, subject, now); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := map[string]string{}
+	for _, memory := range memories {
+		result[memory.Key] = memory.Value
+	}
+	return result, nil
+}
+
+// ExpireConversationMemories forgets the memories that expired before the Unix nanoseconds
+// now. Returns the number of forgotten memories.
+func (s *Storage) ExpireConversationMemories(ctx context.Context, now int64) (int64, error) {
+	var result int64
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM ConversationMemory WHERE Expires < ?", now)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		result, err = res.RowsAffected()
+		return juicemud.WithStack(err)
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package dbm
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is an LRU cache of serialized records, so that records used often don't have
+// to be read from disk every time, while records untouched for a long time are evicted.
+type Cache struct {
+	mutex      sync.Mutex
+	size       int
+	entries    map[string]*list.Element
+	order      *list.List
+	generation uint64
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+}
+
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// CacheStats is a snapshot of the state of a Cache.
+type CacheStats struct {
+	Size      int
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Resize changes the maximum number of entries, evicting the least recently used ones
+// if there are too many.
+func (c *Cache) Resize(size int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.size = size
+	c.evict()
+}
+
+func (c *Cache) evict() {
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// get returns the cached value of key, and the generation to give put if it wasn't found.
+func (c *Cache) get(key string) ([]byte, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, found := c.entries[key]; found {
+		c.order.MoveToFront(element)
+		c.hits++
+		return element.Value.(*cacheEntry).value, 0, true
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// put caches value for key, unless something was invalidated since generation was returned
+// by get, since value might then be stale.
+func (c *Cache) put(key string, value []byte, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if generation != c.generation || c.size < 1 {
+		return
+	}
+	if element, found := c.entries[key]; found {
+		element.Value.(*cacheEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value})
+	c.evict()
+}
+
+func (c *Cache) invalidate(keys ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	for _, key := range keys {
+		if element, found := c.entries[key]; found {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return CacheStats{
+		Size:      c.size,
+		Entries:   c.order.Len(),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
@@ -3,6 +3,7 @@ package dbm
 import (
 	"bytes"
 	"fmt"
+	"iter"
 	"os"
 
 	"github.com/estraier/tkrzw-go"
@@ -10,20 +11,49 @@ import (
 )
 
 type Hash struct {
-	dbm *tkrzw.DBM
+	dbm   *tkrzw.DBM
+	cache *Cache
+}
+
+// WithCache returns a copy of the hash reading through, and invalidating, cache.
+func (h Hash) WithCache(cache *Cache) Hash {
+	h.cache = cache
+	return h
+}
+
+func (h Hash) Cache() *Cache {
+	return h.cache
+}
+
+func (h Hash) invalidate(keys ...string) {
+	if h.cache != nil {
+		h.cache.invalidate(keys...)
+	}
 }
 
 func (h Hash) Get(k string) ([]byte, error) {
+	var generation uint64
+	if h.cache != nil {
+		var found bool
+		var b []byte
+		if b, generation, found = h.cache.get(k); found {
+			return b, nil
+		}
+	}
 	b, stat := h.dbm.Get(k)
 	if stat.GetCode() == tkrzw.StatusNotFoundError {
 		return nil, juicemud.WithStack(os.ErrNotExist)
 	} else if !stat.IsOK() {
 		return nil, juicemud.WithStack(stat)
 	}
+	if h.cache != nil {
+		h.cache.put(k, b, generation)
+	}
 	return b, nil
 }
 
 func (h Hash) Set(k string, v []byte, overwrite bool) error {
+	defer h.invalidate(k)
 	if stat := h.dbm.Set(k, v, overwrite); !stat.IsOK() {
 		return juicemud.WithStack(stat)
 	}
@@ -31,12 +61,36 @@ func (h Hash) Set(k string, v []byte, overwrite bool) error {
 }
 
 func (h Hash) Del(k string) error {
+	defer h.invalidate(k)
 	if stat := h.dbm.Remove(k); !stat.IsOK() {
 		return juicemud.WithStack(stat)
 	}
 	return nil
 }
 
+// Rebuild rewrites the database file, reclaiming space used by removed and overwritten records.
+func (h Hash) Rebuild() error {
+	if stat := h.dbm.Rebuild(nil); !stat.IsOK() {
+		return juicemud.WithStack(stat)
+	}
+	return nil
+}
+
+func (h Hash) Close() error {
+	if stat := h.dbm.Close(); !stat.IsOK() {
+		return juicemud.WithStack(stat)
+	}
+	return nil
+}
+
+func (h Hash) Count() (int64, error) {
+	count, stat := h.dbm.Count()
+	if !stat.IsOK() {
+		return 0, juicemud.WithStack(stat)
+	}
+	return count, nil
+}
+
 type Serializable[T any] interface {
 	Marshal([]byte)
 	Unmarshal([]byte) error
@@ -49,11 +103,9 @@ type TypeHash[T any, S Serializable[T]] struct {
 }
 
 func (h TypeHash[T, S]) Get(k string) (*T, error) {
-	b, stat := h.dbm.Get(k)
-	if stat.GetCode() == tkrzw.StatusNotFoundError {
-		return nil, juicemud.WithStack(os.ErrNotExist)
-	} else if !stat.IsOK() {
-		return nil, juicemud.WithStack(stat)
+	b, err := h.Hash.Get(k)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
 	}
 	t := S(new(T))
 	if err := t.Unmarshal(b); err != nil {
@@ -63,11 +115,28 @@ func (h TypeHash[T, S]) Get(k string) (*T, error) {
 }
 
 func (h TypeHash[T, S]) GetMulti(keys map[string]bool) (map[string]*T, error) {
+	byteResults := map[string][]byte{}
 	ids := make([]string, 0, len(keys))
+	var generation uint64
 	for key := range keys {
+		if h.cache != nil {
+			b, gen, found := h.cache.get(key)
+			if found {
+				byteResults[key] = b
+				continue
+			}
+			generation = gen
+		}
 		ids = append(ids, key)
 	}
-	byteResults := h.dbm.GetMulti(ids)
+	if len(ids) > 0 {
+		for key, b := range h.dbm.GetMulti(ids) {
+			byteResults[key] = b
+			if h.cache != nil {
+				h.cache.put(key, b, generation)
+			}
+		}
+	}
 	results := map[string]*T{}
 	for key, byteResult := range byteResults {
 		result := S(new(T))
@@ -79,7 +148,42 @@ func (h TypeHash[T, S]) GetMulti(keys map[string]bool) (map[string]*T, error) {
 	return results, nil
 }
 
+// Each iterates over all values in the hash, in undefined order. If an error occurs it
+// is yielded with a nil value, and the iteration stops.
+func (h TypeHash[T, S]) Each() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		iter := h.dbm.MakeIterator()
+		defer iter.Destruct()
+		if stat := iter.First(); !stat.IsOK() {
+			yield(nil, juicemud.WithStack(stat))
+			return
+		}
+		for {
+			_, b, stat := iter.Get()
+			if stat.GetCode() == tkrzw.StatusNotFoundError {
+				return
+			} else if !stat.IsOK() {
+				yield(nil, juicemud.WithStack(stat))
+				return
+			}
+			t := S(new(T))
+			if err := t.Unmarshal(b); err != nil {
+				yield(nil, juicemud.WithStack(err))
+				return
+			}
+			if !yield((*T)(t), nil) {
+				return
+			}
+			if stat := iter.Next(); !stat.IsOK() {
+				yield(nil, juicemud.WithStack(stat))
+				return
+			}
+		}
+	}
+}
+
 func (h TypeHash[T, S]) Set(k string, v *T, overwrite bool) error {
+	defer h.invalidate(k)
 	s := S(v)
 	b := make([]byte, s.Size())
 	s.Marshal(b)
@@ -183,6 +287,13 @@ func (h Hash) Proc(pairs []Proc, write bool) error {
 			},
 		}
 	}
+	if write {
+		keys := make([]string, len(pairs))
+		for index, pair := range pairs {
+			keys[index] = pair.Key()
+		}
+		defer h.invalidate(keys...)
+	}
 	if stat := h.dbm.ProcessMulti(procs, write); !stat.IsOK() {
 		return juicemud.WithStack(stat)
 	}
@@ -226,7 +337,7 @@ func OpenHash(path string) (Hash, error) {
 	if !stat.IsOK() {
 		return Hash{}, juicemud.WithStack(stat)
 	}
-	return Hash{dbm}, nil
+	return Hash{dbm: dbm}, nil
 }
 
 func OpenTypeHash[T any, S Serializable[T]](path string) (TypeHash[T, S], error) {
@@ -247,7 +358,7 @@ func OpenTree(path string) (Tree, error) {
 	if !stat.IsOK() {
 		return Tree{}, juicemud.WithStack(stat)
 	}
-	return Tree{Hash{dbm}}, nil
+	return Tree{Hash{dbm: dbm}}, nil
 }
 
 func OpenTypeTree[T any, S Serializable[T]](path string) (TypeTree[T, S], error) {
@@ -244,3 +244,36 @@ func TestFirst(t *testing.T) {
 		}
 	})
 }
+
+func TestCache(t *testing.T) {
+	WithHash(t, func(h Hash) {
+		h = h.WithCache(NewCache(2))
+		for _, k := range []string{"a", "b", "c"} {
+			if err := h.Set(k, []byte(k), true); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := h.Get(k); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if stats := h.Cache().Stats(); stats.Entries != 2 || stats.Misses != 3 || stats.Evictions != 1 {
+			t.Errorf("got %+v, want 2 entries, 3 misses and 1 eviction", stats)
+		}
+		if err := h.Set("c", []byte("C"), true); err != nil {
+			t.Fatal(err)
+		}
+		got, err := h.Get("c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "C" {
+			t.Errorf("got %q, want \"C\"", got)
+		}
+		if _, err := h.Get("c"); err != nil {
+			t.Fatal(err)
+		}
+		if stats := h.Cache().Stats(); stats.Hits != 1 {
+			t.Errorf("got %+v, want 1 hit", stats)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+	"github.com/zond/sqly"
+)
+
+// DeadLetter is an event that couldn't be delivered, kept so that it can be inspected and redelivered.
+type DeadLetter struct {
+	Id       int64  `sqly:"pkey,autoinc"`
+	Object   string `sqly:"index"`
+	Name     string
+	Message  string
+	Tag      string
+	Priority int64
+	Attempts int64
+	Error    string
+	FailedAt int64
+}
+
+// Event returns an event delivering the dead letter again at the given time.
+func (d *DeadLetter) Event(at structs.Timestamp) *structs.Event {
+	return &structs.Event{
+		At:       uint64(at),
+		Object:   d.Object,
+		Priority: d.Priority,
+		Call: structs.Call{
+			Name:    d.Name,
+			Message: d.Message,
+			Tag:     d.Tag,
+		},
+	}
+}
+
+func (s *Storage) StoreDeadLetter(ctx context.Context, ev *structs.Event, attempts int, cause error) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		return juicemud.WithStack(tx.Upsert(ctx, &DeadLetter{
+			Object:   ev.Object,
+			Name:     ev.Call.Name,
+			Message:  ev.Call.Message,
+			Tag:      ev.Call.Tag,
+			Priority: ev.Priority,
+			Attempts: int64(attempts),
+			Error:    cause.Error(),
+			FailedAt: int64(s.queue.After(0)),
+		}, false))
+	}))
+}
+
+// DeadLetters returns the limit most recent dead letters.
+func (s *Storage) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	result := []DeadLetter{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM DeadLetter ORDER BY Id DESC LIMIT ?", limit); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+func (s *Storage) CountDeadLetters(ctx context.Context) (int64, error) {
+	var result int64
+	if err := getSQL(ctx, s.sql, &result, "SELECT COUNT(*) FROM DeadLetter"); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// RedeliverDeadLetters pushes the dead letters with the given IDs, or all dead letters if
+// ids is empty, back into the queue and removes them from the dead letter store.
+func (s *Storage) RedeliverDeadLetters(ctx context.Context, ids []int64) (int, error) {
+	letters := []DeadLetter{}
+	if len(ids) == 0 {
+		if err := sqlx.SelectContext(ctx, s.sql, &letters, "SELECT * FROM DeadLetter ORDER BY Id"); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+	} else {
+		query, args, err := sqlx.In("SELECT * FROM DeadLetter WHERE Id IN (?) ORDER BY Id", ids)
+		if err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+		if err := sqlx.SelectContext(ctx, s.sql, &letters, query, args...); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+	}
+	for _, letter := range letters {
+		if err := s.queue.Push(ctx, letter.Event(s.queue.After(0))); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+		if _, err := s.sql.ExecContext(ctx, "DELETE FROM DeadLetter WHERE Id = ?", letter.Id); err != nil {
+			return 0, juicemud.WithStack(err)
+		}
+	}
+	return len(letters), nil
+}
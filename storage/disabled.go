@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// DisabledSource is a source path whose scripts aren't run, to stop bad scripts in an emergency.
+type DisabledSource struct {
+	Id         int64  `sqly:"pkey,autoinc"`
+	Path       string `sqly:"unique"`
+	DisabledBy string
+	DisabledAt int64
+}
+
+// DisableSource stops the scripts of the path of source from running until EnableSource is
+// called for it.
+func (s *Storage) DisableSource(ctx context.Context, source *DisabledSource) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM DisabledSource WHERE Path = ?", source.Path); err != nil {
+			return juicemud.WithStack(err)
+		}
+		return juicemud.WithStack(tx.Upsert(ctx, source, false))
+	}))
+}
+
+// EnableSource lets the scripts of path run again.
+func (s *Storage) EnableSource(ctx context.Context, path string) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM DisabledSource WHERE Path = ?", path)
+		return juicemud.WithStack(err)
+	}))
+}
+
+// DisabledSources returns the disabled source paths, in path order.
+func (s *Storage) DisabledSources(ctx context.Context) ([]DisabledSource, error) {
+	result := []DisabledSource{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM DisabledSource ORDER BY Path"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
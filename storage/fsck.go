@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage/dbm"
+	"github.com/zond/juicemud/structs"
+)
+
+// Problem is a referential integrity problem found by Fsck.
+type Problem struct {
+	Object      string
+	Description string
+	// Repaired is true if Fsck was asked to repair, and managed to repair the problem.
+	Repaired bool
+
+	repair func(ctx context.Context) error
+}
+
+func (p Problem) String() string {
+	if p.Repaired {
+		return fmt.Sprintf("%q: %s (repaired)", p.Object, p.Description)
+	}
+	return fmt.Sprintf("%q: %s", p.Object, p.Description)
+}
+
+// forceMove moves the object to newLocation without requiring that the old location exists
+// or contains the object.
+func (s *Storage) forceMove(id string, newLocation string) error {
+	object, err := s.objects.Get(id)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	pairs := []dbm.Proc{
+		s.objects.SProc(id, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", id)
+			}
+			if value.Location != object.Location {
+				return nil, errors.Errorf("%q moved from %q to %q during repair", id, object.Location, value.Location)
+			}
+			value.Location = newLocation
+			return value, nil
+		}),
+		s.objects.SProc(newLocation, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find new location %q", newLocation)
+			}
+			if value.Content == nil {
+				value.Content = map[string]bool{}
+			}
+			value.Content[id] = true
+			return value, nil
+		}),
+	}
+	if object.Location != "" && object.Location != newLocation {
+		pairs = append(pairs, s.objects.SProc(object.Location, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value != nil {
+				delete(value.Content, id)
+			}
+			return value, nil
+		}))
+	}
+	return juicemud.WithStack(s.objects.Proc(pairs, true))
+}
+
+func (s *Storage) modifyObject(id string, f func(*structs.Object)) error {
+	return juicemud.WithStack(s.objects.Proc([]dbm.Proc{
+		s.objects.SProc(id, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", id)
+			}
+			f(value)
+			return value, nil
+		}),
+	}, true))
+}
+
+// Fsck validates the referential integrity of the world: that content lists match
+// the Location fields of the content, that exits point at existing objects, that
+// users have existing objects, and that there are no containment cycles.
+//
+// If repair is true, it will try to repair each found problem, using lostAndFound as
+// location for objects without valid location.
+func (s *Storage) Fsck(ctx context.Context, repair bool, lostAndFound string) ([]Problem, error) {
+	objects := map[string]*structs.Object{}
+	for object, err := range s.objects.Each() {
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		objects[object.Id] = object
+	}
+	problems := []Problem{}
+	addProblem := func(id string, repair func(context.Context) error, format string, args ...any) {
+		problems = append(problems, Problem{
+			Object:      id,
+			Description: fmt.Sprintf(format, args...),
+			repair:      repair,
+		})
+	}
+	for id, object := range objects {
+		if object.Location != "" {
+			if location, found := objects[object.Location]; !found {
+				addProblem(id, func(context.Context) error {
+					return s.forceMove(id, lostAndFound)
+				}, "located in missing %q", object.Location)
+			} else if !location.Content[id] {
+				addProblem(id, func(context.Context) error {
+					return s.modifyObject(location.Id, func(o *structs.Object) {
+						if o.Content == nil {
+							o.Content = map[string]bool{}
+						}
+						o.Content[id] = true
+					})
+				}, "located in %q, but not in its content", object.Location)
+			}
+		}
+		for contentID := range object.Content {
+			content, found := objects[contentID]
+			if !found {
+				addProblem(id, func(context.Context) error {
+					return s.modifyObject(id, func(o *structs.Object) {
+						delete(o.Content, contentID)
+					})
+				}, "contains missing %q", contentID)
+			} else if content.Location != id {
+				addProblem(id, func(context.Context) error {
+					return s.modifyObject(id, func(o *structs.Object) {
+						delete(o.Content, contentID)
+					})
+				}, "contains %q, which is located in %q", contentID, content.Location)
+			}
+		}
+		for _, exit := range object.Exits {
+			if _, found := objects[exit.Destination]; !found {
+				destination := exit.Destination
+				addProblem(id, func(context.Context) error {
+					return s.modifyObject(id, func(o *structs.Object) {
+						exits := []structs.Exit{}
+						for _, exit := range o.Exits {
+							if exit.Destination != destination {
+								exits = append(exits, exit)
+							}
+						}
+						o.Exits = exits
+					})
+				}, "has exit to missing %q", destination)
+			}
+		}
+		// Only the lowest ID of each containment cycle reports it, since moving
+		// that one breaks the cycle for all members.
+		seen := map[string]bool{id: true}
+		for loc := object.Location; loc != ""; {
+			if loc == id {
+				addProblem(id, func(context.Context) error {
+					return s.forceMove(id, lostAndFound)
+				}, "is part of a containment cycle")
+				break
+			}
+			if seen[loc] || loc < id {
+				break
+			}
+			seen[loc] = true
+			next, found := objects[loc]
+			if !found {
+				break
+			}
+			loc = next.Location
+		}
+	}
+	users := []User{}
+	if err := s.sql.SelectContext(ctx, &users, "SELECT * FROM User"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	for _, user := range users {
+		if _, found := objects[user.Object]; !found {
+			addProblem(user.Object, nil, "is the object of user %q, but is missing", user.Name)
+		}
+	}
+	if repair {
+		for i := range problems {
+			if problems[i].repair == nil {
+				continue
+			}
+			if err := problems[i].repair(ctx); err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			problems[i].Repaired = true
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+const (
+	// GlobalNamespaceQuota is the maximum total size, in bytes, of the values in a global namespace.
+	GlobalNamespaceQuota = 1 << 20
+)
+
+var (
+	ErrGlobalQuotaExceeded = fmt.Errorf("global namespace quota exceeded")
+)
+
+// GlobalValue is a JSON value in the global store shared between all objects, for data that
+// doesn't belong to any single object.
+type GlobalValue struct {
+	Id        int64  `sqly:"pkey,autoinc"`
+	Namespace string `sqly:"index"`
+	Key       string `sqly:"uniqueWith(Namespace)"`
+	Value     string
+}
+
+func loadGlobal(ctx context.Context, db sqlx.QueryerContext, namespace string, key string) (*GlobalValue, error) {
+	result := &GlobalValue{}
+	if err := getSQL(ctx, db, result, "SELECT * FROM GlobalValue WHERE Namespace = ? AND Key = ?", namespace, key); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// GetGlobal returns the JSON value of key in namespace, or os.ErrNotExist if there is none.
+func (s *Storage) GetGlobal(ctx context.Context, namespace string, key string) (string, error) {
+	value, err := loadGlobal(ctx, s.sql, namespace, key)
+	if err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return value.Value, nil
+}
+
+// SetGlobal sets key in namespace to the JSON value, or removes it if value is empty.
+func (s *Storage) SetGlobal(ctx context.Context, namespace string, key string, value string) error {
+	_, err := s.CASGlobal(ctx, namespace, key, nil, value)
+	return juicemud.WithStack(err)
+}
+
+// CASGlobal sets key in namespace to the JSON value (or removes it if value is empty) if the
+// current value is equal to expected. A nil expected matches any current value, and an empty
+// expected matches only a missing value. Returns whether the value was swapped.
+func (s *Storage) CASGlobal(ctx context.Context, namespace string, key string, expected *string, value string) (bool, error) {
+	swapped := false
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		current, err := loadGlobal(ctx, tx, namespace, key)
+		if errors.Is(err, os.ErrNotExist) {
+			current = &GlobalValue{Namespace: namespace, Key: key}
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if expected != nil && *expected != current.Value {
+			return nil
+		}
+		swapped = true
+		if value == "" {
+			if current.Id != 0 {
+				if _, err := tx.ExecContext(ctx, "DELETE FROM GlobalValue WHERE Id = ?", current.Id); err != nil {
+					return juicemud.WithStack(err)
+				}
+			}
+			return nil
+		}
+		var used int64
+		if err := getSQL(ctx, tx, &used, "SELECT COALESCE(SUM(LENGTH(Value)), 0) FROM GlobalValue WHERE Namespace = ?", namespace); err != nil {
+			return juicemud.WithStack(err)
+		}
+		if used-int64(len(current.Value))+int64(len(value)) > GlobalNamespaceQuota {
+			return errors.Wrapf(ErrGlobalQuotaExceeded, "%q would use more than %v bytes", namespace, GlobalNamespaceQuota)
+		}
+		current.Value = value
+		return juicemud.WithStack(tx.Upsert(ctx, current, true))
+	}); err != nil {
+		return false, juicemud.WithStack(err)
+	}
+	return swapped, nil
+}
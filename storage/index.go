@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+	"github.com/zond/sqly"
+)
+
+const (
+	TagIndex      = "tag"
+	SourceIndex   = "source"
+	KeywordIndex  = "keyword"
+	TopicIndex    = "topic"
+	DecayIndex    = "decay"
+	AmbienceIndex = "ambience"
+	DespawnIndex  = "despawn"
+)
+
+var (
+	// IndexKinds are the kinds of object index maintained by the storage.
+	IndexKinds = []string{TagIndex, SourceIndex, KeywordIndex, TopicIndex, DecayIndex, AmbienceIndex, DespawnIndex}
+
+	keywordStopWords = map[string]bool{
+		"a":   true,
+		"an":  true,
+		"the": true,
+		"of":  true,
+	}
+)
+
+// ObjectIndex is a secondary index entry pointing from a (Kind, Value) pair to an object,
+// so that objects can be found without scanning the whole object database.
+type ObjectIndex struct {
+	Id     int64  `sqly:"pkey,autoinc"`
+	Object string `sqly:"index"`
+	Kind   string
+	Value  string `sqly:"index"`
+}
+
+type indexKey struct {
+	kind  string
+	value string
+}
+
+func objectIndexKeys(object *structs.Object) map[indexKey]bool {
+	result := map[indexKey]bool{}
+	if object == nil {
+		return result
+	}
+	for _, tag := range object.Tags {
+		result[indexKey{kind: TagIndex, value: tag}] = true
+	}
+	if object.SourcePath != "" {
+		result[indexKey{kind: SourceIndex, value: object.SourcePath}] = true
+	}
+	for _, topic := range object.Subscriptions {
+		result[indexKey{kind: TopicIndex, value: topic}] = true
+	}
+	if object.Decaying() {
+		result[indexKey{kind: DecayIndex, value: "true"}] = true
+	}
+	if len(object.Ambience) > 0 {
+		result[indexKey{kind: AmbienceIndex, value: "true"}] = true
+	}
+	if object.DespawnAt > 0 {
+		result[indexKey{kind: DespawnIndex, value: "true"}] = true
+	}
+	for _, desc := range object.Descriptions {
+		for _, keyword := range Keywords(desc.Short) {
+			result[indexKey{kind: KeywordIndex, value: keyword}] = true
+		}
+	}
+	return result
+}
+
+// Keywords returns the lower case words of s that are indexed as description keywords.
+func Keywords(s string) []string {
+	result := []string{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !keywordStopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+func (s *Storage) updateObjectIndex(ctx context.Context, previous *structs.Object, current *structs.Object) error {
+	oldKeys := objectIndexKeys(previous)
+	newKeys := objectIndexKeys(current)
+	toAdd := []indexKey{}
+	for key := range newKeys {
+		if !oldKeys[key] {
+			toAdd = append(toAdd, key)
+		}
+	}
+	toRemove := []indexKey{}
+	for key := range oldKeys {
+		if !newKeys[key] {
+			toRemove = append(toRemove, key)
+		}
+	}
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		return nil
+	}
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		for _, key := range toRemove {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM ObjectIndex WHERE Object = ? AND Kind = ? AND Value = ?", current.Id, key.kind, key.value); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		for _, key := range toAdd {
+			if err := tx.Upsert(ctx, &ObjectIndex{
+				Object: current.Id,
+				Kind:   key.kind,
+				Value:  key.value,
+			}, false); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
+// removeObjectIndex removes the index entries of the object with the given ID.
+func (s *Storage) removeObjectIndex(ctx context.Context, id string) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM ObjectIndex WHERE Object = ?", id)
+		return juicemud.WithStack(err)
+	}))
+}
+
+func getIndexedIDs(ctx context.Context, db sqlx.QueryerContext, kind string, value string) (map[string]bool, error) {
+	entries := []ObjectIndex{}
+	if err := sqlx.SelectContext(ctx, db, &entries, "SELECT * FROM ObjectIndex WHERE Kind = ? AND Value = ?", kind, value); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := map[string]bool{}
+	for _, entry := range entries {
+		result[entry.Object] = true
+	}
+	return result, nil
+}
+
+// ObjectIDsByTag returns the IDs of all objects tagged with tag.
+func (s *Storage) ObjectIDsByTag(ctx context.Context, tag string) (map[string]bool, error) {
+	return getIndexedIDs(ctx, s.sql, TagIndex, tag)
+}
+
+// ObjectIDsByTopic returns the IDs of all objects subscribed to topic.
+func (s *Storage) ObjectIDsByTopic(ctx context.Context, topic string) (map[string]bool, error) {
+	return getIndexedIDs(ctx, s.sql, TopicIndex, topic)
+}
+
+// DecayingObjectIDs returns the IDs of all objects losing durability over time, or waiting
+// to be removed as trash.
+func (s *Storage) DecayingObjectIDs(ctx context.Context) (map[string]bool, error) {
+	return getIndexedIDs(ctx, s.sql, DecayIndex, "true")
+}
+
+// AmbientObjectIDs returns the IDs of all objects with ambient messages.
+func (s *Storage) AmbientObjectIDs(ctx context.Context) (map[string]bool, error) {
+	return getIndexedIDs(ctx, s.sql, AmbienceIndex, "true")
+}
+
+// DespawningObjectIDs returns the IDs of all objects scheduled to despawn.
+func (s *Storage) DespawningObjectIDs(ctx context.Context) (map[string]bool, error) {
+	return getIndexedIDs(ctx, s.sql, DespawnIndex, "true")
+}
+
+// FindObjectIDs returns the IDs of all objects matching every index kind/value pair in query.
+func (s *Storage) FindObjectIDs(ctx context.Context, query map[string]string) (map[string]bool, error) {
+	var result map[string]bool
+	for kind, value := range query {
+		if kind == KeywordIndex {
+			value = strings.ToLower(value)
+		}
+		ids, err := getIndexedIDs(ctx, s.sql, kind, value)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		if result == nil {
+			result = ids
+		} else {
+			for id := range result {
+				if !ids[id] {
+					delete(result, id)
+				}
+			}
+		}
+		if len(result) == 0 {
+			break
+		}
+	}
+	if result == nil {
+		result = map[string]bool{}
+	}
+	return result, nil
+}
+
+type IndexStat struct {
+	Kind           string
+	Entries        int64
+	DistinctValues int64
+}
+
+// IndexStats returns the number of entries and distinct values per index kind.
+func (s *Storage) IndexStats(ctx context.Context) ([]IndexStat, error) {
+	result := []IndexStat{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT Kind, COUNT(*) AS Entries, COUNT(DISTINCT Value) AS DistinctValues FROM ObjectIndex GROUP BY Kind ORDER BY Kind"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+func (s *Storage) CountObjects(_ context.Context) (int64, error) {
+	return s.objects.Count()
+}
+
+// ReindexObjects drops the object index and rebuilds it from all stored objects.
+// Needed for objects stored before an index kind existed.
+func (s *Storage) ReindexObjects(ctx context.Context) error {
+	if _, err := s.sql.ExecContext(ctx, "DELETE FROM ObjectIndex"); err != nil {
+		return juicemud.WithStack(err)
+	}
+	for object, err := range s.objects.Each() {
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if err := s.updateObjectIndex(ctx, nil, object); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// StateSize is the size of the state of an object.
+type StateSize struct {
+	Object     string
+	SourcePath string
+	Size       int
+}
+
+// LargestStates returns the limit objects with the largest states, largest first.
+func (s *Storage) LargestStates(_ context.Context, limit int) ([]StateSize, error) {
+	result := []StateSize{}
+	for object, err := range s.objects.Each() {
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		size := StateSize{Object: object.Id, SourcePath: object.SourcePath, Size: len(object.State)}
+		index, _ := slices.BinarySearchFunc(result, size, func(a, b StateSize) int {
+			return b.Size - a.Size
+		})
+		if index < limit {
+			result = slices.Insert(result, index, size)
+			if len(result) > limit {
+				result = result[:limit]
+			}
+		}
+	}
+	return result, nil
+}
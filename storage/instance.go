@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// Instance is a private copy of the area at Template, built for the objects in Party.
+type Instance struct {
+	Id int64 `sqly:"pkey,autoinc"`
+	// Key prefixes the IDs of the rooms of the instance.
+	Key      string `sqly:"unique"`
+	Template string `sqly:"index"`
+	// Party is the JSON array of the sorted IDs of the objects the instance was built for.
+	Party string
+	// Rooms is the JSON array of the IDs of the rooms of the instance.
+	Rooms   string
+	Created int64
+}
+
+// StoreInstance creates instance.
+func (s *Storage) StoreInstance(ctx context.Context, instance *Instance) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		return juicemud.WithStack(tx.Upsert(ctx, instance, false))
+	}))
+}
+
+// LoadInstance returns the instance of template built for party, or os.ErrNotExist if there
+// is none.
+func (s *Storage) LoadInstance(ctx context.Context, template string, party string) (*Instance, error) {
+	result := &Instance{}
+	if err := getSQL(ctx, s.sql, result, "SELECT * FROM Instance WHERE Template = ? AND Party = ?", template, party); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// Instances returns all instances, ordered by when they were created.
+func (s *Storage) Instances(ctx context.Context) ([]Instance, error) {
+	result := []Instance{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM Instance ORDER BY Created"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// DeleteInstance forgets the instance with key. The rooms of the instance must be deleted
+// separately.
+func (s *Storage) DeleteInstance(ctx context.Context, key string) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM Instance WHERE Key = ?", key)
+		return juicemud.WithStack(err)
+	}))
+}
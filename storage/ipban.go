@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// IPBan is a CIDR range whose connections are closed before they get to log in.
+type IPBan struct {
+	Id       int64  `sqly:"pkey,autoinc"`
+	Range    string `sqly:"unique"`
+	Reason   string
+	BannedBy string
+	BannedAt int64
+}
+
+// BanIP bans the range of ban, replacing any previous ban of it.
+func (s *Storage) BanIP(ctx context.Context, ban *IPBan) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM IPBan WHERE Range = ?", ban.Range); err != nil {
+			return juicemud.WithStack(err)
+		}
+		return juicemud.WithStack(tx.Upsert(ctx, ban, false))
+	}))
+}
+
+// UnbanIP lifts the ban of cidr, and returns whether there was one.
+func (s *Storage) UnbanIP(ctx context.Context, cidr string) (bool, error) {
+	removed := false
+	err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM IPBan WHERE Range = ?", cidr)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		count, err := res.RowsAffected()
+		removed = count > 0
+		return juicemud.WithStack(err)
+	})
+	return removed, juicemud.WithStack(err)
+}
+
+// IPBans returns the banned ranges, in range order.
+func (s *Storage) IPBans(ctx context.Context) ([]IPBan, error) {
+	result := []IPBan{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM IPBan ORDER BY Range"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+const (
+	DailyScores   = "daily"
+	WeeklyScores  = "weekly"
+	AllTimeScores = "all-time"
+)
+
+var (
+	// ScoreWindows are the windows every board keeps scores for.
+	ScoreWindows = []string{DailyScores, WeeklyScores, AllTimeScores}
+)
+
+// Score is the best value Object reported to Board during the Period of Timeframe, which is
+// one of the ScoreWindows.
+type Score struct {
+	Id        int64  `sqly:"pkey,autoinc"`
+	Board     string `sqly:"index"`
+	Timeframe string
+	Period    int64 `sqly:"index"`
+	Object    string
+	Value     float64
+	Reported  int64
+}
+
+// ScorePeriod returns the start, in nanoseconds since the epoch, of the UTC day or ISO week
+// of t, or 0 for all-time scores.
+func ScorePeriod(window string, t time.Time) (int64, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case DailyScores:
+		return day.UnixNano(), nil
+	case WeeklyScores:
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -sinceMonday).UnixNano(), nil
+	case AllTimeScores:
+		return 0, nil
+	}
+	return 0, errors.Errorf("unknown score window %q", window)
+}
+
+// ReportScore records value for object in all windows of board, unless object already
+// has a higher score in the window.
+func (s *Storage) ReportScore(ctx context.Context, board string, object string, value float64, at time.Time) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		for _, window := range ScoreWindows {
+			period, err := ScorePeriod(window, at)
+			if err != nil {
+				return juicemud.WithStack(err)
+			}
+			score := &Score{}
+			if err := getSQL(ctx, tx, score, "SELECT * FROM Score WHERE Board = ? AND Timeframe = ? AND Period = ? AND Object = ?", board, window, period, object); errors.Is(err, os.ErrNotExist) {
+				score = &Score{Board: board, Timeframe: window, Period: period, Object: object}
+			} else if err != nil {
+				return juicemud.WithStack(err)
+			} else if score.Value >= value {
+				continue
+			}
+			score.Value = value
+			score.Reported = at.UnixNano()
+			if err := tx.Upsert(ctx, score, true); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
+// TopScores returns the limit best scores of board in the current period of window at at,
+// best first.
+func (s *Storage) TopScores(ctx context.Context, board string, window string, at time.Time, limit int) ([]Score, error) {
+	period, err := ScorePeriod(window, at)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := []Score{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM Score WHERE Board = ? AND Timeframe = ? AND Period = ? ORDER BY Value DESC, Reported LIMIT ?", board, window, period, limit); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// ScoreBoards returns the names of all boards with scores.
+func (s *Storage) ScoreBoards(ctx context.Context) ([]string, error) {
+	result := []string{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT DISTINCT Board FROM Score WHERE Timeframe = ? ORDER BY Board", AllTimeScores); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// UserLogin is an IP a user has logged in from.
+type UserLogin struct {
+	Id   int64 `sqly:"pkey,autoinc"`
+	User int64 `sqly:"index"`
+	IP   string
+	// First and Last are when the user first and last logged in from IP, in Unix nanoseconds.
+	First int64
+	Last  int64
+}
+
+// RecordLogin records that user logged in from ip at the Unix nanoseconds at, and returns
+// whether ip is new for a user that has logged in from other IPs before.
+func (s *Storage) RecordLogin(ctx context.Context, user int64, ip string, at int64) (bool, error) {
+	newIP := false
+	err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		login := &UserLogin{}
+		err := getSQL(ctx, tx, login, "SELECT * FROM UserLogin WHERE User = ? AND IP = ?", user, ip)
+		if err == nil {
+			login.Last = at
+			return juicemud.WithStack(tx.Upsert(ctx, login, true))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return juicemud.WithStack(err)
+		}
+		previous := 0
+		if err := getSQL(ctx, tx, &previous, "SELECT COUNT(*) FROM UserLogin WHERE User = ?", user); err != nil {
+			return juicemud.WithStack(err)
+		}
+		newIP = previous > 0
+		return juicemud.WithStack(tx.Upsert(ctx, &UserLogin{User: user, IP: ip, First: at, Last: at}, false))
+	})
+	return newIP, juicemud.WithStack(err)
+}
+
+// UserLogins returns the IPs user has logged in from, in the order they were first used.
+func (s *Storage) UserLogins(ctx context.Context, user int64) ([]UserLogin, error) {
+	result := []UserLogin{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM UserLogin WHERE User = ? ORDER BY First", user); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
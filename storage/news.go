@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+// News is an entry of the news users are shown unread entries of when they log in.
+type News struct {
+	Id     int64 `sqly:"pkey,autoinc"`
+	Title  string
+	Body   string
+	Author string
+	// Posted is when the entry was posted or last edited, in Unix nanoseconds.
+	Posted int64 `sqly:"index"`
+}
+
+// LoadNews returns the news entry with id, or os.ErrNotExist if there is none.
+func (s *Storage) LoadNews(ctx context.Context, id int64) (*News, error) {
+	result := &News{}
+	if err := getSQL(ctx, s.sql, result, "SELECT * FROM News WHERE Id = ?", id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// ListNews returns the at most limit latest news entries, latest first.
+func (s *Storage) ListNews(ctx context.Context, limit int) ([]News, error) {
+	result := []News{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM News ORDER BY Posted DESC, Id DESC LIMIT ?", limit); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// CountNewsSince returns the number of news entries posted after the Unix nanoseconds since.
+func (s *Storage) CountNewsSince(ctx context.Context, since int64) (int, error) {
+	result := 0
+	if err := getSQL(ctx, s.sql, &result, "SELECT COUNT(*) FROM News WHERE Posted > ?", since); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// StoreNews posts news, or replaces the entry with its Id if it isn't zero.
+func (s *Storage) StoreNews(ctx context.Context, news *News) error {
+	return juicemud.WithStack(s.sql.Upsert(ctx, news, news.Id != 0))
+}
+
+// RemoveNews removes the news entry with id, and returns whether there was one.
+func (s *Storage) RemoveNews(ctx context.Context, id int64) (bool, error) {
+	removed := false
+	err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM News WHERE Id = ?", id)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		count, err := res.RowsAffected()
+		removed = count > 0
+		return juicemud.WithStack(err)
+	})
+	return removed, juicemud.WithStack(err)
+}
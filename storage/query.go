@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+)
+
+const (
+	// DefaultQueryLimit is the page size of queries without a limit.
+	DefaultQueryLimit = 50
+	// MaxQueryLimit is the largest page size of queries.
+	MaxQueryLimit = 1000
+)
+
+// ObjectFilter selects objects in QueryObjects. Empty fields don't filter.
+type ObjectFilter struct {
+	SourcePath string
+	Location   string
+	// Tags must all be on the objects.
+	Tags []string
+	// ModifiedAfter and ModifiedBefore are Unix nanoseconds bounding when the objects were
+	// last stored.
+	ModifiedAfter  int64
+	ModifiedBefore int64
+	// Index are index kind/value pairs, like in FindObjectIDs, the objects must be indexed
+	// with. Only the indices check them, so Matches ignores them.
+	Index map[string]string
+}
+
+// Matches returns whether object passes the filter.
+func (f *ObjectFilter) Matches(object *structs.Object) bool {
+	if f.SourcePath != "" && object.SourcePath != f.SourcePath {
+		return false
+	}
+	if f.Location != "" && object.Location != f.Location {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(object.Tags, tag) {
+			return false
+		}
+	}
+	if f.ModifiedAfter != 0 && object.Modified <= f.ModifiedAfter {
+		return false
+	}
+	if f.ModifiedBefore != 0 && object.Modified >= f.ModifiedBefore {
+		return false
+	}
+	return true
+}
+
+// candidateIDs returns the IDs of the objects that can match filter, using the indices and
+// the content of the location, or nil if the filter can't narrow them down.
+func (s *Storage) candidateIDs(ctx context.Context, filter *ObjectFilter) (map[string]bool, error) {
+	var result map[string]bool
+	intersect := func(ids map[string]bool) {
+		if result == nil {
+			result = ids
+			return
+		}
+		for id := range result {
+			if !ids[id] {
+				delete(result, id)
+			}
+		}
+	}
+	if filter.Location != "" {
+		location, err := s.LoadObject(ctx, filter.Location, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]bool{}, nil
+		} else if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		ids := map[string]bool{}
+		for id := range location.Content {
+			ids[id] = true
+		}
+		intersect(ids)
+	}
+	if filter.SourcePath != "" {
+		ids, err := getIndexedIDs(ctx, s.sql, SourceIndex, filter.SourcePath)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		intersect(ids)
+	}
+	for _, tag := range filter.Tags {
+		ids, err := getIndexedIDs(ctx, s.sql, TagIndex, tag)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		intersect(ids)
+	}
+	if len(filter.Index) > 0 {
+		ids, err := s.FindObjectIDs(ctx, filter.Index)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		intersect(ids)
+	}
+	return result, nil
+}
+
+// QueryObjects returns up to limit objects matching filter, in ID order, starting after the
+// ID cursor. Returns the cursor of the next page too, which is empty after the last page.
+// Filters with only modification times scan all objects.
+func (s *Storage) QueryObjects(ctx context.Context, filter *ObjectFilter, cursor string, limit int) ([]*structs.Object, string, error) {
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	limit = min(limit, MaxQueryLimit)
+	candidates, err := s.candidateIDs(ctx, filter)
+	if err != nil {
+		return nil, "", juicemud.WithStack(err)
+	}
+	result := []*structs.Object{}
+	if candidates == nil {
+		for object, err := range s.objects.Each() {
+			if err != nil {
+				return nil, "", juicemud.WithStack(err)
+			}
+			if object.Id > cursor && filter.Matches(object) {
+				result = append(result, object)
+			}
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].Id < result[j].Id
+		})
+		if len(result) > limit {
+			return result[:limit], result[limit-1].Id, nil
+		}
+		return result, "", nil
+	}
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		object, err := s.LoadObject(ctx, id, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, "", juicemud.WithStack(err)
+		}
+		if !filter.Matches(object) {
+			continue
+		}
+		if len(result) == limit {
+			return result, result[limit-1].Id, nil
+		}
+		result = append(result, object)
+	}
+	return result, "", nil
+}
@@ -12,12 +12,15 @@ import (
 	"github.com/zond/juicemud/structs"
 )
 
+// Queue is a persistent queue of events, handed out in the order of their keys: by At, then
+// by descending Priority, then in the order they were pushed. Each event is handed to the
+// handler before the next one, so any ordering guarantees beyond that are up to the handler.
 type Queue struct {
 	tree      dbm.TypeTree[structs.Event, *structs.Event]
 	cond      *sync.Cond
 	closed    bool
 	nextEvent *structs.Event
-	offset    structs.Timestamp
+	started   structs.Timestamp
 }
 
 func New(ctx context.Context, t dbm.Tree) *Queue {
@@ -28,11 +31,11 @@ func New(ctx context.Context, t dbm.Tree) *Queue {
 }
 
 func (q *Queue) After(dur time.Duration) structs.Timestamp {
-	return structs.Timestamp(time.Now().Add(dur).UnixNano()) + q.offset
+	return structs.Timestamp(time.Now().Add(dur).UnixNano())
 }
 
 func (q *Queue) At(t time.Time) structs.Timestamp {
-	return structs.Timestamp(t.UnixNano()) + q.offset
+	return structs.Timestamp(t.UnixNano())
 }
 
 func (q *Queue) until(at structs.Timestamp) time.Duration {
@@ -40,7 +43,7 @@ func (q *Queue) until(at structs.Timestamp) time.Duration {
 }
 
 func (q *Queue) now() structs.Timestamp {
-	return structs.Timestamp(time.Now().UnixNano()) + q.offset
+	return structs.Timestamp(time.Now().UnixNano())
 }
 
 func (q *Queue) peekFirst(_ context.Context) (*structs.Event, error) {
@@ -74,7 +77,7 @@ func (q *Queue) Push(ctx context.Context, ev *structs.Event) error {
 		return juicemud.WithStack(err)
 	}
 
-	if q.nextEvent == nil || ev.At < q.nextEvent.At {
+	if q.nextEvent == nil || ev.Key < q.nextEvent.Key {
 		q.nextEvent = ev
 		q.cond.Broadcast()
 	}
@@ -82,14 +85,21 @@ func (q *Queue) Push(ctx context.Context, ev *structs.Event) error {
 	return nil
 }
 
+// Overdue returns whether the event was due before the queue started, i.e. it was
+// persisted while the server was down and is delivered late.
+func (q *Queue) Overdue(ev *structs.Event) bool {
+	return structs.Timestamp(ev.At) < q.started
+}
+
+// Start hands all events, including the ones persisted before a restart, to the handler
+// when they are due. Events that became due while the server was down are handed out
+// immediately.
 func (q *Queue) Start(ctx context.Context, handler func(context.Context, *structs.Event)) error {
 	var err error
 	if q.nextEvent, err = q.peekFirst(ctx); err != nil {
 		return juicemud.WithStack(err)
 	}
-	if q.nextEvent != nil {
-		q.offset = structs.Timestamp(q.nextEvent.At)
-	}
+	q.started = q.now()
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 	for !q.closed || q.nextEvent != nil {
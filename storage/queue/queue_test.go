@@ -32,19 +32,19 @@ func TestQueue(t *testing.T) {
 			runWG.Done()
 		}()
 		if err := q.Push(ctx, &structs.Event{
-			At:     int64(q.After(100 * time.Millisecond)),
+			At:     uint64(q.After(100 * time.Millisecond)),
 			Object: "a",
 		}); err != nil {
 			t.Fatal(err)
 		}
 		if err := q.Push(ctx, &structs.Event{
-			At:     int64(q.After(10 * time.Millisecond)),
+			At:     uint64(q.After(10 * time.Millisecond)),
 			Object: "b",
 		}); err != nil {
 			t.Fatal(err)
 		}
 		if err := q.Push(ctx, &structs.Event{
-			At:     int64(q.After(200 * time.Millisecond)),
+			At:     uint64(q.After(200 * time.Millisecond)),
 			Object: "c",
 		}); err != nil {
 			t.Fatal(err)
@@ -58,3 +58,33 @@ func TestQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestQueuePriority(t *testing.T) {
+	ctx := context.Background()
+	dbm.WithTree(t, func(tr dbm.Tree) {
+		got := []string{}
+		q := New(ctx, tr)
+		at := uint64(q.After(50 * time.Millisecond))
+		for _, ev := range []*structs.Event{
+			{At: at, Object: "a"},
+			{At: at, Object: "b", Priority: -1},
+			{At: at, Object: "c", Priority: 2},
+			{At: at, Object: "d"},
+			{At: at, Object: "e", Priority: 1},
+		} {
+			if err := q.Push(ctx, ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+		q.Close()
+		if err := q.Start(ctx, func(_ context.Context, ev *structs.Event) {
+			got = append(got, ev.Object)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"c", "e", "a", "d", "b"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+const (
+	// MaxRecordingsPerUser is the number of session recordings kept for each user, older
+	// ones are removed when new ones are started.
+	MaxRecordingsPerUser = 10
+)
+
+// Recording is a session of a user that opted in to having their sessions recorded.
+type Recording struct {
+	Id      int64 `sqly:"pkey,autoinc"`
+	User    int64 `sqly:"index"`
+	Started int64
+	Width   int64
+	Height  int64
+}
+
+// RecordingFrame is output sent to the terminal of a recorded session.
+type RecordingFrame struct {
+	Id        int64 `sqly:"pkey,autoinc"`
+	Recording int64 `sqly:"index"`
+	At        int64
+	Output    string
+}
+
+// CreateRecording stores a new recording, and removes the oldest recordings of the same user
+// exceeding MaxRecordingsPerUser.
+func (s *Storage) CreateRecording(ctx context.Context, recording *Recording) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		if err := tx.Upsert(ctx, recording, false); err != nil {
+			return juicemud.WithStack(err)
+		}
+		old := []int64{}
+		if err := sqlx.SelectContext(ctx, tx, &old, "SELECT Id FROM Recording WHERE User = ? ORDER BY Id DESC LIMIT -1 OFFSET ?", recording.User, MaxRecordingsPerUser); err != nil {
+			return juicemud.WithStack(err)
+		}
+		for _, id := range old {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM RecordingFrame WHERE Recording = ?", id); err != nil {
+				return juicemud.WithStack(err)
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM Recording WHERE Id = ?", id); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
+func (s *Storage) AppendRecordingFrames(ctx context.Context, frames []RecordingFrame) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		for i := range frames {
+			if err := tx.Upsert(ctx, &frames[i], false); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
+// Recordings returns the recordings of user, most recent first.
+func (s *Storage) Recordings(ctx context.Context, user *User) ([]Recording, error) {
+	result := []Recording{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM Recording WHERE User = ? ORDER BY Id DESC", user.Id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+func (s *Storage) LoadRecording(ctx context.Context, id int64) (*Recording, error) {
+	result := &Recording{}
+	if err := getSQL(ctx, s.sql, result, "SELECT * FROM Recording WHERE Id = ?", id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// RecordingFrames returns the frames of the recording with the given ID, in order.
+func (s *Storage) RecordingFrames(ctx context.Context, id int64) ([]RecordingFrame, error) {
+	result := []RecordingFrame{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM RecordingFrame WHERE Recording = ? ORDER BY Id", id); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/sqly"
+)
+
+var (
+	ErrServiceTaken = fmt.Errorf("service already registered")
+)
+
+// Service is a world level object, like a weather or economy daemon, found by name. Source and
+// Location are what the object is recreated from if it goes missing.
+type Service struct {
+	Id       int64  `sqly:"pkey,autoinc"`
+	Name     string `sqly:"unique"`
+	Object   string
+	Source   string
+	Location string
+}
+
+func loadService(ctx context.Context, db sqlx.QueryerContext, name string) (*Service, error) {
+	result := &Service{}
+	if err := getSQL(ctx, db, result, "SELECT * FROM Service WHERE Name = ?", name); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// LoadService returns the service named name, or os.ErrNotExist if there is none.
+func (s *Storage) LoadService(ctx context.Context, name string) (*Service, error) {
+	return loadService(ctx, s.sql, name)
+}
+
+// Services returns all services, ordered by name.
+func (s *Storage) Services(ctx context.Context) ([]Service, error) {
+	result := []Service{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM Service ORDER BY Name"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// RegisterService makes service.Object the service named service.Name, unless another object
+// that still exists already is, in which case it returns ErrServiceTaken.
+func (s *Storage) RegisterService(ctx context.Context, service *Service) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		current, err := loadService(ctx, tx, service.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			service.Id = 0
+			return juicemud.WithStack(tx.Upsert(ctx, service, false))
+		} else if err != nil {
+			return juicemud.WithStack(err)
+		}
+		if current.Object != service.Object {
+			if _, err := s.LoadObject(ctx, current.Object, nil); err == nil {
+				return errors.Wrapf(ErrServiceTaken, "%q is %q", current.Object, service.Name)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return juicemud.WithStack(err)
+			}
+		}
+		service.Id = current.Id
+		return juicemud.WithStack(tx.Upsert(ctx, service, true))
+	}))
+}
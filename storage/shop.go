@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage/dbm"
+	"github.com/zond/juicemud/structs"
+)
+
+var (
+	ErrSoldOut = fmt.Errorf("sold out")
+)
+
+// restock changes the quantity of the item with source in the stock of shop by delta, unless
+// it's unlimited.
+func restock(shop *structs.Object, source string, delta int64) error {
+	index := slices.IndexFunc(shop.Stock, func(item structs.StockItem) bool {
+		return item.Source == source
+	})
+	if index == -1 {
+		return errors.Errorf("%q doesn't stock %q", shop.Id, source)
+	}
+	if shop.Stock[index].Quantity < 0 {
+		return nil
+	}
+	if shop.Stock[index].Quantity+delta < 0 {
+		return errors.Wrapf(ErrSoldOut, "%q is out of %q", shop.Id, source)
+	}
+	shop.Stock[index].Quantity += delta
+	return nil
+}
+
+// Buy atomically makes customer pay price to shop for one of the stock item with the source of
+// item, and stores item, which must be new, in customer. Changes nothing if the shop is sold out
+// or the customer lacks the money.
+func (s *Storage) Buy(ctx context.Context, shop string, customer string, price int64, item *structs.Object) error {
+	if shop == customer {
+		return errors.Errorf("%q can't buy from itself", customer)
+	}
+	if price < 0 {
+		return errors.Errorf("%q can't sell for negative amounts", shop)
+	}
+	item.Location = customer
+	item.Modified = time.Now().UnixNano()
+	if err := s.objects.Proc([]dbm.Proc{
+		s.objects.SProc(shop, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", shop)
+			}
+			if err := restock(value, item.SourcePath, -1); err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			value.Money += price
+			return value, nil
+		}),
+		s.objects.SProc(customer, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", customer)
+			}
+			if value.Money < price {
+				return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", customer, value.Money)
+			}
+			value.Money -= price
+			if value.Content == nil {
+				value.Content = map[string]bool{}
+			}
+			value.Content[item.Id] = true
+			return value, nil
+		}),
+		s.objects.SProc(item.Id, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value != nil {
+				return nil, errors.Errorf("%q already exists", item.Id)
+			}
+			return item, nil
+		}),
+	}, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(s.updateObjectIndex(ctx, nil, item))
+}
+
+// Sell atomically makes shop pay price to seller for the item with the given ID, running source,
+// which is removed and restocked, and calls unequip with seller. Changes nothing if the seller
+// doesn't carry the item, the item isn't empty, or the shop lacks the money.
+func (s *Storage) Sell(ctx context.Context, shop string, seller string, item string, source string, price int64, unequip func(seller *structs.Object)) error {
+	if shop == seller {
+		return errors.Errorf("%q can't sell to itself", seller)
+	}
+	if price < 0 {
+		return errors.Errorf("%q can't buy for negative amounts", shop)
+	}
+	if err := s.objects.Proc([]dbm.Proc{
+		s.objects.SProc(shop, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", shop)
+			}
+			if value.Money < price {
+				return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", shop, value.Money)
+			}
+			if err := restock(value, source, 1); err != nil {
+				return nil, juicemud.WithStack(err)
+			}
+			value.Money -= price
+			return value, nil
+		}),
+		s.objects.SProc(seller, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", seller)
+			}
+			if !value.Content[item] {
+				return nil, errors.Errorf("%q doesn't carry %q", seller, item)
+			}
+			delete(value.Content, item)
+			value.Money += price
+			unequip(value)
+			return value, nil
+		}),
+		s.objects.SProc(item, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", item)
+			}
+			if value.Location != seller {
+				return nil, errors.Errorf("%q isn't carried by %q", item, seller)
+			}
+			if len(value.Content) > 0 {
+				return nil, errors.Errorf("can't sell %q, it contains %v objects", item, len(value.Content))
+			}
+			if value.SourcePath != source {
+				return nil, errors.Errorf("%q runs %q, not %q", item, value.SourcePath, source)
+			}
+			return nil, nil
+		}),
+	}, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(s.removeObjectIndex(ctx, item))
+}
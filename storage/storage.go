@@ -21,6 +21,11 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+const (
+	// DefaultObjectCacheSize is the default number of objects kept in memory between loads.
+	DefaultObjectCacheSize = 100000
+)
+
 func New(ctx context.Context, dir string) (*Storage, error) {
 	sql, err := sqly.Open("sqlite", filepath.Join(dir, "sqlite.db"))
 	if err != nil {
@@ -34,6 +39,7 @@ func New(ctx context.Context, dir string) (*Storage, error) {
 	if err != nil {
 		return nil, juicemud.WithStack(err)
 	}
+	objects.Hash = objects.Hash.WithCache(dbm.NewCache(DefaultObjectCacheSize))
 	queueTree, err := dbm.OpenTree(filepath.Join(dir, "queue"))
 	if err != nil {
 		return nil, juicemud.WithStack(err)
@@ -43,13 +49,14 @@ func New(ctx context.Context, dir string) (*Storage, error) {
 		return nil, juicemud.WithStack(err)
 	}
 	s := &Storage{
-		sql:      sql,
-		sources:  sources,
-		modTimes: modTimes,
-		objects:  objects,
-		queue:    queue.New(ctx, queueTree),
-	}
-	for _, prototype := range []any{File{}, FileSync{}, Group{}, User{}, GroupMember{}} {
+		sql:       sql,
+		sources:   sources,
+		modTimes:  modTimes,
+		objects:   objects,
+		queueTree: queueTree,
+		queue:     queue.New(ctx, queueTree),
+	}
+	for _, prototype := range []any{File{}, FileSync{}, Group{}, User{}, GroupMember{}, ObjectIndex{}, DeadLetter{}, GlobalValue{}, APIToken{}, Recording{}, RecordingFrame{}, WorldEvent{}, Achievement{}, AchievementUnlock{}, Score{}, Instance{}, TrashedObject{}, DisabledSource{}, ConversationMemory{}, Service{}, News{}, UserLogin{}, IPBan{}, AnalyticsCounter{}} {
 		if err := sql.CreateTableIfNotExists(ctx, prototype); err != nil {
 			return nil, err
 		}
@@ -59,6 +66,7 @@ func New(ctx context.Context, dir string) (*Storage, error) {
 
 type Storage struct {
 	queue           *queue.Queue
+	queueTree       dbm.Tree
 	sql             *sqly.DB
 	sources         dbm.Hash
 	modTimes        dbm.Hash
@@ -66,6 +74,11 @@ type Storage struct {
 	movementHandler MovementHandler
 }
 
+// ObjectCache returns the cache of recently used objects.
+func (s *Storage) ObjectCache() *dbm.Cache {
+	return s.objects.Cache()
+}
+
 func (s *Storage) Queue() *queue.Queue {
 	return s.queue
 }
@@ -74,8 +87,13 @@ type EventHandler func(context.Context, *structs.Event)
 
 type MovementHandler func(context.Context, *Movement) error
 
-func (s *Storage) StartQueue(ctx context.Context, eventHandler EventHandler, movementHandler MovementHandler) error {
+// SetMovementHandler sets the handler notified of objects stored in new locations.
+// It must be set before any object is stored.
+func (s *Storage) SetMovementHandler(movementHandler MovementHandler) {
 	s.movementHandler = movementHandler
+}
+
+func (s *Storage) StartQueue(ctx context.Context, eventHandler EventHandler) error {
 	return juicemud.WithStack(s.queue.Start(ctx, eventHandler))
 }
 
@@ -123,6 +141,7 @@ func (s *Storage) StoreSource(ctx context.Context, path string, content []byte)
 	return s.sync(ctx)
 }
 
+// Refresh reruns and stores an object loaded after its source changed.
 type Refresh func(ctx context.Context, object *structs.Object) error
 
 func (s *Storage) maybeRefresh(ctx context.Context, obj *structs.Object, ref Refresh) error {
@@ -132,14 +151,7 @@ func (s *Storage) maybeRefresh(ctx context.Context, obj *structs.Object, ref Ref
 			return juicemud.WithStack(err)
 		}
 		if t > obj.SourceModTime {
-			oldLoc := obj.Location
-			if err := ref(ctx, obj); err != nil {
-				return juicemud.WithStack(err)
-			}
-			obj.SourceModTime = t
-			if err := s.StoreObject(ctx, &oldLoc, obj); err != nil {
-				return juicemud.WithStack(err)
-			}
+			return juicemud.WithStack(ref(ctx, obj))
 		}
 	}
 	return nil
@@ -196,19 +208,26 @@ type Movement struct {
 	Destination string
 }
 
+// StoreObject stores object, moving it from claimedOldLocation if given and different from its
+// Location. Content is maintained by the objects moving in and out, so objects that already exist
+// keep their stored Content, in case it changed while the object was loaded.
 func (s *Storage) StoreObject(ctx context.Context, claimedOldLocation *string, object *structs.Object) error {
+	object.Modified = time.Now().UnixNano()
 	var m *Movement
 	var pairs []dbm.Proc
+	var previous *structs.Object
 	if claimedOldLocation == nil || *claimedOldLocation == object.Location {
 		if object.Location == "" {
 			pairs = []dbm.Proc{
 				s.objects.SProc(object.Id, func(key string, value *structs.Object) (*structs.Object, error) {
+					previous = value
 					if value == nil {
 						return object, nil
 					}
 					if value.Location != object.Location {
 						return nil, errors.Errorf("object is moved from %q to %q without updating old location", value.Location, object.Location)
 					}
+					object.Content = value.Content
 					return object, nil
 				}),
 			}
@@ -222,12 +241,14 @@ func (s *Storage) StoreObject(ctx context.Context, claimedOldLocation *string, o
 					return value, nil
 				}),
 				s.objects.SProc(object.Id, func(key string, value *structs.Object) (*structs.Object, error) {
+					previous = value
 					if value == nil {
 						return object, nil
 					}
 					if value.Location != object.Location {
 						return nil, errors.Errorf("object is moved from %q to %q without updating old location", value.Location, object.Location)
 					}
+					object.Content = value.Content
 					return object, nil
 				}),
 			}
@@ -242,12 +263,14 @@ func (s *Storage) StoreObject(ctx context.Context, claimedOldLocation *string, o
 		// be removed from old loc, and added to new loc, before all are saved.
 		pairs = []dbm.Proc{
 			s.objects.SProc(object.Id, func(key string, value *structs.Object) (*structs.Object, error) {
+				previous = value
 				if value == nil {
 					return nil, errors.Errorf("can't find old version of %q", object.Id)
 				}
 				if value.Location != *claimedOldLocation {
 					return nil, errors.Errorf("object in %q claims to move from %q to %q", value.Location, *claimedOldLocation, object.Location)
 				}
+				object.Content = value.Content
 				return object, nil
 			}),
 			s.objects.SProc(object.Location, func(key string, value *structs.Object) (*structs.Object, error) {
@@ -272,6 +295,9 @@ func (s *Storage) StoreObject(ctx context.Context, claimedOldLocation *string, o
 	if err := s.objects.Proc(pairs, true); err != nil {
 		return juicemud.WithStack(err)
 	}
+	if err := s.updateObjectIndex(ctx, previous, object); err != nil {
+		return juicemud.WithStack(err)
+	}
 	if m != nil {
 		if err := s.movementHandler(ctx, m); err != nil {
 			return juicemud.WithStack(err)
@@ -280,6 +306,42 @@ func (s *Storage) StoreObject(ctx context.Context, claimedOldLocation *string, o
 	return nil
 }
 
+// DeleteObject removes the object with the given ID, which must be empty, from its location
+// and the database.
+func (s *Storage) DeleteObject(ctx context.Context, id string) error {
+	object, err := s.LoadObject(ctx, id, nil)
+	if err != nil {
+		return juicemud.WithStack(err)
+	}
+	pairs := []dbm.Proc{
+		s.objects.SProc(id, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", id)
+			}
+			if len(value.Content) > 0 {
+				return nil, errors.Errorf("can't delete %q, it contains %v objects", id, len(value.Content))
+			}
+			if value.Location != object.Location {
+				return nil, errors.Errorf("%q was moved from %q to %q while deleting it", id, object.Location, value.Location)
+			}
+			return nil, nil
+		}),
+	}
+	if object.Location != "" {
+		pairs = append(pairs, s.objects.SProc(object.Location, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find location %q", object.Location)
+			}
+			delete(value.Content, id)
+			return value, nil
+		}))
+	}
+	if err := s.objects.Proc(pairs, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	return juicemud.WithStack(s.removeObjectIndex(ctx, id))
+}
+
 type FileSync struct {
 	Id      int64 `sqly:"pkey,autoinc"`
 	Remove  string
@@ -687,6 +749,21 @@ type User struct {
 	PasswordHash string
 	Owner        bool
 	Object       string
+	// Charset is the character set the terminal of the user can show, or empty to
+	// guess it from the locale of each session.
+	Charset string
+	// Record is whether the sessions of the user are recorded.
+	Record bool
+	// Language is the language the user prefers descriptions in, or empty to use the locale
+	// of each session.
+	Language string
+	// LastLogin is when the user last logged in, in Unix nanoseconds.
+	LastLogin int64
+	// LastLoginIP is the IP the user last logged in from.
+	LastLoginIP string
+	// LoginPolicy is what happens when the user logs in while already connected, or empty
+	// to use the policy of the server.
+	LoginPolicy string
 }
 
 type contextKey int
@@ -727,6 +804,24 @@ func (s *Storage) LoadUser(ctx context.Context, name string) (*User, error) {
 	return user, nil
 }
 
+// LoadUserByObject returns the user of the object with the given ID, or os.ErrNotExist if
+// the object isn't a user.
+func (s *Storage) LoadUserByObject(ctx context.Context, object string) (*User, error) {
+	user := &User{}
+	if err := getSQL(ctx, s.sql, user, "SELECT * FROM User WHERE Object = ?", object); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return user, nil
+}
+
+func (s *Storage) Users(ctx context.Context) ([]User, error) {
+	result := []User{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM User ORDER BY Name"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
 func (s *Storage) UserGroups(ctx context.Context, user *User) (Groups, error) {
 	members := []GroupMember{}
 	if err := s.sql.SelectContext(ctx, &members, "SELECT * FROM GroupMember WHERE User = ?", user.Id); err != nil {
@@ -751,6 +846,31 @@ func (s *Storage) StoreUser(ctx context.Context, user *User, overwrite bool) err
 	return s.sql.Upsert(ctx, user, overwrite)
 }
 
+// DeleteUser deletes user with its logins, recordings, API tokens, group memberships, and the
+// achievement unlocks of its object.
+func (s *Storage) DeleteUser(ctx context.Context, user *User) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		for _, stmt := range []string{
+			"DELETE FROM RecordingFrame WHERE Recording IN (SELECT Id FROM Recording WHERE User = ?)",
+			"DELETE FROM Recording WHERE User = ?",
+			"DELETE FROM UserLogin WHERE User = ?",
+			"DELETE FROM APIToken WHERE User = ?",
+			"DELETE FROM GroupMember WHERE User = ?",
+			"DELETE FROM User WHERE Id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, user.Id); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		if user.Object != "" {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM AchievementUnlock WHERE Object = ?", user.Object); err != nil {
+				return juicemud.WithStack(err)
+			}
+		}
+		return nil
+	}))
+}
+
 func (s *Storage) UserAccessToGroup(ctx context.Context, user *User, groupName string) (bool, error) {
 	if user.Owner {
 		return true, nil
@@ -1,11 +1,19 @@
 package storage
 
 import (
+	"context"
 	"log"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v4"
 	"github.com/bxcodec/faker/v4/pkg/options"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
 	"github.com/zond/juicemud/structs"
 	"rogchap.com/v8go"
 
@@ -23,6 +31,144 @@ func init() {
 	}
 }
 
+func withStorage(t testing.TB, f func(*Storage)) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := New(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f(s)
+}
+
+func TestRepairOrphan(t *testing.T) {
+	withStorage(t, func(s *Storage) {
+		if err := s.objects.Set("orphan", &structs.Object{Id: "orphan", Location: "missing"}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.objects.Set("room", &structs.Object{Id: "room"}, true); err != nil {
+			t.Fatal(err)
+		}
+		orphans, err := s.FindOrphans(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []Orphan{{Object: "orphan", Location: "missing"}}; !reflect.DeepEqual(orphans, want) {
+			t.Errorf("got %+v, want %+v", orphans, want)
+		}
+		if err := s.RepairOrphan(context.Background(), "orphan", "room"); err != nil {
+			t.Fatal(err)
+		}
+		orphan, err := s.objects.Get("orphan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		room, err := s.objects.Get("room")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orphan.Location != "room" || !room.Content["orphan"] {
+			t.Errorf("got location %q and content %+v, want orphan in room", orphan.Location, room.Content)
+		}
+		if err := s.RepairOrphan(context.Background(), "orphan", "room"); err == nil {
+			t.Errorf("repaired an object that isn't orphaned")
+		}
+	})
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	withStorage(t, func(s *Storage) {
+		for _, name := range []string{"first", "second"} {
+			ev := &structs.Event{Object: "target", Priority: 2, Call: structs.Call{Name: name, Message: "{}", Tag: "emit"}}
+			if err := s.StoreDeadLetter(ctx, ev, 1, errors.New("failed")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		letters, err := s.DeadLetters(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(letters) != 2 || letters[0].Name != "second" || letters[1].Name != "first" {
+			t.Fatalf("got %+v, want the second and the first letter", letters)
+		}
+		if letters[1].Object != "target" || letters[1].Priority != 2 || letters[1].Attempts != 1 || letters[1].Error != "failed" {
+			t.Errorf("got %+v, want the event, attempts and error stored", letters[1])
+		}
+		if count, err := s.RedeliverDeadLetters(ctx, []int64{letters[1].Id}); err != nil || count != 1 {
+			t.Fatalf("got %v, %v, want 1 redelivered letter", count, err)
+		}
+		if count, err := s.CountDeadLetters(ctx); err != nil || count != 1 {
+			t.Errorf("got %v, %v, want 1 remaining letter", count, err)
+		}
+		delivered := make(chan *structs.Event, 1)
+		go func() {
+			s.StartQueue(ctx, func(_ context.Context, ev *structs.Event) {
+				delivered <- ev
+			})
+		}()
+		select {
+		case ev := <-delivered:
+			if ev.Object != "target" || ev.Priority != 2 || ev.Call.Name != "first" || ev.Call.Tag != "emit" {
+				t.Errorf("got %+v, want the first letter", ev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the redelivered letter")
+		}
+		s.Queue().Close()
+	})
+}
+
+func TestCASGlobal(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		missing := ""
+		if swapped, err := s.CASGlobal(ctx, "/quests", "dragon", &missing, `"slain"`); err != nil || !swapped {
+			t.Fatalf("got %v, %v, want a swap of the missing value", swapped, err)
+		}
+		if swapped, err := s.CASGlobal(ctx, "/quests", "dragon", &missing, `"alive"`); err != nil || swapped {
+			t.Fatalf("got %v, %v, want no swap of the existing value", swapped, err)
+		}
+		slain := `"slain"`
+		if swapped, err := s.CASGlobal(ctx, "/quests", "dragon", &slain, ""); err != nil || !swapped {
+			t.Fatalf("got %v, %v, want a removal of the matching value", swapped, err)
+		}
+		if _, err := s.GetGlobal(ctx, "/quests", "dragon"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v, want %v", err, os.ErrNotExist)
+		}
+	})
+}
+
+func TestGlobalQuota(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		half := `"` + strings.Repeat("x", GlobalNamespaceQuota/2-2) + `"`
+		if err := s.SetGlobal(ctx, "/quests", "a", half); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetGlobal(ctx, "/quests", "b", half); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetGlobal(ctx, "/quests", "c", `1`); !errors.Is(err, ErrGlobalQuotaExceeded) {
+			t.Errorf("got %v, want %v", err, ErrGlobalQuotaExceeded)
+		}
+		if err := s.SetGlobal(ctx, "/other", "c", `1`); err != nil {
+			t.Errorf("got %v, want other namespaces to have their own quota", err)
+		}
+		if err := s.SetGlobal(ctx, "/quests", "a", `1`); err != nil {
+			t.Errorf("got %v, want replacements to not count the replaced value", err)
+		}
+		if err := s.SetGlobal(ctx, "/quests", "c", `1`); err != nil {
+			t.Errorf("got %v, want room after shrinking a value", err)
+		}
+	})
+}
+
 func BenchmarkV8JSON(b *testing.B) {
 	b.StopTimer()
 	iso := v8go.NewIsolate()
@@ -70,3 +216,353 @@ func BenchmarkGoccy(b *testing.B) {
 		}
 	}
 }
+
+func TestKeywords(t *testing.T) {
+	got := Keywords("The Sword of a thousand-year King, (rusty)")
+	want := []string{"sword", "thousand", "year", "king", "rusty"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestScorePeriod(t *testing.T) {
+	// A Thursday.
+	at := time.Date(2024, 10, 17, 15, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		window string
+		want   time.Time
+	}{
+		{DailyScores, time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{WeeklyScores, time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{AllTimeScores, time.Unix(0, 0)},
+	} {
+		got, err := ScorePeriod(tc.window, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want.UnixNano() {
+			t.Errorf("%s: got %v, want %v", tc.window, time.Unix(0, got).UTC(), tc.want)
+		}
+	}
+	if _, err := ScorePeriod("hourly", at); err == nil {
+		t.Errorf("wanted an error for an unknown window")
+	}
+}
+
+func TestObjectFilterMatches(t *testing.T) {
+	object := &structs.Object{
+		Id:         "a",
+		SourcePath: "/orb.js",
+		Location:   "room",
+		Tags:       []string{"magic", "red"},
+		Modified:   100,
+	}
+	for _, tc := range []struct {
+		filter ObjectFilter
+		want   bool
+	}{
+		{ObjectFilter{}, true},
+		{ObjectFilter{SourcePath: "/orb.js", Location: "room"}, true},
+		{ObjectFilter{SourcePath: "/sword.js"}, false},
+		{ObjectFilter{Location: "hall"}, false},
+		{ObjectFilter{Tags: []string{"red", "magic"}}, true},
+		{ObjectFilter{Tags: []string{"red", "blue"}}, false},
+		{ObjectFilter{ModifiedAfter: 50, ModifiedBefore: 150}, true},
+		{ObjectFilter{ModifiedAfter: 100}, false},
+		{ObjectFilter{ModifiedBefore: 100}, false},
+	} {
+		if got := tc.filter.Matches(object); got != tc.want {
+			t.Errorf("%+v: got %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestBuySell(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		for _, object := range []*structs.Object{
+			{Id: "room", Content: map[string]bool{"shop": true, "customer": true}},
+			{Id: "shop", Location: "room", Stock: []structs.StockItem{{Name: "sword", Source: "/sword.js", Price: 10, Quantity: 1}}},
+			{Id: "customer", Location: "room", Money: 5},
+		} {
+			if err := s.objects.Set(object.Id, object, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check := func(wantShopMoney int64, wantQuantity int64, wantCustomerMoney int64, wantSword bool) {
+			t.Helper()
+			objects, err := s.objects.GetMulti(map[string]bool{"shop": true, "customer": true, "sword": true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			shop, customer := objects["shop"], objects["customer"]
+			if shop.Money != wantShopMoney || shop.Stock[0].Quantity != wantQuantity {
+				t.Errorf("got shop money %v and quantity %v, want %v and %v", shop.Money, shop.Stock[0].Quantity, wantShopMoney, wantQuantity)
+			}
+			if customer.Money != wantCustomerMoney || customer.Content["sword"] != wantSword {
+				t.Errorf("got customer money %v and content %+v, want %v and sword %v", customer.Money, customer.Content, wantCustomerMoney, wantSword)
+			}
+			if sword, found := objects["sword"]; found != wantSword || (found && sword.Location != "customer") {
+				t.Errorf("got sword %+v, want it to exist %v in customer", sword, wantSword)
+			}
+		}
+		sword := func() *structs.Object {
+			return &structs.Object{Id: "sword", SourcePath: "/sword.js"}
+		}
+		if err := s.Buy(ctx, "shop", "customer", 10, sword()); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("got %v, want %v", err, ErrInsufficientFunds)
+		}
+		check(0, 1, 5, false)
+		if err := s.modifyObject("customer", func(customer *structs.Object) { customer.Money = 15 }); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Buy(ctx, "shop", "customer", 10, sword()); err != nil {
+			t.Fatal(err)
+		}
+		check(10, 0, 5, true)
+		if err := s.Buy(ctx, "shop", "customer", 10, &structs.Object{Id: "another", SourcePath: "/sword.js"}); !errors.Is(err, ErrSoldOut) {
+			t.Errorf("got %v, want %v", err, ErrSoldOut)
+		}
+		check(10, 0, 5, true)
+
+		unequipped := false
+		unequip := func(*structs.Object) { unequipped = true }
+		if err := s.Sell(ctx, "shop", "customer", "sword", "/sword.js", 20, unequip); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("got %v, want %v", err, ErrInsufficientFunds)
+		}
+		check(10, 0, 5, true)
+		if err := s.Sell(ctx, "shop", "customer", "sword", "/other.js", 5, unequip); err == nil {
+			t.Errorf("sold the sword as another item")
+		}
+		check(10, 0, 5, true)
+		unequipped = false
+		if err := s.Sell(ctx, "shop", "customer", "sword", "/sword.js", 5, unequip); err != nil {
+			t.Fatal(err)
+		}
+		check(5, 1, 10, false)
+		if !unequipped {
+			t.Errorf("the sword wasn't unequipped from the customer")
+		}
+	})
+}
+
+func TestTrashObject(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		set := func(objects ...*structs.Object) {
+			t.Helper()
+			for _, object := range objects {
+				if err := s.objects.Set(object.Id, object, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		exists := func(id string) bool {
+			t.Helper()
+			if _, err := s.objects.Get(id); errors.Is(err, os.ErrNotExist) {
+				return false
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			return true
+		}
+		trashed := func() []string {
+			t.Helper()
+			entries := []TrashedObject{}
+			if err := sqlx.SelectContext(ctx, s.sql, &entries, "SELECT * FROM TrashedObject ORDER BY Depth"); err != nil {
+				t.Fatal(err)
+			}
+			result := []string{}
+			for _, entry := range entries {
+				result = append(result, entry.Object)
+			}
+			return result
+		}
+
+		// Objects that can't be put in the trash aren't removed.
+		set(
+			&structs.Object{Id: "room", Content: map[string]bool{"chest": true}},
+			&structs.Object{Id: "chest", Location: "room", Content: map[string]bool{}},
+		)
+		if _, err := s.sql.ExecContext(ctx, "DROP TABLE TrashedObject"); err != nil {
+			t.Fatal(err)
+		}
+		if count, err := s.TrashObject(ctx, "chest"); err == nil || count != 0 {
+			t.Errorf("got %v, %v, want nothing trashed and an error", count, err)
+		}
+		if !exists("chest") {
+			t.Errorf("got the chest removed, want it kept")
+		}
+		if err := s.sql.CreateTableIfNotExists(ctx, TrashedObject{}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The chest can't be removed from a location that doesn't exist.
+		set(
+			&structs.Object{Id: "room", Content: map[string]bool{}},
+			&structs.Object{Id: "chest", Location: "gone", Content: map[string]bool{"coin": true}},
+			&structs.Object{Id: "coin", Location: "chest", Content: map[string]bool{}},
+		)
+		if count, err := s.TrashObject(ctx, "chest"); err == nil || count != 1 {
+			t.Errorf("got %v, %v, want the coin trashed and then an error", count, err)
+		}
+		if !exists("chest") || exists("coin") {
+			t.Errorf("got chest %v and coin %v, want only the chest left", exists("chest"), exists("coin"))
+		}
+		if got := trashed(); !slices.Equal(got, []string{"coin"}) {
+			t.Errorf("got %+v in the trash, want only the coin", got)
+		}
+
+		// Rerunning it trashes the rest, and restoring it restores both.
+		set(
+			&structs.Object{Id: "room", Content: map[string]bool{"chest": true}},
+			&structs.Object{Id: "chest", Location: "room", Content: map[string]bool{}},
+		)
+		if count, err := s.TrashObject(ctx, "chest"); err != nil || count != 1 {
+			t.Errorf("got %v, %v, want the chest trashed", count, err)
+		}
+		if got := trashed(); !slices.Equal(got, []string{"chest", "coin"}) {
+			t.Errorf("got %+v in the trash, want the chest and the coin", got)
+		}
+		if count, err := s.RestoreObject(ctx, "chest"); err != nil || count != 2 {
+			t.Errorf("got %v, %v, want the chest and the coin restored", count, err)
+		}
+		chest, err := s.objects.Get("chest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chest.Location != "room" || !chest.Content["coin"] {
+			t.Errorf("got chest %+v, want it in the room with the coin", chest)
+		}
+		if got := trashed(); len(got) != 0 {
+			t.Errorf("got %+v in the trash, want it empty", got)
+		}
+
+		// Restoring objects a failed restore already restored restores the rest.
+		if count, err := s.TrashObject(ctx, "chest"); err != nil || count != 2 {
+			t.Errorf("got %v, %v, want the chest and the coin trashed", count, err)
+		}
+		set(
+			&structs.Object{Id: "room", Content: map[string]bool{"chest": true}},
+			&structs.Object{Id: "chest", Location: "room", Content: map[string]bool{}},
+		)
+		if count, err := s.RestoreObject(ctx, "chest"); err != nil || count != 2 {
+			t.Errorf("got %v, %v, want the chest and the coin restored", count, err)
+		}
+		if !exists("coin") || len(trashed()) != 0 {
+			t.Errorf("got coin %v and %+v in the trash, want the coin restored and the trash empty", exists("coin"), trashed())
+		}
+	})
+}
+
+func TestStoreAchievement(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		name := func() string {
+			t.Helper()
+			achievement, err := s.LoadAchievement(ctx, "dragon")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return achievement.Name
+		}
+		if err := s.StoreAchievement(ctx, &Achievement{Key: "dragon", Name: "Dragon slayer", Object: "judge"}, "judge"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.StoreAchievement(ctx, &Achievement{Key: "dragon", Name: "Dragon friend", Object: "impostor"}, "impostor"); !errors.Is(err, ErrAchievementTaken) {
+			t.Errorf("got %v, want %v", err, ErrAchievementTaken)
+		}
+		if got := name(); got != "Dragon slayer" {
+			t.Errorf("got %q, want the achievement unchanged", got)
+		}
+		if err := s.StoreAchievement(ctx, &Achievement{Key: "dragon", Name: "Dragon tamer", Object: "judge"}, "judge"); err != nil {
+			t.Errorf("got %v, want the judge to redefine it", err)
+		}
+		if got := name(); got != "Dragon tamer" {
+			t.Errorf("got %q, want the achievement redefined by the judge", got)
+		}
+		if err := s.StoreAchievement(ctx, &Achievement{Key: "dragon", Name: "Dragon rider"}, ""); err != nil {
+			t.Errorf("got %v, want the boot script to redefine it", err)
+		}
+		if got := name(); got != "Dragon rider" {
+			t.Errorf("got %q, want the achievement redefined by the boot script", got)
+		}
+	})
+}
+
+func TestBank(t *testing.T) {
+	ctx := context.Background()
+	withStorage(t, func(s *Storage) {
+		s.SetMovementHandler(func(context.Context, *Movement) error { return nil })
+		for _, object := range []*structs.Object{
+			{Id: "room", Content: map[string]bool{"bank": true, "customer": true}},
+			{Id: "bank", Location: "room"},
+			{Id: "customer", Location: "room", Money: 10, Content: map[string]bool{"sword": true}, Equipment: map[string]string{"hand": "sword"}},
+			{Id: "sword", Location: "customer"},
+			{Id: "vault", Content: map[string]bool{}},
+		} {
+			if err := s.objects.Set(object.Id, object, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check := func(wantBank int64, wantCustomer int64, wantVault int64, wantSwordIn string) {
+			t.Helper()
+			objects, err := s.objects.GetMulti(map[string]bool{"bank": true, "customer": true, "vault": true, "sword": true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [3]int64{objects["bank"].Money, objects["customer"].Money, objects["vault"].Money}; got != [3]int64{wantBank, wantCustomer, wantVault} {
+				t.Errorf("got bank, customer and vault money %v, want %v", got, [3]int64{wantBank, wantCustomer, wantVault})
+			}
+			if sword := objects["sword"]; sword.Location != wantSwordIn || !objects[wantSwordIn].Content["sword"] {
+				t.Errorf("got sword in %q, want it in %q", sword.Location, wantSwordIn)
+			}
+		}
+		transfer := func(withdraw bool, item string, money int64, fee int64, capacity int64) error {
+			return s.Bank(ctx, &BankTransfer{
+				Bank:     "bank",
+				Customer: "customer",
+				Vault:    "vault",
+				Withdraw: withdraw,
+				Item:     item,
+				Money:    money,
+				Fee:      fee,
+				Capacity: capacity,
+			}, func(customer *structs.Object) {
+				customer.Equipment = nil
+			})
+		}
+
+		if err := transfer(false, "", 6, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		check(1, 3, 6, "customer")
+		if err := transfer(true, "", 7, 1, 1); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("got %v, want %v", err, ErrInsufficientFunds)
+		}
+		check(1, 3, 6, "customer")
+		if err := transfer(false, "sword", 0, 4, 1); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("got %v, want %v", err, ErrInsufficientFunds)
+		}
+		check(1, 3, 6, "customer")
+		if err := transfer(false, "sword", 0, 1, 0); !errors.Is(err, ErrVaultFull) {
+			t.Errorf("got %v, want %v", err, ErrVaultFull)
+		}
+		check(1, 3, 6, "customer")
+		if err := transfer(false, "sword", 0, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		check(2, 2, 6, "vault")
+		customer, err := s.objects.Get("customer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(customer.Equipment) != 0 {
+			t.Errorf("got equipment %+v, want the deposited sword unequipped", customer.Equipment)
+		}
+		if err := transfer(true, "sword", 6, 2, 1); err != nil {
+			t.Fatal(err)
+		}
+		check(4, 6, 0, "customer")
+	})
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/storage/dbm"
+	"github.com/zond/juicemud/structs"
+)
+
+var (
+	ErrStateConflict     = fmt.Errorf("state changed during transaction")
+	ErrInsufficientFunds = fmt.Errorf("insufficient funds")
+)
+
+// StateUpdate replaces the state of Object with State, if the current state is Expected
+// or Expected is nil.
+type StateUpdate struct {
+	Object   string
+	Expected *string
+	State    string
+}
+
+// UpdateStates atomically applies all updates, or none of them if any object is missing
+// or any current state isn't the expected one, in which case ErrStateConflict is returned.
+func (s *Storage) UpdateStates(ctx context.Context, updates []StateUpdate) error {
+	seen := map[string]bool{}
+	pairs := []dbm.Proc{}
+	for _, update := range updates {
+		if seen[update.Object] {
+			return errors.Errorf("%q updated more than once", update.Object)
+		}
+		seen[update.Object] = true
+		pairs = append(pairs, s.objects.SProc(update.Object, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", update.Object)
+			}
+			if update.Expected != nil && value.State != *update.Expected {
+				return nil, errors.Wrapf(ErrStateConflict, "%q", update.Object)
+			}
+			value.State = update.State
+			return value, nil
+		}))
+	}
+	return juicemud.WithStack(s.objects.Proc(pairs, true))
+}
+
+// TransferMoney atomically moves amount money from the object with ID from to the object with
+// ID to, or returns ErrInsufficientFunds if from has less than amount.
+func (s *Storage) TransferMoney(ctx context.Context, from string, to string, amount int64) error {
+	if amount < 0 {
+		return errors.Errorf("can't transfer negative amounts")
+	}
+	if from == to {
+		return errors.Errorf("can't transfer from %q to itself", from)
+	}
+	return juicemud.WithStack(s.objects.Proc([]dbm.Proc{
+		s.objects.SProc(from, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", from)
+			}
+			if value.Money < amount {
+				return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", from, value.Money)
+			}
+			value.Money -= amount
+			return value, nil
+		}),
+		s.objects.SProc(to, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", to)
+			}
+			value.Money += amount
+			return value, nil
+		}),
+	}, true))
+}
+
+// TradeOffer is what Party gives the other party of a trade.
+type TradeOffer struct {
+	Party string
+	Items []string
+	Money int64
+}
+
+// Trade atomically makes the parties of a and b give their items and money to each other, or
+// changes nothing if a party doesn't carry its items or lacks the money.
+func (s *Storage) Trade(ctx context.Context, a TradeOffer, b TradeOffer) error {
+	if a.Party == b.Party {
+		return errors.Errorf("%q can't trade with itself", a.Party)
+	}
+	movements := map[string]*Movement{}
+	pairs := []dbm.Proc{}
+	for _, parties := range [][2]TradeOffer{{a, b}, {b, a}} {
+		giver, taker := parties[0], parties[1]
+		if giver.Money < 0 {
+			return errors.Errorf("%q can't give negative amounts", giver.Party)
+		}
+		pairs = append(pairs, s.objects.SProc(giver.Party, func(key string, value *structs.Object) (*structs.Object, error) {
+			if value == nil {
+				return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", giver.Party)
+			}
+			if value.Money < giver.Money {
+				return nil, errors.Wrapf(ErrInsufficientFunds, "%q has %v", giver.Party, value.Money)
+			}
+			if value.Content == nil {
+				value.Content = map[string]bool{}
+			}
+			for _, item := range giver.Items {
+				if !value.Content[item] {
+					return nil, errors.Errorf("%q doesn't carry %q", giver.Party, item)
+				}
+				delete(value.Content, item)
+			}
+			for _, item := range taker.Items {
+				value.Content[item] = true
+			}
+			value.Money += taker.Money - giver.Money
+			return value, nil
+		}))
+		for _, item := range giver.Items {
+			pairs = append(pairs, s.objects.SProc(item, func(key string, value *structs.Object) (*structs.Object, error) {
+				if value == nil {
+					return nil, errors.Wrapf(os.ErrNotExist, "can't find %q", item)
+				}
+				if value.Location != giver.Party {
+					return nil, errors.Errorf("%q isn't carried by %q", item, giver.Party)
+				}
+				value.Location = taker.Party
+				movements[item] = &Movement{Object: value, Source: giver.Party, Destination: taker.Party}
+				return value, nil
+			}))
+		}
+	}
+	if err := s.objects.Proc(pairs, true); err != nil {
+		return juicemud.WithStack(err)
+	}
+	for _, movement := range movements {
+		if err := s.movementHandler(ctx, movement); err != nil {
+			return juicemud.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/zond/juicemud"
+	"github.com/zond/juicemud/structs"
+	"github.com/zond/sqly"
+)
+
+const (
+	// DefaultTrashRetention is how long removed objects can be restored by default.
+	DefaultTrashRetention = 7 * 24 * time.Hour
+)
+
+// TrashedObject is a removed object, kept so that it can be restored until it expires. Objects
+// are trashed along with their content, which share the Root of the removed object.
+type TrashedObject struct {
+	Id     int64  `sqly:"pkey,autoinc"`
+	Object string `sqly:"index"`
+	Root   string `sqly:"index"`
+	// Depth is how deep in the content of Root the object was.
+	Depth     int64
+	Location  string
+	Short     string
+	TrashedAt int64
+	Content   []byte
+}
+
+// ObjectTree returns the object with the given ID followed by its content, recursively, in
+// breadth first order.
+func (s *Storage) ObjectTree(ctx context.Context, id string) ([]*structs.Object, error) {
+	root, err := s.LoadObject(ctx, id, nil)
+	if err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	result := []*structs.Object{root}
+	for index := 0; index < len(result); index++ {
+		content, err := s.LoadObjects(ctx, result[index].Content, nil)
+		if err != nil {
+			return nil, juicemud.WithStack(err)
+		}
+		for _, object := range content {
+			result = append(result, object)
+		}
+	}
+	return result, nil
+}
+
+// TrashObject removes the object with the given ID and its content, recursively, and keeps
+// them in the trash. Each object is put in the trash before it's removed, so that a failure
+// never loses it, and rerunning it trashes the rest. Returns the number of trashed objects.
+func (s *Storage) TrashObject(ctx context.Context, id string) (int, error) {
+	tree, err := s.ObjectTree(ctx, id)
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	depths := map[string]int64{id: 0}
+	for _, object := range tree[1:] {
+		depths[object.Id] = depths[object.Location] + 1
+	}
+	now := time.Now().UnixNano()
+	for index := len(tree) - 1; index >= 0; index-- {
+		object := tree[index]
+		short := ""
+		if len(object.Descriptions) > 0 {
+			short = object.Descriptions[0].Short
+		}
+		content := make([]byte, object.Size())
+		object.Marshal(content)
+		trashed := &TrashedObject{
+			Object:    object.Id,
+			Root:      id,
+			Depth:     depths[object.Id],
+			Location:  object.Location,
+			Short:     short,
+			TrashedAt: now,
+			Content:   content,
+		}
+		if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+			return juicemud.WithStack(tx.Upsert(ctx, trashed, false))
+		}); err != nil {
+			return len(tree) - 1 - index, juicemud.WithStack(err)
+		}
+		if err := s.DeleteObject(ctx, object.Id); err != nil {
+			// The object is still around, so it mustn't be restored on top of itself.
+			if untrashErr := s.untrash(ctx, trashed.Id); untrashErr != nil {
+				return len(tree) - 1 - index, errors.Wrapf(err, "and then %v", untrashErr)
+			}
+			return len(tree) - 1 - index, juicemud.WithStack(err)
+		}
+	}
+	return len(tree), nil
+}
+
+// untrash removes the trash entry with the given ID.
+func (s *Storage) untrash(ctx context.Context, id int64) error {
+	return juicemud.WithStack(s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM TrashedObject WHERE Id = ?", id)
+		return juicemud.WithStack(err)
+	}))
+}
+
+// TrashedObjects returns the removed objects in the trash, without their content, most
+// recently removed first.
+func (s *Storage) TrashedObjects(ctx context.Context) ([]TrashedObject, error) {
+	result := []TrashedObject{}
+	if err := sqlx.SelectContext(ctx, s.sql, &result, "SELECT * FROM TrashedObject WHERE Depth = 0 ORDER BY TrashedAt DESC, Id DESC"); err != nil {
+		return nil, juicemud.WithStack(err)
+	}
+	return result, nil
+}
+
+// RestoreObject puts the removed object with the given ID, and its content, back where they
+// were, and removes them from the trash. They are removed from the trash after they are all
+// restored, so that a failure never loses them, and rerunning it restores the rest. Returns the
+// number of restored objects.
+func (s *Storage) RestoreObject(ctx context.Context, id string) (int, error) {
+	trashed := []TrashedObject{}
+	if err := sqlx.SelectContext(ctx, s.sql, &trashed, "SELECT * FROM TrashedObject WHERE Root = ? ORDER BY Depth, Id", id); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	if len(trashed) == 0 || trashed[0].Object != id {
+		return 0, errors.Wrapf(os.ErrNotExist, "%q isn't in the trash", id)
+	}
+	if trashed[0].Location != "" {
+		if _, err := s.LoadObject(ctx, trashed[0].Location, nil); err != nil {
+			return 0, errors.Wrapf(err, "can't find %q to restore %q to", trashed[0].Location, id)
+		}
+	}
+	for index, entry := range trashed {
+		object := &structs.Object{}
+		if err := object.Unmarshal(entry.Content); err != nil {
+			return index, juicemud.WithStack(err)
+		}
+		// Objects that exist were restored by a restore that failed, since IDs are unique.
+		if _, err := s.LoadObject(ctx, entry.Object, nil); errors.Is(err, os.ErrNotExist) {
+			// The content adds itself to the object when restored.
+			object.Content = map[string]bool{}
+			if err := s.StoreObject(ctx, nil, object); err != nil {
+				return index, juicemud.WithStack(err)
+			}
+		} else if err != nil {
+			return index, juicemud.WithStack(err)
+		}
+	}
+	// Removing the entries after restoring all objects leaves them in the trash if restoring fails.
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM TrashedObject WHERE Root = ?", id)
+		return juicemud.WithStack(err)
+	}); err != nil {
+		return len(trashed), juicemud.WithStack(err)
+	}
+	return len(trashed), nil
+}
+
+// ExpireTrash permanently removes the objects trashed before the given Unix nanoseconds.
+// Returns the number of removed objects.
+func (s *Storage) ExpireTrash(ctx context.Context, before int64) (int64, error) {
+	var result int64
+	if err := s.sql.Write(ctx, func(tx *sqly.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM TrashedObject WHERE TrashedAt < ?", before)
+		if err != nil {
+			return juicemud.WithStack(err)
+		}
+		result, err = res.RowsAffected()
+		return juicemud.WithStack(err)
+	}); err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	return result, nil
+}
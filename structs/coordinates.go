@@ -0,0 +1,96 @@
+package structs
+
+import (
+	"strings"
+)
+
+// Point is a position in the optional 3D room grid. X grows east, Y grows north, and Z grows up.
+type Point struct {
+	X, Y, Z int64
+}
+
+func (p Point) Add(o Point) Point {
+	return Point{X: p.X + o.X, Y: p.Y + o.Y, Z: p.Z + o.Z}
+}
+
+var (
+	// Directions are the exit names that imply a direction in the room grid.
+	Directions = map[string]Point{
+		"n":         {0, 1, 0},
+		"north":     {0, 1, 0},
+		"s":         {0, -1, 0},
+		"south":     {0, -1, 0},
+		"e":         {1, 0, 0},
+		"east":      {1, 0, 0},
+		"w":         {-1, 0, 0},
+		"west":      {-1, 0, 0},
+		"ne":        {1, 1, 0},
+		"northeast": {1, 1, 0},
+		"nw":        {-1, 1, 0},
+		"northwest": {-1, 1, 0},
+		"se":        {1, -1, 0},
+		"southeast": {1, -1, 0},
+		"sw":        {-1, -1, 0},
+		"southwest": {-1, -1, 0},
+		"u":         {0, 0, 1},
+		"up":        {0, 0, 1},
+		"d":         {0, 0, -1},
+		"down":      {0, 0, -1},
+	}
+	reverseDirections = map[string]string{
+		"n":         "s",
+		"north":     "south",
+		"e":         "w",
+		"east":      "west",
+		"ne":        "sw",
+		"northeast": "southwest",
+		"nw":        "se",
+		"northwest": "southeast",
+		"u":         "d",
+		"up":        "down",
+		"s":         "n",
+		"south":     "north",
+		"w":         "e",
+		"west":      "east",
+		"sw":        "ne",
+		"southwest": "northeast",
+		"se":        "nw",
+		"southeast": "northwest",
+		"d":         "u",
+		"down":      "up",
+	}
+)
+
+// Direction returns the grid offset implied by an exit name, if any.
+func Direction(name string) (Point, bool) {
+	p, found := Directions[strings.ToLower(name)]
+	return p, found
+}
+
+// ReverseDirection returns the exit name leading back from an exit with the given name, if any.
+func ReverseDirection(name string) (string, bool) {
+	reverse, found := reverseDirections[strings.ToLower(name)]
+	return reverse, found
+}
+
+// Point returns the coordinates of the object, if it has any.
+func (o *Object) Point() (Point, bool) {
+	if len(o.Coordinates) != 3 {
+		return Point{}, false
+	}
+	return Point{X: o.Coordinates[0], Y: o.Coordinates[1], Z: o.Coordinates[2]}, true
+}
+
+func (o *Object) SetPoint(p Point) {
+	o.Coordinates = []int64{p.X, p.Y, p.Z}
+}
+
+// ExitNamed returns the first exit whose first description has the given short name.
+func (o *Object) ExitNamed(name string) (*Exit, bool) {
+	for i := range o.Exits {
+		if len(o.Exits[i].Descriptions) > 0 && strings.EqualFold(o.Exits[i].Descriptions[0].Short, name) {
+			return &o.Exits[i], true
+		}
+	}
+	return nil, false
+}
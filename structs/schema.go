@@ -12,6 +12,7 @@ import (
 type Skill struct {
     Theoretical float32
     Practical float32
+    LastUsed int64
 }
 
 // Reserved Ids - Skill
@@ -26,6 +27,7 @@ func (skill *Skill) Size() int {
 func (skill *Skill) size(id uint16) (s int) {
     s += bstd.SizeFloat32() + 2
     s += bstd.SizeFloat32() + 2
+    s += bstd.SizeInt64() + 2
 
     if id > 255 {
         s += 5
@@ -39,6 +41,7 @@ func (skill *Skill) size(id uint16) (s int) {
 func (skill *Skill) SizePlain() (s int) {
     s += bstd.SizeFloat32()
     s += bstd.SizeFloat32()
+    s += bstd.SizeInt64()
     return
 }
 
@@ -54,6 +57,8 @@ func (skill *Skill) marshal(tn int, b []byte, id uint16) (n int) {
     n = bstd.MarshalFloat32(n, b, skill.Theoretical)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 2)
     n = bstd.MarshalFloat32(n, b, skill.Practical)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 3)
+    n = bstd.MarshalInt64(n, b, skill.LastUsed)
 
     n += 2
     b[n-2] = 1
@@ -66,6 +71,7 @@ func (skill *Skill) MarshalPlain(tn int, b []byte) (n int) {
     n = tn
     n = bstd.MarshalFloat32(n, b, skill.Theoretical)
     n = bstd.MarshalFloat32(n, b, skill.Practical)
+    n = bstd.MarshalInt64(n, b, skill.LastUsed)
     return n
 }
 
@@ -106,6 +112,17 @@ func (skill *Skill) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, e
             return
         }
     }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, skillRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, skill.LastUsed, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
     n += 2
     return
 }
@@ -119,6 +136,9 @@ func (skill *Skill) UnmarshalPlain(tn int, b []byte) (n int, err error) {
     if n, skill.Practical, err = bstd.UnmarshalFloat32(n, b); err != nil {
         return
     }
+    if n, skill.LastUsed, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
     return
 }
 
@@ -127,6 +147,7 @@ type Challenge struct {
     Skill string
     Level float32
     Message string
+    Faction string
 }
 
 // Reserved Ids - Challenge
@@ -142,6 +163,7 @@ func (challenge *Challenge) size(id uint16) (s int) {
     s += bstd.SizeString(challenge.Skill) + 2
     s += bstd.SizeFloat32() + 2
     s += bstd.SizeString(challenge.Message) + 2
+    s += bstd.SizeString(challenge.Faction) + 2
 
     if id > 255 {
         s += 5
@@ -156,6 +178,7 @@ func (challenge *Challenge) SizePlain() (s int) {
     s += bstd.SizeString(challenge.Skill)
     s += bstd.SizeFloat32()
     s += bstd.SizeString(challenge.Message)
+    s += bstd.SizeString(challenge.Faction)
     return
 }
 
@@ -173,6 +196,8 @@ func (challenge *Challenge) marshal(tn int, b []byte, id uint16) (n int) {
     n = bstd.MarshalFloat32(n, b, challenge.Level)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 3)
     n = bstd.MarshalString(n, b, challenge.Message)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 4)
+    n = bstd.MarshalString(n, b, challenge.Faction)
 
     n += 2
     b[n-2] = 1
@@ -186,6 +211,7 @@ func (challenge *Challenge) MarshalPlain(tn int, b []byte) (n int) {
     n = bstd.MarshalString(n, b, challenge.Skill)
     n = bstd.MarshalFloat32(n, b, challenge.Level)
     n = bstd.MarshalString(n, b, challenge.Message)
+    n = bstd.MarshalString(n, b, challenge.Faction)
     return n
 }
 
@@ -237,6 +263,17 @@ func (challenge *Challenge) unmarshal(tn int, b []byte, r []uint16, id uint16) (
             return
         }
     }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, challengeRIds, 4); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, challenge.Faction, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
     n += 2
     return
 }
@@ -253,6 +290,123 @@ func (challenge *Challenge) UnmarshalPlain(tn int, b []byte) (n int, err error)
     if n, challenge.Message, err = bstd.UnmarshalString(n, b); err != nil {
         return
     }
+    if n, challenge.Faction, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - Translation
+type Translation struct {
+    Short string
+    Long string
+}
+
+// Reserved Ids - Translation
+var translationRIds = []uint16{}
+
+// Size - Translation
+func (translation *Translation) Size() int {
+    return translation.size(0)
+}
+
+// Nested Size - Translation
+func (translation *Translation) size(id uint16) (s int) {
+    s += bstd.SizeString(translation.Short) + 2
+    s += bstd.SizeString(translation.Long) + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - Translation
+func (translation *Translation) SizePlain() (s int) {
+    s += bstd.SizeString(translation.Short)
+    s += bstd.SizeString(translation.Long)
+    return
+}
+
+// Marshal - Translation
+func (translation *Translation) Marshal(b []byte) {
+    translation.marshal(0, b, 0)
+}
+
+// Nested Marshal - Translation
+func (translation *Translation) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, translation.Short)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, translation.Long)
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - Translation
+func (translation *Translation) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, translation.Short)
+    n = bstd.MarshalString(n, b, translation.Long)
+    return n
+}
+
+// Unmarshal - Translation
+func (translation *Translation) Unmarshal(b []byte) (err error) {
+    _, err = translation.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - Translation
+func (translation *Translation) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, translationRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, translation.Short, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, translationRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, translation.Long, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - Translation
+func (translation *Translation) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, translation.Short, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, translation.Long, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
     return
 }
 
@@ -262,6 +416,7 @@ type Description struct {
     Long string
     Tags []string
     Challenges []Challenge
+    Translations map[string]Translation
 }
 
 // Reserved Ids - Description
@@ -278,6 +433,7 @@ func (description *Description) size(id uint16) (s int) {
     s += bstd.SizeString(description.Long) + 2
     s += bstd.SizeSlice(description.Tags, bstd.SizeString) + 2
     s += bstd.SizeSlice(description.Challenges, func (s Challenge) int { return s.SizePlain() }) + 2
+    s += bstd.SizeMap(description.Translations, bstd.SizeString, func (s Translation) int { return s.SizePlain() }) + 2
 
     if id > 255 {
         s += 5
@@ -293,6 +449,7 @@ func (description *Description) SizePlain() (s int) {
     s += bstd.SizeString(description.Long)
     s += bstd.SizeSlice(description.Tags, bstd.SizeString)
     s += bstd.SizeSlice(description.Challenges, func (s Challenge) int { return s.SizePlain() })
+    s += bstd.SizeMap(description.Translations, bstd.SizeString, func (s Translation) int { return s.SizePlain() })
     return
 }
 
@@ -312,6 +469,8 @@ func (description *Description) marshal(tn int, b []byte, id uint16) (n int) {
     n = bstd.MarshalSlice(n, b, description.Tags, bstd.MarshalString)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 4)
     n = bstd.MarshalSlice(n, b, description.Challenges, func (n int, b []byte, s Challenge) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 5)
+    n = bstd.MarshalMap(n, b, description.Translations, bstd.MarshalString, func (n int, b []byte, s Translation) int { return s.MarshalPlain(n, b) })
 
     n += 2
     b[n-2] = 1
@@ -326,6 +485,7 @@ func (description *Description) MarshalPlain(tn int, b []byte) (n int) {
     n = bstd.MarshalString(n, b, description.Long)
     n = bstd.MarshalSlice(n, b, description.Tags, bstd.MarshalString)
     n = bstd.MarshalSlice(n, b, description.Challenges, func (n int, b []byte, s Challenge) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalMap(n, b, description.Translations, bstd.MarshalString, func (n int, b []byte, s Translation) int { return s.MarshalPlain(n, b) })
     return n
 }
 
@@ -388,6 +548,17 @@ func (description *Description) unmarshal(tn int, b []byte, r []uint16, id uint1
             return
         }
     }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, descriptionRIds, 5); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, description.Translations, err = bstd.UnmarshalMap[string, Translation](n, b, bstd.UnmarshalString, func (n int, b []byte, s *Translation) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
     n += 2
     return
 }
@@ -407,6 +578,9 @@ func (description *Description) UnmarshalPlain(tn int, b []byte) (n int, err err
     if n, description.Challenges, err = bstd.UnmarshalSlice[Challenge](n, b, func (n int, b []byte, s *Challenge) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
         return
     }
+    if n, description.Translations, err = bstd.UnmarshalMap[string, Translation](n, b, bstd.UnmarshalString, func (n int, b []byte, s *Translation) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
     return
 }
 
@@ -417,6 +591,8 @@ type Exit struct {
     TransmitChallenges map[string][]Challenge
     Tags []string
     Destination string
+    TravelTime float32
+    TravelMessage string
 }
 
 // Reserved Ids - Exit
@@ -434,6 +610,8 @@ func (exit *Exit) size(id uint16) (s int) {
     s += bstd.SizeMap(exit.TransmitChallenges, bstd.SizeString, func (s []Challenge) int { return bstd.SizeSlice(s, func (s Challenge) int { return s.SizePlain() }) }) + 2
     s += bstd.SizeSlice(exit.Tags, bstd.SizeString) + 2
     s += bstd.SizeString(exit.Destination) + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeString(exit.TravelMessage) + 2
 
     if id > 255 {
         s += 5
@@ -450,6 +628,8 @@ func (exit *Exit) SizePlain() (s int) {
     s += bstd.SizeMap(exit.TransmitChallenges, bstd.SizeString, func (s []Challenge) int { return bstd.SizeSlice(s, func (s Challenge) int { return s.SizePlain() }) })
     s += bstd.SizeSlice(exit.Tags, bstd.SizeString)
     s += bstd.SizeString(exit.Destination)
+    s += bstd.SizeFloat32()
+    s += bstd.SizeString(exit.TravelMessage)
     return
 }
 
@@ -471,6 +651,10 @@ func (exit *Exit) marshal(tn int, b []byte, id uint16) (n int) {
     n = bstd.MarshalSlice(n, b, exit.Tags, bstd.MarshalString)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 5)
     n = bstd.MarshalString(n, b, exit.Destination)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 6)
+    n = bstd.MarshalFloat32(n, b, exit.TravelTime)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 7)
+    n = bstd.MarshalString(n, b, exit.TravelMessage)
 
     n += 2
     b[n-2] = 1
@@ -486,6 +670,8 @@ func (exit *Exit) MarshalPlain(tn int, b []byte) (n int) {
     n = bstd.MarshalMap(n, b, exit.TransmitChallenges, bstd.MarshalString, func (n int, b []byte, s []Challenge) int { return bstd.MarshalSlice(n, b, s, func (n int, b []byte, s Challenge) int { return s.MarshalPlain(n, b) }) })
     n = bstd.MarshalSlice(n, b, exit.Tags, bstd.MarshalString)
     n = bstd.MarshalString(n, b, exit.Destination)
+    n = bstd.MarshalFloat32(n, b, exit.TravelTime)
+    n = bstd.MarshalString(n, b, exit.TravelMessage)
     return n
 }
 
@@ -559,6 +745,28 @@ func (exit *Exit) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err
             return
         }
     }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, exitRIds, 6); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, exit.TravelTime, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, exitRIds, 7); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, exit.TravelMessage, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
     n += 2
     return
 }
@@ -581,43 +789,43 @@ func (exit *Exit) UnmarshalPlain(tn int, b []byte) (n int, err error) {
     if n, exit.Destination, err = bstd.UnmarshalString(n, b); err != nil {
         return
     }
+    if n, exit.TravelTime, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, exit.TravelMessage, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
     return
 }
 
-// Struct - Object
-type Object struct {
+// Struct - Effect
+type Effect struct {
     Id string
-    Callbacks map[string]map[string]bool
-    State string
-    Location string
-    Content map[string]bool
-    Skills map[string]Skill
-    Descriptions []Description
-    Exits []Exit
-    SourcePath string
-    SourceModTime int64
+    Name string
+    Expires int64
+    SkillBonuses map[string]float32
+    Immobile bool
+    TickInterval int64
+    Description string
 }
 
-// Reserved Ids - Object
-var objectRIds = []uint16{}
+// Reserved Ids - Effect
+var effectRIds = []uint16{}
 
-// Size - Object
-func (object *Object) Size() int {
-    return object.size(0)
+// Size - Effect
+func (effect *Effect) Size() int {
+    return effect.size(0)
 }
 
-// Nested Size - Object
-func (object *Object) size(id uint16) (s int) {
-    s += bstd.SizeString(object.Id) + 2
-    s += bstd.SizeMap(object.Callbacks, bstd.SizeString, func (s map[string]bool) int { return bstd.SizeMap(s, bstd.SizeString, bstd.SizeBool) }) + 2
-    s += bstd.SizeString(object.State) + 2
-    s += bstd.SizeString(object.Location) + 2
-    s += bstd.SizeMap(object.Content, bstd.SizeString, bstd.SizeBool) + 2
-    s += bstd.SizeMap(object.Skills, bstd.SizeString, func (s Skill) int { return s.SizePlain() }) + 2
-    s += bstd.SizeSlice(object.Descriptions, func (s Description) int { return s.SizePlain() }) + 2
-    s += bstd.SizeSlice(object.Exits, func (s Exit) int { return s.SizePlain() }) + 2
-    s += bstd.SizeString(object.SourcePath) + 2
+// Nested Size - Effect
+func (effect *Effect) size(id uint16) (s int) {
+    s += bstd.SizeString(effect.Id) + 2
+    s += bstd.SizeString(effect.Name) + 2
     s += bstd.SizeInt64() + 2
+    s += bstd.SizeMap(effect.SkillBonuses, bstd.SizeString, bstd.SizeFloat32) + 2
+    s += bstd.SizeBool() + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeString(effect.Description) + 2
 
     if id > 255 {
         s += 5
@@ -627,49 +835,40 @@ func (object *Object) size(id uint16) (s int) {
     return
 }
 
-// SizePlain - Object
-func (object *Object) SizePlain() (s int) {
-    s += bstd.SizeString(object.Id)
-    s += bstd.SizeMap(object.Callbacks, bstd.SizeString, func (s map[string]bool) int { return bstd.SizeMap(s, bstd.SizeString, bstd.SizeBool) })
-    s += bstd.SizeString(object.State)
-    s += bstd.SizeString(object.Location)
-    s += bstd.SizeMap(object.Content, bstd.SizeString, bstd.SizeBool)
-    s += bstd.SizeMap(object.Skills, bstd.SizeString, func (s Skill) int { return s.SizePlain() })
-    s += bstd.SizeSlice(object.Descriptions, func (s Description) int { return s.SizePlain() })
-    s += bstd.SizeSlice(object.Exits, func (s Exit) int { return s.SizePlain() })
-    s += bstd.SizeString(object.SourcePath)
+// SizePlain - Effect
+func (effect *Effect) SizePlain() (s int) {
+    s += bstd.SizeString(effect.Id)
+    s += bstd.SizeString(effect.Name)
     s += bstd.SizeInt64()
+    s += bstd.SizeMap(effect.SkillBonuses, bstd.SizeString, bstd.SizeFloat32)
+    s += bstd.SizeBool()
+    s += bstd.SizeInt64()
+    s += bstd.SizeString(effect.Description)
     return
 }
 
-// Marshal - Object
-func (object *Object) Marshal(b []byte) {
-    object.marshal(0, b, 0)
+// Marshal - Effect
+func (effect *Effect) Marshal(b []byte) {
+    effect.marshal(0, b, 0)
 }
 
-// Nested Marshal - Object
-func (object *Object) marshal(tn int, b []byte, id uint16) (n int) {
+// Nested Marshal - Effect
+func (effect *Effect) marshal(tn int, b []byte, id uint16) (n int) {
     n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
-    n = bstd.MarshalString(n, b, object.Id)
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 2)
-    n = bstd.MarshalMap(n, b, object.Callbacks, bstd.MarshalString, func (n int, b []byte, s map[string]bool) int { return bstd.MarshalMap(n, b, s, bstd.MarshalString, bstd.MarshalBool) })
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 3)
-    n = bstd.MarshalString(n, b, object.State)
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 4)
-    n = bstd.MarshalString(n, b, object.Location)
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 5)
-    n = bstd.MarshalMap(n, b, object.Content, bstd.MarshalString, bstd.MarshalBool)
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 6)
-    n = bstd.MarshalMap(n, b, object.Skills, bstd.MarshalString, func (n int, b []byte, s Skill) int { return s.MarshalPlain(n, b) })
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 7)
-    n = bstd.MarshalSlice(n, b, object.Descriptions, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) })
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 8)
-    n = bstd.MarshalSlice(n, b, object.Exits, func (n int, b []byte, s Exit) int { return s.MarshalPlain(n, b) })
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 9)
-    n = bstd.MarshalString(n, b, object.SourcePath)
-    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 10)
-    n = bstd.MarshalInt64(n, b, object.SourceModTime)
+    n = bstd.MarshalString(n, b, effect.Id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, effect.Name)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 3)
+    n = bstd.MarshalInt64(n, b, effect.Expires)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 4)
+    n = bstd.MarshalMap(n, b, effect.SkillBonuses, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed8, 5)
+    n = bstd.MarshalBool(n, b, effect.Immobile)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 6)
+    n = bstd.MarshalInt64(n, b, effect.TickInterval)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 7)
+    n = bstd.MarshalString(n, b, effect.Description)
 
     n += 2
     b[n-2] = 1
@@ -677,30 +876,27 @@ func (object *Object) marshal(tn int, b []byte, id uint16) (n int) {
     return
 }
 
-// MarshalPlain - Object
-func (object *Object) MarshalPlain(tn int, b []byte) (n int) {
+// MarshalPlain - Effect
+func (effect *Effect) MarshalPlain(tn int, b []byte) (n int) {
     n = tn
-    n = bstd.MarshalString(n, b, object.Id)
-    n = bstd.MarshalMap(n, b, object.Callbacks, bstd.MarshalString, func (n int, b []byte, s map[string]bool) int { return bstd.MarshalMap(n, b, s, bstd.MarshalString, bstd.MarshalBool) })
-    n = bstd.MarshalString(n, b, object.State)
-    n = bstd.MarshalString(n, b, object.Location)
-    n = bstd.MarshalMap(n, b, object.Content, bstd.MarshalString, bstd.MarshalBool)
-    n = bstd.MarshalMap(n, b, object.Skills, bstd.MarshalString, func (n int, b []byte, s Skill) int { return s.MarshalPlain(n, b) })
-    n = bstd.MarshalSlice(n, b, object.Descriptions, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) })
-    n = bstd.MarshalSlice(n, b, object.Exits, func (n int, b []byte, s Exit) int { return s.MarshalPlain(n, b) })
-    n = bstd.MarshalString(n, b, object.SourcePath)
-    n = bstd.MarshalInt64(n, b, object.SourceModTime)
+    n = bstd.MarshalString(n, b, effect.Id)
+    n = bstd.MarshalString(n, b, effect.Name)
+    n = bstd.MarshalInt64(n, b, effect.Expires)
+    n = bstd.MarshalMap(n, b, effect.SkillBonuses, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bstd.MarshalBool(n, b, effect.Immobile)
+    n = bstd.MarshalInt64(n, b, effect.TickInterval)
+    n = bstd.MarshalString(n, b, effect.Description)
     return n
 }
 
-// Unmarshal - Object
-func (object *Object) Unmarshal(b []byte) (err error) {
-    _, err = object.unmarshal(0, b, []uint16{}, 0)
+// Unmarshal - Effect
+func (effect *Effect) Unmarshal(b []byte) (err error) {
+    _, err = effect.unmarshal(0, b, []uint16{}, 0)
     return
 }
 
-// Nested Unmarshal - Object
-func (object *Object) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+// Nested Unmarshal - Effect
+func (effect *Effect) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
     var ok bool
     if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
         if err == bgenimpl.ErrEof {
@@ -708,113 +904,2028 @@ func (object *Object) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int,
         }
         return
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 1); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 1); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Id, err = bstd.UnmarshalString(n, b); err != nil {
+        if n, effect.Id, err = bstd.UnmarshalString(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 2); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 2); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Callbacks, err = bstd.UnmarshalMap[string, map[string]bool](n, b, bstd.UnmarshalString, func (n int, b []byte) (int, map[string]bool, error) { return bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool) }); err != nil {
+        if n, effect.Name, err = bstd.UnmarshalString(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 3); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 3); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.State, err = bstd.UnmarshalString(n, b); err != nil {
+        if n, effect.Expires, err = bstd.UnmarshalInt64(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 4); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 4); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Location, err = bstd.UnmarshalString(n, b); err != nil {
+        if n, effect.SkillBonuses, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 5); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 5); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Content, err = bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool); err != nil {
+        if n, effect.Immobile, err = bstd.UnmarshalBool(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 6); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 6); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Skills, err = bstd.UnmarshalMap[string, Skill](n, b, bstd.UnmarshalString, func (n int, b []byte, s *Skill) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        if n, effect.TickInterval, err = bstd.UnmarshalInt64(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 7); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, effectRIds, 7); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.Descriptions, err = bstd.UnmarshalSlice[Description](n, b, func (n int, b []byte, s *Description) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        if n, effect.Description, err = bstd.UnmarshalString(n, b); err != nil {
             return
         }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 8); err != nil {
-        if err == bgenimpl.ErrEof {
-            return n, nil
-        }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - Effect
+func (effect *Effect) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, effect.Id, err = bstd.UnmarshalString(n, b); err != nil {
         return
     }
-    if ok {
-        if n, object.Exits, err = bstd.UnmarshalSlice[Exit](n, b, func (n int, b []byte, s *Exit) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
-            return
-        }
+    if n, effect.Name, err = bstd.UnmarshalString(n, b); err != nil {
+        return
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 9); err != nil {
-        if err == bgenimpl.ErrEof {
-            return n, nil
-        }
+    if n, effect.Expires, err = bstd.UnmarshalInt64(n, b); err != nil {
         return
     }
-    if ok {
-        if n, object.SourcePath, err = bstd.UnmarshalString(n, b); err != nil {
-            return
-        }
+    if n, effect.SkillBonuses, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+        return
+    }
+    if n, effect.Immobile, err = bstd.UnmarshalBool(n, b); err != nil {
+        return
+    }
+    if n, effect.TickInterval, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, effect.Description, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - StockItem
+type StockItem struct {
+    Name string
+    Source string
+    Price int64
+    Quantity int64
+}
+
+// Reserved Ids - StockItem
+var stockItemRIds = []uint16{}
+
+// Size - StockItem
+func (stockItem *StockItem) Size() int {
+    return stockItem.size(0)
+}
+
+// Nested Size - StockItem
+func (stockItem *StockItem) size(id uint16) (s int) {
+    s += bstd.SizeString(stockItem.Name) + 2
+    s += bstd.SizeString(stockItem.Source) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeInt64() + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - StockItem
+func (stockItem *StockItem) SizePlain() (s int) {
+    s += bstd.SizeString(stockItem.Name)
+    s += bstd.SizeString(stockItem.Source)
+    s += bstd.SizeInt64()
+    s += bstd.SizeInt64()
+    return
+}
+
+// Marshal - StockItem
+func (stockItem *StockItem) Marshal(b []byte) {
+    stockItem.marshal(0, b, 0)
+}
+
+// Nested Marshal - StockItem
+func (stockItem *StockItem) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, stockItem.Name)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, stockItem.Source)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 3)
+    n = bstd.MarshalInt64(n, b, stockItem.Price)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 4)
+    n = bstd.MarshalInt64(n, b, stockItem.Quantity)
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - StockItem
+func (stockItem *StockItem) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, stockItem.Name)
+    n = bstd.MarshalString(n, b, stockItem.Source)
+    n = bstd.MarshalInt64(n, b, stockItem.Price)
+    n = bstd.MarshalInt64(n, b, stockItem.Quantity)
+    return n
+}
+
+// Unmarshal - StockItem
+func (stockItem *StockItem) Unmarshal(b []byte) (err error) {
+    _, err = stockItem.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - StockItem
+func (stockItem *StockItem) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, stockItemRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, stockItem.Name, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, stockItemRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, stockItem.Source, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, stockItemRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, stockItem.Price, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, stockItemRIds, 4); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, stockItem.Quantity, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - StockItem
+func (stockItem *StockItem) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, stockItem.Name, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, stockItem.Source, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, stockItem.Price, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, stockItem.Quantity, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - Ambience
+type Ambience struct {
+    Text string
+    Interval float32
+}
+
+// Reserved Ids - Ambience
+var ambienceRIds = []uint16{}
+
+// Size - Ambience
+func (ambience *Ambience) Size() int {
+    return ambience.size(0)
+}
+
+// Nested Size - Ambience
+func (ambience *Ambience) size(id uint16) (s int) {
+    s += bstd.SizeString(ambience.Text) + 2
+    s += bstd.SizeFloat32() + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - Ambience
+func (ambience *Ambience) SizePlain() (s int) {
+    s += bstd.SizeString(ambience.Text)
+    s += bstd.SizeFloat32()
+    return
+}
+
+// Marshal - Ambience
+func (ambience *Ambience) Marshal(b []byte) {
+    ambience.marshal(0, b, 0)
+}
+
+// Nested Marshal - Ambience
+func (ambience *Ambience) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, ambience.Text)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 2)
+    n = bstd.MarshalFloat32(n, b, ambience.Interval)
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - Ambience
+func (ambience *Ambience) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, ambience.Text)
+    n = bstd.MarshalFloat32(n, b, ambience.Interval)
+    return n
+}
+
+// Unmarshal - Ambience
+func (ambience *Ambience) Unmarshal(b []byte) (err error) {
+    _, err = ambience.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - Ambience
+func (ambience *Ambience) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, ambienceRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, ambience.Text, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, ambienceRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, ambience.Interval, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - Ambience
+func (ambience *Ambience) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, ambience.Text, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, ambience.Interval, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - CommandArgument
+type CommandArgument struct {
+    Name string
+    Kind string
+    Optional bool
+}
+
+// Reserved Ids - CommandArgument
+var commandArgumentRIds = []uint16{}
+
+// Size - CommandArgument
+func (commandArgument *CommandArgument) Size() int {
+    return commandArgument.size(0)
+}
+
+// Nested Size - CommandArgument
+func (commandArgument *CommandArgument) size(id uint16) (s int) {
+    s += bstd.SizeString(commandArgument.Name) + 2
+    s += bstd.SizeString(commandArgument.Kind) + 2
+    s += bstd.SizeBool() + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - CommandArgument
+func (commandArgument *CommandArgument) SizePlain() (s int) {
+    s += bstd.SizeString(commandArgument.Name)
+    s += bstd.SizeString(commandArgument.Kind)
+    s += bstd.SizeBool()
+    return
+}
+
+// Marshal - CommandArgument
+func (commandArgument *CommandArgument) Marshal(b []byte) {
+    commandArgument.marshal(0, b, 0)
+}
+
+// Nested Marshal - CommandArgument
+func (commandArgument *CommandArgument) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, commandArgument.Name)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, commandArgument.Kind)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed8, 3)
+    n = bstd.MarshalBool(n, b, commandArgument.Optional)
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - CommandArgument
+func (commandArgument *CommandArgument) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, commandArgument.Name)
+    n = bstd.MarshalString(n, b, commandArgument.Kind)
+    n = bstd.MarshalBool(n, b, commandArgument.Optional)
+    return n
+}
+
+// Unmarshal - CommandArgument
+func (commandArgument *CommandArgument) Unmarshal(b []byte) (err error) {
+    _, err = commandArgument.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - CommandArgument
+func (commandArgument *CommandArgument) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandArgumentRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, commandArgument.Name, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandArgumentRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, commandArgument.Kind, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandArgumentRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, commandArgument.Optional, err = bstd.UnmarshalBool(n, b); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - CommandArgument
+func (commandArgument *CommandArgument) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, commandArgument.Name, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, commandArgument.Kind, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, commandArgument.Optional, err = bstd.UnmarshalBool(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - Command
+type Command struct {
+    Name string
+    Help string
+    Arguments []CommandArgument
+}
+
+// Reserved Ids - Command
+var commandRIds = []uint16{}
+
+// Size - Command
+func (command *Command) Size() int {
+    return command.size(0)
+}
+
+// Nested Size - Command
+func (command *Command) size(id uint16) (s int) {
+    s += bstd.SizeString(command.Name) + 2
+    s += bstd.SizeString(command.Help) + 2
+    s += bstd.SizeSlice(command.Arguments, func (s CommandArgument) int { return s.SizePlain() }) + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - Command
+func (command *Command) SizePlain() (s int) {
+    s += bstd.SizeString(command.Name)
+    s += bstd.SizeString(command.Help)
+    s += bstd.SizeSlice(command.Arguments, func (s CommandArgument) int { return s.SizePlain() })
+    return
+}
+
+// Marshal - Command
+func (command *Command) Marshal(b []byte) {
+    command.marshal(0, b, 0)
+}
+
+// Nested Marshal - Command
+func (command *Command) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, command.Name)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, command.Help)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 3)
+    n = bstd.MarshalSlice(n, b, command.Arguments, func (n int, b []byte, s CommandArgument) int { return s.MarshalPlain(n, b) })
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - Command
+func (command *Command) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, command.Name)
+    n = bstd.MarshalString(n, b, command.Help)
+    n = bstd.MarshalSlice(n, b, command.Arguments, func (n int, b []byte, s CommandArgument) int { return s.MarshalPlain(n, b) })
+    return n
+}
+
+// Unmarshal - Command
+func (command *Command) Unmarshal(b []byte) (err error) {
+    _, err = command.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - Command
+func (command *Command) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, command.Name, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, command.Help, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, commandRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, command.Arguments, err = bstd.UnmarshalSlice[CommandArgument](n, b, func (n int, b []byte, s *CommandArgument) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - Command
+func (command *Command) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, command.Name, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, command.Help, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, command.Arguments, err = bstd.UnmarshalSlice[CommandArgument](n, b, func (n int, b []byte, s *CommandArgument) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - DialogueChoice
+type DialogueChoice struct {
+    Text string
+    Next string
+    RequiredAchievements []string
+    ForbiddenAchievements []string
+    RequiredSkills map[string]float32
+    Condition string
+    GrantAchievements []string
+    Action string
+}
+
+// Reserved Ids - DialogueChoice
+var dialogueChoiceRIds = []uint16{}
+
+// Size - DialogueChoice
+func (dialogueChoice *DialogueChoice) Size() int {
+    return dialogueChoice.size(0)
+}
+
+// Nested Size - DialogueChoice
+func (dialogueChoice *DialogueChoice) size(id uint16) (s int) {
+    s += bstd.SizeString(dialogueChoice.Text) + 2
+    s += bstd.SizeString(dialogueChoice.Next) + 2
+    s += bstd.SizeSlice(dialogueChoice.RequiredAchievements, bstd.SizeString) + 2
+    s += bstd.SizeSlice(dialogueChoice.ForbiddenAchievements, bstd.SizeString) + 2
+    s += bstd.SizeMap(dialogueChoice.RequiredSkills, bstd.SizeString, bstd.SizeFloat32) + 2
+    s += bstd.SizeString(dialogueChoice.Condition) + 2
+    s += bstd.SizeSlice(dialogueChoice.GrantAchievements, bstd.SizeString) + 2
+    s += bstd.SizeString(dialogueChoice.Action) + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - DialogueChoice
+func (dialogueChoice *DialogueChoice) SizePlain() (s int) {
+    s += bstd.SizeString(dialogueChoice.Text)
+    s += bstd.SizeString(dialogueChoice.Next)
+    s += bstd.SizeSlice(dialogueChoice.RequiredAchievements, bstd.SizeString)
+    s += bstd.SizeSlice(dialogueChoice.ForbiddenAchievements, bstd.SizeString)
+    s += bstd.SizeMap(dialogueChoice.RequiredSkills, bstd.SizeString, bstd.SizeFloat32)
+    s += bstd.SizeString(dialogueChoice.Condition)
+    s += bstd.SizeSlice(dialogueChoice.GrantAchievements, bstd.SizeString)
+    s += bstd.SizeString(dialogueChoice.Action)
+    return
+}
+
+// Marshal - DialogueChoice
+func (dialogueChoice *DialogueChoice) Marshal(b []byte) {
+    dialogueChoice.marshal(0, b, 0)
+}
+
+// Nested Marshal - DialogueChoice
+func (dialogueChoice *DialogueChoice) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, dialogueChoice.Text)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 2)
+    n = bstd.MarshalString(n, b, dialogueChoice.Next)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 3)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.RequiredAchievements, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 4)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.ForbiddenAchievements, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 5)
+    n = bstd.MarshalMap(n, b, dialogueChoice.RequiredSkills, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 6)
+    n = bstd.MarshalString(n, b, dialogueChoice.Condition)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 7)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.GrantAchievements, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 8)
+    n = bstd.MarshalString(n, b, dialogueChoice.Action)
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - DialogueChoice
+func (dialogueChoice *DialogueChoice) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, dialogueChoice.Text)
+    n = bstd.MarshalString(n, b, dialogueChoice.Next)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.RequiredAchievements, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.ForbiddenAchievements, bstd.MarshalString)
+    n = bstd.MarshalMap(n, b, dialogueChoice.RequiredSkills, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bstd.MarshalString(n, b, dialogueChoice.Condition)
+    n = bstd.MarshalSlice(n, b, dialogueChoice.GrantAchievements, bstd.MarshalString)
+    n = bstd.MarshalString(n, b, dialogueChoice.Action)
+    return n
+}
+
+// Unmarshal - DialogueChoice
+func (dialogueChoice *DialogueChoice) Unmarshal(b []byte) (err error) {
+    _, err = dialogueChoice.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - DialogueChoice
+func (dialogueChoice *DialogueChoice) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.Text, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.Next, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.RequiredAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 4); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.ForbiddenAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 5); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.RequiredSkills, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 6); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.Condition, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 7); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.GrantAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueChoiceRIds, 8); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueChoice.Action, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - DialogueChoice
+func (dialogueChoice *DialogueChoice) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, dialogueChoice.Text, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, dialogueChoice.Next, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, dialogueChoice.RequiredAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, dialogueChoice.ForbiddenAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, dialogueChoice.RequiredSkills, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+        return
+    }
+    if n, dialogueChoice.Condition, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, dialogueChoice.GrantAchievements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, dialogueChoice.Action, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - DialogueNode
+type DialogueNode struct {
+    Text string
+    Choices []DialogueChoice
+}
+
+// Reserved Ids - DialogueNode
+var dialogueNodeRIds = []uint16{}
+
+// Size - DialogueNode
+func (dialogueNode *DialogueNode) Size() int {
+    return dialogueNode.size(0)
+}
+
+// Nested Size - DialogueNode
+func (dialogueNode *DialogueNode) size(id uint16) (s int) {
+    s += bstd.SizeString(dialogueNode.Text) + 2
+    s += bstd.SizeSlice(dialogueNode.Choices, func (s DialogueChoice) int { return s.SizePlain() }) + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - DialogueNode
+func (dialogueNode *DialogueNode) SizePlain() (s int) {
+    s += bstd.SizeString(dialogueNode.Text)
+    s += bstd.SizeSlice(dialogueNode.Choices, func (s DialogueChoice) int { return s.SizePlain() })
+    return
+}
+
+// Marshal - DialogueNode
+func (dialogueNode *DialogueNode) Marshal(b []byte) {
+    dialogueNode.marshal(0, b, 0)
+}
+
+// Nested Marshal - DialogueNode
+func (dialogueNode *DialogueNode) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, dialogueNode.Text)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 2)
+    n = bstd.MarshalSlice(n, b, dialogueNode.Choices, func (n int, b []byte, s DialogueChoice) int { return s.MarshalPlain(n, b) })
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - DialogueNode
+func (dialogueNode *DialogueNode) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, dialogueNode.Text)
+    n = bstd.MarshalSlice(n, b, dialogueNode.Choices, func (n int, b []byte, s DialogueChoice) int { return s.MarshalPlain(n, b) })
+    return n
+}
+
+// Unmarshal - DialogueNode
+func (dialogueNode *DialogueNode) Unmarshal(b []byte) (err error) {
+    _, err = dialogueNode.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - DialogueNode
+func (dialogueNode *DialogueNode) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueNodeRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueNode.Text, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, dialogueNodeRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, dialogueNode.Choices, err = bstd.UnmarshalSlice[DialogueChoice](n, b, func (n int, b []byte, s *DialogueChoice) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    n += 2
+    return
+}
+
+// UnmarshalPlain - DialogueNode
+func (dialogueNode *DialogueNode) UnmarshalPlain(tn int, b []byte) (n int, err error) {
+    n = tn
+    if n, dialogueNode.Text, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, dialogueNode.Choices, err = bstd.UnmarshalSlice[DialogueChoice](n, b, func (n int, b []byte, s *DialogueChoice) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    return
+}
+
+// Struct - Object
+type Object struct {
+    Id string
+    Callbacks map[string]map[string]bool
+    State string
+    Location string
+    Content map[string]bool
+    Skills map[string]Skill
+    Descriptions []Description
+    Exits []Exit
+    SourcePath string
+    SourceModTime int64
+    Tags []string
+    Coordinates []int64
+    Subscriptions []string
+    SerialEvents []string
+    Invisibility []Challenge
+    Reputation map[string]float32
+    Effects []Effect
+    Slot string
+    SkillBonuses map[string]float32
+    Equipment map[string]string
+    Durability float32
+    MaxDurability float32
+    DecayRate float32
+    WearRate float32
+    Decayed int64
+    Health float32
+    MaxHealth float32
+    Cooldowns map[string]int64
+    RandomHi uint64
+    RandomLo uint64
+    Money int64
+    Stock []StockItem
+    Owner string
+    Guests map[string]bool
+    HousePrice int64
+    LockToken string
+    KeyToken string
+    Locked bool
+    Ambience []Ambience
+    Details map[string]string
+    Senses map[string][]Description
+    Commands []Command
+    CommandHookPriority int64
+    Terrain string
+    Movements []string
+    Modified int64
+    StateVersion int64
+    PublicKeys []string
+    DespawnAt int64
+    RequiredCommands []string
+    UsedCommands []string
+    Titles []string
+    Title string
+    Nicknames map[string]string
+    Pronouns string
+    Speaking string
+    Dialogue map[string]DialogueNode
+}
+
+// Reserved Ids - Object
+var objectRIds = []uint16{}
+
+// Size - Object
+func (object *Object) Size() int {
+    return object.size(0)
+}
+
+// Nested Size - Object
+func (object *Object) size(id uint16) (s int) {
+    s += bstd.SizeString(object.Id) + 2
+    s += bstd.SizeMap(object.Callbacks, bstd.SizeString, func (s map[string]bool) int { return bstd.SizeMap(s, bstd.SizeString, bstd.SizeBool) }) + 2
+    s += bstd.SizeString(object.State) + 2
+    s += bstd.SizeString(object.Location) + 2
+    s += bstd.SizeMap(object.Content, bstd.SizeString, bstd.SizeBool) + 2
+    s += bstd.SizeMap(object.Skills, bstd.SizeString, func (s Skill) int { return s.SizePlain() }) + 2
+    s += bstd.SizeSlice(object.Descriptions, func (s Description) int { return s.SizePlain() }) + 2
+    s += bstd.SizeSlice(object.Exits, func (s Exit) int { return s.SizePlain() }) + 2
+    s += bstd.SizeString(object.SourcePath) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeSlice(object.Tags, bstd.SizeString) + 2
+    s += bstd.SizeSlice(object.Coordinates, bstd.SizeInt64) + 2
+    s += bstd.SizeSlice(object.Subscriptions, bstd.SizeString) + 2
+    s += bstd.SizeSlice(object.SerialEvents, bstd.SizeString) + 2
+    s += bstd.SizeSlice(object.Invisibility, func (s Challenge) int { return s.SizePlain() }) + 2
+    s += bstd.SizeMap(object.Reputation, bstd.SizeString, bstd.SizeFloat32) + 2
+    s += bstd.SizeSlice(object.Effects, func (s Effect) int { return s.SizePlain() }) + 2
+    s += bstd.SizeString(object.Slot) + 2
+    s += bstd.SizeMap(object.SkillBonuses, bstd.SizeString, bstd.SizeFloat32) + 2
+    s += bstd.SizeMap(object.Equipment, bstd.SizeString, bstd.SizeString) + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeFloat32() + 2
+    s += bstd.SizeMap(object.Cooldowns, bstd.SizeString, bstd.SizeInt64) + 2
+    s += bstd.SizeUint64() + 2
+    s += bstd.SizeUint64() + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeSlice(object.Stock, func (s StockItem) int { return s.SizePlain() }) + 2
+    s += bstd.SizeString(object.Owner) + 2
+    s += bstd.SizeMap(object.Guests, bstd.SizeString, bstd.SizeBool) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeString(object.LockToken) + 2
+    s += bstd.SizeString(object.KeyToken) + 2
+    s += bstd.SizeBool() + 2
+    s += bstd.SizeSlice(object.Ambience, func (s Ambience) int { return s.SizePlain() }) + 2
+    s += bstd.SizeMap(object.Details, bstd.SizeString, bstd.SizeString) + 2
+    s += bstd.SizeMap(object.Senses, bstd.SizeString, func (s []Description) int { return bstd.SizeSlice(s, func (s Description) int { return s.SizePlain() }) }) + 2
+    s += bstd.SizeSlice(object.Commands, func (s Command) int { return s.SizePlain() }) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeString(object.Terrain) + 2
+    s += bstd.SizeSlice(object.Movements, bstd.SizeString) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeSlice(object.PublicKeys, bstd.SizeString) + 2
+    s += bstd.SizeInt64() + 2
+    s += bstd.SizeSlice(object.RequiredCommands, bstd.SizeString) + 2
+    s += bstd.SizeSlice(object.UsedCommands, bstd.SizeString) + 2
+    s += bstd.SizeSlice(object.Titles, bstd.SizeString) + 2
+    s += bstd.SizeString(object.Title) + 2
+    s += bstd.SizeMap(object.Nicknames, bstd.SizeString, bstd.SizeString) + 2
+    s += bstd.SizeString(object.Pronouns) + 2
+    s += bstd.SizeString(object.Speaking) + 2
+    s += bstd.SizeMap(object.Dialogue, bstd.SizeString, func (s DialogueNode) int { return s.SizePlain() }) + 2
+
+    if id > 255 {
+        s += 5
+        return
+    }
+    s += 4
+    return
+}
+
+// SizePlain - Object
+func (object *Object) SizePlain() (s int) {
+    s += bstd.SizeString(object.Id)
+    s += bstd.SizeMap(object.Callbacks, bstd.SizeString, func (s map[string]bool) int { return bstd.SizeMap(s, bstd.SizeString, bstd.SizeBool) })
+    s += bstd.SizeString(object.State)
+    s += bstd.SizeString(object.Location)
+    s += bstd.SizeMap(object.Content, bstd.SizeString, bstd.SizeBool)
+    s += bstd.SizeMap(object.Skills, bstd.SizeString, func (s Skill) int { return s.SizePlain() })
+    s += bstd.SizeSlice(object.Descriptions, func (s Description) int { return s.SizePlain() })
+    s += bstd.SizeSlice(object.Exits, func (s Exit) int { return s.SizePlain() })
+    s += bstd.SizeString(object.SourcePath)
+    s += bstd.SizeInt64()
+    s += bstd.SizeSlice(object.Tags, bstd.SizeString)
+    s += bstd.SizeSlice(object.Coordinates, bstd.SizeInt64)
+    s += bstd.SizeSlice(object.Subscriptions, bstd.SizeString)
+    s += bstd.SizeSlice(object.SerialEvents, bstd.SizeString)
+    s += bstd.SizeSlice(object.Invisibility, func (s Challenge) int { return s.SizePlain() })
+    s += bstd.SizeMap(object.Reputation, bstd.SizeString, bstd.SizeFloat32)
+    s += bstd.SizeSlice(object.Effects, func (s Effect) int { return s.SizePlain() })
+    s += bstd.SizeString(object.Slot)
+    s += bstd.SizeMap(object.SkillBonuses, bstd.SizeString, bstd.SizeFloat32)
+    s += bstd.SizeMap(object.Equipment, bstd.SizeString, bstd.SizeString)
+    s += bstd.SizeFloat32()
+    s += bstd.SizeFloat32()
+    s += bstd.SizeFloat32()
+    s += bstd.SizeFloat32()
+    s += bstd.SizeInt64()
+    s += bstd.SizeFloat32()
+    s += bstd.SizeFloat32()
+    s += bstd.SizeMap(object.Cooldowns, bstd.SizeString, bstd.SizeInt64)
+    s += bstd.SizeUint64()
+    s += bstd.SizeUint64()
+    s += bstd.SizeInt64()
+    s += bstd.SizeSlice(object.Stock, func (s StockItem) int { return s.SizePlain() })
+    s += bstd.SizeString(object.Owner)
+    s += bstd.SizeMap(object.Guests, bstd.SizeString, bstd.SizeBool)
+    s += bstd.SizeInt64()
+    s += bstd.SizeString(object.LockToken)
+    s += bstd.SizeString(object.KeyToken)
+    s += bstd.SizeBool()
+    s += bstd.SizeSlice(object.Ambience, func (s Ambience) int { return s.SizePlain() })
+    s += bstd.SizeMap(object.Details, bstd.SizeString, bstd.SizeString)
+    s += bstd.SizeMap(object.Senses, bstd.SizeString, func (s []Description) int { return bstd.SizeSlice(s, func (s Description) int { return s.SizePlain() }) })
+    s += bstd.SizeSlice(object.Commands, func (s Command) int { return s.SizePlain() })
+    s += bstd.SizeInt64()
+    s += bstd.SizeString(object.Terrain)
+    s += bstd.SizeSlice(object.Movements, bstd.SizeString)
+    s += bstd.SizeInt64()
+    s += bstd.SizeInt64()
+    s += bstd.SizeSlice(object.PublicKeys, bstd.SizeString)
+    s += bstd.SizeInt64()
+    s += bstd.SizeSlice(object.RequiredCommands, bstd.SizeString)
+    s += bstd.SizeSlice(object.UsedCommands, bstd.SizeString)
+    s += bstd.SizeSlice(object.Titles, bstd.SizeString)
+    s += bstd.SizeString(object.Title)
+    s += bstd.SizeMap(object.Nicknames, bstd.SizeString, bstd.SizeString)
+    s += bstd.SizeString(object.Pronouns)
+    s += bstd.SizeString(object.Speaking)
+    s += bstd.SizeMap(object.Dialogue, bstd.SizeString, func (s DialogueNode) int { return s.SizePlain() })
+    return
+}
+
+// Marshal - Object
+func (object *Object) Marshal(b []byte) {
+    object.marshal(0, b, 0)
+}
+
+// Nested Marshal - Object
+func (object *Object) marshal(tn int, b []byte, id uint16) (n int) {
+    n = bgenimpl.MarshalTag(tn, b, bgenimpl.Container, id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 1)
+    n = bstd.MarshalString(n, b, object.Id)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 2)
+    n = bstd.MarshalMap(n, b, object.Callbacks, bstd.MarshalString, func (n int, b []byte, s map[string]bool) int { return bstd.MarshalMap(n, b, s, bstd.MarshalString, bstd.MarshalBool) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 3)
+    n = bstd.MarshalString(n, b, object.State)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 4)
+    n = bstd.MarshalString(n, b, object.Location)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 5)
+    n = bstd.MarshalMap(n, b, object.Content, bstd.MarshalString, bstd.MarshalBool)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 6)
+    n = bstd.MarshalMap(n, b, object.Skills, bstd.MarshalString, func (n int, b []byte, s Skill) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 7)
+    n = bstd.MarshalSlice(n, b, object.Descriptions, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 8)
+    n = bstd.MarshalSlice(n, b, object.Exits, func (n int, b []byte, s Exit) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 9)
+    n = bstd.MarshalString(n, b, object.SourcePath)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 10)
+    n = bstd.MarshalInt64(n, b, object.SourceModTime)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 11)
+    n = bstd.MarshalSlice(n, b, object.Tags, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 12)
+    n = bstd.MarshalSlice(n, b, object.Coordinates, bstd.MarshalInt64)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 13)
+    n = bstd.MarshalSlice(n, b, object.Subscriptions, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 14)
+    n = bstd.MarshalSlice(n, b, object.SerialEvents, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 15)
+    n = bstd.MarshalSlice(n, b, object.Invisibility, func (n int, b []byte, s Challenge) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 16)
+    n = bstd.MarshalMap(n, b, object.Reputation, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 17)
+    n = bstd.MarshalSlice(n, b, object.Effects, func (n int, b []byte, s Effect) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 18)
+    n = bstd.MarshalString(n, b, object.Slot)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 19)
+    n = bstd.MarshalMap(n, b, object.SkillBonuses, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 20)
+    n = bstd.MarshalMap(n, b, object.Equipment, bstd.MarshalString, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 21)
+    n = bstd.MarshalFloat32(n, b, object.Durability)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 22)
+    n = bstd.MarshalFloat32(n, b, object.MaxDurability)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 23)
+    n = bstd.MarshalFloat32(n, b, object.DecayRate)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 24)
+    n = bstd.MarshalFloat32(n, b, object.WearRate)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 25)
+    n = bstd.MarshalInt64(n, b, object.Decayed)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 26)
+    n = bstd.MarshalFloat32(n, b, object.Health)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed32, 27)
+    n = bstd.MarshalFloat32(n, b, object.MaxHealth)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 28)
+    n = bstd.MarshalMap(n, b, object.Cooldowns, bstd.MarshalString, bstd.MarshalInt64)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 29)
+    n = bstd.MarshalUint64(n, b, object.RandomHi)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 30)
+    n = bstd.MarshalUint64(n, b, object.RandomLo)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 31)
+    n = bstd.MarshalInt64(n, b, object.Money)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 32)
+    n = bstd.MarshalSlice(n, b, object.Stock, func (n int, b []byte, s StockItem) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 33)
+    n = bstd.MarshalString(n, b, object.Owner)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 34)
+    n = bstd.MarshalMap(n, b, object.Guests, bstd.MarshalString, bstd.MarshalBool)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 35)
+    n = bstd.MarshalInt64(n, b, object.HousePrice)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 36)
+    n = bstd.MarshalString(n, b, object.LockToken)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 37)
+    n = bstd.MarshalString(n, b, object.KeyToken)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed8, 38)
+    n = bstd.MarshalBool(n, b, object.Locked)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 39)
+    n = bstd.MarshalSlice(n, b, object.Ambience, func (n int, b []byte, s Ambience) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 40)
+    n = bstd.MarshalMap(n, b, object.Details, bstd.MarshalString, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 41)
+    n = bstd.MarshalMap(n, b, object.Senses, bstd.MarshalString, func (n int, b []byte, s []Description) int { return bstd.MarshalSlice(n, b, s, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) }) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 42)
+    n = bstd.MarshalSlice(n, b, object.Commands, func (n int, b []byte, s Command) int { return s.MarshalPlain(n, b) })
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 43)
+    n = bstd.MarshalInt64(n, b, object.CommandHookPriority)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 44)
+    n = bstd.MarshalString(n, b, object.Terrain)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 45)
+    n = bstd.MarshalSlice(n, b, object.Movements, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 46)
+    n = bstd.MarshalInt64(n, b, object.Modified)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 47)
+    n = bstd.MarshalInt64(n, b, object.StateVersion)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 48)
+    n = bstd.MarshalSlice(n, b, object.PublicKeys, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 49)
+    n = bstd.MarshalInt64(n, b, object.DespawnAt)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 50)
+    n = bstd.MarshalSlice(n, b, object.RequiredCommands, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 51)
+    n = bstd.MarshalSlice(n, b, object.UsedCommands, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 52)
+    n = bstd.MarshalSlice(n, b, object.Titles, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 53)
+    n = bstd.MarshalString(n, b, object.Title)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 54)
+    n = bstd.MarshalMap(n, b, object.Nicknames, bstd.MarshalString, bstd.MarshalString)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 55)
+    n = bstd.MarshalString(n, b, object.Pronouns)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 56)
+    n = bstd.MarshalString(n, b, object.Speaking)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.ArrayMap, 57)
+    n = bstd.MarshalMap(n, b, object.Dialogue, bstd.MarshalString, func (n int, b []byte, s DialogueNode) int { return s.MarshalPlain(n, b) })
+
+    n += 2
+    b[n-2] = 1
+    b[n-1] = 1
+    return
+}
+
+// MarshalPlain - Object
+func (object *Object) MarshalPlain(tn int, b []byte) (n int) {
+    n = tn
+    n = bstd.MarshalString(n, b, object.Id)
+    n = bstd.MarshalMap(n, b, object.Callbacks, bstd.MarshalString, func (n int, b []byte, s map[string]bool) int { return bstd.MarshalMap(n, b, s, bstd.MarshalString, bstd.MarshalBool) })
+    n = bstd.MarshalString(n, b, object.State)
+    n = bstd.MarshalString(n, b, object.Location)
+    n = bstd.MarshalMap(n, b, object.Content, bstd.MarshalString, bstd.MarshalBool)
+    n = bstd.MarshalMap(n, b, object.Skills, bstd.MarshalString, func (n int, b []byte, s Skill) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalSlice(n, b, object.Descriptions, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalSlice(n, b, object.Exits, func (n int, b []byte, s Exit) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalString(n, b, object.SourcePath)
+    n = bstd.MarshalInt64(n, b, object.SourceModTime)
+    n = bstd.MarshalSlice(n, b, object.Tags, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, object.Coordinates, bstd.MarshalInt64)
+    n = bstd.MarshalSlice(n, b, object.Subscriptions, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, object.SerialEvents, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, object.Invisibility, func (n int, b []byte, s Challenge) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalMap(n, b, object.Reputation, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bstd.MarshalSlice(n, b, object.Effects, func (n int, b []byte, s Effect) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalString(n, b, object.Slot)
+    n = bstd.MarshalMap(n, b, object.SkillBonuses, bstd.MarshalString, bstd.MarshalFloat32)
+    n = bstd.MarshalMap(n, b, object.Equipment, bstd.MarshalString, bstd.MarshalString)
+    n = bstd.MarshalFloat32(n, b, object.Durability)
+    n = bstd.MarshalFloat32(n, b, object.MaxDurability)
+    n = bstd.MarshalFloat32(n, b, object.DecayRate)
+    n = bstd.MarshalFloat32(n, b, object.WearRate)
+    n = bstd.MarshalInt64(n, b, object.Decayed)
+    n = bstd.MarshalFloat32(n, b, object.Health)
+    n = bstd.MarshalFloat32(n, b, object.MaxHealth)
+    n = bstd.MarshalMap(n, b, object.Cooldowns, bstd.MarshalString, bstd.MarshalInt64)
+    n = bstd.MarshalUint64(n, b, object.RandomHi)
+    n = bstd.MarshalUint64(n, b, object.RandomLo)
+    n = bstd.MarshalInt64(n, b, object.Money)
+    n = bstd.MarshalSlice(n, b, object.Stock, func (n int, b []byte, s StockItem) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalString(n, b, object.Owner)
+    n = bstd.MarshalMap(n, b, object.Guests, bstd.MarshalString, bstd.MarshalBool)
+    n = bstd.MarshalInt64(n, b, object.HousePrice)
+    n = bstd.MarshalString(n, b, object.LockToken)
+    n = bstd.MarshalString(n, b, object.KeyToken)
+    n = bstd.MarshalBool(n, b, object.Locked)
+    n = bstd.MarshalSlice(n, b, object.Ambience, func (n int, b []byte, s Ambience) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalMap(n, b, object.Details, bstd.MarshalString, bstd.MarshalString)
+    n = bstd.MarshalMap(n, b, object.Senses, bstd.MarshalString, func (n int, b []byte, s []Description) int { return bstd.MarshalSlice(n, b, s, func (n int, b []byte, s Description) int { return s.MarshalPlain(n, b) }) })
+    n = bstd.MarshalSlice(n, b, object.Commands, func (n int, b []byte, s Command) int { return s.MarshalPlain(n, b) })
+    n = bstd.MarshalInt64(n, b, object.CommandHookPriority)
+    n = bstd.MarshalString(n, b, object.Terrain)
+    n = bstd.MarshalSlice(n, b, object.Movements, bstd.MarshalString)
+    n = bstd.MarshalInt64(n, b, object.Modified)
+    n = bstd.MarshalInt64(n, b, object.StateVersion)
+    n = bstd.MarshalSlice(n, b, object.PublicKeys, bstd.MarshalString)
+    n = bstd.MarshalInt64(n, b, object.DespawnAt)
+    n = bstd.MarshalSlice(n, b, object.RequiredCommands, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, object.UsedCommands, bstd.MarshalString)
+    n = bstd.MarshalSlice(n, b, object.Titles, bstd.MarshalString)
+    n = bstd.MarshalString(n, b, object.Title)
+    n = bstd.MarshalMap(n, b, object.Nicknames, bstd.MarshalString, bstd.MarshalString)
+    n = bstd.MarshalString(n, b, object.Pronouns)
+    n = bstd.MarshalString(n, b, object.Speaking)
+    n = bstd.MarshalMap(n, b, object.Dialogue, bstd.MarshalString, func (n int, b []byte, s DialogueNode) int { return s.MarshalPlain(n, b) })
+    return n
+}
+
+// Unmarshal - Object
+func (object *Object) Unmarshal(b []byte) (err error) {
+    _, err = object.unmarshal(0, b, []uint16{}, 0)
+    return
+}
+
+// Nested Unmarshal - Object
+func (object *Object) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, err error) {
+    var ok bool
+    if n, ok, err = bgenimpl.HandleCompatibility(tn, b, r, id); !ok {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 1); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Id, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 2); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Callbacks, err = bstd.UnmarshalMap[string, map[string]bool](n, b, bstd.UnmarshalString, func (n int, b []byte) (int, map[string]bool, error) { return bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 3); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.State, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 4); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Location, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 5); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Content, err = bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 6); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Skills, err = bstd.UnmarshalMap[string, Skill](n, b, bstd.UnmarshalString, func (n int, b []byte, s *Skill) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 7); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Descriptions, err = bstd.UnmarshalSlice[Description](n, b, func (n int, b []byte, s *Description) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 8); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Exits, err = bstd.UnmarshalSlice[Exit](n, b, func (n int, b []byte, s *Exit) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 9); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.SourcePath, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 10); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.SourceModTime, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 11); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Tags, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 12); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Coordinates, err = bstd.UnmarshalSlice[int64](n, b, bstd.UnmarshalInt64); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 13); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Subscriptions, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 14); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.SerialEvents, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 15); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Invisibility, err = bstd.UnmarshalSlice[Challenge](n, b, func (n int, b []byte, s *Challenge) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 16); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Reputation, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 17); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Effects, err = bstd.UnmarshalSlice[Effect](n, b, func (n int, b []byte, s *Effect) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 18); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Slot, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 19); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.SkillBonuses, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 20); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Equipment, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 21); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Durability, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 22); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.MaxDurability, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 23); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.DecayRate, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 24); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.WearRate, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 25); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Decayed, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 26); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Health, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 27); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.MaxHealth, err = bstd.UnmarshalFloat32(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 28); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Cooldowns, err = bstd.UnmarshalMap[string, int64](n, b, bstd.UnmarshalString, bstd.UnmarshalInt64); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 29); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.RandomHi, err = bstd.UnmarshalUint64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 30); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.RandomLo, err = bstd.UnmarshalUint64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 31); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Money, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 32); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Stock, err = bstd.UnmarshalSlice[StockItem](n, b, func (n int, b []byte, s *StockItem) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 33); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Owner, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 34); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Guests, err = bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 35); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.HousePrice, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 36); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.LockToken, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 37); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.KeyToken, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 38); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Locked, err = bstd.UnmarshalBool(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 39); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Ambience, err = bstd.UnmarshalSlice[Ambience](n, b, func (n int, b []byte, s *Ambience) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 40); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Details, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+            return
+        }
     }
-    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 10); err != nil {
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 41); err != nil {
         if err == bgenimpl.ErrEof {
             return n, nil
         }
         return
     }
     if ok {
-        if n, object.SourceModTime, err = bstd.UnmarshalInt64(n, b); err != nil {
+        if n, object.Senses, err = bstd.UnmarshalMap[string, []Description](n, b, bstd.UnmarshalString, func (n int, b []byte) (int, []Description, error) { return bstd.UnmarshalSlice[Description](n, b, func (n int, b []byte, s *Description) (int, error) { return s.UnmarshalPlain(n, b) }) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 42); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Commands, err = bstd.UnmarshalSlice[Command](n, b, func (n int, b []byte, s *Command) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 43); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.CommandHookPriority, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 44); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Terrain, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 45); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Movements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 46); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Modified, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 47); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.StateVersion, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 48); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.PublicKeys, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 49); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.DespawnAt, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 50); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.RequiredCommands, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 51); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.UsedCommands, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 52); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Titles, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 53); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Title, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 54); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Nicknames, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 55); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Pronouns, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 56); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Speaking, err = bstd.UnmarshalString(n, b); err != nil {
+            return
+        }
+    }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, objectRIds, 57); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, object.Dialogue, err = bstd.UnmarshalMap[string, DialogueNode](n, b, bstd.UnmarshalString, func (n int, b []byte, s *DialogueNode) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
             return
         }
     }
@@ -855,6 +2966,147 @@ func (object *Object) UnmarshalPlain(tn int, b []byte) (n int, err error) {
     if n, object.SourceModTime, err = bstd.UnmarshalInt64(n, b); err != nil {
         return
     }
+    if n, object.Tags, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Coordinates, err = bstd.UnmarshalSlice[int64](n, b, bstd.UnmarshalInt64); err != nil {
+        return
+    }
+    if n, object.Subscriptions, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.SerialEvents, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Invisibility, err = bstd.UnmarshalSlice[Challenge](n, b, func (n int, b []byte, s *Challenge) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    if n, object.Reputation, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+        return
+    }
+    if n, object.Effects, err = bstd.UnmarshalSlice[Effect](n, b, func (n int, b []byte, s *Effect) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    if n, object.Slot, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.SkillBonuses, err = bstd.UnmarshalMap[string, float32](n, b, bstd.UnmarshalString, bstd.UnmarshalFloat32); err != nil {
+        return
+    }
+    if n, object.Equipment, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Durability, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.MaxDurability, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.DecayRate, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.WearRate, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.Decayed, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.Health, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.MaxHealth, err = bstd.UnmarshalFloat32(n, b); err != nil {
+        return
+    }
+    if n, object.Cooldowns, err = bstd.UnmarshalMap[string, int64](n, b, bstd.UnmarshalString, bstd.UnmarshalInt64); err != nil {
+        return
+    }
+    if n, object.RandomHi, err = bstd.UnmarshalUint64(n, b); err != nil {
+        return
+    }
+    if n, object.RandomLo, err = bstd.UnmarshalUint64(n, b); err != nil {
+        return
+    }
+    if n, object.Money, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.Stock, err = bstd.UnmarshalSlice[StockItem](n, b, func (n int, b []byte, s *StockItem) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    if n, object.Owner, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Guests, err = bstd.UnmarshalMap[string, bool](n, b, bstd.UnmarshalString, bstd.UnmarshalBool); err != nil {
+        return
+    }
+    if n, object.HousePrice, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.LockToken, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.KeyToken, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Locked, err = bstd.UnmarshalBool(n, b); err != nil {
+        return
+    }
+    if n, object.Ambience, err = bstd.UnmarshalSlice[Ambience](n, b, func (n int, b []byte, s *Ambience) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    if n, object.Details, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Senses, err = bstd.UnmarshalMap[string, []Description](n, b, bstd.UnmarshalString, func (n int, b []byte) (int, []Description, error) { return bstd.UnmarshalSlice[Description](n, b, func (n int, b []byte, s *Description) (int, error) { return s.UnmarshalPlain(n, b) }) }); err != nil {
+        return
+    }
+    if n, object.Commands, err = bstd.UnmarshalSlice[Command](n, b, func (n int, b []byte, s *Command) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
+    if n, object.CommandHookPriority, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.Terrain, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Movements, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Modified, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.StateVersion, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.PublicKeys, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.DespawnAt, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
+    if n, object.RequiredCommands, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.UsedCommands, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Titles, err = bstd.UnmarshalSlice[string](n, b, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Title, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Nicknames, err = bstd.UnmarshalMap[string, string](n, b, bstd.UnmarshalString, bstd.UnmarshalString); err != nil {
+        return
+    }
+    if n, object.Pronouns, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Speaking, err = bstd.UnmarshalString(n, b); err != nil {
+        return
+    }
+    if n, object.Dialogue, err = bstd.UnmarshalMap[string, DialogueNode](n, b, bstd.UnmarshalString, func (n int, b []byte, s *DialogueNode) (int, error) { return s.UnmarshalPlain(n, b) }); err != nil {
+        return
+    }
     return
 }
 
@@ -998,6 +3250,7 @@ type Event struct {
     Object string
     Call Call
     Key string
+    Priority int64
 }
 
 // Reserved Ids - Event
@@ -1014,6 +3267,7 @@ func (event *Event) size(id uint16) (s int) {
     s += bstd.SizeString(event.Object) + 2
     s += event.Call.size(3)
     s += bstd.SizeString(event.Key) + 2
+    s += bstd.SizeInt64() + 2
 
     if id > 255 {
         s += 5
@@ -1029,6 +3283,7 @@ func (event *Event) SizePlain() (s int) {
     s += bstd.SizeString(event.Object)
     s += event.Call.SizePlain()
     s += bstd.SizeString(event.Key)
+    s += bstd.SizeInt64()
     return
 }
 
@@ -1047,6 +3302,8 @@ func (event *Event) marshal(tn int, b []byte, id uint16) (n int) {
     n = event.Call.marshal(n, b, 3)
     n = bgenimpl.MarshalTag(n, b, bgenimpl.Bytes, 4)
     n = bstd.MarshalString(n, b, event.Key)
+    n = bgenimpl.MarshalTag(n, b, bgenimpl.Fixed64, 5)
+    n = bstd.MarshalInt64(n, b, event.Priority)
 
     n += 2
     b[n-2] = 1
@@ -1061,6 +3318,7 @@ func (event *Event) MarshalPlain(tn int, b []byte) (n int) {
     n = bstd.MarshalString(n, b, event.Object)
     n = event.Call.MarshalPlain(n, b)
     n = bstd.MarshalString(n, b, event.Key)
+    n = bstd.MarshalInt64(n, b, event.Priority)
     return n
 }
 
@@ -1115,6 +3373,17 @@ func (event *Event) unmarshal(tn int, b []byte, r []uint16, id uint16) (n int, e
             return
         }
     }
+    if n, ok, err = bgenimpl.HandleCompatibility(n, b, eventRIds, 5); err != nil {
+        if err == bgenimpl.ErrEof {
+            return n, nil
+        }
+        return
+    }
+    if ok {
+        if n, event.Priority, err = bstd.UnmarshalInt64(n, b); err != nil {
+            return
+        }
+    }
     n += 2
     return
 }
@@ -1134,6 +3403,9 @@ func (event *Event) UnmarshalPlain(tn int, b []byte) (n int, err error) {
     if n, event.Key, err = bstd.UnmarshalString(n, b); err != nil {
         return
     }
+    if n, event.Priority, err = bstd.UnmarshalInt64(n, b); err != nil {
+        return
+    }
     return
 }
 
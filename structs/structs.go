@@ -6,6 +6,8 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/binary"
+	mathrand "math/rand/v2"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,8 +21,54 @@ var (
 	encoding                 = base64.StdEncoding.WithPadding(base64.NoPadding)
 )
 
+var (
+	// Factions are the factions objects can have reputation with, by name.
+	Factions = juicemud.NewSyncMap[string, Faction]()
+)
+
 const (
-	objectIDLen = 16
+	// TrashTag marks objects that are removed when fully decayed.
+	TrashTag = "trash"
+	// NoPvPTag marks rooms where users can't damage other users.
+	NoPvPTag = "noPvP"
+	// NoStealTag marks objects whose content, and rooms where the content of anything in them,
+	// can't be taken by others.
+	NoStealTag = "noSteal"
+	// SanctuaryTag marks rooms where nothing can be damaged.
+	SanctuaryTag = "sanctuary"
+	// BankTag marks objects giving access to vaults.
+	BankTag = "bank"
+	// FurnitureTag marks objects created by the owners of houses, which they can remove again.
+	FurnitureTag = "furniture"
+)
+
+var (
+	// FlagTags are the tags enforced by the engine.
+	FlagTags = []string{NoPvPTag, NoStealTag, SanctuaryTag, BankTag}
+)
+
+const (
+	Hostile  = "hostile"
+	Neutral  = "neutral"
+	Friendly = "friendly"
+)
+
+// Faction defines the reputation thresholds at or below which members of a faction treat
+// others as hostile, and at or above which they treat them as friendly.
+type Faction struct {
+	Hostile  float32
+	Friendly float32
+}
+
+// DefaultFaction is used for factions that haven't been defined.
+var DefaultFaction = Faction{
+	Hostile:  -100,
+	Friendly: 100,
+}
+
+const (
+	objectIDLen  = 16
+	lockTokenLen = 32
 )
 
 type Timestamp uint64
@@ -36,6 +84,21 @@ func NextObjectID() (string, error) {
 	return encoding.EncodeToString(result), nil
 }
 
+// NextLockToken returns a new random token for a lock and its keys. Tokens are never shown to
+// JavaScript, so keys can only be made by the engine.
+func NextLockToken() (string, error) {
+	result := make([]byte, lockTokenLen)
+	if _, err := rand.Read(result); err != nil {
+		return "", juicemud.WithStack(err)
+	}
+	return encoding.EncodeToString(result), nil
+}
+
+// Fits returns whether o is a key to the lock of lock.
+func (o *Object) Fits(lock *Object) bool {
+	return o.KeyToken != "" && o.KeyToken == lock.LockToken
+}
+
 func (o *Object) HasCallback(name string, tag string) bool {
 	tags, found := o.Callbacks[name]
 	if !found {
@@ -59,16 +122,37 @@ func MakeObject(ctx context.Context) (*Object, error) {
 	return object, nil
 }
 
+// CreateKey sets a key that orders events by At, then by descending Priority, then by
+// the order in which the keys were created.
 func (e *Event) CreateKey() {
 	eventCounter := juicemud.Increment(&lastEventCounter)
 	atSize := binary.Size(e.At)
-	k := make([]byte, atSize+binary.Size(eventCounter))
+	prioritySize := binary.Size(e.Priority)
+	k := make([]byte, atSize+prioritySize+binary.Size(eventCounter))
 	binary.BigEndian.PutUint64(k, uint64(e.At))
-	binary.BigEndian.PutUint64(k[atSize:], eventCounter)
+	// Flipping the sign bit makes negative priorities sort before positive ones,
+	// and inverting all bits makes higher priorities sort first.
+	binary.BigEndian.PutUint64(k[atSize:], ^(uint64(e.Priority) ^ (1 << 63)))
+	binary.BigEndian.PutUint64(k[atSize+prioritySize:], eventCounter)
 	e.Key = string(k)
 }
 
+// Check returns whether challenger passes the challenge against target. Challenges with a
+// Faction are failed by challengers hostile to the faction, and challenges with a Skill by
+// challengers failing the skill check.
 func (c *Challenge) Check(challenger *Object, target *Object) bool {
+	return c.Resolve(challenger, target).Succeeded()
+}
+
+// Resolve returns how well challenger does against the challenge against target. Challengers
+// hostile to the Faction of a challenge fail it, without it being critical.
+func (c *Challenge) Resolve(challenger *Object, target *Object) skills.Grade {
+	if c.Faction != "" && challenger.Standing(c.Faction) == Hostile {
+		return skills.Failure
+	}
+	if c.Skill == "" && c.Faction != "" {
+		return skills.Success
+	}
 	return skills.Application{
 		Use: skills.Use{
 			User:  challenger.Id,
@@ -76,9 +160,191 @@ func (c *Challenge) Check(challenger *Object, target *Object) bool {
 			At:    time.Now(),
 		},
 		Target:    target.Id,
-		Level:     challenger.Skills[c.Skill].Practical,
+		Level:     challenger.SkillLevel(c.Skill, time.Now()),
 		Challenge: c.Level,
-	}.Check()
+	}.Resolve()
+}
+
+// Active returns whether e hasn't expired at now.
+func (e *Effect) Active(now time.Time) bool {
+	return e.Expires == 0 || e.Expires > now.UnixNano()
+}
+
+// SkillLevel returns the level of o in skill at now, falling back on related skills if o
+// doesn't know it, including the bonuses of active effects.
+func (o *Object) SkillLevel(skill string, now time.Time) float32 {
+	return skills.EffectiveLevel(skill, func(name string) (float32, bool) {
+		s, found := o.Skills[name]
+		return s.Practical, found
+	}) + o.SkillBonus(skill, now)
+}
+
+// Attribute returns the value of the derived attribute with the given name for o at now, and
+// whether there is such an attribute.
+func (o *Object) Attribute(name string, now time.Time) (float32, bool) {
+	attribute, found := skills.Attributes.GetHas(name)
+	if !found {
+		return 0, false
+	}
+	return attribute.Value(func(skill string) float32 {
+		return o.SkillLevel(skill, now)
+	}), true
+}
+
+// SkillBonus returns the sum of the bonuses to skill from the effects of o active at now.
+func (o *Object) SkillBonus(skill string, now time.Time) float32 {
+	result := float32(0)
+	for i := range o.Effects {
+		if o.Effects[i].Active(now) {
+			result += o.Effects[i].SkillBonuses[skill]
+		}
+	}
+	return result
+}
+
+// OnCooldown returns whether the cooldown with the given name of o is running at now.
+func (o *Object) OnCooldown(name string, now time.Time) bool {
+	return o.Cooldowns[name] > now.UnixNano()
+}
+
+// StartCooldown puts o on the cooldown with the given name for duration from now, and forgets
+// the cooldowns of o that are over.
+func (o *Object) StartCooldown(name string, now time.Time, duration time.Duration) {
+	for key, until := range o.Cooldowns {
+		if until <= now.UnixNano() {
+			delete(o.Cooldowns, key)
+		}
+	}
+	if duration <= 0 {
+		return
+	}
+	if o.Cooldowns == nil {
+		o.Cooldowns = map[string]int64{}
+	}
+	o.Cooldowns[name] = now.Add(duration).UnixNano()
+}
+
+// Seed makes the pseudo-random generator of o produce the sequence given by seed.
+func (o *Object) Seed(seed uint64) {
+	o.RandomHi, o.RandomLo = seed, seed^0x9e3779b97f4a7c15
+}
+
+// Random returns a pseudo-random number in [0, 1) from the generator of o, which is stored
+// with o so its sequence survives reloads. Unseeded generators are seeded randomly.
+func (o *Object) Random() (float64, error) {
+	if o.RandomHi == 0 && o.RandomLo == 0 {
+		o.Seed(mathrand.Uint64())
+	}
+	pcg := mathrand.NewPCG(o.RandomHi, o.RandomLo)
+	result := mathrand.New(pcg).Float64()
+	// PCG only exposes its state as "pcg:" followed by the big endian high and low halves.
+	state, err := pcg.MarshalBinary()
+	if err != nil {
+		return 0, juicemud.WithStack(err)
+	}
+	state = state[len("pcg:"):]
+	o.RandomHi, o.RandomLo = binary.BigEndian.Uint64(state), binary.BigEndian.Uint64(state[8:])
+	return result, nil
+}
+
+// Immobile returns whether any effect of o active at now prevents it from moving.
+func (o *Object) Immobile(now time.Time) bool {
+	for i := range o.Effects {
+		if o.Effects[i].Immobile && o.Effects[i].Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flagged returns whether o has the tag.
+func (o *Object) Flagged(tag string) bool {
+	return slices.Contains(o.Tags, tag)
+}
+
+// Decaying returns whether o loses durability over time, or is trash waiting to be removed.
+func (o *Object) Decaying() bool {
+	return o.DecayRate > 0 && (o.Durability > 0 || slices.Contains(o.Tags, TrashTag))
+}
+
+// Decay reduces the durability of o by DecayRate per hour since it last decayed, and returns
+// whether that broke it.
+func (o *Object) Decay(now time.Time) bool {
+	if o.Decayed == 0 || o.DecayRate <= 0 {
+		o.Decayed = now.UnixNano()
+		return false
+	}
+	hours := float32(now.Sub(time.Unix(0, o.Decayed)).Hours())
+	o.Decayed = now.UnixNano()
+	return o.Damage(o.DecayRate * hours)
+}
+
+// Damage reduces the durability of o by amount, and returns whether that broke it.
+func (o *Object) Damage(amount float32) bool {
+	if o.MaxDurability <= 0 || o.Durability <= 0 {
+		return false
+	}
+	o.Durability = max(0, o.Durability-amount)
+	return o.Durability == 0
+}
+
+// Standing returns whether faction considers o Hostile, Neutral or Friendly.
+func (o *Object) Standing(faction string) string {
+	thresholds, found := Factions.GetHas(faction)
+	if !found {
+		thresholds = DefaultFaction
+	}
+	reputation := o.Reputation[faction]
+	switch {
+	case reputation <= thresholds.Hostile:
+		return Hostile
+	case reputation >= thresholds.Friendly:
+		return Friendly
+	}
+	return Neutral
+}
+
+// Translate replaces the short and long description of d with those of the translation to the
+// first of languages d has one for, if any.
+func (d *Description) Translate(languages []string) {
+	for _, language := range languages {
+		if translation, found := d.Translations[language]; found {
+			if translation.Short != "" {
+				d.Short = translation.Short
+			}
+			if translation.Long != "" {
+				d.Long = translation.Long
+			}
+			return
+		}
+	}
+}
+
+// Translate translates the descriptions of o and its exits to the first of languages they
+// have translations for.
+func (o *Object) Translate(languages []string) {
+	for i := range o.Descriptions {
+		o.Descriptions[i].Translate(languages)
+	}
+	for i := range o.Exits {
+		for j := range o.Exits[i].Descriptions {
+			o.Exits[i].Descriptions[j].Translate(languages)
+		}
+	}
+}
+
+// Personalize replaces the short descriptions of o with the nickname viewer gave it, if any,
+// and adds the title o chose.
+func (o *Object) Personalize(viewer *Object) {
+	nickname, nicknamed := viewer.Nicknames[o.Id]
+	for i := range o.Descriptions {
+		if nicknamed {
+			o.Descriptions[i].Short = nickname
+		}
+		if o.Title != "" {
+			o.Descriptions[i].Short += ", " + o.Title
+		}
+	}
 }
 
 type Descriptions []Description
@@ -109,6 +375,19 @@ func (o Objects) Short() []string {
 	return result
 }
 
+// Detectable returns whether viewer notices o at all, i.e. passes the invisibility challenges of o.
+func (o *Object) Detectable(viewer *Object) bool {
+	if viewer.Id == o.Id {
+		return true
+	}
+	for _, challenge := range o.Invisibility {
+		if !challenge.Check(viewer, o) {
+			return false
+		}
+	}
+	return true
+}
+
 func (o *Object) Inspect(viewer *Object) (*Description, Exits) {
 	desc := Descriptions(o.Descriptions).Detect(o, viewer)
 	exits := Exits{}
@@ -10,6 +10,9 @@ type Location struct {
 func (l *Location) Inspect(viewer *Object) (*Description, Exits, Objects) {
 	siblings := Objects{}
 	for _, cont := range l.Content {
+		if !cont.Detectable(viewer) {
+			continue
+		}
 		if desc, _ := cont.Inspect(viewer); desc != nil {
 			cont.Descriptions = []Description{*desc}
 			siblings = append(siblings, *cont)
@@ -54,3 +57,12 @@ func (n *Neighbourhood) All() iter.Seq2[string, *Object] {
 		}
 	}
 }
+
+// Redact removes the lock and key tokens of all objects in n, so that JavaScript can't copy them.
+func (n *Neighbourhood) Redact() {
+	for _, objects := range []iter.Seq2[string, *Object]{n.Self.All(), n.All()} {
+		for _, obj := range objects {
+			obj.LockToken, obj.KeyToken = "", ""
+		}
+	}
+}